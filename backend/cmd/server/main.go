@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -106,6 +107,18 @@ func main() {
 	}
 	log.Printf("Database connected successfully (type: %s)", dbConfig.Type)
 
+	// 首次创建缺陷工作流表时(升级)，已有项目写入宽松工作流以保持原有的状态流转行为
+	seedDefectWorkflows := !db.Migrator().HasTable(&models.DefectWorkflow{})
+
+	// 缺陷关联表添加外键约束前，清理缺陷或执行结果已被删除的历史关联
+	if db.Migrator().HasTable(&models.DefectCaseLink{}) {
+		if n, err := repositories.NewDefectCaseLinkRepository(db).DeleteOrphans(); err != nil {
+			log.Printf("warning: failed to delete orphan defect case links: %v", err)
+		} else if n > 0 {
+			log.Printf("Deleted %d orphan defect case links", n)
+		}
+	}
+
 	// 自动迁移数据库模型
 	if err := db.AutoMigrate(
 		&models.User{},
@@ -124,11 +137,22 @@ func main() {
 		&models.CaseVersion{},
 		&models.AutoTestCaseVersion{},
 		&models.ExecutionTask{},
-		&models.ExecutionCaseResult{}, // 测试执行结果表
+		&models.ExecutionCaseResult{},    // 测试执行结果表
+		&models.ExecutionRun{},           // 测试执行批次表
+		&models.ExecutionAttempt{},       // 用例执行记录表
+		&models.ExecutionArtifact{},      // 执行产物表
+		&models.ExecutionSchedule{},      // 定时执行计划表
+		&models.EnvironmentProfile{},     // 执行环境表
+		&models.ManualExecutionSession{}, // 手工执行会话表
+		&models.ManualStepResult{},       // 手工执行步骤结果表
 		&models.Defect{},
 		&models.DefectAttachment{},
 		&models.DefectSubject{},
 		&models.DefectPhase{},
+		&models.DefectWorkflow{},
+		&models.DefectHistory{},
+		&models.DefectCaseLink{},
+		&models.DefectSavedFilter{},
 		&models.DefectComment{},
 		&models.CaseReviewItem{},      // T44: 审阅条目表
 		&models.CaseGroup{},           // 用例集表
@@ -167,7 +191,22 @@ func main() {
 	defectAttachmentRepo := repositories.NewDefectAttachmentRepository(db)
 	defectSubjectRepo := repositories.NewDefectSubjectRepository(db)
 	defectPhaseRepo := repositories.NewDefectPhaseRepository(db)
+	defectWorkflowRepo := repositories.NewDefectWorkflowRepository(db)
+	if seedDefectWorkflows {
+		transitions, err := json.Marshal(models.PermissiveDefectTransitions())
+		if err != nil {
+			log.Fatalf("failed to encode permissive defect workflow: %v", err)
+		}
+		seeded, err := defectWorkflowRepo.SeedMissing(string(transitions))
+		if err != nil {
+			log.Fatalf("failed to seed defect workflows: %v", err)
+		}
+		log.Printf("seeded permissive defect workflow for %d existing projects", seeded)
+	}
 	defectCommentRepo := repositories.NewDefectCommentRepository(db)
+	defectHistoryRepo := repositories.NewDefectHistoryRepository(db)
+	defectCaseLinkRepo := repositories.NewDefectCaseLinkRepository(db)
+	defectSavedFilterRepo := repositories.NewDefectSavedFilterRepository(db)
 
 	// 审阅条目相关Repository (T44)
 	reviewItemRepo := repositories.NewReviewItemRepository(db)
@@ -182,13 +221,15 @@ func main() {
 	userDefinedVarRepo := repositories.NewUserDefinedVariableRepository(db)
 
 	authService := services.NewAuthService(userRepo)
-	projectService := services.NewProjectService(projectRepo, memberRepo, userRepo, db)
-	manualCaseService := services.NewManualTestCaseService(manualCaseRepo, projectService)
-	autoCaseService := services.NewAutoTestCaseService(autoCaseRepo, projectService, db, caseGroupRepo)
-	apiCaseService := services.NewApiTestCaseService(apiCaseRepo, projectService)
 	executionTaskRepo := repositories.NewExecutionTaskRepository(db)
 	executionCaseResultRepo := repositories.NewExecutionCaseResultRepository(db)
-	excelService := services.NewExcelService(manualCaseRepo, projectRepo, executionCaseResultRepo)
+	executionRunRepo := repositories.NewExecutionRunRepository(db)
+	executionAttemptRepo := repositories.NewExecutionAttemptRepository(db)
+	executionArtifactRepo := repositories.NewExecutionArtifactRepository(db)
+	executionScheduleRepo := repositories.NewExecutionScheduleRepository(db)
+	environmentProfileRepo := repositories.NewEnvironmentProfileRepository(db)
+	manualExecutionRepo := repositories.NewManualExecutionRepository(db)
+	excelService := services.NewExcelService(manualCaseRepo, projectRepo, executionCaseResultRepo, executionTaskRepo, defectRepo)
 	versionService := services.NewVersionService(db, caseVersionRepo, excelService)
 	reviewService := services.NewReviewService(caseReviewRepo)
 
@@ -213,20 +254,37 @@ func main() {
 	// 用户自定义变量相关Service (需要在executionTaskService之前初始化)
 	userDefinedVarService := services.NewUserDefinedVariableService(userDefinedVarRepo)
 
-	executionTaskService := services.NewExecutionTaskService(executionTaskRepo, projectRepo, executionCaseResultRepo, userRepo, userDefinedVarService)
+	// 脚本执行器(远程Node执行器/playwright-go/原生API/fake)，远程执行器节点池定期探测 /health
+	executorPool := services.NewExecutorPool(services.DefaultExecutorConfig())
+	executorPool.Start()
+	executorRegistry := services.NewDefaultExecutorRegistry(executorPool)
+	executionTaskService := services.NewExecutionTaskService(executionTaskRepo, projectRepo, executionCaseResultRepo, userRepo, executionRunRepo, executionScheduleRepo, executionAttemptRepo, executionArtifactRepo, environmentProfileRepo, caseGroupRepo, userDefinedVarService, executorRegistry, getStorageBasePath())
+	// 恢复服务重启前未完成的执行批次
+	if err := executionTaskService.ResumeRuns(); err != nil {
+		log.Printf("warning: failed to resume execution runs: %v", err)
+	}
+	// 项目服务删除项目前需停止项目中正在执行的批次
+	projectService := services.NewProjectService(projectRepo, memberRepo, userRepo, db, executionTaskService, getStorageBasePath())
+	manualCaseService := services.NewManualTestCaseService(manualCaseRepo, projectService)
+	autoCaseService := services.NewAutoTestCaseService(autoCaseRepo, projectService, db, caseGroupRepo)
+	apiCaseService := services.NewApiTestCaseService(apiCaseRepo, projectService)
 	executionCaseResultService := services.NewExecutionCaseResultService(
 		executionCaseResultRepo,
 		executionTaskRepo,
 		manualCaseRepo,
 		autoCaseRepo,
 		apiCaseRepo,
+		memberRepo,
+		defectCaseLinkRepo,
 	)
 
 	// 缺陷管理相关Service
-	defectService := services.NewDefectService(defectRepo, userRepo)
-	defectAttachmentService := services.NewDefectAttachmentService(defectAttachmentRepo, getStorageBasePath())
-	defectConfigService := services.NewDefectConfigService(defectSubjectRepo, defectPhaseRepo)
+	defectService := services.NewDefectService(defectRepo, userRepo, defectWorkflowRepo, memberRepo, defectHistoryRepo, defectCommentRepo, defectCaseLinkRepo)
+	defectAttachmentService := services.NewDefectAttachmentService(defectAttachmentRepo, executionArtifactRepo, getStorageBasePath())
+	defectConfigService := services.NewDefectConfigService(defectSubjectRepo, defectPhaseRepo, defectWorkflowRepo)
 	defectCommentService := services.NewDefectCommentService(defectCommentRepo, defectRepo)
+	defectLinkService := services.NewDefectLinkService(defectService, defectAttachmentService, executionCaseResultRepo, executionTaskRepo, defectPhaseRepo, defectCaseLinkRepo)
+	defectFilterService := services.NewDefectFilterService(defectSavedFilterRepo)
 
 	// 原始需求文档相关Service (T48)
 	rawDocumentService := services.NewRawDocumentService(rawDocumentRepo, storageDir)
@@ -278,10 +336,12 @@ func main() {
 	aiReportHandler := handlers.NewAIReportHandler(aiReportService)
 
 	// 缺陷管理相关Handler
-	defectHandler := handlers.NewDefectHandler(defectService, projectRepo)
+	defectHandler := handlers.NewDefectHandler(defectService, defectFilterService, projectRepo)
 	defectAttachmentHandler := handlers.NewDefectAttachmentHandler(defectAttachmentService)
 	defectConfigHandler := handlers.NewDefectConfigHandler(defectConfigService)
 	defectCommentHandler := handlers.NewDefectCommentHandler(defectCommentService)
+	defectLinkHandler := handlers.NewDefectLinkHandler(defectService, defectLinkService)
+	defectFilterHandler := handlers.NewDefectFilterHandler(defectFilterService)
 
 	// 原始需求文档相关Handler (T48)
 	rawDocumentHandler := handlers.NewRawDocumentHandler(rawDocumentService)
@@ -296,6 +356,19 @@ func main() {
 	scriptTestService := services.NewScriptTestService(userDefinedVarService)
 	scriptTestHandler := handlers.NewScriptTestHandler(scriptTestService)
 
+	// 执行前变量检查服务和Handler
+	variableLintService := services.NewVariableLintService(executionTaskRepo, executionCaseResultRepo, caseGroupRepo, autoCaseRepo, apiCaseRepo, userDefinedVarService)
+	variableLintHandler := handlers.NewVariableLintHandler(variableLintService)
+
+	// 定时执行计划：启动进程内调度器
+	executionScheduleService := services.NewExecutionScheduleService(executionScheduleRepo, executionTaskRepo, executionRunRepo, executionTaskService)
+	executionScheduleService.Start()
+	executionScheduleHandler := handlers.NewExecutionScheduleHandler(executionScheduleService)
+	manualExecutionService := services.NewManualExecutionService(manualExecutionRepo, executionTaskRepo, executionCaseResultRepo, executionAttemptRepo, executionArtifactRepo, userRepo, getStorageBasePath())
+	manualExecutionHandler := handlers.NewManualExecutionHandler(manualExecutionService)
+	environmentProfileService := services.NewEnvironmentProfileService(environmentProfileRepo)
+	environmentProfileHandler := handlers.NewEnvironmentProfileHandler(environmentProfileService, executionTaskService)
+
 	// 初始化管理员账号
 	if err := authService.InitAdminUsers(); err != nil {
 		log.Printf("warning: failed to init admin users: %v", err)
@@ -409,6 +482,10 @@ func main() {
 
 			// 手工测试用例模版导出（全局路由，不依赖项目）
 			authenticated.GET("/manual-cases/template", versionHandler.ExportTemplate)
+
+			// 已注册执行器的能力和健康状态
+			authenticated.GET("/executors", executionTaskHandler.ListExecutors)
+			authenticated.GET("/executors/pool", executionTaskHandler.GetExecutorPool)
 		}
 
 		// 3. 项目管理路由(PM + PMemb 可查看,PM 可操作)
@@ -559,6 +636,9 @@ func main() {
 			projects.DELETE("/:id/case-groups/:groupId/variables/:varId",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				userDefinedVarHandler.DeleteVariable)
+			projects.GET("/:id/case-groups/:groupId/variable-lint",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				variableLintHandler.LintCaseGroup)
 
 			// 手工测试用例路由 - 允许PM和PM Member访问
 			projects.GET("/:id/manual-cases/metadata",
@@ -831,10 +911,61 @@ func main() {
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				aiReportHandler.DeleteReport)
 
+			// 项目执行器设置
+			projects.GET("/:id/executor",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.GetProjectExecutor)
+			projects.PUT("/:id/executor",
+				middleware.RequireRole(constants.RoleProjectManager),
+				executionTaskHandler.SetProjectExecutor)
+
 			// 测试执行任务路由
 			projects.GET("/:id/execution-tasks",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				executionTaskHandler.GetTasks)
+			projects.GET("/:id/execution-tasks/flaky-cases",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.ListFlakyCases)
+
+			// 定时执行计划路由
+			projects.GET("/:id/execution-schedules",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionScheduleHandler.ListSchedules)
+			projects.GET("/:id/execution-schedules/upcoming",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionScheduleHandler.ListUpcoming)
+			projects.GET("/:id/execution-schedules/history",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionScheduleHandler.ListHistory)
+			projects.POST("/:id/execution-tasks/:task_uuid/schedules",
+				middleware.RequireRole(constants.RoleProjectManager),
+				executionScheduleHandler.CreateSchedule)
+			projects.PUT("/:id/execution-schedules/:schedule_id",
+				middleware.RequireRole(constants.RoleProjectManager),
+				executionScheduleHandler.UpdateSchedule)
+			projects.DELETE("/:id/execution-schedules/:schedule_id",
+				middleware.RequireRole(constants.RoleProjectManager),
+				executionScheduleHandler.DeleteSchedule)
+
+			// 执行环境与矩阵执行路由
+			projects.GET("/:id/env-profiles",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				environmentProfileHandler.ListProfiles)
+			projects.POST("/:id/env-profiles",
+				middleware.RequireRole(constants.RoleProjectManager),
+				environmentProfileHandler.CreateProfile)
+			projects.PUT("/:id/env-profiles/:profile_id",
+				middleware.RequireRole(constants.RoleProjectManager),
+				environmentProfileHandler.UpdateProfile)
+			projects.DELETE("/:id/env-profiles/:profile_id",
+				middleware.RequireRole(constants.RoleProjectManager),
+				environmentProfileHandler.DeleteProfile)
+			projects.POST("/:id/execution-tasks/:task_uuid/matrix-runs",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				environmentProfileHandler.StartMatrixRun)
+			projects.GET("/:id/execution-tasks/:task_uuid/matrix-runs/:matrix_uuid",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				environmentProfileHandler.GetMatrixRun)
 			projects.POST("/:id/execution-tasks",
 				middleware.RequireRole(constants.RoleProjectManager),
 				executionTaskHandler.CreateTask)
@@ -847,9 +978,78 @@ func main() {
 			projects.POST("/:id/execution-tasks/:task_uuid/execute",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				executionTaskHandler.ExecuteTask)
+			// CI流水线触发(X-API-Token认证)，可等待结果并输出JUnit/TAP/JSON
+			projects.POST("/:id/execution-tasks/:task_uuid/ci-runs",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.TriggerCIRun)
 			projects.POST("/:id/execution-tasks/:task_uuid/cases/:case_result_id/execute",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				executionTaskHandler.ExecuteSingleCase)
+			projects.GET("/:id/execution-tasks/:task_uuid/runs",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.ListRuns)
+			projects.GET("/:id/execution-tasks/:task_uuid/runs/compare",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.CompareRuns)
+			projects.GET("/:id/execution-tasks/:task_uuid/runs/:run_uuid",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.GetRun)
+			projects.GET("/:id/execution-tasks/:task_uuid/runs/:run_uuid/report",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.GetRunReport)
+			projects.GET("/:id/execution-tasks/:task_uuid/events",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.ListEvents)
+			projects.GET("/:id/execution-tasks/:task_uuid/events/stream",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.StreamEvents)
+			projects.GET("/:id/execution-tasks/:task_uuid/export/report",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				exportHandler.ExportExecutionReport)
+			projects.GET("/:id/execution-tasks/compare",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.CompareTasks)
+			projects.GET("/:id/execution-tasks/compare/export",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				exportHandler.ExportTaskComparison)
+			projects.POST("/:id/execution-tasks/:task_uuid/runs/:run_uuid/cancel",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.CancelRun)
+			projects.GET("/:id/execution-tasks/:task_uuid/cases/:case_result_id/attempts",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.ListAttempts)
+			projects.GET("/:id/execution-tasks/:task_uuid/cases/:case_result_id/artifacts",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.ListArtifacts)
+			projects.GET("/:id/execution-tasks/:task_uuid/cases/:case_result_id/artifacts/:artifact_id",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				executionTaskHandler.DownloadArtifact)
+
+			// 手工用例引导式执行(逐步记录结果/证据，可暂停后继续)
+			projects.GET("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.GetSession)
+			projects.POST("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/start",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.StartSession)
+			projects.POST("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/pause",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.PauseSession)
+			projects.PUT("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/steps/:step_index",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.UpdateStep)
+			projects.POST("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/steps/:step_index/evidence",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.UploadEvidence)
+			projects.POST("/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/finish",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.FinishSession)
+			projects.GET("/:id/execution-tasks/:task_uuid/manual-effort",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				manualExecutionHandler.GetEffortMetrics)
+			projects.PUT("/:id/execution-tasks/:task_uuid/case-assignments",
+				middleware.RequireRole(constants.RoleProjectManager),
+				executionCaseResultHandler.AssignCases)
 
 			// 执行任务变量路由
 			projects.GET("/:id/execution-tasks/:task_uuid/variables",
@@ -858,6 +1058,9 @@ func main() {
 			projects.PUT("/:id/execution-tasks/:task_uuid/variables",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				userDefinedVarHandler.SaveTaskVariables)
+			projects.GET("/:id/execution-tasks/:task_uuid/variable-lint",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				variableLintHandler.LintTask)
 
 			// 脚本测试路由 - 用于用例详情页面的脚本测试功能
 			projects.POST("/:id/script-test",
@@ -880,6 +1083,15 @@ func main() {
 			projects.GET("/:id/defects/export",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				defectHandler.ExportDefects)
+			projects.GET("/:id/defects/duplicates",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectHandler.GetDuplicateGroups)
+			projects.POST("/:id/defects/merge",
+				middleware.RequireRole(constants.RoleProjectManager),
+				defectHandler.MergeDefects)
+			projects.PUT("/:id/defects/bulk",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectHandler.BulkUpdateDefects)
 			projects.GET("/:id/defects/:defectId",
 				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
 				defectHandler.GetDefect)
@@ -889,6 +1101,23 @@ func main() {
 			projects.DELETE("/:id/defects/:defectId",
 				middleware.RequireRole(constants.RoleProjectManager),
 				defectHandler.DeleteDefect)
+			projects.GET("/:id/defects/:defectId/timeline",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectHandler.GetTimeline)
+
+			// 缺陷与执行结果关联路由
+			projects.POST("/:id/defects/from-result",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectLinkHandler.CreateFromResult)
+			projects.GET("/:id/defects/:defectId/case-links",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectLinkHandler.ListLinks)
+			projects.POST("/:id/defects/:defectId/case-links",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectLinkHandler.LinkCaseResults)
+			projects.DELETE("/:id/defects/:defectId/case-links/:caseResultId",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectLinkHandler.UnlinkCaseResult)
 
 			// 缺陷说明管理路由
 			projects.GET("/:id/defects/:defectId/comments",
@@ -931,6 +1160,28 @@ func main() {
 			projects.DELETE("/:id/defect-phases/:phaseId",
 				middleware.RequireRole(constants.RoleProjectManager),
 				defectConfigHandler.DeletePhase)
+
+			// 缺陷配置管理路由 - 工作流
+			projects.GET("/:id/defect-workflow",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectConfigHandler.GetWorkflow)
+			projects.PUT("/:id/defect-workflow",
+				middleware.RequireRole(constants.RoleProjectManager),
+				defectConfigHandler.UpdateWorkflow)
+
+			// 保存的缺陷筛选路由
+			projects.GET("/:id/defect-filters",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectFilterHandler.List)
+			projects.POST("/:id/defect-filters",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectFilterHandler.Create)
+			projects.PUT("/:id/defect-filters/:filterId",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectFilterHandler.Update)
+			projects.DELETE("/:id/defect-filters/:filterId",
+				middleware.RequireRole(constants.RoleProjectManager, constants.RoleProjectMember),
+				defectFilterHandler.Delete)
 		}
 
 		// 测试执行用例结果路由
@@ -964,6 +1215,8 @@ func main() {
 				defectAttachmentHandler.List)
 			projectDefects.POST("/:defectId/attachments",
 				defectAttachmentHandler.Upload)
+			projectDefects.POST("/:defectId/attachments/from-execution",
+				defectAttachmentHandler.AttachExecutionArtifacts)
 			projectDefects.GET("/:defectId/attachments/:attId",
 				defectAttachmentHandler.Download)
 			projectDefects.DELETE("/:defectId/attachments/:attId",
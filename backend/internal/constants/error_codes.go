@@ -13,6 +13,12 @@ const (
 	ErrFileRequired         = 40007 // 文件未提供
 	ErrFileNotFound         = 40008 // 文件不存在
 
+	// 缺陷工作流错误 (401xx)
+	ErrDefectTransitionNotAllowed   = 40100 // 不允许的缺陷状态流转
+	ErrDefectTransitionFieldMissing = 40101 // 状态流转缺少必填字段
+	ErrDefectTransitionForbidden    = 40102 // 当前角色无权执行该状态流转
+	ErrDefectWorkflowInvalid        = 40103 // 缺陷工作流定义无效
+
 	// 服务端错误 (50xxx)
 	ErrExportFailed        = 50000 // 导出失败
 	ErrExcelGenerateFailed = 50001 // Excel生成失败
@@ -35,6 +41,11 @@ var ErrorMessages = map[int]string{
 	ErrExcelGenerateFailed:  "Excel生成失败",
 	ErrFileStoreFailed:      "文件存储失败",
 	ErrImportFailed:         "导入失败",
+
+	ErrDefectTransitionNotAllowed:   "不允许的缺陷状态流转",
+	ErrDefectTransitionFieldMissing: "状态流转缺少必填字段",
+	ErrDefectTransitionForbidden:    "当前角色无权执行该状态流转",
+	ErrDefectWorkflowInvalid:        "缺陷工作流定义无效",
 }
 
 // GetErrorMessage 获取错误消息
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"webtest/internal/models"
 	"webtest/internal/services"
 	"webtest/internal/utils"
@@ -86,24 +87,36 @@ func (h *ApiTestCaseHandler) CreateCase(c *gin.Context) {
 	userID := userIDVal.(uint)
 
 	var req struct {
-		CaseType   string `json:"case_type"`
-		CaseGroup  string `json:"case_group"`
-		GroupID    int    `json:"group_id"` // 支持通过group_id传入
-		CaseNumber string `json:"case_number"`
-		Screen     string `json:"screen"`
-		URL        string `json:"url"`
-		Method     string `json:"method"`
-		Header     string `json:"header"`
-		Body       string `json:"body"`
-		Response   string `json:"response"`
-		TestResult string `json:"test_result"`
-		Remark     string `json:"remark"`
-		ScriptCode string `json:"script_code"`
+		CaseType    string      `json:"case_type"`
+		CaseGroup   string      `json:"case_group"`
+		GroupID     int         `json:"group_id"` // 支持通过group_id传入
+		CaseNumber  string      `json:"case_number"`
+		Screen      string      `json:"screen"`
+		URL         string      `json:"url"`
+		Method      string      `json:"method"`
+		Header      string      `json:"header"`
+		Body        string      `json:"body"`
+		Response    string      `json:"response"`
+		Assertions  interface{} `json:"assertions"`  // JSON数组或其字符串形式
+		Extractions interface{} `json:"extractions"` // JSON数组或其字符串形式
+		TestResult  string      `json:"test_result"`
+		Remark      string      `json:"remark"`
+		ScriptCode  string      `json:"script_code"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
 		return
 	}
+	assertions, err := services.NormalizeAPIAssertions(req.Assertions)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "断言格式错误: "+err.Error())
+		return
+	}
+	extractions, err := services.NormalizeAPIExtractions(req.Extractions)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "提取规则格式错误: "+err.Error())
+		return
+	}
 
 	// 创建用例数据
 	newCase := &models.ApiTestCase{
@@ -117,6 +130,8 @@ func (h *ApiTestCaseHandler) CreateCase(c *gin.Context) {
 		Header:       req.Header,
 		Body:         req.Body,
 		Response:     req.Response,
+		Assertions:   assertions,
+		Extractions:  extractions,
 		TestResult:   req.TestResult,
 		Remark:       req.Remark,
 		ScriptCode:   req.ScriptCode,
@@ -314,6 +329,10 @@ func (h *ApiTestCaseHandler) UpdateCase(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
 			return
 		}
+		if strings.HasPrefix(err.Error(), "断言格式错误") || strings.HasPrefix(err.Error(), "提取规则格式错误") {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "更新用例失败")
 		return
 	}
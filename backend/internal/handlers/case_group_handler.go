@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
+	"webtest/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -150,6 +151,8 @@ func (h *CaseGroupHandler) UpdateCaseGroup(c *gin.Context) {
 		MetaPort     *string `json:"meta_port"`
 		MetaUser     *string `json:"meta_user"`
 		MetaPassword *string `json:"meta_password"`
+		// 自动执行的浏览器设置，传 {} 清除
+		BrowserOptions *services.BrowserOptions `json:"browser_options"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -192,6 +195,14 @@ func (h *CaseGroupHandler) UpdateCaseGroup(c *gin.Context) {
 	if req.MetaPassword != nil {
 		group.MetaPassword = *req.MetaPassword
 	}
+	if req.BrowserOptions != nil {
+		browserOptions, err := services.EncodeBrowserOptions(req.BrowserOptions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		group.BrowserOptions = browserOptions
+	}
 
 	if err := h.repo.Update(group); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -15,6 +15,7 @@ type DefectAttachmentHandler interface {
 	Upload(c *gin.Context)
 	Download(c *gin.Context)
 	Delete(c *gin.Context)
+	AttachExecutionArtifacts(c *gin.Context)
 }
 
 type defectAttachmentHandler struct {
@@ -141,3 +142,45 @@ func (h *defectAttachmentHandler) Delete(c *gin.Context) {
 
 	utils.ResponseSuccess(c, gin.H{"message": "attachment deleted successfully"})
 }
+
+// AttachExecutionArtifacts 将执行用例结果的产物(截图/trace等)附加到缺陷
+// POST /api/v1/projects/:id/defects/:defectId/attachments/from-execution
+func (h *defectAttachmentHandler) AttachExecutionArtifacts(c *gin.Context) {
+	defectID := c.Param("defectId")
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req struct {
+		CaseResultID uint `json:"case_result_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "case_result_id is required")
+		return
+	}
+
+	attachments, err := h.attachmentService.AttachExecutionArtifacts(defectID, uint(projectID), userID, req.CaseResultID)
+	if err != nil {
+		log.Printf("[Attachment From Artifact Failed] defect_id=%s, case_result_id=%d, error=%v", defectID, req.CaseResultID, err)
+		if err.Error() == "execution artifacts not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return
+		}
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{
+		"attachments": attachments,
+		"total":       len(attachments),
+	})
+}
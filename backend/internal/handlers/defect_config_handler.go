@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"strconv"
 	"webtest/internal/models"
@@ -22,6 +23,9 @@ type DefectConfigHandler interface {
 	CreatePhase(c *gin.Context)
 	UpdatePhase(c *gin.Context)
 	DeletePhase(c *gin.Context)
+	// 工作流管理
+	GetWorkflow(c *gin.Context)
+	UpdateWorkflow(c *gin.Context)
 }
 
 type defectConfigHandler struct {
@@ -250,3 +254,63 @@ func (h *defectConfigHandler) DeletePhase(c *gin.Context) {
 
 	utils.ResponseSuccess(c, gin.H{"message": "phase deleted successfully"})
 }
+
+// ========== 工作流管理 ==========
+
+// GetWorkflow 获取缺陷工作流
+// GET /api/v1/projects/:id/defect-workflow
+func (h *defectConfigHandler) GetWorkflow(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := strconv.ParseUint(projectIDStr, 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	workflow, err := h.configService.GetWorkflow(uint(projectID))
+	if err != nil {
+		log.Printf("[Defect Workflow Get Failed] project_id=%d, error=%v", projectID, err)
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, workflow)
+}
+
+// UpdateWorkflow 更新缺陷工作流(transitions为空时恢复默认工作流)
+// PUT /api/v1/projects/:id/defect-workflow
+func (h *defectConfigHandler) UpdateWorkflow(c *gin.Context) {
+	projectIDStr := c.Param("id")
+	projectID, err := strconv.ParseUint(projectIDStr, 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req models.DefectWorkflowUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	workflow, err := h.configService.UpdateWorkflow(uint(projectID), userID, &req)
+	if err != nil {
+		var wfErr *services.DefectWorkflowError
+		if errors.As(err, &wfErr) {
+			utils.ResponseErrorWithCode(c, 400, wfErr.Code, wfErr.Message)
+			return
+		}
+		log.Printf("[Defect Workflow Update Failed] project_id=%d, error=%v", projectID, err)
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, workflow)
+}
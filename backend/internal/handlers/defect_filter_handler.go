@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"webtest/internal/models"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefectFilterHandler 保存的缺陷筛选处理器接口
+type DefectFilterHandler interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+type defectFilterHandler struct {
+	filterService services.DefectFilterService
+}
+
+// NewDefectFilterHandler 创建保存的缺陷筛选处理器实例
+func NewDefectFilterHandler(filterService services.DefectFilterService) DefectFilterHandler {
+	return &defectFilterHandler{filterService: filterService}
+}
+
+// List 获取可见的筛选(项目共享+本人)
+// GET /api/v1/projects/:id/defect-filters
+func (h *defectFilterHandler) List(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	filters, err := h.filterService.List(uint(projectID), c.GetUint("userID"))
+	if err != nil {
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"items": filters, "total": len(filters)})
+}
+
+// Create 保存筛选
+// POST /api/v1/projects/:id/defect-filters
+func (h *defectFilterHandler) Create(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	var req models.DefectSavedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	filter, err := h.filterService.Create(uint(projectID), c.GetUint("userID"), c.GetString("role"), &req)
+	if err != nil {
+		log.Printf("[Defect Filter Create Failed] project_id=%d, error=%v", projectID, err)
+		respondDefectFilterError(c, err)
+		return
+	}
+
+	utils.ResponseSuccessWithCode(c, 201, filter)
+}
+
+// Update 修改筛选
+// PUT /api/v1/projects/:id/defect-filters/:filterId
+func (h *defectFilterHandler) Update(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+	filterID, err := strconv.ParseUint(c.Param("filterId"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid filter id")
+		return
+	}
+
+	var req models.DefectSavedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	filter, err := h.filterService.Update(uint(projectID), c.GetUint("userID"), c.GetString("role"), uint(filterID), &req)
+	if err != nil {
+		log.Printf("[Defect Filter Update Failed] filter_id=%d, error=%v", filterID, err)
+		respondDefectFilterError(c, err)
+		return
+	}
+
+	utils.ResponseSuccess(c, filter)
+}
+
+// Delete 删除筛选
+// DELETE /api/v1/projects/:id/defect-filters/:filterId
+func (h *defectFilterHandler) Delete(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+	filterID, err := strconv.ParseUint(c.Param("filterId"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid filter id")
+		return
+	}
+
+	if err := h.filterService.Delete(uint(projectID), c.GetUint("userID"), c.GetString("role"), uint(filterID)); err != nil {
+		log.Printf("[Defect Filter Delete Failed] filter_id=%d, error=%v", filterID, err)
+		respondDefectFilterError(c, err)
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"message": "defect filter deleted successfully"})
+}
+
+// respondDefectFilterError 按错误类型返回筛选操作的HTTP状态码
+func respondDefectFilterError(c *gin.Context, err error) {
+	var queryErr *services.DefectQueryError
+	switch {
+	case errors.As(err, &queryErr):
+		utils.ResponseError(c, 400, err.Error())
+	case err.Error() == "defect filter not found":
+		utils.ResponseError(c, 404, err.Error())
+	case err.Error() == "only project managers can manage project filters":
+		utils.ResponseError(c, 403, err.Error())
+	case err.Error() == "defect filter name already exists":
+		utils.ResponseError(c, 409, err.Error())
+	default:
+		utils.ResponseError(c, 500, err.Error())
+	}
+}
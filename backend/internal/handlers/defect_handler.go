@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
+	"webtest/internal/constants"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
 	"webtest/internal/services"
@@ -20,27 +22,33 @@ type DefectHandler interface {
 	CreateDefect(c *gin.Context)
 	GetDefect(c *gin.Context)
 	UpdateDefect(c *gin.Context)
+	BulkUpdateDefects(c *gin.Context)
 	DeleteDefect(c *gin.Context)
 	ExportTemplate(c *gin.Context)
 	ImportDefects(c *gin.Context)
 	ExportDefects(c *gin.Context)
+	GetTimeline(c *gin.Context)
+	GetDuplicateGroups(c *gin.Context)
+	MergeDefects(c *gin.Context)
 }
 
 type defectHandler struct {
 	defectService services.DefectService
+	filterService services.DefectFilterService
 	projectRepo   repositories.ProjectRepository
 }
 
 // NewDefectHandler 创建缺陷处理器实例
-func NewDefectHandler(defectService services.DefectService, projectRepo repositories.ProjectRepository) DefectHandler {
+func NewDefectHandler(defectService services.DefectService, filterService services.DefectFilterService, projectRepo repositories.ProjectRepository) DefectHandler {
 	return &defectHandler{
 		defectService: defectService,
+		filterService: filterService,
 		projectRepo:   projectRepo,
 	}
 }
 
 // GetDefects 获取缺陷列表
-// GET /api/v1/projects/:id/defects
+// GET /api/v1/projects/:id/defects?q=<查询语句>&sort=-created,priority&filter=<保存的筛选ID或名称>
 func (h *defectHandler) GetDefects(c *gin.Context) {
 	projectIDStr := c.Param("id")
 	projectID, err := strconv.ParseUint(projectIDStr, 10, 32)
@@ -50,14 +58,28 @@ func (h *defectHandler) GetDefects(c *gin.Context) {
 	}
 
 	// 获取查询参数
-	status := c.Query("status")
-	keyword := c.Query("keyword")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	size, _ := strconv.Atoi(c.DefaultQuery("size", "50"))
+	params := models.DefectListParams{
+		Status:  c.Query("status"),
+		Keyword: c.Query("keyword"),
+		Query:   c.Query("q"),
+		Sort:    c.Query("sort"),
+		Filter:  c.Query("filter"),
+	}
+	params.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	params.Size, _ = strconv.Atoi(c.DefaultQuery("size", "50"))
+
+	if err := h.filterService.Apply(uint(projectID), c.GetUint("userID"), &params); err != nil {
+		if err.Error() == "defect filter not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return
+		}
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
 
-	result, err := h.defectService.List(uint(projectID), status, keyword, page, size)
+	result, err := h.defectService.List(uint(projectID), params)
 	if err != nil {
-		log.Printf("[Defect List Failed] project_id=%d, error=%v", projectID, err)
+		log.Printf("[Defect List Failed] project_id=%d, query=%q, error=%v", projectID, params.Query, err)
 		utils.ResponseError(c, 400, err.Error())
 		return
 	}
@@ -95,9 +117,16 @@ func (h *defectHandler) CreateDefect(c *gin.Context) {
 		return
 	}
 
+	// 疑似重复只作提示，不阻止创建
+	duplicates, err := h.defectService.FindDuplicates(uint(projectID), defect, models.DefectDuplicateOptions{})
+	if err != nil {
+		log.Printf("[Defect Duplicate Check Failed] defect_id=%s, error=%v", defect.DefectID, err)
+	}
+
 	utils.ResponseSuccessWithCode(c, 201, gin.H{
-		"id":        defect.ID,
-		"defect_id": defect.DefectID,
+		"id":         defect.ID,
+		"defect_id":  defect.DefectID,
+		"duplicates": duplicates,
 	})
 }
 
@@ -149,12 +178,21 @@ func (h *defectHandler) UpdateDefect(c *gin.Context) {
 		return
 	}
 
-	if err := h.defectService.Update(defect.ID, userID, &req); err != nil {
+	if err := h.defectService.Update(defect.ID, userID, &req, defectChangeSource(c)); err != nil {
 		if err.Error() == "defect not found" {
 			utils.ResponseError(c, 404, err.Error())
 			return
 		}
 		log.Printf("[Defect Update Failed] defect_id=%s, user_id=%d, error=%v", defectID, userID, err)
+		var wfErr *services.DefectWorkflowError
+		if errors.As(err, &wfErr) {
+			httpCode := 400
+			if wfErr.Code == constants.ErrDefectTransitionForbidden {
+				httpCode = 403
+			}
+			utils.ResponseErrorWithCode(c, httpCode, wfErr.Code, wfErr.Message)
+			return
+		}
 		utils.ResponseError(c, 400, err.Error())
 		return
 	}
@@ -162,6 +200,163 @@ func (h *defectHandler) UpdateDefect(c *gin.Context) {
 	utils.ResponseSuccess(c, gin.H{"message": "defect updated successfully"})
 }
 
+// GetTimeline 获取缺陷时间线(创建、字段变更、说明)
+// GET /api/v1/projects/:id/defects/:defectId/timeline
+func (h *defectHandler) GetTimeline(c *gin.Context) {
+	defectID := c.Param("defectId")
+
+	defect, err := h.defectService.GetByDefectID(defectID)
+	if err != nil {
+		if err.Error() == "defect not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return
+		}
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	timeline, err := h.defectService.GetTimeline(defect.ID)
+	if err != nil {
+		log.Printf("[Defect Timeline Failed] defect_id=%s, error=%v", defectID, err)
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"items": timeline})
+}
+
+// GetDuplicateGroups 获取未关闭缺陷的疑似重复分组
+// GET /api/v1/projects/:id/defects/duplicates?threshold=0.6&same_case_id=false&same_component=false
+func (h *defectHandler) GetDuplicateGroups(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	var opts models.DefectDuplicateOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	groups, err := h.defectService.ListDuplicateGroups(uint(projectID), opts)
+	if err != nil {
+		log.Printf("[Defect Duplicate Groups Failed] project_id=%d, error=%v", projectID, err)
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"groups": groups, "total": len(groups)})
+}
+
+// MergeDefects 将重复缺陷合并到目标缺陷
+// POST /api/v1/projects/:id/defects/merge
+func (h *defectHandler) MergeDefects(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req models.DefectMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	target, err := h.defectService.Merge(uint(projectID), userID, &req)
+	if err != nil {
+		log.Printf("[Defect Merge Failed] project_id=%d, target=%s, error=%v", projectID, req.TargetDefectID, err)
+		switch err.Error() {
+		case "defect not found":
+			utils.ResponseError(c, 404, err.Error())
+		case "cannot merge a defect into itself":
+			utils.ResponseError(c, 400, err.Error())
+		default:
+			utils.ResponseError(c, 500, err.Error())
+		}
+		return
+	}
+
+	utils.ResponseSuccess(c, target)
+}
+
+// defectChangeSource 根据认证方式识别变更来源：API Token 调用(MCP工具)记为 mcp，登录用户记为 web
+func defectChangeSource(c *gin.Context) string {
+	if c.GetString("authType") == "api_token" {
+		return models.DefectChangeSourceMCP
+	}
+	return models.DefectChangeSourceWeb
+}
+
+// BulkUpdateDefects 批量更新缺陷，变更历史来源记为 bulk
+// PUT /api/v1/projects/:id/defects/bulk
+func (h *defectHandler) BulkUpdateDefects(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req models.DefectBulkUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+	continueOnError := req.ContinueOnError == nil || *req.ContinueOnError
+
+	results := make([]gin.H, 0, len(req.Updates))
+	successCount, failedCount := 0, 0
+	for i := range req.Updates {
+		item := &req.Updates[i]
+		if err := h.bulkUpdateDefect(uint(projectID), userID, item); err != nil {
+			log.Printf("[Defect BulkUpdate Failed] defect_id=%s, user_id=%d, error=%v", item.DefectID, userID, err)
+			failedCount++
+			message := err.Error()
+			var wfErr *services.DefectWorkflowError
+			if errors.As(err, &wfErr) {
+				message = wfErr.Message
+			}
+			results = append(results, gin.H{"index": i, "defect_id": item.DefectID, "status": "failed", "error": message})
+			if !continueOnError {
+				break
+			}
+			continue
+		}
+		successCount++
+		results = append(results, gin.H{"index": i, "defect_id": item.DefectID, "status": "success"})
+	}
+
+	utils.ResponseSuccess(c, gin.H{"success": successCount, "failed": failedCount, "results": results})
+}
+
+// bulkUpdateDefect 更新批量请求中的单个缺陷，缺陷须属于该项目
+func (h *defectHandler) bulkUpdateDefect(projectID uint, userID uint, item *models.DefectBulkUpdateItem) error {
+	defect, err := h.defectService.GetByDefectID(item.DefectID)
+	if err != nil {
+		return err
+	}
+	if defect.ProjectID != projectID {
+		return errors.New("defect not found")
+	}
+	return h.defectService.Update(defect.ID, userID, &item.DefectUpdateRequest, models.DefectChangeSourceBulk)
+}
+
 // DeleteDefect 删除缺陷
 // DELETE /api/v1/projects/:id/defects/:defectId
 func (h *defectHandler) DeleteDefect(c *gin.Context) {
@@ -254,8 +449,19 @@ func (h *defectHandler) ImportDefects(c *gin.Context) {
 	}
 	defer src.Close()
 
+	// 重复处理模式: 空(不检测)/skip/merge
+	opts := models.DefectImportOptions{DuplicateMode: c.PostForm("duplicate_mode")}
+	if !models.IsValidDefectDuplicateMode(opts.DuplicateMode) {
+		utils.ResponseError(c, 400, "invalid duplicate_mode, must be skip or merge")
+		return
+	}
+	if err := c.ShouldBind(&opts.Duplicate); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
 	// 导入缺陷（强制使用XLSX格式）
-	result, err := h.defectService.ImportWithFormat(uint(projectID), userID, src, true)
+	result, err := h.defectService.ImportWithFormat(uint(projectID), userID, src, true, opts)
 	if err != nil {
 		log.Printf("[Defect Import Failed] project_id=%d, user_id=%d, error=%v", projectID, userID, err)
 		utils.ResponseError(c, 400, err.Error())
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+	"webtest/internal/models"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefectLinkHandler 缺陷与执行结果关联处理器接口
+type DefectLinkHandler interface {
+	CreateFromResult(c *gin.Context)
+	ListLinks(c *gin.Context)
+	LinkCaseResults(c *gin.Context)
+	UnlinkCaseResult(c *gin.Context)
+}
+
+type defectLinkHandler struct {
+	defectService services.DefectService
+	linkService   services.DefectLinkService
+}
+
+// NewDefectLinkHandler 创建缺陷关联处理器实例
+func NewDefectLinkHandler(defectService services.DefectService, linkService services.DefectLinkService) DefectLinkHandler {
+	return &defectLinkHandler{
+		defectService: defectService,
+		linkService:   linkService,
+	}
+}
+
+// CreateFromResult 从NG执行结果提交缺陷
+// POST /api/v1/projects/:id/defects/from-result
+func (h *defectLinkHandler) CreateFromResult(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req models.DefectFromResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	defect, err := h.linkService.CreateFromCaseResult(uint(projectID), userID, &req)
+	if err != nil {
+		log.Printf("[Defect From Result Failed] project_id=%d, case_result_id=%d, error=%v", projectID, req.CaseResultID, err)
+		switch err.Error() {
+		case "execution case result not found":
+			utils.ResponseError(c, 404, err.Error())
+		case "only NG results can be filed as defects", "invalid priority value", "invalid severity value":
+			utils.ResponseError(c, 400, err.Error())
+		default:
+			utils.ResponseError(c, 500, err.Error())
+		}
+		return
+	}
+
+	utils.ResponseSuccessWithCode(c, 201, gin.H{
+		"id":        defect.ID,
+		"defect_id": defect.DefectID,
+	})
+}
+
+// ListLinks 获取缺陷关联的执行结果
+// GET /api/v1/projects/:id/defects/:defectId/case-links
+func (h *defectLinkHandler) ListLinks(c *gin.Context) {
+	defect, ok := h.getDefect(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.linkService.ListByDefect(defect.ID)
+	if err != nil {
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"items": links, "total": len(links)})
+}
+
+// LinkCaseResults 将执行结果关联到已有缺陷
+// POST /api/v1/projects/:id/defects/:defectId/case-links
+func (h *defectLinkHandler) LinkCaseResults(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid project id")
+		return
+	}
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ResponseError(c, 401, "unauthorized")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	defect, ok := h.getDefect(c)
+	if !ok {
+		return
+	}
+
+	var req models.DefectCaseLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, 400, "validation failed: "+err.Error())
+		return
+	}
+
+	links, err := h.linkService.LinkCaseResults(uint(projectID), userID, defect, req.CaseResultIDs)
+	if err != nil {
+		log.Printf("[Defect Link Failed] defect_id=%s, error=%v", defect.DefectID, err)
+		if err.Error() == "execution case result not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return
+		}
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"items": links, "total": len(links)})
+}
+
+// UnlinkCaseResult 取消缺陷与执行结果的关联
+// DELETE /api/v1/projects/:id/defects/:defectId/case-links/:caseResultId
+func (h *defectLinkHandler) UnlinkCaseResult(c *gin.Context) {
+	caseResultID, err := strconv.ParseUint(c.Param("caseResultId"), 10, 32)
+	if err != nil {
+		utils.ResponseError(c, 400, "invalid case result id")
+		return
+	}
+
+	defect, ok := h.getDefect(c)
+	if !ok {
+		return
+	}
+
+	if err := h.linkService.UnlinkCaseResult(defect, uint(caseResultID)); err != nil {
+		if err.Error() == "defect case link not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return
+		}
+		log.Printf("[Defect Unlink Failed] defect_id=%s, case_result_id=%d, error=%v", defect.DefectID, caseResultID, err)
+		utils.ResponseError(c, 500, err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, gin.H{"message": "defect case link deleted successfully"})
+}
+
+// getDefect 按显示ID获取缺陷，失败时写入错误响应
+func (h *defectLinkHandler) getDefect(c *gin.Context) (*models.Defect, bool) {
+	defect, err := h.defectService.GetByDefectID(c.Param("defectId"))
+	if err != nil {
+		if err.Error() == "defect not found" {
+			utils.ResponseError(c, 404, err.Error())
+			return nil, false
+		}
+		utils.ResponseError(c, 500, err.Error())
+		return nil, false
+	}
+	return defect, true
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnvironmentProfileHandler 执行环境及矩阵执行处理器
+type EnvironmentProfileHandler struct {
+	service     services.EnvironmentProfileService
+	taskService services.ExecutionTaskService
+}
+
+// NewEnvironmentProfileHandler 创建处理器实例
+func NewEnvironmentProfileHandler(service services.EnvironmentProfileService, taskService services.ExecutionTaskService) *EnvironmentProfileHandler {
+	return &EnvironmentProfileHandler{service: service, taskService: taskService}
+}
+
+// ListProfiles 获取项目的执行环境
+// GET /api/v1/projects/:id/env-profiles
+func (h *EnvironmentProfileHandler) ListProfiles(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+
+	profiles, err := h.service.ListProfiles(uint(projectID))
+	if err != nil {
+		log.Printf("[EnvProfile List Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取执行环境失败")
+		return
+	}
+	utils.SuccessResponse(c, profiles)
+}
+
+// CreateProfile 创建执行环境
+// POST /api/v1/projects/:id/env-profiles
+func (h *EnvironmentProfileHandler) CreateProfile(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	var req services.CreateEnvironmentProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	profile, err := h.service.CreateProfile(uint(projectID), userID.(uint), req)
+	if err != nil {
+		log.Printf("[EnvProfile Create Failed] project_id=%d, error=%v", projectID, err)
+		h.respondError(c, err, "创建执行环境失败")
+		return
+	}
+
+	log.Printf("[EnvProfile Create] user_id=%d, project_id=%d, profile_id=%d", userID, projectID, profile.ID)
+	utils.SuccessResponse(c, profile)
+}
+
+// UpdateProfile 更新执行环境
+// PUT /api/v1/projects/:id/env-profiles/:profile_id
+func (h *EnvironmentProfileHandler) UpdateProfile(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	profileID, err := strconv.ParseUint(c.Param("profile_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的执行环境ID")
+		return
+	}
+
+	var req services.UpdateEnvironmentProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(uint(projectID), uint(profileID), req)
+	if err != nil {
+		log.Printf("[EnvProfile Update Failed] project_id=%d, profile_id=%d, error=%v", projectID, profileID, err)
+		h.respondError(c, err, "更新执行环境失败")
+		return
+	}
+	utils.SuccessResponse(c, profile)
+}
+
+// DeleteProfile 删除执行环境
+// DELETE /api/v1/projects/:id/env-profiles/:profile_id
+func (h *EnvironmentProfileHandler) DeleteProfile(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	profileID, err := strconv.ParseUint(c.Param("profile_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的执行环境ID")
+		return
+	}
+
+	if err := h.service.DeleteProfile(uint(projectID), uint(profileID)); err != nil {
+		log.Printf("[EnvProfile Delete Failed] project_id=%d, profile_id=%d, error=%v", projectID, profileID, err)
+		h.respondError(c, err, "删除执行环境失败")
+		return
+	}
+	utils.MessageResponse(c, http.StatusOK, "删除成功")
+}
+
+// StartMatrixRun 在多个执行环境下并行执行任务
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/matrix-runs
+func (h *EnvironmentProfileHandler) StartMatrixRun(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	userID, _ := c.Get("userID")
+
+	var req services.MatrixRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	runs, err := h.taskService.StartMatrixRun(uint(projectID), userID.(uint), taskUUID, req)
+	if err != nil {
+		log.Printf("[MatrixRun Start Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondError(c, err, "矩阵执行失败")
+		return
+	}
+
+	log.Printf("[MatrixRun Start] user_id=%d, project_id=%d, task_uuid=%s, matrix_uuid=%s", userID, projectID, taskUUID, runs[0].MatrixUUID)
+	utils.ResponseSuccessWithCode(c, http.StatusAccepted, gin.H{
+		"matrix_uuid": runs[0].MatrixUUID,
+		"runs":        runs,
+	})
+}
+
+// GetMatrixRun 获取矩阵执行结果(各环境统计和按用例对齐的结果)
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/matrix-runs/:matrix_uuid
+func (h *EnvironmentProfileHandler) GetMatrixRun(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	matrixUUID := c.Param("matrix_uuid")
+
+	summary, err := h.taskService.GetMatrixRun(uint(projectID), taskUUID, matrixUUID)
+	if err != nil {
+		log.Printf("[MatrixRun Get Failed] project_id=%d, matrix_uuid=%s, error=%v", projectID, matrixUUID, err)
+		h.respondError(c, err, "获取矩阵执行结果失败")
+		return
+	}
+	utils.SuccessResponse(c, summary)
+}
+
+// respondError 将服务层错误映射为HTTP状态码
+func (h *EnvironmentProfileHandler) respondError(c *gin.Context, err error, fallback string) {
+	msg := err.Error()
+	switch msg {
+	case "任务不存在", "执行环境不存在", "矩阵执行不存在":
+		utils.ErrorResponse(c, http.StatusNotFound, msg)
+	case "任务不属于该项目", "执行环境不属于该项目":
+		utils.ErrorResponse(c, http.StatusForbidden, msg)
+	case "执行环境名称已存在", "任务正在执行中":
+		utils.ErrorResponse(c, http.StatusConflict, msg)
+	case "执行环境名称不能为空", "视口宽高需同时设置", "变量名不能为空",
+		"手工测试类型不支持自动执行", "没有可执行的用例":
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
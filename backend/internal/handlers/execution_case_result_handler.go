@@ -51,8 +51,24 @@ func (h *ExecutionCaseResultHandler) GetExecutionCaseResults(c *gin.Context) {
 	userID := userIDVal.(uint)
 	log.Printf("[ExecutionCaseResult Get] userID=%d, taskUUID=%s", userID, taskUUID)
 
-	// 调用服务
-	results, err := h.service.GetCaseResults(taskUUID)
+	// 调用服务(assignee=me 仅返回分配给自己的用例，assignee=none 返回未分配的用例，也可指定成员ID)
+	var results []*models.ExecutionCaseResult
+	var err error
+	switch assignee := c.Query("assignee"); assignee {
+	case "":
+		results, err = h.service.GetCaseResults(taskUUID)
+	case "me":
+		results, err = h.service.GetCaseResultsByAssignee(taskUUID, userID)
+	case "none":
+		results, err = h.service.GetCaseResultsByAssignee(taskUUID, 0)
+	default:
+		assigneeID, parseErr := strconv.ParseUint(assignee, 10, 32)
+		if parseErr != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "无效的负责人")
+			return
+		}
+		results, err = h.service.GetCaseResultsByAssignee(taskUUID, uint(assigneeID))
+	}
 	if err != nil {
 		log.Printf("[ExecutionCaseResult Get] Service error: user_id=%d, task_uuid=%s, error=%v", userID, taskUUID, err)
 		utils.ErrorResponse(c, http.StatusInternalServerError, "获取执行结果失败: "+err.Error())
@@ -147,10 +163,50 @@ func (h *ExecutionCaseResultHandler) GetExecutionStatistics(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[ExecutionStatistics Get] user_id=%d, task_uuid=%s, total=%d", userID, taskUUID, stats["total"])
+	log.Printf("[ExecutionStatistics Get] user_id=%d, task_uuid=%s, total=%d", userID, taskUUID, stats.Total)
 	utils.SuccessResponse(c, stats)
 }
 
+// AssignCases 将用例批量分配给项目成员(按用例ID、用例集或大功能)
+// PUT /api/v1/projects/:id/execution-tasks/:task_uuid/case-assignments
+func (h *ExecutionCaseResultHandler) AssignCases(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	userID, _ := c.Get("userID")
+
+	var req services.AssignCasesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	count, err := h.service.AssignCases(uint(projectID), taskUUID, req)
+	if err != nil {
+		log.Printf("[ExecutionCaseResult Assign Failed] user_id=%v, task_uuid=%s, assignee_id=%d, error=%v", userID, taskUUID, req.AssigneeID, err)
+		switch err.Error() {
+		case "任务不存在":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "任务不属于该项目":
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		case "请指定分配范围", "负责人不是项目成员":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "分配用例失败")
+		}
+		return
+	}
+
+	log.Printf("[ExecutionCaseResult Assign] user_id=%v, task_uuid=%s, assignee_id=%d, count=%d", userID, taskUUID, req.AssigneeID, count)
+	utils.SuccessResponse(c, map[string]interface{}{
+		"assignee_id": req.AssigneeID,
+		"count":       count,
+	})
+}
+
 // InitExecutionResults 初始化任务的执行结果
 // POST /api/v1/execution-tasks/:taskUuid/case-results/init
 func (h *ExecutionCaseResultHandler) InitExecutionResults(c *gin.Context) {
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExecutionScheduleHandler 定时执行计划处理器
+type ExecutionScheduleHandler struct {
+	service services.ExecutionScheduleService
+}
+
+// NewExecutionScheduleHandler 创建处理器实例
+func NewExecutionScheduleHandler(service services.ExecutionScheduleService) *ExecutionScheduleHandler {
+	return &ExecutionScheduleHandler{service: service}
+}
+
+// ListSchedules 获取项目的定时执行计划
+// GET /api/v1/projects/:id/execution-schedules
+func (h *ExecutionScheduleHandler) ListSchedules(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(uint(projectID))
+	if err != nil {
+		log.Printf("[ExecutionSchedule List Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取执行计划失败")
+		return
+	}
+	utils.SuccessResponse(c, schedules)
+}
+
+// CreateSchedule 为执行任务创建定时执行计划
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/schedules
+func (h *ExecutionScheduleHandler) CreateSchedule(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	userID, _ := c.Get("userID")
+
+	var req services.CreateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(uint(projectID), userID.(uint), taskUUID, req)
+	if err != nil {
+		log.Printf("[ExecutionSchedule Create Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondError(c, err, "创建执行计划失败")
+		return
+	}
+
+	log.Printf("[ExecutionSchedule Create] user_id=%d, project_id=%d, task_uuid=%s, schedule_id=%d", userID, projectID, taskUUID, schedule.ID)
+	utils.SuccessResponse(c, schedule)
+}
+
+// UpdateSchedule 更新定时执行计划
+// PUT /api/v1/projects/:id/execution-schedules/:schedule_id
+func (h *ExecutionScheduleHandler) UpdateSchedule(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	scheduleID, err := strconv.ParseUint(c.Param("schedule_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的执行计划ID")
+		return
+	}
+
+	var req services.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	schedule, err := h.service.UpdateSchedule(uint(projectID), uint(scheduleID), req)
+	if err != nil {
+		log.Printf("[ExecutionSchedule Update Failed] project_id=%d, schedule_id=%d, error=%v", projectID, scheduleID, err)
+		h.respondError(c, err, "更新执行计划失败")
+		return
+	}
+	utils.SuccessResponse(c, schedule)
+}
+
+// DeleteSchedule 删除定时执行计划
+// DELETE /api/v1/projects/:id/execution-schedules/:schedule_id
+func (h *ExecutionScheduleHandler) DeleteSchedule(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	scheduleID, err := strconv.ParseUint(c.Param("schedule_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的执行计划ID")
+		return
+	}
+
+	if err := h.service.DeleteSchedule(uint(projectID), uint(scheduleID)); err != nil {
+		log.Printf("[ExecutionSchedule Delete Failed] project_id=%d, schedule_id=%d, error=%v", projectID, scheduleID, err)
+		h.respondError(c, err, "删除执行计划失败")
+		return
+	}
+	utils.MessageResponse(c, http.StatusOK, "删除成功")
+}
+
+// ListUpcoming 获取即将触发的定时执行
+// GET /api/v1/projects/:id/execution-schedules/upcoming?limit=20
+func (h *ExecutionScheduleHandler) ListUpcoming(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	upcoming, err := h.service.ListUpcoming(uint(projectID), limit)
+	if err != nil {
+		log.Printf("[ExecutionSchedule Upcoming Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取即将执行的计划失败")
+		return
+	}
+	utils.SuccessResponse(c, upcoming)
+}
+
+// ListHistory 获取定时触发的历史执行批次
+// GET /api/v1/projects/:id/execution-schedules/history?limit=20
+func (h *ExecutionScheduleHandler) ListHistory(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	runs, err := h.service.ListHistory(uint(projectID), limit)
+	if err != nil {
+		log.Printf("[ExecutionSchedule History Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取定时执行记录失败")
+		return
+	}
+	utils.SuccessResponse(c, runs)
+}
+
+// respondError 将服务层错误映射为HTTP状态码
+func (h *ExecutionScheduleHandler) respondError(c *gin.Context, err error, fallback string) {
+	msg := err.Error()
+	switch {
+	case msg == "任务不存在" || msg == "执行计划不存在":
+		utils.ErrorResponse(c, http.StatusNotFound, msg)
+	case msg == "任务不属于该项目" || msg == "执行计划不属于该项目":
+		utils.ErrorResponse(c, http.StatusForbidden, msg)
+	case msg == "手工测试类型不支持定时执行" || strings.HasPrefix(msg, "Cron表达式格式错误"):
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
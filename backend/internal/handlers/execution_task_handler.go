@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"webtest/internal/services"
 	"webtest/internal/utils"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
@@ -86,6 +91,10 @@ func (h *ExecutionTaskHandler) CreateTask(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusConflict, err.Error())
 			return
 		}
+		if err.Error() == "并发数超过服务器上限" || isBrowserOptionsError(err) {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "创建任务失败")
 		return
 	}
@@ -151,7 +160,7 @@ func (h *ExecutionTaskHandler) UpdateTask(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusConflict, err.Error())
 			return
 		}
-		if err.Error() == "结束日期不能早于开始日期" {
+		if err.Error() == "结束日期不能早于开始日期" || err.Error() == "并发数超过服务器上限" || isBrowserOptionsError(err) {
 			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -209,7 +218,7 @@ func (h *ExecutionTaskHandler) DeleteTask(c *gin.Context) {
 	utils.MessageResponse(c, http.StatusOK, "任务已删除")
 }
 
-// ExecuteTask 执行测试任务
+// ExecuteTask 创建后台执行批次
 // POST /api/v1/projects/:id/execution-tasks/:task_uuid/execute
 func (h *ExecutionTaskHandler) ExecuteTask(c *gin.Context) {
 	// 获取项目ID
@@ -235,8 +244,8 @@ func (h *ExecutionTaskHandler) ExecuteTask(c *gin.Context) {
 	}
 	userID := userIDVal.(uint)
 
-	// 调用服务执行
-	result, err := h.service.ExecuteTask(uint(projectID), userID, taskUUID)
+	// 调用服务创建执行批次
+	run, err := h.service.ExecuteTask(uint(projectID), userID, taskUUID)
 	if err != nil {
 		log.Printf("[ExecutionTask Execute Failed] user_id=%d, project_id=%d, task_uuid=%s, error=%v",
 			userID, projectID, taskUUID, err)
@@ -245,6 +254,14 @@ func (h *ExecutionTaskHandler) ExecuteTask(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 			return
 		}
+		if err.Error() == "任务不属于该项目" {
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+			return
+		}
+		if err.Error() == "任务正在执行中" {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
 		if err.Error() == "手工测试类型不支持自动执行" {
 			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
@@ -257,9 +274,138 @@ func (h *ExecutionTaskHandler) ExecuteTask(c *gin.Context) {
 		return
 	}
 
-	log.Printf("[ExecutionTask Execute] user_id=%d, project_id=%d, task_uuid=%s, total=%d, ok=%d, ng=%d",
-		userID, projectID, taskUUID, result.Total, result.OKCount, result.NGCount)
-	utils.SuccessResponse(c, result)
+	log.Printf("[ExecutionTask Execute] user_id=%d, project_id=%d, task_uuid=%s, run_uuid=%s, total=%d",
+		userID, projectID, taskUUID, run.RunUUID, run.Total)
+	utils.ResponseSuccessWithCode(c, http.StatusAccepted, run)
+}
+
+// defaultCIWaitTimeout CI触发等待模式的默认超时
+const defaultCIWaitTimeout = 600 * time.Second
+
+// TriggerCIRun CI流水线触发任务执行(支持X-API-Token认证)
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/ci-runs?format=json|junit|tap
+// wait=false 时立即返回批次(202)；wait=true 时等待批次结束后按 format 返回结果报告，超时仍返回批次(202)
+func (h *ExecutionTaskHandler) TriggerCIRun(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	var req services.CITriggerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	run, err := h.service.TriggerCIRun(uint(projectID), userID, taskUUID, req)
+	if err != nil {
+		log.Printf("[ExecutionTask CITrigger Failed] user_id=%d, project_id=%d, task_uuid=%s, error=%v", userID, projectID, taskUUID, err)
+		switch err.Error() {
+		case "任务不存在":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case "任务不属于该项目":
+			utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+		case "任务正在执行中":
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		case "手工测试类型不支持自动执行", "没有可执行的用例", "变量名不能为空":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "执行任务失败")
+		}
+		return
+	}
+	log.Printf("[ExecutionTask CITrigger] user_id=%d, project_id=%d, task_uuid=%s, run_uuid=%s, version=%s, env=%s, wait=%v",
+		userID, projectID, taskUUID, run.RunUUID, req.TestVersion, req.TestEnv, req.Wait)
+
+	if !req.Wait {
+		utils.ResponseSuccessWithCode(c, http.StatusAccepted, run)
+		return
+	}
+
+	timeout := defaultCIWaitTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+	h.waitAndWriteReport(c, uint(projectID), taskUUID, run.RunUUID, timeout)
+}
+
+// GetRunReport 获取执行批次的结果报告
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/runs/:run_uuid/report?format=json|junit|tap&wait=秒数
+// wait>0 时等待批次结束(最长wait秒)再生成报告，超时仍返回批次(202)
+func (h *ExecutionTaskHandler) GetRunReport(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	runUUID := c.Param("run_uuid")
+
+	waitSeconds, _ := strconv.Atoi(c.DefaultQuery("wait", "0"))
+	if waitSeconds > 3600 {
+		waitSeconds = 3600
+	}
+	if waitSeconds > 0 {
+		h.waitAndWriteReport(c, uint(projectID), taskUUID, runUUID, time.Duration(waitSeconds)*time.Second)
+		return
+	}
+	h.writeRunReport(c, uint(projectID), taskUUID, runUUID)
+}
+
+// waitAndWriteReport 等待批次结束后输出报告，超时返回批次当前状态
+func (h *ExecutionTaskHandler) waitAndWriteReport(c *gin.Context, projectID uint, taskUUID string, runUUID string, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	run, err := h.service.WaitRun(ctx, projectID, taskUUID, runUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask WaitRun Failed] project_id=%d, run_uuid=%s, error=%v", projectID, runUUID, err)
+		h.respondRunError(c, err, "等待执行批次失败")
+		return
+	}
+	if run.IsActive() {
+		utils.ResponseSuccessWithCode(c, http.StatusAccepted, run)
+		return
+	}
+	h.writeRunReport(c, projectID, taskUUID, runUUID)
+}
+
+// writeRunReport 按 format 参数输出JSON/JUnit XML/TAP格式的结果报告
+func (h *ExecutionTaskHandler) writeRunReport(c *gin.Context, projectID uint, taskUUID string, runUUID string) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "junit" && format != "tap" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "不支持的报告格式")
+		return
+	}
+
+	report, err := h.service.GetRunReport(projectID, taskUUID, runUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask RunReport Failed] project_id=%d, run_uuid=%s, error=%v", projectID, runUUID, err)
+		h.respondRunError(c, err, "生成执行报告失败")
+		return
+	}
+
+	switch format {
+	case "junit":
+		data, err := services.RenderJUnitReport(report)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "生成执行报告失败")
+			return
+		}
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+	case "tap":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(services.RenderTAPReport(report)))
+	default:
+		utils.SuccessResponse(c, report)
+	}
 }
 
 // ExecuteSingleCase 执行单条测试用例
@@ -306,7 +452,8 @@ func (h *ExecutionTaskHandler) ExecuteSingleCase(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
 			return
 		}
-		if err.Error() == "手工测试类型不支持自动执行" || err.Error() == "用例没有脚本代码，无法执行" {
+		if err.Error() == "手工测试类型不支持自动执行" || err.Error() == "用例没有脚本代码，无法执行" ||
+			strings.HasPrefix(err.Error(), "用例引用了未定义的变量") {
 			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -318,3 +465,348 @@ func (h *ExecutionTaskHandler) ExecuteSingleCase(c *gin.Context) {
 		userID, projectID, taskUUID, caseResultID, result.OKCount)
 	utils.SuccessResponse(c, result)
 }
+
+// ListRuns 获取任务的执行批次列表
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/runs
+func (h *ExecutionTaskHandler) ListRuns(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+
+	runs, err := h.service.ListRuns(uint(projectID), taskUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask ListRuns Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondRunError(c, err, "获取执行批次失败")
+		return
+	}
+	utils.SuccessResponse(c, runs)
+}
+
+// GetRun 获取执行批次进度
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/runs/:run_uuid
+func (h *ExecutionTaskHandler) GetRun(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	runUUID := c.Param("run_uuid")
+
+	detail, err := h.service.GetRun(uint(projectID), taskUUID, runUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask GetRun Failed] project_id=%d, task_uuid=%s, run_uuid=%s, error=%v", projectID, taskUUID, runUUID, err)
+		h.respondRunError(c, err, "获取执行批次失败")
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// CancelRun 取消执行批次
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/runs/:run_uuid/cancel
+func (h *ExecutionTaskHandler) CancelRun(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	runUUID := c.Param("run_uuid")
+
+	userIDVal, exists := c.Get("userID")
+	if !exists {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "未授权")
+		return
+	}
+	userID := userIDVal.(uint)
+
+	run, err := h.service.CancelRun(uint(projectID), userID, taskUUID, runUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask CancelRun Failed] user_id=%d, project_id=%d, run_uuid=%s, error=%v", userID, projectID, runUUID, err)
+		h.respondRunError(c, err, "取消执行批次失败")
+		return
+	}
+
+	log.Printf("[ExecutionTask CancelRun] user_id=%d, project_id=%d, run_uuid=%s, status=%s", userID, projectID, runUUID, run.Status)
+	utils.SuccessResponse(c, run)
+}
+
+// ListAttempts 获取用例结果的执行记录
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/attempts
+func (h *ExecutionTaskHandler) ListAttempts(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	caseResultID, err := strconv.ParseUint(c.Param("case_result_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的用例ID")
+		return
+	}
+
+	attempts, err := h.service.ListAttempts(uint(projectID), taskUUID, uint(caseResultID))
+	if err != nil {
+		log.Printf("[ExecutionTask ListAttempts Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		h.respondRunError(c, err, "获取执行记录失败")
+		return
+	}
+	utils.SuccessResponse(c, attempts)
+}
+
+// ListFlakyCases 获取项目的不稳定用例排行(重试后才通过的用例)
+// GET /api/v1/projects/:id/execution-tasks/flaky-cases?limit=20
+func (h *ExecutionTaskHandler) ListFlakyCases(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	reports, err := h.service.ListFlakyCases(uint(projectID), limit)
+	if err != nil {
+		log.Printf("[ExecutionTask ListFlakyCases Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取不稳定用例失败")
+		return
+	}
+	utils.SuccessResponse(c, reports)
+}
+
+// ListArtifacts 获取用例结果的执行产物(截图/trace/视频/控制台日志)
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/artifacts
+func (h *ExecutionTaskHandler) ListArtifacts(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	caseResultID, err := strconv.ParseUint(c.Param("case_result_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的用例ID")
+		return
+	}
+
+	artifacts, err := h.service.ListArtifacts(uint(projectID), taskUUID, uint(caseResultID))
+	if err != nil {
+		log.Printf("[ExecutionTask ListArtifacts Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		h.respondRunError(c, err, "获取执行产物失败")
+		return
+	}
+	utils.SuccessResponse(c, artifacts)
+}
+
+// DownloadArtifact 下载执行产物文件
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/artifacts/:artifact_id
+func (h *ExecutionTaskHandler) DownloadArtifact(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	caseResultID, err := strconv.ParseUint(c.Param("case_result_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的用例ID")
+		return
+	}
+	artifactID, err := strconv.ParseUint(c.Param("artifact_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的产物ID")
+		return
+	}
+
+	artifact, file, err := h.service.OpenArtifact(uint(projectID), taskUUID, uint(caseResultID), uint(artifactID))
+	if err != nil {
+		log.Printf("[ExecutionTask DownloadArtifact Failed] project_id=%d, case_result_id=%d, artifact_id=%d, error=%v", projectID, caseResultID, artifactID, err)
+		if err.Error() == "产物不存在" || err.Error() == "产物文件不存在" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		h.respondRunError(c, err, "下载执行产物失败")
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", "attachment; filename="+artifact.FileName)
+	c.DataFromReader(http.StatusOK, artifact.FileSize, artifact.MimeType, file, nil)
+}
+
+// CompareRuns 对比同一任务的两个执行批次
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/runs/compare?base=xxx&target=yyy
+func (h *ExecutionTaskHandler) CompareRuns(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	baseRunUUID := c.Query("base")
+	targetRunUUID := c.Query("target")
+	if baseRunUUID == "" || targetRunUUID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "base和target参数不能为空")
+		return
+	}
+
+	comparison, err := h.service.CompareRuns(uint(projectID), taskUUID, baseRunUUID, targetRunUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask CompareRuns Failed] project_id=%d, task_uuid=%s, base=%s, target=%s, error=%v", projectID, taskUUID, baseRunUUID, targetRunUUID, err)
+		h.respondRunError(c, err, "对比执行批次失败")
+		return
+	}
+	utils.SuccessResponse(c, comparison)
+}
+
+// CompareTasks 对比两个执行任务的用例结果(按CaseID匹配)
+// GET /api/v1/projects/:id/execution-tasks/compare?base=xxx&target=yyy&format=json|markdown
+func (h *ExecutionTaskHandler) CompareTasks(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	baseTaskUUID := c.Query("base")
+	targetTaskUUID := c.Query("target")
+	if baseTaskUUID == "" || targetTaskUUID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "base和target参数不能为空")
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "不支持的报告格式")
+		return
+	}
+
+	comparison, err := h.service.CompareTasks(uint(projectID), baseTaskUUID, targetTaskUUID)
+	if err != nil {
+		log.Printf("[ExecutionTask CompareTasks Failed] project_id=%d, base=%s, target=%s, error=%v", projectID, baseTaskUUID, targetTaskUUID, err)
+		h.respondRunError(c, err, "对比执行任务失败")
+		return
+	}
+
+	if format == "markdown" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(services.RenderComparisonMarkdown(comparison)))
+		return
+	}
+	utils.SuccessResponse(c, comparison)
+}
+
+// eventHeartbeatInterval SSE连接的心跳间隔，防止代理因空闲断开连接
+const eventHeartbeatInterval = 15 * time.Second
+
+// StreamEvents 以Server-Sent Events推送执行任务的进度事件
+// 同一任务的所有观察者收到相同的事件序列；重连时通过Last-Event-ID或since补发缓冲中的事件
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/events/stream?since=0
+func (h *ExecutionTaskHandler) StreamEvents(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+	since := eventCursor(c)
+
+	backlog, events, unsubscribe, err := h.service.SubscribeEvents(uint(projectID), taskUUID, since)
+	if err != nil {
+		log.Printf("[ExecutionTask StreamEvents Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondRunError(c, err, "订阅执行进度失败")
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	for _, ev := range backlog {
+		writeExecutionEvent(c, ev)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return false
+			}
+			writeExecutionEvent(c, ev)
+			return true
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// ListEvents 获取执行任务序号大于since的进度事件，供不支持SSE的客户端轮询
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/events?since=0
+func (h *ExecutionTaskHandler) ListEvents(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+
+	events, err := h.service.ListEvents(uint(projectID), taskUUID, eventCursor(c))
+	if err != nil {
+		log.Printf("[ExecutionTask ListEvents Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondRunError(c, err, "获取执行进度失败")
+		return
+	}
+	utils.SuccessResponse(c, events)
+}
+
+// eventCursor 从Last-Event-ID头或since参数读取已收到的最后事件序号
+func eventCursor(c *gin.Context) int64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("since")
+	}
+	since, _ := strconv.ParseInt(value, 10, 64)
+	return since
+}
+
+// writeExecutionEvent 写出一条SSE事件，事件名为事件类型，id为任务内序号
+func writeExecutionEvent(c *gin.Context, ev services.ExecutionEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(ev.Seq, 10),
+		Event: ev.Type,
+		Data:  ev,
+	})
+}
+
+// isBrowserOptionsError 浏览器设置校验错误
+func isBrowserOptionsError(err error) bool {
+	switch err.Error() {
+	case "不支持的浏览器类型", "视口宽高需同时设置", "storageState必须是JSON对象", "请求头名称不能为空":
+		return true
+	}
+	return false
+}
+
+// respondRunError 将执行批次相关错误映射为HTTP状态码
+func (h *ExecutionTaskHandler) respondRunError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "任务不存在", "执行批次不存在", "用例不存在":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "任务不属于该项目":
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case "用例不属于该任务", "不能对比同一个任务", "执行类型不一致，无法对比":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case "执行批次已结束", "执行批次不是任务的最近一次执行":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
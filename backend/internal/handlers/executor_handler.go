@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListExecutors 获取已注册执行器的能力和健康状态
+// GET /api/v1/executors
+func (h *ExecutionTaskHandler) ListExecutors(c *gin.Context) {
+	utils.SuccessResponse(c, h.service.ListExecutors(c.Request.Context()))
+}
+
+// GetExecutorPool 获取远程执行器节点池的各节点状态和吞吐量
+// GET /api/v1/executors/pool
+func (h *ExecutionTaskHandler) GetExecutorPool(c *gin.Context) {
+	status, err := h.service.GetExecutorPool()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, status)
+}
+
+// GetProjectExecutor 获取项目的执行器设置
+// GET /api/v1/projects/:id/executor
+func (h *ExecutionTaskHandler) GetProjectExecutor(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+
+	selection, err := h.service.GetProjectExecutor(uint(projectID))
+	if err != nil {
+		if err.Error() == "项目不存在" {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("[ExecutionTask GetProjectExecutor Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "获取项目执行器失败")
+		return
+	}
+	utils.SuccessResponse(c, selection)
+}
+
+// SetProjectExecutor 设置项目的执行器(script_executor 为空时恢复默认)
+// PUT /api/v1/projects/:id/executor
+func (h *ExecutionTaskHandler) SetProjectExecutor(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+
+	var req services.SetProjectExecutorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[ExecutionTask SetProjectExecutor Bind Failed] project_id=%d, error=%v", projectID, err)
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	selection, err := h.service.SetProjectExecutor(uint(projectID), req)
+	if err != nil {
+		switch err.Error() {
+		case "执行器不存在":
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		case "项目不存在":
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		default:
+			log.Printf("[ExecutionTask SetProjectExecutor Failed] project_id=%d, error=%v", projectID, err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "设置项目执行器失败")
+		}
+		return
+	}
+	utils.SuccessResponse(c, selection)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"webtest/internal/constants"
 	"webtest/internal/services"
@@ -113,3 +115,81 @@ func (h *ExportHandler) ExportCases(c *gin.Context) {
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", fileData)
 }
+
+// ExportExecutionReport 导出执行任务结果报告
+// @Summary 导出执行任务结果报告(概要/用例集统计/NG一览/用例明细)
+// @Tags Export
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path int true "项目ID"
+// @Param task_uuid path string true "执行任务UUID"
+// @Success 200 {file} xlsx "执行报告文件"
+// @Router /api/v1/projects/:id/execution-tasks/:task_uuid/export/report [get]
+func (h *ExportHandler) ExportExecutionReport(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": constants.GetErrorMessage(constants.ErrInvalidInput)})
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+
+	fileData, filename, err := h.excelService.ExportExecutionReport(uint(projectID), taskUUID)
+	if err != nil {
+		switch err.Error() {
+		case "任务不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "任务不属于该项目":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[ExecutionReport Export Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": constants.GetErrorMessage(constants.ErrExportFailed)})
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", fileData)
+}
+
+// ExportTaskComparison 导出两个执行任务的对比结果
+// @Summary 导出执行任务对比结果
+// @Tags Export
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param id path int true "项目ID"
+// @Param base query string true "基准任务UUID"
+// @Param target query string true "目标任务UUID"
+// @Success 200 {file} xlsx "对比结果文件"
+// @Router /api/v1/projects/:id/execution-tasks/compare/export [get]
+func (h *ExportHandler) ExportTaskComparison(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": constants.GetErrorMessage(constants.ErrInvalidInput)})
+		return
+	}
+	baseTaskUUID := c.Query("base")
+	targetTaskUUID := c.Query("target")
+	if baseTaskUUID == "" || targetTaskUUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "base和target参数不能为空"})
+		return
+	}
+
+	fileData, filename, err := h.excelService.ExportTaskComparison(uint(projectID), baseTaskUUID, targetTaskUUID)
+	if err != nil {
+		switch err.Error() {
+		case "任务不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "任务不属于该项目":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "不能对比同一个任务", "执行类型不一致，无法对比":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[TaskComparison Export Failed] project_id=%d, base=%s, target=%s, error=%v", projectID, baseTaskUUID, targetTaskUUID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": constants.GetErrorMessage(constants.ErrExportFailed)})
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", fileData)
+}
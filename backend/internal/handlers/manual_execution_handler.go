@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ManualExecutionHandler 手工用例引导式执行处理器
+type ManualExecutionHandler struct {
+	service services.ManualExecutionService
+}
+
+// NewManualExecutionHandler 创建处理器实例
+func NewManualExecutionHandler(service services.ManualExecutionService) *ManualExecutionHandler {
+	return &ManualExecutionHandler{service: service}
+}
+
+// manualCaseParams 解析项目ID、任务UUID和用例结果ID
+func manualCaseParams(c *gin.Context) (uint, string, uint, bool) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return 0, "", 0, false
+	}
+	caseResultID, err := strconv.ParseUint(c.Param("case_result_id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的用例ID")
+		return 0, "", 0, false
+	}
+	return uint(projectID), c.Param("task_uuid"), uint(caseResultID), true
+}
+
+// GetSession 获取用例最近一次的手工执行会话
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session
+func (h *ManualExecutionHandler) GetSession(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.service.GetSession(projectID, taskUUID, caseResultID)
+	if err != nil {
+		log.Printf("[ManualExecution GetSession Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		respondManualError(c, err, "获取执行会话失败")
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// StartSession 开始或恢复手工执行
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/start
+func (h *ManualExecutionHandler) StartSession(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	detail, err := h.service.StartSession(projectID, userID.(uint), taskUUID, caseResultID)
+	if err != nil {
+		log.Printf("[ManualExecution Start Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		respondManualError(c, err, "开始执行失败")
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// PauseSession 暂停手工执行
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/pause
+func (h *ManualExecutionHandler) PauseSession(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	detail, err := h.service.PauseSession(projectID, userID.(uint), taskUUID, caseResultID)
+	if err != nil {
+		log.Printf("[ManualExecution Pause Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		respondManualError(c, err, "暂停执行失败")
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// UpdateStep 记录步骤结果
+// PUT /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/steps/:step_index
+func (h *ManualExecutionHandler) UpdateStep(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+	stepIndex, err := strconv.Atoi(c.Param("step_index"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的步骤序号")
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	var req services.UpdateManualStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	step, err := h.service.UpdateStep(projectID, userID.(uint), taskUUID, caseResultID, stepIndex, req)
+	if err != nil {
+		log.Printf("[ManualExecution UpdateStep Failed] case_result_id=%d, step=%d, error=%v", caseResultID, stepIndex, err)
+		respondManualError(c, err, "记录步骤结果失败")
+		return
+	}
+	utils.SuccessResponse(c, step)
+}
+
+// UploadEvidence 上传步骤证据(multipart file)
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/steps/:step_index/evidence
+func (h *ManualExecutionHandler) UploadEvidence(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+	stepIndex, err := strconv.Atoi(c.Param("step_index"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的步骤序号")
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "请选择要上传的文件")
+		return
+	}
+
+	artifact, err := h.service.UploadEvidence(projectID, userID.(uint), taskUUID, caseResultID, stepIndex, file)
+	if err != nil {
+		log.Printf("[ManualExecution UploadEvidence Failed] case_result_id=%d, step=%d, error=%v", caseResultID, stepIndex, err)
+		respondManualError(c, err, "上传证据失败")
+		return
+	}
+	utils.SuccessResponse(c, artifact)
+}
+
+// FinishSession 完成手工执行并写回用例结果
+// POST /api/v1/projects/:id/execution-tasks/:task_uuid/cases/:case_result_id/manual-session/finish
+func (h *ManualExecutionHandler) FinishSession(c *gin.Context) {
+	projectID, taskUUID, caseResultID, ok := manualCaseParams(c)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	var req services.FinishManualSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "参数验证失败")
+		return
+	}
+
+	detail, err := h.service.FinishSession(projectID, userID.(uint), taskUUID, caseResultID, req)
+	if err != nil {
+		log.Printf("[ManualExecution Finish Failed] project_id=%d, task_uuid=%s, case_result_id=%d, error=%v", projectID, taskUUID, caseResultID, err)
+		respondManualError(c, err, "完成执行失败")
+		return
+	}
+	utils.SuccessResponse(c, detail)
+}
+
+// GetEffortMetrics 获取任务按测试人员和用例集汇总的实际工时
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/manual-effort
+func (h *ManualExecutionHandler) GetEffortMetrics(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+
+	metrics, err := h.service.GetEffortMetrics(uint(projectID), taskUUID)
+	if err != nil {
+		log.Printf("[ManualExecution Effort Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		respondManualError(c, err, "获取工时统计失败")
+		return
+	}
+	utils.SuccessResponse(c, metrics)
+}
+
+// respondManualError 将手工执行相关错误映射为HTTP状态码
+func respondManualError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "任务不存在", "用例不存在", "执行会话不存在", "步骤不存在":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "任务不属于该项目", "只能操作自己的执行会话":
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	case "用例不属于该任务", "仅手工测试任务支持手工执行", "存在未执行的步骤", "文件大小超出限制":
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case "该用例正由其他测试人员执行", "执行会话已结束", "执行会话已暂停":
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
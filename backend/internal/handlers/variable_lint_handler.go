@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"webtest/internal/services"
+	"webtest/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VariableLintHandler 执行前变量检查处理器
+type VariableLintHandler struct {
+	service services.VariableLintService
+}
+
+// NewVariableLintHandler 创建处理器实例
+func NewVariableLintHandler(service services.VariableLintService) *VariableLintHandler {
+	return &VariableLintHandler{service: service}
+}
+
+// LintTask 检查执行任务用例引用的变量
+// GET /api/v1/projects/:id/execution-tasks/:task_uuid/variable-lint
+func (h *VariableLintHandler) LintTask(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	taskUUID := c.Param("task_uuid")
+
+	report, err := h.service.LintTask(uint(projectID), taskUUID)
+	if err != nil {
+		log.Printf("[VariableLint Task Failed] project_id=%d, task_uuid=%s, error=%v", projectID, taskUUID, err)
+		h.respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, report)
+}
+
+// LintCaseGroup 检查用例集用例引用的变量
+// GET /api/v1/projects/:id/case-groups/:groupId/variable-lint?group_type=web
+func (h *VariableLintHandler) LintCaseGroup(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的项目ID")
+		return
+	}
+	groupID, err := strconv.ParseUint(c.Param("groupId"), 10, 32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "无效的用例集ID")
+		return
+	}
+	groupType := c.Query("group_type")
+	if groupType == "" {
+		groupType = "web"
+	}
+
+	report, err := h.service.LintCaseGroup(uint(projectID), uint(groupID), groupType)
+	if err != nil {
+		log.Printf("[VariableLint Group Failed] project_id=%d, group_id=%d, error=%v", projectID, groupID, err)
+		h.respondError(c, err)
+		return
+	}
+	utils.SuccessResponse(c, report)
+}
+
+// respondError 将服务层错误映射为HTTP状态码
+func (h *VariableLintHandler) respondError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "任务不存在", "用例集不存在":
+		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+	case "任务不属于该项目", "用例集不属于该项目":
+		utils.ErrorResponse(c, http.StatusForbidden, err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "变量检查失败")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 
 	"webtest/internal/mcp/client"
 	"webtest/internal/mcp/tools"
+	"webtest/internal/models"
 )
 
 // ListDefectsHandler handles listing defects.
@@ -42,6 +44,18 @@ func (h *ListDefectsHandler) InputSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "严重程度过滤（可选）",
 			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "查询语句（可选），如 status in (New, Active) and severity=Critical and created>=2024-01-01",
+			},
+			"sort": map[string]interface{}{
+				"type":        "string",
+				"description": "排序字段（可选），逗号分隔，前缀-表示降序，如 -priority,created",
+			},
+			"filter": map[string]interface{}{
+				"type":        "string",
+				"description": "保存的筛选ID或名称（可选），与query同时指定时取交集",
+			},
 		},
 		"required": []interface{}{"project_id"},
 	}
@@ -61,8 +75,18 @@ func (h *ListDefectsHandler) Execute(ctx context.Context, args map[string]interf
 	if status := GetOptionalString(args, "status", ""); status != "" {
 		params["status"] = status
 	}
+	query := GetOptionalString(args, "query", "")
 	if severity := GetOptionalString(args, "severity", ""); severity != "" {
-		params["severity"] = severity
+		query = models.CombineDefectQueries(query, fmt.Sprintf("severity=%q", severity))
+	}
+	if query != "" {
+		params["q"] = query
+	}
+	if sort := GetOptionalString(args, "sort", ""); sort != "" {
+		params["sort"] = sort
+	}
+	if filter := GetOptionalString(args, "filter", ""); filter != "" {
+		params["filter"] = filter
 	}
 
 	data, err := h.client.Get(ctx, path, params)
@@ -330,8 +354,10 @@ func (h *UpdateDefectHandler) executeBatchUpdate(ctx context.Context, projectID
 	}
 
 	results := []map[string]interface{}{}
-	successCount := 0
 	failedCount := 0
+	updates := []map[string]interface{}{}
+	indexes := []int{}   // updates 中每一项在 defects 中的下标
+	defectIDs := []int{} // updates 中每一项的缺陷ID
 
 	for idx, defectItem := range defectsInterface {
 		defectData, ok := defectItem.(map[string]interface{})
@@ -367,7 +393,7 @@ func (h *UpdateDefectHandler) executeBatchUpdate(ctx context.Context, projectID
 			continue
 		}
 
-		// 准备更新数据（移除id字段）
+		// 准备更新数据（id字段转换为显示ID）
 		updateData := make(map[string]interface{})
 		for k, v := range defectData {
 			if k != "id" {
@@ -389,29 +415,55 @@ func (h *UpdateDefectHandler) executeBatchUpdate(ctx context.Context, projectID
 			continue
 		}
 
-		// 调用单个更新API
-		idStr := fmt.Sprintf("%06d", defectID)
-		updatePath := fmt.Sprintf("/api/v1/projects/%d/defects/%s", projectID, idStr)
-		_, err := h.client.Put(ctx, updatePath, updateData)
+		updateData["defect_id"] = fmt.Sprintf("%06d", defectID)
+		updates = append(updates, updateData)
+		indexes = append(indexes, idx)
+		defectIDs = append(defectIDs, defectID)
+	}
+
+	// 调用批量更新API，变更历史来源由服务端记为 bulk
+	successCount := 0
+	if len(updates) > 0 {
+		path := fmt.Sprintf("/api/v1/projects/%d/defects/bulk", projectID)
+		data, err := h.client.Put(ctx, path, map[string]interface{}{
+			"updates":           updates,
+			"continue_on_error": continueOnError,
+		})
 		if err != nil {
-			failedCount++
-			results = append(results, map[string]interface{}{
-				"index":     idx,
-				"defect_id": defectID,
-				"status":    "failed",
-				"error":     err.Error(),
-			})
-			if !continueOnError {
-				break
-			}
-			continue
+			return tools.NewErrorResult(err.Error()), nil
 		}
 
-		successCount++
-		results = append(results, map[string]interface{}{
-			"index":     idx,
-			"defect_id": defectID,
-			"status":    "success",
+		var resp struct {
+			Data struct {
+				Results []struct {
+					Index  int    `json:"index"`
+					Status string `json:"status"`
+					Error  string `json:"error"`
+				} `json:"results"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return tools.NewErrorResult(fmt.Sprintf("解析批量更新结果失败: %v", err)), nil
+		}
+		for _, r := range resp.Data.Results {
+			if r.Index < 0 || r.Index >= len(indexes) {
+				continue
+			}
+			item := map[string]interface{}{
+				"index":     indexes[r.Index],
+				"defect_id": defectIDs[r.Index],
+				"status":    r.Status,
+			}
+			if r.Status == "success" {
+				successCount++
+			} else {
+				failedCount++
+				item["error"] = r.Error
+			}
+			results = append(results, item)
+		}
+		sort.SliceStable(results, func(a, b int) bool {
+			return results[a]["index"].(int) < results[b]["index"].(int)
 		})
 	}
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"webtest/internal/mcp/client"
 	"webtest/internal/mcp/tools"
@@ -423,7 +424,7 @@ func (h *GetExecutionTaskCasesHandler) Name() string {
 }
 
 func (h *GetExecutionTaskCasesHandler) Description() string {
-	return "获取指定执行任务的全部用例列表（含所有用例字段：中文、日文、英文描述、前置条件、测试步骤、期望结果、执行状态、备注、script_code脚本代码等）"
+	return "获取指定执行任务的全部用例列表（含所有用例字段：中文、日文、英文描述、前置条件、测试步骤、期望结果、执行状态、备注、script_code脚本代码、负责人等），并附带按负责人汇总的执行进度"
 }
 
 func (h *GetExecutionTaskCasesHandler) InputSchema() map[string]interface{} {
@@ -438,6 +439,10 @@ func (h *GetExecutionTaskCasesHandler) InputSchema() map[string]interface{} {
 				"type":        "integer",
 				"description": "项目ID（可选，如不提供则默认为1）",
 			},
+			"assignee": map[string]interface{}{
+				"type":        "string",
+				"description": "按负责人过滤（可选）：me 表示分配给当前用户的用例，none 表示未分配的用例，也可传成员用户ID",
+			},
 		},
 		"required": []interface{}{"task_id"},
 	}
@@ -551,12 +556,33 @@ func (h *GetExecutionTaskCasesHandler) Execute(ctx context.Context, args map[str
 	casesParams := map[string]string{
 		"size": "99999",
 	}
+	if assignee := GetOptionalString(args, "assignee", ""); assignee != "" {
+		casesParams["assignee"] = assignee
+	}
 	casesData, err := h.client.Get(ctx, casesPath, casesParams)
 	if err != nil {
 		return tools.NewErrorResult("failed to get case results: " + err.Error()), nil
 	}
 
-	return tools.NewJSONResult(string(casesData)), nil
+	// 步骤3: 附加按负责人汇总的执行进度（获取失败时仅返回用例列表）
+	statsPath := fmt.Sprintf("/api/v1/execution-tasks/%s/statistics", taskUUID)
+	statsData, err := h.client.Get(ctx, statsPath, nil)
+	if err != nil {
+		return tools.NewJSONResult(string(casesData)), nil
+	}
+	var casesResp map[string]interface{}
+	var statsResp struct {
+		Data struct {
+			Assignees json.RawMessage `json:"assignees"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(casesData, &casesResp) != nil || json.Unmarshal(statsData, &statsResp) != nil {
+		return tools.NewJSONResult(string(casesData)), nil
+	}
+	casesResp["assignee_progress"] = statsResp.Data.Assignees
+
+	resultJSON, _ := json.Marshal(casesResp)
+	return tools.NewJSONResult(string(resultJSON)), nil
 }
 
 // UpdateExecutionCaseResultHandler handles updating execution case result.
@@ -808,3 +834,176 @@ func isUUIDFormat(s string) bool {
 	}
 	return count == 4 && len(s) == 36
 }
+
+// CompareExecutionTasksHandler handles comparing the case results of two execution tasks.
+type CompareExecutionTasksHandler struct {
+	*BaseHandler
+}
+
+func NewCompareExecutionTasksHandler(c *client.BackendClient) *CompareExecutionTasksHandler {
+	return &CompareExecutionTasksHandler{BaseHandler: NewBaseHandler(c)}
+}
+
+func (h *CompareExecutionTasksHandler) Name() string {
+	return "compare_execution_tasks"
+}
+
+func (h *CompareExecutionTasksHandler) Description() string {
+	return "对比两个执行任务(如同一用例集在两个测试版本上的执行)的用例结果，按用例匹配并分类为新增失败、新增通过、持续失败、新增用例、移除用例等，包含API/Web用例的响应时间差。返回Markdown格式，可直接用于生成测试结果(T类型)AI报告"
+}
+
+func (h *CompareExecutionTasksHandler) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"project_id": map[string]interface{}{
+				"type":        "integer",
+				"description": "项目ID",
+			},
+			"base_task_uuid": map[string]interface{}{
+				"type":        "string",
+				"description": "基准执行任务UUID(通常为旧版本)",
+			},
+			"target_task_uuid": map[string]interface{}{
+				"type":        "string",
+				"description": "目标执行任务UUID(通常为新版本)",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"description": "返回格式（可选，默认markdown）",
+				"enum":        []interface{}{"markdown", "json"},
+			},
+		},
+		"required": []interface{}{"project_id", "base_task_uuid", "target_task_uuid"},
+	}
+}
+
+func (h *CompareExecutionTasksHandler) Execute(ctx context.Context, args map[string]interface{}) (tools.ToolResult, error) {
+	projectID, err := GetInt(args, "project_id")
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+	baseTaskUUID, err := GetString(args, "base_task_uuid")
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+	targetTaskUUID, err := GetString(args, "target_task_uuid")
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+	format := GetOptionalString(args, "format", "markdown")
+
+	path := fmt.Sprintf("/api/v1/projects/%d/execution-tasks/compare", projectID)
+	data, err := h.client.Get(ctx, path, map[string]string{
+		"base":   baseTaskUUID,
+		"target": targetTaskUUID,
+		"format": format,
+	})
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+
+	if format == "json" {
+		return tools.NewJSONResult(string(data)), nil
+	}
+	return tools.NewTextResult(string(data)), nil
+}
+
+// GetExecutionEventsHandler handles following the progress events of an execution task.
+type GetExecutionEventsHandler struct {
+	*BaseHandler
+}
+
+func NewGetExecutionEventsHandler(c *client.BackendClient) *GetExecutionEventsHandler {
+	return &GetExecutionEventsHandler{BaseHandler: NewBaseHandler(c)}
+}
+
+func (h *GetExecutionEventsHandler) Name() string {
+	return "get_execution_events"
+}
+
+func (h *GetExecutionEventsHandler) Description() string {
+	return "获取执行任务的实时进度事件(run_started/case_started/case_finished/run_finished)。传入上次返回的next_since即可持续跟踪执行进度；wait_seconds大于0时在没有新事件时等待，直到有新事件或超时"
+}
+
+func (h *GetExecutionEventsHandler) InputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"project_id": map[string]interface{}{
+				"type":        "integer",
+				"description": "项目ID",
+			},
+			"task_uuid": map[string]interface{}{
+				"type":        "string",
+				"description": "执行任务UUID",
+			},
+			"since": map[string]interface{}{
+				"type":        "integer",
+				"description": "已收到的最后事件序号（可选，默认0返回缓冲中的全部事件）",
+			},
+			"wait_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "没有新事件时的最长等待秒数（可选，默认0不等待，最大60）",
+			},
+		},
+		"required": []interface{}{"project_id", "task_uuid"},
+	}
+}
+
+func (h *GetExecutionEventsHandler) Execute(ctx context.Context, args map[string]interface{}) (tools.ToolResult, error) {
+	projectID, err := GetInt(args, "project_id")
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+	taskUUID, err := GetString(args, "task_uuid")
+	if err != nil {
+		return tools.NewErrorResult(err.Error()), nil
+	}
+	since := GetOptionalInt(args, "since", 0)
+	waitSeconds := GetOptionalInt(args, "wait_seconds", 0)
+	if waitSeconds > 60 {
+		waitSeconds = 60
+	}
+
+	path := fmt.Sprintf("/api/v1/projects/%d/execution-tasks/%s/events", projectID, taskUUID)
+	deadline := time.Now().Add(time.Duration(waitSeconds) * time.Second)
+	for {
+		data, err := h.client.Get(ctx, path, map[string]string{"since": strconv.Itoa(since)})
+		if err != nil {
+			return tools.NewErrorResult(err.Error()), nil
+		}
+
+		var resp struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return tools.NewErrorResult("failed to parse events: " + err.Error()), nil
+		}
+
+		if len(resp.Data) > 0 || !time.Now().Before(deadline) {
+			nextSince := since
+			finished := false
+			for _, ev := range resp.Data {
+				if seq, ok := ev["seq"].(float64); ok && int(seq) > nextSince {
+					nextSince = int(seq)
+				}
+				if ev["type"] == "run_finished" {
+					finished = true
+				}
+			}
+			result, _ := json.Marshal(map[string]interface{}{
+				"events":       resp.Data,
+				"next_since":   nextSince,
+				"run_finished": finished,
+			})
+			return tools.NewJSONResult(string(result)), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return tools.NewErrorResult(ctx.Err().Error()), nil
+		case <-time.After(time.Second):
+		}
+	}
+}
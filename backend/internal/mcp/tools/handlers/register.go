@@ -55,11 +55,13 @@ func RegisterAllTools(registry *tools.ToolRegistry, c *client.BackendClient) {
 	// ==================== 用例评审相关 (0 tool) ====================
 	// registry.Register(NewCreateReviewItemHandler(c)) // 已禁用：功能合并到create_ai_report工具中
 
-	// ==================== 执行任务相关 (4 tools) ====================
+	// ==================== 执行任务相关 (6 tools) ====================
 	registry.Register(NewListExecutionTasksHandler(c))
 	registry.Register(NewGetExecutionTaskMetadataHandler(c))
 	registry.Register(NewGetExecutionTaskCasesHandler(c))
 	registry.Register(NewUpdateExecutionCaseResultHandler(c))
+	registry.Register(NewCompareExecutionTasksHandler(c))
+	registry.Register(NewGetExecutionEventsHandler(c))
 
 	// ==================== 缺陷管理相关 (2 tools) ====================
 	registry.Register(NewListDefectsHandler(c))
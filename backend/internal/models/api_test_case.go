@@ -45,6 +45,12 @@ type ApiTestCase struct {
 	Body     string `gorm:"type:text" json:"body"`                        // 请求体(支持多行)
 	Response string `gorm:"type:text" json:"response"`                    // 预期响应(支持多行)
 
+	// 响应断言(JSON数组，内置接口执行器据此判定OK/NG)
+	Assertions string `gorm:"type:text" json:"assertions"`
+
+	// 变量提取规则(JSON数组，提取结果供同一批次后续用例以 ${var} 引用)
+	Extractions string `gorm:"type:text" json:"extractions"`
+
 	// 可执行脚本(消除AI幻觉)
 	ScriptCode string `gorm:"type:text" json:"script_code"` // JS脚本代码，S12直接执行此脚本
 
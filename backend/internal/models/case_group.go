@@ -15,14 +15,16 @@ type CaseGroup struct {
 	Description  string `json:"description" gorm:"type:text"`
 	DisplayOrder int    `json:"display_order" gorm:"default:0;index"`
 	// 元数据字段
-	MetaProtocol string         `json:"meta_protocol" gorm:"type:varchar(20);default:'https'"`
-	MetaServer   string         `json:"meta_server" gorm:"type:varchar(255)"`
-	MetaPort     string         `json:"meta_port" gorm:"type:varchar(20)"`
-	MetaUser     string         `json:"meta_user" gorm:"type:varchar(100)"`
-	MetaPassword string         `json:"meta_password" gorm:"type:varchar(255)"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"` // 不返回给前端
+	MetaProtocol string `json:"meta_protocol" gorm:"type:varchar(20);default:'https'"`
+	MetaServer   string `json:"meta_server" gorm:"type:varchar(255)"`
+	MetaPort     string `json:"meta_port" gorm:"type:varchar(20)"`
+	MetaUser     string `json:"meta_user" gorm:"type:varchar(100)"`
+	MetaPassword string `json:"meta_password" gorm:"type:varchar(255)"`
+	// 自动执行时的浏览器设置(JSON)，覆盖任务设置
+	BrowserOptions string         `json:"browser_options" gorm:"type:text"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"` // 不返回给前端
 }
 
 // TableName 指定表名
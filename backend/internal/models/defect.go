@@ -184,7 +184,8 @@ type Defect struct {
 
 	// 关联 - 不存储在数据库
 	Attachments   []DefectAttachment `gorm:"foreignKey:DefectID;references:ID" json:"attachments,omitempty"`
-	CreatedByUser User               `gorm:"foreignKey:CreatedBy;references:ID" json:"created_by_user,omitempty"` // 创建人信息
+	CreatedByUser User               `gorm:"foreignKey:CreatedBy;references:ID" json:"created_by_user,omitempty"`    // 创建人信息
+	CaseLinks     []DefectCaseLink   `gorm:"foreignKey:DefectID;references:ID;constraint:OnDelete:CASCADE" json:"-"` // 关联执行结果(删除缺陷时级联删除)
 }
 
 // TableName 指定表名
@@ -274,6 +275,18 @@ type DefectUpdateRequest struct {
 	Status          *string `json:"status"`
 }
 
+// DefectBulkUpdateItem 批量更新中的单个缺陷，DefectID 为显示ID
+type DefectBulkUpdateItem struct {
+	DefectID string `json:"defect_id" binding:"required"`
+	DefectUpdateRequest
+}
+
+// DefectBulkUpdateRequest 批量更新缺陷请求
+type DefectBulkUpdateRequest struct {
+	Updates         []DefectBulkUpdateItem `json:"updates" binding:"required,min=1,dive"`
+	ContinueOnError *bool                  `json:"continue_on_error"` // 失败后是否继续，默认 true
+}
+
 // ImportError 导入错误记录
 type ImportError struct {
 	Row    int    `json:"row"`
@@ -285,4 +298,9 @@ type ImportResult struct {
 	SuccessCount int           `json:"success_count"`
 	FailCount    int           `json:"fail_count"`
 	Errors       []ImportError `json:"errors"`
+
+	// 重复检测(按导入的重复处理模式)
+	SkippedCount int               `json:"skipped_count"`
+	MergedCount  int               `json:"merged_count"`
+	Duplicates   []ImportDuplicate `json:"duplicates,omitempty"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// DefectCaseLink 缺陷与执行用例结果的关联
+// 一个缺陷可关联多个执行结果；缺陷关闭后，关联用例在下一个执行任务中标记为需复测
+type DefectCaseLink struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DefectID       string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_defect_case_link" json:"defect_id"`               // 关联缺陷UUID
+	DefectNo       string    `gorm:"type:varchar(20);not null" json:"defect_no"`                                                // 缺陷显示ID快照
+	ProjectID      uint      `gorm:"not null;index:idx_defect_case_links_project" json:"project_id"`                            // 所属项目ID
+	CaseResultID   uint      `gorm:"not null;uniqueIndex:idx_defect_case_link;index:idx_dcl_case_result" json:"case_result_id"` // 关联执行结果ID
+	TaskUUID       string    `gorm:"type:varchar(36);not null" json:"task_uuid"`                                                // 执行结果所属任务
+	CaseID         string    `gorm:"type:varchar(36);not null;index:idx_dcl_case_id" json:"case_id"`                            // 用例UUID，用于在后续任务中定位同一用例
+	CaseNum        string    `gorm:"type:varchar(100)" json:"case_num"`                                                         // 用户自定义CaseID快照
+	RetestPending  bool      `gorm:"default:false" json:"retest_pending"`                                                       // 缺陷已关闭，等待下一个执行任务复测
+	RetestTaskUUID string    `gorm:"type:varchar(36)" json:"retest_task_uuid"`                                                  // 已安排复测的执行任务
+	CreatedBy      uint      `gorm:"not null" json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	CaseResult ExecutionCaseResult `gorm:"foreignKey:CaseResultID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName 指定表名
+func (DefectCaseLink) TableName() string {
+	return "defect_case_links"
+}
+
+// DefectFromResultRequest 从NG执行结果提交缺陷请求
+// 未填写的标题、描述、发现版本、测试阶段由执行结果和任务预填
+type DefectFromResultRequest struct {
+	CaseResultID    uint   `json:"case_result_id" binding:"required"`
+	Title           string `json:"title" binding:"max=200"`
+	Description     string `json:"description"` // 追加在预填的步骤/期望结果之前
+	SubjectID       *uint  `json:"subject_id"`
+	Subject         string `json:"subject"`
+	Priority        string `json:"priority"`
+	Severity        string `json:"severity"`
+	Type            string `json:"type"`
+	Frequency       string `json:"frequency"`
+	DetectedVersion string `json:"detected_version"`
+	PhaseID         *uint  `json:"phase_id"`
+	Phase           string `json:"phase"`
+	Language        string `json:"language"`         // 预填内容语言(cn/jp/en)，默认按任务显示语言
+	AttachArtifacts bool   `json:"attach_artifacts"` // 是否附加最近一次执行的截图/trace等产物
+}
+
+// DefectCaseLinkRequest 将执行结果关联到已有缺陷请求
+type DefectCaseLinkRequest struct {
+	CaseResultIDs []uint `json:"case_result_ids" binding:"required,min=1"`
+}
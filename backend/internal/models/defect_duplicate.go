@@ -0,0 +1,62 @@
+package models
+
+// DefaultDefectDuplicateThreshold 默认的疑似重复相似度阈值
+const DefaultDefectDuplicateThreshold = 0.6
+
+// 导入时的重复处理模式
+const (
+	DefectDuplicateModeNone  = ""      // 不检测，全部新建
+	DefectDuplicateModeSkip  = "skip"  // 跳过疑似重复行
+	DefectDuplicateModeMerge = "merge" // 将疑似重复行合并到已有缺陷(只补充空字段)
+)
+
+// IsValidDefectDuplicateMode 检查重复处理模式是否有效
+func IsValidDefectDuplicateMode(mode string) bool {
+	return mode == DefectDuplicateModeNone || mode == DefectDuplicateModeSkip || mode == DefectDuplicateModeMerge
+}
+
+// DefectDuplicateOptions 重复检测选项
+type DefectDuplicateOptions struct {
+	Threshold     float64 `form:"threshold" json:"threshold"`           // 相似度阈值(0-1)，为0时使用默认值
+	SameCaseID    bool    `form:"same_case_id" json:"same_case_id"`     // 要求Case ID相同
+	SameComponent bool    `form:"same_component" json:"same_component"` // 要求组件相同
+}
+
+// DefectImportOptions 缺陷导入选项
+type DefectImportOptions struct {
+	DuplicateMode string                 `json:"duplicate_mode"`
+	Duplicate     DefectDuplicateOptions `json:"duplicate"`
+}
+
+// DefectDuplicateCandidate 疑似重复的缺陷
+type DefectDuplicateCandidate struct {
+	ID        string  `json:"id"`
+	DefectID  string  `json:"defect_id"`
+	Title     string  `json:"title"`
+	Status    string  `json:"status"`
+	CaseID    string  `json:"case_id"`
+	Component string  `json:"component"`
+	Score     float64 `json:"score"` // 标题/描述的相似度(0-1)
+}
+
+// DefectDuplicateGroup 疑似重复的缺陷分组
+type DefectDuplicateGroup struct {
+	Defects []DefectDuplicateCandidate `json:"defects"`
+	Score   float64                    `json:"score"` // 组内最高相似度
+}
+
+// ImportDuplicate 导入时检测到的疑似重复行
+type ImportDuplicate struct {
+	Row         int     `json:"row"`
+	Title       string  `json:"title"`
+	DuplicateOf string  `json:"duplicate_of"` // 已有缺陷显示ID
+	Score       float64 `json:"score"`
+	Action      string  `json:"action"` // skipped/merged
+}
+
+// DefectMergeRequest 合并重复缺陷请求
+// 来源缺陷的空字段补充到目标缺陷，关联的执行结果转移到目标缺陷，来源缺陷标记为 Rejected
+type DefectMergeRequest struct {
+	TargetDefectID  string   `json:"target_defect_id" binding:"required"`
+	SourceDefectIDs []string `json:"source_defect_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 保存的缺陷筛选的可见范围
+const (
+	DefectFilterScopeUser    = "user"    // 仅创建人可见
+	DefectFilterScopeProject = "project" // 项目成员可见，由项目经理维护
+)
+
+// DefectSavedFilter 保存的缺陷筛选(查询语句+排序)
+type DefectSavedFilter struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID uint      `gorm:"not null;index:idx_defect_saved_filters_project" json:"project_id"` // 所属项目ID
+	UserID    uint      `gorm:"not null" json:"user_id"`                                           // 创建人ID
+	Scope     string    `gorm:"type:varchar(10);not null;default:'user'" json:"scope"`             // user/project
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`                            // 筛选名称
+	Query     string    `gorm:"type:text" json:"query"`                                            // 查询语句
+	Sort      string    `gorm:"type:varchar(200)" json:"sort"`                                     // 排序，如 -created,priority
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DefectSavedFilter) TableName() string {
+	return "defect_saved_filters"
+}
+
+// DefectSavedFilterRequest 保存缺陷筛选请求
+type DefectSavedFilterRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Query string `json:"query"`
+	Sort  string `json:"sort" binding:"max=200"`
+	Scope string `json:"scope" binding:"omitempty,oneof=user project"` // 默认 user
+}
+
+// DefectListParams 缺陷列表查询参数
+type DefectListParams struct {
+	Status  string `form:"status"`
+	Keyword string `form:"keyword"`
+	Query   string `form:"q"`      // 查询语句
+	Sort    string `form:"sort"`   // 排序，前缀-表示降序
+	Filter  string `form:"filter"` // 保存的筛选(ID或名称)，与 q 同时指定时取交集
+	Page    int    `form:"page"`
+	Size    int    `form:"size"`
+}
+
+// CombineDefectQueries 以 and 连接两个缺陷查询语句，任一为空时返回另一个
+func CombineDefectQueries(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return "(" + a + ") and (" + b + ")"
+}
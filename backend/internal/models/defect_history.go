@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// 缺陷变更来源
+const (
+	DefectChangeSourceWeb    = "web"    // 页面操作
+	DefectChangeSourceMCP    = "mcp"    // API Token 调用(MCP工具)
+	DefectChangeSourceBulk   = "bulk"   // 批量更新接口
+	DefectChangeSourceImport = "import" // CSV/XLSX 导入
+	DefectChangeSourceMerge  = "merge"  // 合并重复缺陷
+)
+
+// DefectHistory 缺陷字段级变更记录
+// 同一次更新产生的多条记录共享 ChangeSetID
+type DefectHistory struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	DefectID    string    `gorm:"type:varchar(36);not null;index:idx_defect_histories_defect_id" json:"defect_id"` // 关联缺陷UUID
+	ProjectID   uint      `gorm:"not null;index:idx_defect_histories_project" json:"project_id"`                   // 所属项目ID
+	ChangeSetID string    `gorm:"type:varchar(36);not null" json:"change_set_id"`                                  // 同一次更新的批次ID
+	Field       string    `gorm:"type:varchar(50);not null" json:"field"`                                          // 字段名(数据库列名)
+	OldValue    string    `gorm:"type:text" json:"old_value"`
+	NewValue    string    `gorm:"type:text" json:"new_value"`
+	ActorID     uint      `gorm:"not null" json:"actor_id"`                // 操作人ID
+	ActorName   string    `gorm:"type:varchar(100)" json:"actor_name"`     // 操作人昵称快照
+	Source      string    `gorm:"type:varchar(20);not null" json:"source"` // web/mcp/bulk/import/merge
+	CreatedAt   time.Time `gorm:"index:idx_defect_histories_created_at" json:"created_at"`
+}
+
+// TableName 指定表名
+func (DefectHistory) TableName() string {
+	return "defect_histories"
+}
+
+// DefectFieldChange 单个字段的变更
+type DefectFieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// DefectTimelineEntry 缺陷时间线条目(创建、字段变更或说明)
+type DefectTimelineEntry struct {
+	Type      string              `json:"type"` // created/change/comment
+	Time      time.Time           `json:"time"`
+	ActorID   uint                `json:"actor_id"`
+	ActorName string              `json:"actor_name"`
+	Source    string              `json:"source,omitempty"`
+	Changes   []DefectFieldChange `json:"changes,omitempty"`
+	Comment   *DefectComment      `json:"comment,omitempty"`
+}
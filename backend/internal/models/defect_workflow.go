@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+	"webtest/internal/constants"
+)
+
+// DefectTransition 缺陷状态流转规则
+type DefectTransition struct {
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	RequiredFields []string `json:"required_fields,omitempty"` // 流转后必须有值的字段(见 DefectTransitionFields)
+	Roles          []string `json:"roles,omitempty"`           // 允许执行的项目角色(ProjectMember.Role)，为空表示不限
+}
+
+// DefectTransitionFields 流转规则中可要求填写的字段
+var DefectTransitionFields = []string{
+	"resolution",
+	"fix_version",
+	"assignee",
+	"component",
+	"sqa_memo",
+	"recovery_method",
+	"location",
+	"models",
+}
+
+// IsValidDefectTransitionField 检查字段是否可在流转规则中要求填写
+func IsValidDefectTransitionField(field string) bool {
+	for _, f := range DefectTransitionFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultDefectTransitions 项目未配置工作流时使用的默认流转规则
+// 引入工作流之前已存在的项目在升级时写入 PermissiveDefectTransitions，不受默认规则影响
+func DefaultDefectTransitions() []DefectTransition {
+	pm := []string{constants.RoleProjectManager}
+	resolveFields := []string{"resolution", "fix_version"}
+	return []DefectTransition{
+		{From: string(DefectStatusNew), To: string(DefectStatusConfirmed)},
+		{From: string(DefectStatusNew), To: string(DefectStatusInProgress)},
+		{From: string(DefectStatusNew), To: string(DefectStatusRejected), Roles: pm},
+		{From: string(DefectStatusConfirmed), To: string(DefectStatusInProgress)},
+		{From: string(DefectStatusConfirmed), To: string(DefectStatusRejected), Roles: pm},
+		{From: string(DefectStatusInProgress), To: string(DefectStatusResolved), RequiredFields: resolveFields},
+		{From: string(DefectStatusInProgress), To: string(DefectStatusRejected), Roles: pm},
+		{From: string(DefectStatusResolved), To: string(DefectStatusClosed)},
+		{From: string(DefectStatusResolved), To: string(DefectStatusReopened)},
+		{From: string(DefectStatusReopened), To: string(DefectStatusInProgress)},
+		{From: string(DefectStatusReopened), To: string(DefectStatusResolved), RequiredFields: resolveFields},
+		{From: string(DefectStatusClosed), To: string(DefectStatusReopened), Roles: pm},
+		{From: string(DefectStatusRejected), To: string(DefectStatusReopened), Roles: pm},
+		{From: string(DefectStatusRejected), To: string(DefectStatusClosed)},
+	}
+}
+
+// PermissiveDefectTransitions 任意两个状态之间均可流转、不限角色和字段的规则
+// 与引入工作流之前的行为一致，升级时写入已有项目，项目经理可随时改为默认或自定义规则
+func PermissiveDefectTransitions() []DefectTransition {
+	var transitions []DefectTransition
+	for _, from := range ValidDefectStatuses {
+		for _, to := range ValidDefectStatuses {
+			if from == to || from == DefectStatusActive || to == DefectStatusActive {
+				continue
+			}
+			transitions = append(transitions, DefectTransition{From: string(from), To: string(to)})
+		}
+	}
+	return transitions
+}
+
+// DefectWorkflow 项目的缺陷工作流定义
+type DefectWorkflow struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID   uint      `gorm:"not null;uniqueIndex:idx_defect_workflows_project" json:"project_id"` // 所属项目ID
+	Transitions string    `gorm:"type:text" json:"-"`                                                  // 流转规则(JSON数组)
+	UpdatedBy   uint      `json:"updated_by"`                                                          // 更新人ID
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DefectWorkflow) TableName() string {
+	return "defect_workflows"
+}
+
+// DefectWorkflowResponse 缺陷工作流响应
+type DefectWorkflowResponse struct {
+	ProjectID   uint               `json:"project_id"`
+	IsDefault   bool               `json:"is_default"` // 项目未配置，使用默认工作流
+	Statuses    []DefectStatus     `json:"statuses"`
+	Fields      []string           `json:"fields"` // 可要求填写的字段
+	Transitions []DefectTransition `json:"transitions"`
+}
+
+// DefectWorkflowUpdateRequest 更新缺陷工作流请求(transitions为空时恢复默认工作流)
+type DefectWorkflowUpdateRequest struct {
+	Transitions []DefectTransition `json:"transitions"`
+}
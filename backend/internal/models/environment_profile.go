@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// EnvironmentProfile 项目的命名执行环境(如 staging/UAT/各浏览器)
+// 由一组变量覆盖和浏览器/设备/视口设置组成，矩阵执行时每个环境对应一个执行批次
+type EnvironmentProfile struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID      uint      `gorm:"not null;uniqueIndex:idx_ep_project_name" json:"project_id"`
+	Name           string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_ep_project_name" json:"name"`
+	Description    string    `gorm:"type:text" json:"description"`
+	Variables      string    `gorm:"type:text" json:"-"`              // 变量覆盖(JSON对象: 变量名 -> 值)，优先于任务变量
+	Browser        string    `gorm:"type:varchar(20)" json:"browser"` // chromium/firefox/webkit，空为执行器默认
+	Device         string    `gorm:"type:varchar(100)" json:"device"` // Playwright设备名，如 "iPhone 13"
+	ViewportWidth  int       `gorm:"default:0" json:"viewport_width"` // 0为默认视口
+	ViewportHeight int       `gorm:"default:0" json:"viewport_height"`
+	CreatedBy      uint      `gorm:"not null" json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EnvironmentProfile) TableName() string {
+	return "environment_profiles"
+}
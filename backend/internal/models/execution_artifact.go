@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// 执行产物类型
+const (
+	ArtifactTypeScreenshot = "screenshot"
+	ArtifactTypeTrace      = "trace"
+	ArtifactTypeVideo      = "video"
+	ArtifactTypeConsoleLog = "console_log"
+	ArtifactTypeEvidence   = "evidence" // 手工执行时测试人员上传的步骤证据
+)
+
+// ExecutionArtifact 执行产物(Web自动化的失败截图、trace、视频、控制台日志，手工执行的步骤证据)
+// 文件保存在存储目录下，记录关联到用例结果和对应的执行记录
+type ExecutionArtifact struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID    uint      `gorm:"not null;index:idx_eart_project" json:"project_id"`
+	TaskUUID     string    `gorm:"type:varchar(36);not null;index:idx_eart_task" json:"task_uuid"`
+	CaseResultID uint      `gorm:"not null;index:idx_eart_case_result" json:"case_result_id"`
+	AttemptID    uint      `gorm:"default:0;index:idx_eart_attempt" json:"attempt_id"`
+	RunUUID      string    `gorm:"type:varchar(36)" json:"run_uuid"`
+	SessionID    uint      `gorm:"default:0;index:idx_eart_session" json:"session_id"` // 手工执行会话(步骤证据)
+	StepIndex    int       `gorm:"default:0" json:"step_index"`                        // 证据所属步骤，从1开始
+	ArtifactType string    `gorm:"type:varchar(20);not null" json:"artifact_type"`     // screenshot/trace/video/console_log/evidence
+	FileName     string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	FilePath     string    `gorm:"type:varchar(500);not null" json:"-"` // 相对存储目录的路径
+	FileSize     int64     `gorm:"not null" json:"file_size"`
+	MimeType     string    `gorm:"type:varchar(100)" json:"mime_type"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ExecutionArtifact) TableName() string {
+	return "execution_artifacts"
+}
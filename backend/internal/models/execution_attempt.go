@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ExecutionAttempt 用例结果的单次执行记录
+// 每次自动执行都会追加一条，ExecutionCaseResult 只保留最新结果
+type ExecutionAttempt struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CaseResultID     uint      `gorm:"not null;index:idx_ea_case_result" json:"case_result_id"`
+	TaskUUID         string    `gorm:"type:varchar(36);not null;index:idx_ea_task" json:"task_uuid"`
+	RunUUID          string    `gorm:"type:varchar(36);index:idx_ea_run" json:"run_uuid"` // 单条执行时为空
+	CaseID           string    `gorm:"type:varchar(36);index:idx_ea_case_id" json:"case_id"`
+	TestResult       string    `gorm:"type:varchar(10);not null" json:"test_result"`
+	Output           string    `gorm:"type:text" json:"output"`        // 执行器输出
+	ErrorMessage     string    `gorm:"type:text" json:"error_message"` // 失败原因
+	ErrorStack       string    `gorm:"type:text" json:"error_stack"`   // 执行器返回的错误堆栈
+	DurationMs       int       `gorm:"type:int;default:0" json:"duration_ms"`
+	ExecutedBy       uint      `gorm:"not null" json:"executed_by"`
+	ExecutorName     string    `gorm:"type:varchar(50)" json:"executor_name"`
+	VariableSnapshot string    `gorm:"type:text" json:"variable_snapshot"`    // 执行时的变量(JSON，敏感值已脱敏)
+	RetryIndex       int       `gorm:"type:int;default:0" json:"retry_index"` // 0为首次执行，>0为第N次重试
+	Flaky            bool      `gorm:"default:false" json:"flaky"`            // 本次为重试后通过
+	BrowserOptions   string    `gorm:"type:text" json:"browser_options"`      // 实际使用的浏览器设置(脱敏JSON)
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ExecutionAttempt) TableName() string {
+	return "execution_attempts"
+}
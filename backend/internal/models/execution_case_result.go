@@ -61,6 +61,29 @@ type ExecutionCaseResult struct {
 	ResponseTime string `gorm:"type:varchar(50)" json:"response_time"` // 响应时间
 	ScriptCode   string `gorm:"type:text" json:"script_code"`          // JS脚本代码，用于API测试执行
 
+	Assertions  string `gorm:"type:text" json:"assertions"`  // 响应断言快照(JSON数组)
+	Extractions string `gorm:"type:text" json:"extractions"` // 变量提取规则快照(JSON数组)
+
+	// API 用例执行结果(内置接口执行器写入)
+	StatusCode       int    `gorm:"type:int;default:0" json:"status_code"` // 实际HTTP状态码
+	ActualResponse   string `gorm:"type:text" json:"actual_response"`      // 实际响应体
+	AssertionResults string `gorm:"type:text" json:"assertion_results"`    // 逐条断言结果(JSON数组)
+	ExtractedVars    string `gorm:"type:text" json:"extracted_vars"`       // 本次提取的变量(脱敏JSON)
+
+	// 重试策略结果
+	RetryCount int  `gorm:"type:int;default:0" json:"retry_count"` // 最近一次执行的重试次数
+	Flaky      bool `gorm:"default:false" json:"flaky"`            // 重试后才通过
+
+	BrowserOptions string `gorm:"type:text" json:"browser_options"` // 最近一次执行实际使用的浏览器设置(脱敏JSON)
+
+	// 用例分配
+	AssigneeID   uint   `gorm:"default:0;index:idx_ecr_assignee" json:"assignee_id"` // 负责执行的项目成员，0表示未分配
+	AssigneeName string `gorm:"type:varchar(50)" json:"assignee_name"`               // 负责人昵称快照
+
+	// 缺陷复测：关联缺陷关闭后，该用例在下一个执行任务中标记为需复测
+	RetestRequired bool   `gorm:"default:false" json:"retest_required"`
+	RetestDefectID string `gorm:"type:varchar(20)" json:"retest_defect_id"` // 触发复测的缺陷显示ID
+
 	UpdatedBy uint           `gorm:"not null" json:"updated_by" validate:"required,min=1"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
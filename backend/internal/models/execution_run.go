@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 执行批次状态
+const (
+	ExecutionRunStatusQueued    = "queued"
+	ExecutionRunStatusRunning   = "running"
+	ExecutionRunStatusCancelled = "cancelled"
+	ExecutionRunStatusFinished  = "finished"
+)
+
+// 执行批次触发方式
+const (
+	ExecutionRunTriggerManual   = "manual"
+	ExecutionRunTriggerSchedule = "schedule"
+	ExecutionRunTriggerCI       = "ci"
+)
+
+// ExecutionRun 测试执行任务的一次后台执行批次
+// 每次调用执行接口都会创建一条记录，后台协程按用例逐个更新进度
+type ExecutionRun struct {
+	RunUUID           string     `gorm:"type:varchar(36);primaryKey" json:"run_uuid"`
+	TaskUUID          string     `gorm:"type:varchar(36);not null;index:idx_er_task" json:"task_uuid"`
+	ProjectID         uint       `gorm:"not null;index:idx_er_project" json:"project_id"`
+	Status            string     `gorm:"type:varchar(20);not null;default:queued;index:idx_er_status" json:"status"` // queued/running/cancelled/finished
+	Total             int        `gorm:"not null;default:0" json:"total"`
+	CompletedCount    int        `gorm:"not null;default:0" json:"completed_count"` // 已处理用例数(含Block)
+	OKCount           int        `gorm:"not null;default:0" json:"ok_count"`
+	NGCount           int        `gorm:"not null;default:0" json:"ng_count"`
+	BlockCount        int        `gorm:"not null;default:0" json:"block_count"`
+	CurrentCaseID     uint       `gorm:"default:0" json:"current_case_id"` // 正在执行的用例结果ID
+	ErrorMessage      string     `gorm:"type:text" json:"error_message"`   // 批次异常终止原因
+	RunVariables      string     `gorm:"type:text" json:"-"`               // 批次内提取的变量原值(JSON)，恢复执行时使用
+	ExtractedVars     string     `gorm:"type:text" json:"extracted_vars"`  // 批次内提取的变量(脱敏JSON)
+	VariableOverrides string     `gorm:"type:text" json:"-"`               // 触发时指定的变量覆盖原值(JSON)
+	TriggeredBy       uint       `gorm:"not null" json:"triggered_by"`
+	TriggerType       string     `gorm:"type:varchar(20);not null;default:manual" json:"trigger_type"` // manual/schedule/ci
+	ScheduleID        uint       `gorm:"default:0;index:idx_er_schedule" json:"schedule_id"`           // 定时触发时的执行计划ID
+	MatrixUUID        string     `gorm:"type:varchar(36);index:idx_er_matrix" json:"matrix_uuid"`      // 矩阵执行时同一次触发的批次共享
+	EnvProfileID      uint       `gorm:"default:0" json:"env_profile_id"`                              // 矩阵执行时的执行环境ID
+	EnvProfileName    string     `gorm:"type:varchar(50)" json:"env_profile_name"`                     // 执行环境名称(环境删除后仍可展示)
+	BrowserOptions    string     `gorm:"type:text" json:"browser_options"`                             // 触发时的浏览器设置(JSON)
+	ExecutedBy        string     `gorm:"type:varchar(50)" json:"executed_by"`
+	Owner             string     `gorm:"type:varchar(100);index:idx_er_owner" json:"-"` // 持有批次的服务实例
+	LeaseUntil        *time.Time `json:"-"`                                             // 持有实例的租约到期时间，到期后其他实例可接管
+	StartedAt         *time.Time `json:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ExecutionRun) TableName() string {
+	return "execution_runs"
+}
+
+// BeforeCreate GORM钩子：创建前自动生成UUID
+func (r *ExecutionRun) BeforeCreate(tx *gorm.DB) error {
+	if r.RunUUID == "" {
+		r.RunUUID = uuid.New().String()
+	}
+	if r.Status == "" {
+		r.Status = ExecutionRunStatusQueued
+	}
+	if r.TriggerType == "" {
+		r.TriggerType = ExecutionRunTriggerManual
+	}
+	return nil
+}
+
+// IsActive 批次是否仍在排队或执行中
+func (r *ExecutionRun) IsActive() bool {
+	return r.Status == ExecutionRunStatusQueued || r.Status == ExecutionRunStatusRunning
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// ExecutionSchedule 执行任务的定时执行计划(Cron表达式，按服务器时区计算)
+// 多个服务实例共享同一数据库时，通过 Version 乐观锁保证每次触发只由一个实例执行
+type ExecutionSchedule struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID   uint       `gorm:"not null;index:idx_es_project" json:"project_id"`
+	TaskUUID    string     `gorm:"type:varchar(36);not null;index:idx_es_task" json:"task_uuid"`
+	CronExpr    string     `gorm:"type:varchar(100);not null" json:"cron_expr"`
+	Enabled     bool       `gorm:"not null;default:true;index:idx_es_enabled" json:"enabled"`
+	NextRunAt   *time.Time `gorm:"index:idx_es_next_run" json:"next_run_at"` // 下次触发时间，停用时为空
+	LastRunAt   *time.Time `json:"last_run_at"`                              // 最近一次触发时间
+	LastRunUUID string     `gorm:"type:varchar(36)" json:"last_run_uuid"`    // 最近一次触发创建的执行批次
+	LastError   string     `gorm:"type:text" json:"last_error"`              // 最近一次触发失败原因
+	LastFiredBy string     `gorm:"type:varchar(100)" json:"last_fired_by"`   // 最近一次触发的服务实例
+	Version     int        `gorm:"not null;default:0" json:"-"`              // 乐观锁版本号，每次触发递增
+	CreatedBy   uint       `gorm:"not null" json:"created_by"`               // 定时执行以创建人身份触发
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ExecutionSchedule) TableName() string {
+	return "execution_schedules"
+}
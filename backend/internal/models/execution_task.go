@@ -17,6 +17,10 @@ type ExecutionTask struct {
 	CaseGroupID     uint           `gorm:"type:int;default:0;index:idx_tet_case_group" json:"case_group_id"` // 关联的用例集ID
 	CaseGroupName   string         `gorm:"type:varchar(100)" json:"case_group_name"`                         // 关联的用例集名称
 	DisplayLanguage string         `gorm:"type:varchar(10);default:'cn'" json:"display_language"`            // 显示语言(cn/jp/en/all)
+	Concurrency     int            `gorm:"type:int;not null;default:1" json:"concurrency"`                   // 自动执行并发数(受服务器上限约束)
+	RetryCount      int            `gorm:"type:int;not null;default:0" json:"retry_count"`                   // 自动执行NG后的重试次数
+	RetryBackoffMs  int            `gorm:"type:int;not null;default:0" json:"retry_backoff_ms"`              // 首次重试前的等待时间(毫秒)，之后逐次翻倍
+	BrowserOptions  string         `gorm:"type:text" json:"browser_options"`                                 // 自动执行的浏览器设置(JSON)，用例集设置优先
 	StartDate       *time.Time     `gorm:"type:date" json:"start_date" validate:"omitempty"`
 	EndDate         *time.Time     `gorm:"type:date" json:"end_date" validate:"omitempty,gtefield=StartDate"`
 	TestVersion     string         `gorm:"type:varchar(50)" json:"test_version" validate:"omitempty,max=50"`
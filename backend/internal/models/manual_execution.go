@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// 手工执行会话状态
+const (
+	ManualSessionStatusInProgress = "in_progress"
+	ManualSessionStatusPaused     = "paused"
+	ManualSessionStatusFinished   = "finished"
+)
+
+// ManualExecutionSession 测试人员对一条手工用例的一次引导式执行
+// 暂停期间不计入实际工时，完成时结果写回用例结果并追加一条执行记录
+type ManualExecutionSession struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProjectID     uint       `gorm:"not null;index:idx_mes_project" json:"project_id"`
+	TaskUUID      string     `gorm:"type:varchar(36);not null;index:idx_mes_task" json:"task_uuid"`
+	CaseResultID  uint       `gorm:"not null;index:idx_mes_case_result" json:"case_result_id"`
+	CaseGroupName string     `gorm:"type:varchar(100)" json:"case_group_name"` // 用例集名称快照，按用例集统计工时
+	TesterID      uint       `gorm:"not null;index:idx_mes_tester" json:"tester_id"`
+	TesterName    string     `gorm:"type:varchar(50)" json:"tester_name"`
+	Status        string     `gorm:"type:varchar(20);not null;default:in_progress" json:"status"` // in_progress/paused/finished
+	Language      string     `gorm:"type:varchar(10)" json:"language"`                            // 步骤来源语言(cn/jp/en)
+	EffortMs      int64      `gorm:"not null;default:0" json:"effort_ms"`                         // 已累计的实际工时(不含暂停)
+	ResumedAt     *time.Time `json:"resumed_at"`                                                  // 最近一次开始/恢复时间，暂停后为空
+	TestResult    string     `gorm:"type:varchar(10)" json:"test_result"`                         // 完成时的用例结果
+	AttemptID     uint       `gorm:"default:0" json:"attempt_id"`                                 // 完成时追加的执行记录
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ManualExecutionSession) TableName() string {
+	return "manual_execution_sessions"
+}
+
+// ManualStepResult 手工执行会话中单个测试步骤的结果
+type ManualStepResult struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SessionID    uint      `gorm:"not null;uniqueIndex:idx_msr_session_step" json:"session_id"`
+	StepIndex    int       `gorm:"not null;uniqueIndex:idx_msr_session_step" json:"step_index"` // 从1开始
+	StepText     string    `gorm:"type:text" json:"step_text"`                                  // 步骤内容快照
+	Result       string    `gorm:"type:varchar(10);not null;default:NR" json:"result"`          // NR/OK/NG/Block
+	ActualResult string    `gorm:"type:text" json:"actual_result"`
+	UpdatedBy    uint      `gorm:"not null" json:"updated_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ManualStepResult) TableName() string {
+	return "manual_step_results"
+}
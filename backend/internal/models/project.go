@@ -8,15 +8,16 @@ import (
 
 // Project 项目模型
 type Project struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"not null;size:100" json:"name"`
-	Description string         `gorm:"type:text" json:"description"`
-	Status      string         `gorm:"type:varchar(20);default:pending;not null" json:"status"`
-	OwnerID     *int           `gorm:"type:int;index" json:"owner_id"`
-	OwnerName   string         `gorm:"column:owner_name;->" json:"owner_name"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Name           string         `gorm:"not null;size:100" json:"name"`
+	Description    string         `gorm:"type:text" json:"description"`
+	Status         string         `gorm:"type:varchar(20);default:pending;not null" json:"status"`
+	OwnerID        *int           `gorm:"type:int;index" json:"owner_id"`
+	OwnerName      string         `gorm:"column:owner_name;->" json:"owner_name"`
+	ScriptExecutor string         `gorm:"type:varchar(30)" json:"script_executor"` // 项目指定的用例执行器，空为按任务类型的默认执行器
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefectCaseLinkRepository 缺陷与执行结果关联仓储接口
+type DefectCaseLinkRepository interface {
+	Create(link *models.DefectCaseLink) error
+	CreateWithDefect(defect *models.Defect, link *models.DefectCaseLink) error
+	MergeDefect(m *DefectMerge) error
+	Delete(defectID string, caseResultID uint) error
+	DeleteByDefectID(defectID string) error
+	DeleteDefect(defectID string) error
+	DeleteOrphans() (int64, error)
+	ListByDefectID(defectID string) ([]*models.DefectCaseLink, error)
+	ListByCaseResultID(caseResultID uint) ([]*models.DefectCaseLink, error)
+	SetRetestPending(defectID string, pending bool) error
+	ListPendingRetest(projectID uint, caseIDs []string) ([]*models.DefectCaseLink, error)
+	ScheduleRetest(links []*models.DefectCaseLink, taskUUID string) error
+}
+
+type defectCaseLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewDefectCaseLinkRepository 创建缺陷关联仓储实例
+func NewDefectCaseLinkRepository(db *gorm.DB) DefectCaseLinkRepository {
+	return &defectCaseLinkRepository{db: db}
+}
+
+// Create 创建关联(已存在则忽略)，同时将执行结果的BugID同步为缺陷显示ID
+func (r *defectCaseLinkRepository) Create(link *models.DefectCaseLink) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(link).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ExecutionCaseResult{}).Where("id = ?", link.CaseResultID).
+			UpdateColumn("bug_id", link.DefectNo).Error
+	})
+	if err != nil {
+		return fmt.Errorf("create defect case link %s-%d: %w", link.DefectID, link.CaseResultID, err)
+	}
+	return nil
+}
+
+// CreateWithDefect 在同一事务中创建缺陷和关联，任一失败都不留下缺陷
+func (r *defectCaseLinkRepository) CreateWithDefect(defect *models.Defect, link *models.DefectCaseLink) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(defect).Error; err != nil {
+			return err
+		}
+		return NewDefectCaseLinkRepository(tx).Create(link)
+	})
+}
+
+// DefectMerge 合并单个来源缺陷时的全部写入
+type DefectMerge struct {
+	TargetID        string
+	TargetUpdates   map[string]interface{} // 为空时不更新目标缺陷
+	TargetHistories []*models.DefectHistory
+	SourceID        string
+	SourceUpdates   map[string]interface{}
+	SourceHistories []*models.DefectHistory
+	Links           []*models.DefectCaseLink // 从来源缺陷转移到目标缺陷的关联
+}
+
+// MergeDefect 在同一事务中补充目标缺陷字段、转移关联并更新来源缺陷，任一失败全部回滚
+func (r *defectCaseLinkRepository) MergeDefect(m *DefectMerge) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		defects := NewDefectRepository(tx)
+		if len(m.TargetUpdates) > 0 {
+			if err := defects.UpdateWithHistory(m.TargetID, m.TargetUpdates, m.TargetHistories); err != nil {
+				return err
+			}
+		}
+		links := NewDefectCaseLinkRepository(tx)
+		for _, link := range m.Links {
+			if err := links.Create(link); err != nil {
+				return err
+			}
+			if err := links.Delete(m.SourceID, link.CaseResultID); err != nil {
+				return err
+			}
+		}
+		return defects.UpdateWithHistory(m.SourceID, m.SourceUpdates, m.SourceHistories)
+	})
+}
+
+// Delete 删除关联，执行结果的BugID仍指向该缺陷时一并清空
+func (r *defectCaseLinkRepository) Delete(defectID string, caseResultID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var link models.DefectCaseLink
+		if err := tx.Where("defect_id = ? AND case_result_id = ?", defectID, caseResultID).First(&link).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&link).Error; err != nil {
+			return fmt.Errorf("delete defect case link %d: %w", link.ID, err)
+		}
+		return tx.Model(&models.ExecutionCaseResult{}).
+			Where("id = ? AND bug_id = ?", caseResultID, link.DefectNo).
+			UpdateColumn("bug_id", "").Error
+	})
+}
+
+// DeleteByDefectID 删除缺陷的全部关联，执行结果的BugID仍指向该缺陷时一并清空
+func (r *defectCaseLinkRepository) DeleteByDefectID(defectID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var links []*models.DefectCaseLink
+		if err := tx.Where("defect_id = ?", defectID).Find(&links).Error; err != nil {
+			return fmt.Errorf("list defect case links of %s: %w", defectID, err)
+		}
+		for _, link := range links {
+			if err := tx.Model(&models.ExecutionCaseResult{}).
+				Where("id = ? AND bug_id = ?", link.CaseResultID, link.DefectNo).
+				UpdateColumn("bug_id", "").Error; err != nil {
+				return fmt.Errorf("clear bug id of case result %d: %w", link.CaseResultID, err)
+			}
+		}
+		if err := tx.Where("defect_id = ?", defectID).Delete(&models.DefectCaseLink{}).Error; err != nil {
+			return fmt.Errorf("delete defect case links of %s: %w", defectID, err)
+		}
+		return nil
+	})
+}
+
+// DeleteDefect 在同一事务中删除缺陷及其关联、变更记录，任一失败全部回滚
+func (r *defectCaseLinkRepository) DeleteDefect(defectID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := NewDefectCaseLinkRepository(tx).DeleteByDefectID(defectID); err != nil {
+			return err
+		}
+		if err := NewDefectHistoryRepository(tx).DeleteByDefectID(defectID); err != nil {
+			return err
+		}
+		return NewDefectRepository(tx).Delete(defectID)
+	})
+}
+
+// DeleteOrphans 删除缺陷或执行结果已不存在的关联(添加外键约束前清理历史数据)
+func (r *defectCaseLinkRepository) DeleteOrphans() (int64, error) {
+	result := r.db.
+		Where("defect_id NOT IN (?) OR case_result_id NOT IN (?)",
+			r.db.Model(&models.Defect{}).Select("id"),
+			r.db.Model(&models.ExecutionCaseResult{}).Select("id")).
+		Delete(&models.DefectCaseLink{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("delete orphan defect case links: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// ListByDefectID 查询缺陷关联的执行结果
+func (r *defectCaseLinkRepository) ListByDefectID(defectID string) ([]*models.DefectCaseLink, error) {
+	var links []*models.DefectCaseLink
+	if err := r.db.Where("defect_id = ?", defectID).Order("id ASC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("list defect case links by defect id: %w", err)
+	}
+	return links, nil
+}
+
+// ListByCaseResultID 查询执行结果关联的缺陷
+func (r *defectCaseLinkRepository) ListByCaseResultID(caseResultID uint) ([]*models.DefectCaseLink, error) {
+	var links []*models.DefectCaseLink
+	if err := r.db.Where("case_result_id = ?", caseResultID).Order("id ASC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("list defect case links by case result id: %w", err)
+	}
+	return links, nil
+}
+
+// SetRetestPending 设置缺陷关联用例的待复测标记
+func (r *defectCaseLinkRepository) SetRetestPending(defectID string, pending bool) error {
+	err := r.db.Model(&models.DefectCaseLink{}).Where("defect_id = ?", defectID).
+		UpdateColumn("retest_pending", pending).Error
+	if err != nil {
+		return fmt.Errorf("set retest pending of defect %s: %w", defectID, err)
+	}
+	return nil
+}
+
+// ListPendingRetest 查询项目中指定用例的待复测关联
+func (r *defectCaseLinkRepository) ListPendingRetest(projectID uint, caseIDs []string) ([]*models.DefectCaseLink, error) {
+	var links []*models.DefectCaseLink
+	if len(caseIDs) == 0 {
+		return links, nil
+	}
+	err := r.db.Where("project_id = ? AND retest_pending = ? AND case_id IN ?", projectID, true, caseIDs).
+		Order("id ASC").Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("list pending retest links of project %d: %w", projectID, err)
+	}
+	return links, nil
+}
+
+// ScheduleRetest 在执行任务中将关联用例标记为需复测，并清除关联的待复测标记
+func (r *defectCaseLinkRepository) ScheduleRetest(links []*models.DefectCaseLink, taskUUID string) error {
+	if len(links) == 0 {
+		return nil
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, 0, len(links))
+		for _, link := range links {
+			if err := tx.Model(&models.ExecutionCaseResult{}).
+				Where("task_uuid = ? AND case_id = ?", taskUUID, link.CaseID).
+				UpdateColumns(map[string]interface{}{"retest_required": true, "retest_defect_id": link.DefectNo}).Error; err != nil {
+				return err
+			}
+			ids = append(ids, link.ID)
+		}
+		return tx.Model(&models.DefectCaseLink{}).Where("id IN ?", ids).
+			UpdateColumns(map[string]interface{}{"retest_pending": false, "retest_task_uuid": taskUUID}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("schedule retest in task %s: %w", taskUUID, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefectSavedFilterRepository 保存的缺陷筛选仓储接口
+type DefectSavedFilterRepository interface {
+	Create(filter *models.DefectSavedFilter) error
+	GetByID(id uint) (*models.DefectSavedFilter, error)
+	Update(filter *models.DefectSavedFilter) error
+	Delete(id uint) error
+	ListVisible(projectID uint, userID uint) ([]*models.DefectSavedFilter, error)
+	ExistsByName(projectID uint, scope string, userID uint, name string, excludeID uint) (bool, error)
+}
+
+type defectSavedFilterRepository struct {
+	db *gorm.DB
+}
+
+// NewDefectSavedFilterRepository 创建保存的缺陷筛选仓储实例
+func NewDefectSavedFilterRepository(db *gorm.DB) DefectSavedFilterRepository {
+	return &defectSavedFilterRepository{db: db}
+}
+
+// Create 创建筛选
+func (r *defectSavedFilterRepository) Create(filter *models.DefectSavedFilter) error {
+	if err := r.db.Create(filter).Error; err != nil {
+		return fmt.Errorf("create defect filter: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取筛选
+func (r *defectSavedFilterRepository) GetByID(id uint) (*models.DefectSavedFilter, error) {
+	var filter models.DefectSavedFilter
+	if err := r.db.First(&filter, id).Error; err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// Update 保存筛选的名称、查询、排序和范围
+func (r *defectSavedFilterRepository) Update(filter *models.DefectSavedFilter) error {
+	err := r.db.Model(filter).Select("name", "query", "sort", "scope", "updated_at").Updates(filter).Error
+	if err != nil {
+		return fmt.Errorf("update defect filter %d: %w", filter.ID, err)
+	}
+	return nil
+}
+
+// Delete 删除筛选
+func (r *defectSavedFilterRepository) Delete(id uint) error {
+	if err := r.db.Delete(&models.DefectSavedFilter{}, id).Error; err != nil {
+		return fmt.Errorf("delete defect filter %d: %w", id, err)
+	}
+	return nil
+}
+
+// ListVisible 查询用户可见的筛选：项目共享的筛选和本人的筛选
+func (r *defectSavedFilterRepository) ListVisible(projectID uint, userID uint) ([]*models.DefectSavedFilter, error) {
+	var filters []*models.DefectSavedFilter
+	err := r.db.Where("project_id = ? AND (scope = ? OR user_id = ?)", projectID, models.DefectFilterScopeProject, userID).
+		Order("scope ASC, name ASC").
+		Find(&filters).Error
+	if err != nil {
+		return nil, fmt.Errorf("list defect filters: %w", err)
+	}
+	return filters, nil
+}
+
+// ExistsByName 检查同一范围内名称是否已存在(项目范围按项目，个人范围按用户)
+func (r *defectSavedFilterRepository) ExistsByName(projectID uint, scope string, userID uint, name string, excludeID uint) (bool, error) {
+	query := r.db.Model(&models.DefectSavedFilter{}).
+		Where("project_id = ? AND scope = ? AND name = ? AND id <> ?", projectID, scope, name, excludeID)
+	if scope == models.DefectFilterScopeUser {
+		query = query.Where("user_id = ?", userID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("check defect filter name: %w", err)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DefectHistoryRepository 缺陷变更记录仓储接口
+type DefectHistoryRepository interface {
+	ListByDefectID(defectID string) ([]*models.DefectHistory, error)
+	DeleteByDefectID(defectID string) error
+}
+
+type defectHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewDefectHistoryRepository 创建缺陷变更记录仓储实例
+func NewDefectHistoryRepository(db *gorm.DB) DefectHistoryRepository {
+	return &defectHistoryRepository{db: db}
+}
+
+// ListByDefectID 按缺陷ID查询变更记录（按时间升序）
+func (r *defectHistoryRepository) ListByDefectID(defectID string) ([]*models.DefectHistory, error) {
+	var histories []*models.DefectHistory
+	if err := r.db.Where("defect_id = ?", defectID).
+		Order("created_at ASC, id ASC").
+		Find(&histories).Error; err != nil {
+		return nil, fmt.Errorf("list defect histories by defect id: %w", err)
+	}
+	return histories, nil
+}
+
+// DeleteByDefectID 删除缺陷的全部变更记录
+func (r *defectHistoryRepository) DeleteByDefectID(defectID string) error {
+	if err := r.db.Where("defect_id = ?", defectID).Delete(&models.DefectHistory{}).Error; err != nil {
+		return fmt.Errorf("delete defect histories of %s: %w", defectID, err)
+	}
+	return nil
+}
@@ -15,20 +15,34 @@ type DefectRepository interface {
 	GetByID(id string) (*models.Defect, error)
 	GetByDefectID(defectID string) (*models.Defect, error)
 	Update(id string, updates map[string]interface{}) error
+	UpdateWithHistory(id string, updates map[string]interface{}, histories []*models.DefectHistory) error
 	Delete(id string) error
 
 	// 列表查询
-	List(projectID uint, status, keyword string, page, size int) ([]*models.Defect, int64, error)
+	List(projectID uint, q DefectListQuery) ([]*models.Defect, int64, error)
 	GetStatusCounts(projectID uint) (map[string]int64, error)
 
 	// 辅助方法
 	GetMaxDefectSeq(projectID uint) (int, error)
 	GenerateNextDefectID(projectID uint) (string, error)
 	GetByProjectID(projectID uint) ([]*models.Defect, error)
+	ListOpenByProject(projectID uint) ([]*models.Defect, error)
 	CleanupDuplicateDefects(projectID uint, defectID string) error
 	GetDB() *gorm.DB
 }
 
+// DefectListQuery 缺陷列表查询条件
+// Where/Args 为查询语言解析出的参数化条件，Order 为已校验的排序子句
+type DefectListQuery struct {
+	Status  string
+	Keyword string
+	Where   string
+	Args    []interface{}
+	Order   string
+	Page    int
+	Size    int
+}
+
 type defectRepository struct {
 	db *gorm.DB
 }
@@ -82,6 +96,26 @@ func (r *defectRepository) Update(id string, updates map[string]interface{}) err
 	return nil
 }
 
+// UpdateWithHistory 更新缺陷并写入变更记录(使用事务)
+func (r *defectRepository) UpdateWithHistory(id string, updates map[string]interface{}, histories []*models.DefectHistory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Defect{}).Where("id = ?", id).Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("update defect %s: %w", id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(histories) == 0 {
+			return nil
+		}
+		if err := tx.Create(&histories).Error; err != nil {
+			return fmt.Errorf("create defect histories: %w", err)
+		}
+		return nil
+	})
+}
+
 // Delete 硬删除缺陷
 func (r *defectRepository) Delete(id string) error {
 	result := r.db.Unscoped().Where("id = ?", id).Delete(&models.Defect{})
@@ -95,20 +129,25 @@ func (r *defectRepository) Delete(id string) error {
 }
 
 // List 分页查询缺陷列表（支持keyword检索）
-func (r *defectRepository) List(projectID uint, status, keyword string, page, size int) ([]*models.Defect, int64, error) {
+func (r *defectRepository) List(projectID uint, q DefectListQuery) ([]*models.Defect, int64, error) {
 	var defects []*models.Defect
 	var total int64
 
 	query := r.db.Model(&models.Defect{}).Where("project_id = ?", projectID)
 
 	// 状态筛选
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+
+	// 查询语言条件
+	if q.Where != "" {
+		query = query.Where(q.Where, q.Args...)
 	}
 
 	// 关键词检索（ID、标题、描述、恢复方法）
-	if keyword != "" {
-		keywordPattern := "%" + keyword + "%"
+	if q.Keyword != "" {
+		keywordPattern := "%" + q.Keyword + "%"
 		query = query.Where(
 			"defect_id LIKE ? OR title LIKE ? OR description LIKE ? OR recovery_method LIKE ?",
 			keywordPattern, keywordPattern, keywordPattern, keywordPattern,
@@ -120,12 +159,17 @@ func (r *defectRepository) List(projectID uint, status, keyword string, page, si
 		return nil, 0, fmt.Errorf("count defects: %w", err)
 	}
 
+	order := q.Order
+	if order == "" {
+		order = "created_at DESC, id"
+	}
+
 	// 分页查询
-	offset := (page - 1) * size
+	offset := (q.Page - 1) * q.Size
 	err := query.Preload("CreatedByUser").
-		Order("created_at DESC").
+		Order(order).
 		Offset(offset).
-		Limit(size).
+		Limit(q.Size).
 		Find(&defects).Error
 
 	if err != nil {
@@ -215,6 +259,19 @@ func (r *defectRepository) GenerateNextDefectID(projectID uint) (string, error)
 	return fmt.Sprintf("%06d", nextSeq), nil
 }
 
+// ListOpenByProject 查询项目中未关闭(非Closed/Rejected)的缺陷
+func (r *defectRepository) ListOpenByProject(projectID uint) ([]*models.Defect, error) {
+	var defects []*models.Defect
+	err := r.db.Where("project_id = ? AND status NOT IN ?", projectID,
+		[]string{string(models.DefectStatusClosed), string(models.DefectStatusRejected)}).
+		Order("defect_id ASC").
+		Find(&defects).Error
+	if err != nil {
+		return nil, fmt.Errorf("list open defects by project: %w", err)
+	}
+	return defects, nil
+}
+
 // GetByProjectID 获取项目所有缺陷（用于导出）
 func (r *defectRepository) GetByProjectID(projectID uint) ([]*models.Defect, error) {
 	var defects []*models.Defect
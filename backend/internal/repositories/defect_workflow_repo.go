@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefectWorkflowRepository 缺陷工作流仓储接口
+type DefectWorkflowRepository interface {
+	GetByProjectID(projectID uint) (*models.DefectWorkflow, error)
+	Save(workflow *models.DefectWorkflow) error
+	DeleteByProjectID(projectID uint) error
+	SeedMissing(transitions string) (int64, error)
+}
+
+type defectWorkflowRepository struct {
+	db *gorm.DB
+}
+
+// NewDefectWorkflowRepository 创建缺陷工作流仓储实例
+func NewDefectWorkflowRepository(db *gorm.DB) DefectWorkflowRepository {
+	return &defectWorkflowRepository{db: db}
+}
+
+// GetByProjectID 获取项目的工作流定义
+func (r *defectWorkflowRepository) GetByProjectID(projectID uint) (*models.DefectWorkflow, error) {
+	var workflow models.DefectWorkflow
+	err := r.db.Where("project_id = ?", projectID).First(&workflow).Error
+	if err != nil {
+		return nil, err // 保留gorm.ErrRecordNotFound
+	}
+	return &workflow, nil
+}
+
+// Save 创建或更新项目的工作流定义
+func (r *defectWorkflowRepository) Save(workflow *models.DefectWorkflow) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"transitions", "updated_by", "updated_at"}),
+	}).Create(workflow).Error
+	if err != nil {
+		return fmt.Errorf("save defect workflow of project %d: %w", workflow.ProjectID, err)
+	}
+	return nil
+}
+
+// DeleteByProjectID 删除项目的工作流定义(恢复默认工作流)
+func (r *defectWorkflowRepository) DeleteByProjectID(projectID uint) error {
+	err := r.db.Where("project_id = ?", projectID).Delete(&models.DefectWorkflow{}).Error
+	if err != nil {
+		return fmt.Errorf("delete defect workflow of project %d: %w", projectID, err)
+	}
+	return nil
+}
+
+// SeedMissing 为尚未配置工作流的项目写入指定流转规则(JSON数组)，返回写入的项目数
+func (r *defectWorkflowRepository) SeedMissing(transitions string) (int64, error) {
+	var projectIDs []uint
+	configured := r.db.Model(&models.DefectWorkflow{}).Select("project_id")
+	if err := r.db.Model(&models.Project{}).Where("id NOT IN (?)", configured).Pluck("id", &projectIDs).Error; err != nil {
+		return 0, fmt.Errorf("list projects without defect workflow: %w", err)
+	}
+	if len(projectIDs) == 0 {
+		return 0, nil
+	}
+	workflows := make([]*models.DefectWorkflow, 0, len(projectIDs))
+	for _, id := range projectIDs {
+		workflows = append(workflows, &models.DefectWorkflow{ProjectID: id, Transitions: transitions})
+	}
+	if err := r.db.Create(&workflows).Error; err != nil {
+		return 0, fmt.Errorf("seed defect workflows: %w", err)
+	}
+	return int64(len(workflows)), nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EnvironmentProfileRepository 执行环境仓储接口
+type EnvironmentProfileRepository interface {
+	Create(profile *models.EnvironmentProfile) error
+	GetByID(id uint) (*models.EnvironmentProfile, error)
+	GetByIDs(ids []uint) ([]*models.EnvironmentProfile, error)
+	GetByProjectID(projectID uint) ([]*models.EnvironmentProfile, error)
+	ExistsByName(projectID uint, name string, excludeID uint) (bool, error)
+	Update(id uint, updates map[string]interface{}) error
+	Delete(id uint) error
+}
+
+type environmentProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewEnvironmentProfileRepository 创建执行环境仓储实例
+func NewEnvironmentProfileRepository(db *gorm.DB) EnvironmentProfileRepository {
+	return &environmentProfileRepository{db: db}
+}
+
+// Create 插入执行环境
+func (r *environmentProfileRepository) Create(profile *models.EnvironmentProfile) error {
+	if err := r.db.Create(profile).Error; err != nil {
+		return fmt.Errorf("create environment profile: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID查询执行环境
+func (r *environmentProfileRepository) GetByID(id uint) (*models.EnvironmentProfile, error) {
+	var profile models.EnvironmentProfile
+	err := r.db.First(&profile, id).Error
+	if err != nil {
+		// 保留gorm.ErrRecordNotFound
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetByIDs 批量查询执行环境(按ID升序)
+func (r *environmentProfileRepository) GetByIDs(ids []uint) ([]*models.EnvironmentProfile, error) {
+	var profiles []*models.EnvironmentProfile
+	if len(ids) == 0 {
+		return profiles, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("id ASC").Find(&profiles).Error
+	if err != nil {
+		return nil, fmt.Errorf("get environment profiles by ids: %w", err)
+	}
+	return profiles, nil
+}
+
+// GetByProjectID 获取项目的所有执行环境
+func (r *environmentProfileRepository) GetByProjectID(projectID uint) ([]*models.EnvironmentProfile, error) {
+	var profiles []*models.EnvironmentProfile
+	err := r.db.Where("project_id = ?", projectID).
+		Order("id ASC").
+		Find(&profiles).Error
+	if err != nil {
+		return nil, fmt.Errorf("get environment profiles by project %d: %w", projectID, err)
+	}
+	return profiles, nil
+}
+
+// ExistsByName 检查项目内是否已有同名执行环境(excludeID用于更新时排除自身)
+func (r *environmentProfileRepository) ExistsByName(projectID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	query := r.db.Model(&models.EnvironmentProfile{}).Where("project_id = ? AND name = ?", projectID, name)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("check environment profile name: %w", err)
+	}
+	return count > 0, nil
+}
+
+// Update 更新执行环境字段
+func (r *environmentProfileRepository) Update(id uint, updates map[string]interface{}) error {
+	err := r.db.Model(&models.EnvironmentProfile{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("update environment profile %d: %w", id, err)
+	}
+	return nil
+}
+
+// Delete 删除执行环境
+func (r *environmentProfileRepository) Delete(id uint) error {
+	if err := r.db.Delete(&models.EnvironmentProfile{}, id).Error; err != nil {
+		return fmt.Errorf("delete environment profile %d: %w", id, err)
+	}
+	return nil
+}
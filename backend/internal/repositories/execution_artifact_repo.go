@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExecutionArtifactRepository 执行产物仓储接口
+type ExecutionArtifactRepository interface {
+	Create(artifact *models.ExecutionArtifact) error
+	GetByID(id uint) (*models.ExecutionArtifact, error)
+	GetByCaseResultID(caseResultID uint) ([]*models.ExecutionArtifact, error)
+	GetByAttemptID(attemptID uint) ([]*models.ExecutionArtifact, error)
+	GetBySessionID(sessionID uint) ([]*models.ExecutionArtifact, error)
+	AssignSessionAttempt(sessionID uint, attemptID uint) error
+	DeleteByTaskUUID(taskUUID string) error
+}
+
+type executionArtifactRepository struct {
+	db *gorm.DB
+}
+
+// NewExecutionArtifactRepository 创建执行产物仓储实例
+func NewExecutionArtifactRepository(db *gorm.DB) ExecutionArtifactRepository {
+	return &executionArtifactRepository{db: db}
+}
+
+// Create 插入产物记录
+func (r *executionArtifactRepository) Create(artifact *models.ExecutionArtifact) error {
+	if err := r.db.Create(artifact).Error; err != nil {
+		return fmt.Errorf("create execution artifact: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID获取产物
+func (r *executionArtifactRepository) GetByID(id uint) (*models.ExecutionArtifact, error) {
+	var artifact models.ExecutionArtifact
+	if err := r.db.Where("id = ?", id).First(&artifact).Error; err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// GetByCaseResultID 获取用例结果的所有产物(最新执行在前)
+func (r *executionArtifactRepository) GetByCaseResultID(caseResultID uint) ([]*models.ExecutionArtifact, error) {
+	var artifacts []*models.ExecutionArtifact
+	err := r.db.Where("case_result_id = ?", caseResultID).
+		Order("attempt_id DESC, id ASC").
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get artifacts by case_result_id %d: %w", caseResultID, err)
+	}
+	return artifacts, nil
+}
+
+// GetByAttemptID 获取单次执行记录的产物
+func (r *executionArtifactRepository) GetByAttemptID(attemptID uint) ([]*models.ExecutionArtifact, error) {
+	var artifacts []*models.ExecutionArtifact
+	err := r.db.Where("attempt_id = ?", attemptID).
+		Order("id ASC").
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get artifacts by attempt_id %d: %w", attemptID, err)
+	}
+	return artifacts, nil
+}
+
+// GetBySessionID 获取手工执行会话的步骤证据
+func (r *executionArtifactRepository) GetBySessionID(sessionID uint) ([]*models.ExecutionArtifact, error) {
+	var artifacts []*models.ExecutionArtifact
+	err := r.db.Where("session_id = ?", sessionID).
+		Order("step_index ASC, id ASC").
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get artifacts by session_id %d: %w", sessionID, err)
+	}
+	return artifacts, nil
+}
+
+// AssignSessionAttempt 手工执行完成后将步骤证据关联到执行记录
+func (r *executionArtifactRepository) AssignSessionAttempt(sessionID uint, attemptID uint) error {
+	err := r.db.Model(&models.ExecutionArtifact{}).
+		Where("session_id = ?", sessionID).
+		Update("attempt_id", attemptID).Error
+	if err != nil {
+		return fmt.Errorf("assign attempt to session %d artifacts: %w", sessionID, err)
+	}
+	return nil
+}
+
+// DeleteByTaskUUID 删除任务的所有产物记录
+func (r *executionArtifactRepository) DeleteByTaskUUID(taskUUID string) error {
+	err := r.db.Where("task_uuid = ?", taskUUID).Delete(&models.ExecutionArtifact{}).Error
+	if err != nil {
+		return fmt.Errorf("delete artifacts by task_uuid %s: %w", taskUUID, err)
+	}
+	return nil
+}
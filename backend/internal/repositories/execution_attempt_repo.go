@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CaseFlakyStat 用例在项目执行任务中的不稳定统计
+type CaseFlakyStat struct {
+	CaseID     string `json:"case_id"`
+	Executions int    `json:"executions"`  // 执行次数(不含重试)
+	FlakyCount int    `json:"flaky_count"` // 重试后才通过的次数
+	TaskCount  int    `json:"task_count"`  // 涉及的执行任务数
+}
+
+// ExecutionAttemptRepository 用例执行记录仓储接口
+type ExecutionAttemptRepository interface {
+	Create(attempt *models.ExecutionAttempt) error
+	GetByCaseResultID(caseResultID uint) ([]*models.ExecutionAttempt, error)
+	GetByRunUUID(runUUID string) ([]*models.ExecutionAttempt, error)
+	DeleteByTaskUUID(taskUUID string) error
+	FlakyStatsByProject(projectID uint, limit int) ([]CaseFlakyStat, error)
+}
+
+type executionAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewExecutionAttemptRepository 创建用例执行记录仓储实例
+func NewExecutionAttemptRepository(db *gorm.DB) ExecutionAttemptRepository {
+	return &executionAttemptRepository{db: db}
+}
+
+// Create 插入执行记录
+func (r *executionAttemptRepository) Create(attempt *models.ExecutionAttempt) error {
+	if err := r.db.Create(attempt).Error; err != nil {
+		return fmt.Errorf("create execution attempt: %w", err)
+	}
+	return nil
+}
+
+// GetByCaseResultID 获取用例结果的所有执行记录(按执行时间倒序)
+func (r *executionAttemptRepository) GetByCaseResultID(caseResultID uint) ([]*models.ExecutionAttempt, error) {
+	var attempts []*models.ExecutionAttempt
+	err := r.db.Where("case_result_id = ?", caseResultID).
+		Order("started_at DESC, id DESC").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get attempts by case_result_id %d: %w", caseResultID, err)
+	}
+	return attempts, nil
+}
+
+// GetByRunUUID 获取批次内的所有执行记录(按执行时间升序)
+func (r *executionAttemptRepository) GetByRunUUID(runUUID string) ([]*models.ExecutionAttempt, error) {
+	var attempts []*models.ExecutionAttempt
+	err := r.db.Where("run_uuid = ?", runUUID).
+		Order("started_at ASC, id ASC").
+		Find(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get attempts by run_uuid %s: %w", runUUID, err)
+	}
+	return attempts, nil
+}
+
+// DeleteByTaskUUID 删除任务的所有执行记录
+func (r *executionAttemptRepository) DeleteByTaskUUID(taskUUID string) error {
+	err := r.db.Where("task_uuid = ?", taskUUID).Delete(&models.ExecutionAttempt{}).Error
+	if err != nil {
+		return fmt.Errorf("delete attempts by task_uuid %s: %w", taskUUID, err)
+	}
+	return nil
+}
+
+// FlakyStatsByProject 统计项目下出现过不稳定结果的用例，按不稳定次数倒序
+func (r *executionAttemptRepository) FlakyStatsByProject(projectID uint, limit int) ([]CaseFlakyStat, error) {
+	var stats []CaseFlakyStat
+	err := r.db.Table("execution_attempts").
+		Select("execution_attempts.case_id, "+
+			"SUM(CASE WHEN execution_attempts.retry_index = 0 THEN 1 ELSE 0 END) AS executions, "+
+			"SUM(CASE WHEN execution_attempts.flaky THEN 1 ELSE 0 END) AS flaky_count, "+
+			"COUNT(DISTINCT execution_attempts.task_uuid) AS task_count").
+		Joins("INNER JOIN test_execution_tasks ON test_execution_tasks.task_uuid = execution_attempts.task_uuid").
+		Where("test_execution_tasks.project_id = ? AND test_execution_tasks.deleted_at IS NULL", projectID).
+		Group("execution_attempts.case_id").
+		Having("SUM(CASE WHEN execution_attempts.flaky THEN 1 ELSE 0 END) > 0").
+		Order("flaky_count DESC, executions ASC").
+		Limit(limit).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("flaky stats by project %d: %w", projectID, err)
+	}
+	return stats, nil
+}
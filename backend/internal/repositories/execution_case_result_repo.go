@@ -8,10 +8,29 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// CaseAssignScope 批量分配用例的范围，多个条件同时生效
+type CaseAssignScope struct {
+	CaseResultIDs []uint
+	CaseGroupName string
+	MajorFunction string // 匹配任一语言的大功能
+}
+
+// AssigneeStatistic 按负责人统计的用例数量
+type AssigneeStatistic struct {
+	AssigneeID   uint   `gorm:"column:assignee_id" json:"assignee_id"`
+	AssigneeName string `gorm:"column:assignee_name" json:"assignee_name"`
+	Total        int    `gorm:"column:total" json:"total"`
+	NRCount      int    `gorm:"column:nr_count" json:"nr_count"`
+	OKCount      int    `gorm:"column:ok_count" json:"ok_count"`
+	NGCount      int    `gorm:"column:ng_count" json:"ng_count"`
+	BlockCount   int    `gorm:"column:block_count" json:"block_count"`
+}
+
 // ExecutionCaseResultRepository 测试执行用例结果仓储接口
 type ExecutionCaseResultRepository interface {
 	// 查询方法
 	GetByTaskUUID(taskUUID string) ([]*models.ExecutionCaseResult, error)
+	GetByTaskUUIDAndAssignee(taskUUID string, assigneeID uint) ([]*models.ExecutionCaseResult, error)
 	GetByCaseID(caseID string) ([]*models.ExecutionCaseResult, error)
 	GetByID(id uint) (*models.ExecutionCaseResult, error)
 
@@ -21,10 +40,13 @@ type ExecutionCaseResultRepository interface {
 
 	// 更新和删除
 	UpdateResult(id uint, updates map[string]interface{}) error
+	ResetTestResults(taskUUID string, ids []uint) error
 	DeleteByTaskUUID(taskUUID string) error
+	AssignCases(taskUUID string, scope CaseAssignScope, assigneeID uint, assigneeName string) (int64, error)
 
 	// 统计方法
 	GetStatistics(taskUUID string) (map[string]int, error)
+	GetAssigneeStatistics(taskUUID string) ([]*AssigneeStatistic, error)
 }
 
 type executionCaseResultRepository struct {
@@ -49,6 +71,19 @@ func (r *executionCaseResultRepository) GetByTaskUUID(taskUUID string) ([]*model
 	return results, nil
 }
 
+// GetByTaskUUIDAndAssignee 获取任务中分配给指定成员的执行结果(assigneeID为0时返回未分配的用例)
+func (r *executionCaseResultRepository) GetByTaskUUIDAndAssignee(taskUUID string, assigneeID uint) ([]*models.ExecutionCaseResult, error) {
+	var results []*models.ExecutionCaseResult
+	err := r.db.Where("task_uuid = ? AND assignee_id = ?", taskUUID, assigneeID).
+		Order("display_id ASC, case_id ASC").
+		Find(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("get results by task_uuid %s and assignee %d: %w", taskUUID, assigneeID, err)
+	}
+	return results, nil
+}
+
 // GetByCaseID 获取指定用例的所有执行结果
 func (r *executionCaseResultRepository) GetByCaseID(caseID string) ([]*models.ExecutionCaseResult, error) {
 	var results []*models.ExecutionCaseResult
@@ -142,6 +177,8 @@ func (r *executionCaseResultRepository) BatchUpsert(results []*models.ExecutionC
 			"method",
 			"body",
 			"response",
+			"assertions",
+			"extractions",
 			"updated_by",
 			"updated_at",
 		}),
@@ -178,6 +215,27 @@ func (r *executionCaseResultRepository) UpdateResult(id uint, updates map[string
 	if responseTime, ok := updates["response_time"].(string); ok {
 		existingResult.ResponseTime = responseTime
 	}
+	if statusCode, ok := updates["status_code"].(int); ok {
+		existingResult.StatusCode = statusCode
+	}
+	if actualResponse, ok := updates["actual_response"].(string); ok {
+		existingResult.ActualResponse = actualResponse
+	}
+	if assertionResults, ok := updates["assertion_results"].(string); ok {
+		existingResult.AssertionResults = assertionResults
+	}
+	if extractedVars, ok := updates["extracted_vars"].(string); ok {
+		existingResult.ExtractedVars = extractedVars
+	}
+	if retryCount, ok := updates["retry_count"].(int); ok {
+		existingResult.RetryCount = retryCount
+	}
+	if flaky, ok := updates["flaky"].(bool); ok {
+		existingResult.Flaky = flaky
+	}
+	if browserOptions, ok := updates["browser_options"].(string); ok {
+		existingResult.BrowserOptions = browserOptions
+	}
 	if updatedBy, ok := updates["updated_by"].(uint); ok {
 		existingResult.UpdatedBy = updatedBy
 	}
@@ -191,19 +249,67 @@ func (r *executionCaseResultRepository) UpdateResult(id uint, updates map[string
 	return nil
 }
 
-// DeleteByTaskUUID 删除任务的所有执行结果(硬删除)
-func (r *executionCaseResultRepository) DeleteByTaskUUID(taskUUID string) error {
-	result := r.db.Unscoped().
-		Where("task_uuid = ?", taskUUID).
-		Delete(&models.ExecutionCaseResult{})
+// ResetTestResults 将任务中指定的用例结果重置为NR并清除不稳定标记(执行批次开始前调用)
+// 只重置本批次将执行的用例，不可执行用例(无脚本/手工)保留原结果
+// 使用UpdateColumns跳过钩子，批量更新时模型为空会导致验证失败
+func (r *executionCaseResultRepository) ResetTestResults(taskUUID string, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	err := r.db.Model(&models.ExecutionCaseResult{}).
+		Where("task_uuid = ? AND id IN ?", taskUUID, ids).
+		UpdateColumns(map[string]interface{}{"test_result": "NR", "retry_count": 0, "flaky": false}).Error
+	if err != nil {
+		return fmt.Errorf("reset results by task_uuid %s: %w", taskUUID, err)
+	}
+	return nil
+}
 
-	if result.Error != nil {
-		return fmt.Errorf("delete results by task_uuid %s: %w", taskUUID, result.Error)
+// DeleteByTaskUUID 删除任务的所有执行结果及手工执行会话、缺陷关联(硬删除)
+func (r *executionCaseResultRepository) DeleteByTaskUUID(taskUUID string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 执行结果删除后关联不再有效，避免缺陷指向不存在的执行结果
+		if err := tx.Where("task_uuid = ?", taskUUID).Delete(&models.DefectCaseLink{}).Error; err != nil {
+			return err
+		}
+		sessionIDs := tx.Model(&models.ManualExecutionSession{}).Select("id").Where("task_uuid = ?", taskUUID)
+		if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.ManualStepResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("task_uuid = ?", taskUUID).Delete(&models.ManualExecutionSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("task_uuid = ?", taskUUID).Delete(&models.ExecutionCaseResult{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("delete results by task_uuid %s: %w", taskUUID, err)
 	}
 
 	return nil
 }
 
+// AssignCases 按范围批量设置用例负责人，返回受影响的用例数
+// 使用UpdateColumns跳过钩子，批量更新时模型为空会导致验证失败
+func (r *executionCaseResultRepository) AssignCases(taskUUID string, scope CaseAssignScope, assigneeID uint, assigneeName string) (int64, error) {
+	query := r.db.Model(&models.ExecutionCaseResult{}).Where("task_uuid = ?", taskUUID)
+	if len(scope.CaseResultIDs) > 0 {
+		query = query.Where("id IN ?", scope.CaseResultIDs)
+	}
+	if scope.CaseGroupName != "" {
+		query = query.Where("case_group_name = ?", scope.CaseGroupName)
+	}
+	if scope.MajorFunction != "" {
+		query = query.Where("(major_function_cn = ? OR major_function_jp = ? OR major_function_en = ?)",
+			scope.MajorFunction, scope.MajorFunction, scope.MajorFunction)
+	}
+
+	result := query.UpdateColumns(map[string]interface{}{"assignee_id": assigneeID, "assignee_name": assigneeName})
+	if result.Error != nil {
+		return 0, fmt.Errorf("assign cases of task_uuid %s: %w", taskUUID, result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // GetStatistics 统计任务的用例总数和各状态数量
 func (r *executionCaseResultRepository) GetStatistics(taskUUID string) (map[string]int, error) {
 	type StatResult struct {
@@ -238,3 +344,27 @@ func (r *executionCaseResultRepository) GetStatistics(taskUUID string) (map[stri
 		"block_count": stat.BlockCount,
 	}, nil
 }
+
+// GetAssigneeStatistics 按负责人统计任务的用例数量和各状态数量(未分配的用例归入assignee_id=0)
+func (r *executionCaseResultRepository) GetAssigneeStatistics(taskUUID string) ([]*AssigneeStatistic, error) {
+	var stats []*AssigneeStatistic
+	err := r.db.Model(&models.ExecutionCaseResult{}).
+		Select(`
+			assignee_id,
+			MAX(assignee_name) as assignee_name,
+			COUNT(*) as total,
+			SUM(CASE WHEN test_result = 'NR' THEN 1 ELSE 0 END) as nr_count,
+			SUM(CASE WHEN test_result = 'OK' THEN 1 ELSE 0 END) as ok_count,
+			SUM(CASE WHEN test_result = 'NG' THEN 1 ELSE 0 END) as ng_count,
+			SUM(CASE WHEN test_result = 'Block' THEN 1 ELSE 0 END) as block_count
+		`).
+		Where("task_uuid = ?", taskUUID).
+		Group("assignee_id").
+		Order("assignee_id ASC").
+		Scan(&stats).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("get assignee statistics for task_uuid %s: %w", taskUUID, err)
+	}
+	return stats, nil
+}
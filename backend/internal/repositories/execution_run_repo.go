@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExecutionRunRepository 执行批次仓储接口
+type ExecutionRunRepository interface {
+	Create(run *models.ExecutionRun) error
+	GetByUUID(runUUID string) (*models.ExecutionRun, error)
+	GetByTaskUUID(taskUUID string) ([]*models.ExecutionRun, error)
+	GetActiveByTaskUUID(taskUUID string) (*models.ExecutionRun, error)
+	GetActive() ([]*models.ExecutionRun, error)
+	GetScheduledByProject(projectID uint, limit int) ([]*models.ExecutionRun, error)
+	GetByMatrixUUID(matrixUUID string) ([]*models.ExecutionRun, error)
+	UpdateByUUID(runUUID string, updates map[string]interface{}) error
+	ClaimLease(runUUID string, owner string, now time.Time, until time.Time) (bool, error)
+	RenewLeases(runUUIDs []string, owner string, until time.Time) error
+	DeleteByTaskUUID(taskUUID string) error
+}
+
+type executionRunRepository struct {
+	db *gorm.DB
+}
+
+// NewExecutionRunRepository 创建执行批次仓储实例
+func NewExecutionRunRepository(db *gorm.DB) ExecutionRunRepository {
+	return &executionRunRepository{db: db}
+}
+
+// Create 插入执行批次
+func (r *executionRunRepository) Create(run *models.ExecutionRun) error {
+	if err := r.db.Create(run).Error; err != nil {
+		return fmt.Errorf("create execution run: %w", err)
+	}
+	return nil
+}
+
+// GetByUUID 根据UUID查询执行批次
+func (r *executionRunRepository) GetByUUID(runUUID string) (*models.ExecutionRun, error) {
+	var run models.ExecutionRun
+	err := r.db.Where("run_uuid = ?", runUUID).First(&run).Error
+	if err != nil {
+		// 保留gorm.ErrRecordNotFound
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetByTaskUUID 获取任务的所有执行批次(按创建时间倒序)
+func (r *executionRunRepository) GetByTaskUUID(taskUUID string) ([]*models.ExecutionRun, error) {
+	var runs []*models.ExecutionRun
+	err := r.db.Where("task_uuid = ?", taskUUID).
+		Order("created_at DESC").
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("get runs by task_uuid %s: %w", taskUUID, err)
+	}
+	return runs, nil
+}
+
+// GetActiveByTaskUUID 获取任务当前排队或执行中的批次
+func (r *executionRunRepository) GetActiveByTaskUUID(taskUUID string) (*models.ExecutionRun, error) {
+	var run models.ExecutionRun
+	err := r.db.Where("task_uuid = ? AND status IN ?", taskUUID,
+		[]string{models.ExecutionRunStatusQueued, models.ExecutionRunStatusRunning}).
+		Order("created_at DESC").
+		First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetActive 获取所有排队或执行中的批次(服务重启后用于恢复)
+func (r *executionRunRepository) GetActive() ([]*models.ExecutionRun, error) {
+	var runs []*models.ExecutionRun
+	err := r.db.Where("status IN ?",
+		[]string{models.ExecutionRunStatusQueued, models.ExecutionRunStatusRunning}).
+		Order("created_at ASC").
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("get active runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetScheduledByProject 获取项目中由定时计划触发的执行批次(按创建时间倒序)
+func (r *executionRunRepository) GetScheduledByProject(projectID uint, limit int) ([]*models.ExecutionRun, error) {
+	var runs []*models.ExecutionRun
+	err := r.db.Where("project_id = ? AND trigger_type = ?", projectID, models.ExecutionRunTriggerSchedule).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("get scheduled runs by project %d: %w", projectID, err)
+	}
+	return runs, nil
+}
+
+// GetByMatrixUUID 获取同一次矩阵执行的所有批次(按环境ID升序)
+func (r *executionRunRepository) GetByMatrixUUID(matrixUUID string) ([]*models.ExecutionRun, error) {
+	var runs []*models.ExecutionRun
+	err := r.db.Where("matrix_uuid = ?", matrixUUID).
+		Order("env_profile_id ASC").
+		Find(&runs).Error
+	if err != nil {
+		return nil, fmt.Errorf("get runs by matrix %s: %w", matrixUUID, err)
+	}
+	return runs, nil
+}
+
+// UpdateByUUID 根据UUID更新批次字段
+func (r *executionRunRepository) UpdateByUUID(runUUID string, updates map[string]interface{}) error {
+	result := r.db.Model(&models.ExecutionRun{}).
+		Where("run_uuid = ?", runUUID).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update run %s: %w", runUUID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClaimLease 以条件更新接管租约已到期(或无人持有)的未完成批次
+// 多实例同时接管时只有一个实例的更新生效，返回false表示批次已被其他实例持有
+func (r *executionRunRepository) ClaimLease(runUUID string, owner string, now time.Time, until time.Time) (bool, error) {
+	result := r.db.Model(&models.ExecutionRun{}).
+		Where("run_uuid = ? AND status IN ?", runUUID,
+			[]string{models.ExecutionRunStatusQueued, models.ExecutionRunStatusRunning}).
+		Where("lease_until IS NULL OR lease_until < ?", now).
+		Updates(map[string]interface{}{
+			"owner":       owner,
+			"lease_until": until,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("claim run %s: %w", runUUID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RenewLeases 续期本实例持有的批次租约
+func (r *executionRunRepository) RenewLeases(runUUIDs []string, owner string, until time.Time) error {
+	if len(runUUIDs) == 0 {
+		return nil
+	}
+	err := r.db.Model(&models.ExecutionRun{}).
+		Where("run_uuid IN ? AND owner = ?", runUUIDs, owner).
+		Update("lease_until", until).Error
+	if err != nil {
+		return fmt.Errorf("renew run leases: %w", err)
+	}
+	return nil
+}
+
+// DeleteByTaskUUID 删除任务的所有执行批次
+func (r *executionRunRepository) DeleteByTaskUUID(taskUUID string) error {
+	err := r.db.Where("task_uuid = ?", taskUUID).Delete(&models.ExecutionRun{}).Error
+	if err != nil {
+		return fmt.Errorf("delete runs by task_uuid %s: %w", taskUUID, err)
+	}
+	return nil
+}
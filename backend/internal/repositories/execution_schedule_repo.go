@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ExecutionScheduleRepository 定时执行计划仓储接口
+type ExecutionScheduleRepository interface {
+	Create(schedule *models.ExecutionSchedule) error
+	GetByID(id uint) (*models.ExecutionSchedule, error)
+	GetByProjectID(projectID uint) ([]*models.ExecutionSchedule, error)
+	GetDue(now time.Time) ([]*models.ExecutionSchedule, error)
+	Update(id uint, updates map[string]interface{}) error
+	Claim(id uint, version int, updates map[string]interface{}) (bool, error)
+	Delete(id uint) error
+	DeleteByTaskUUID(taskUUID string) error
+}
+
+type executionScheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewExecutionScheduleRepository 创建定时执行计划仓储实例
+func NewExecutionScheduleRepository(db *gorm.DB) ExecutionScheduleRepository {
+	return &executionScheduleRepository{db: db}
+}
+
+// Create 插入执行计划
+func (r *executionScheduleRepository) Create(schedule *models.ExecutionSchedule) error {
+	if err := r.db.Create(schedule).Error; err != nil {
+		return fmt.Errorf("create execution schedule: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据ID查询执行计划
+func (r *executionScheduleRepository) GetByID(id uint) (*models.ExecutionSchedule, error) {
+	var schedule models.ExecutionSchedule
+	err := r.db.First(&schedule, id).Error
+	if err != nil {
+		// 保留gorm.ErrRecordNotFound
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// GetByProjectID 获取项目的所有执行计划
+func (r *executionScheduleRepository) GetByProjectID(projectID uint) ([]*models.ExecutionSchedule, error) {
+	var schedules []*models.ExecutionSchedule
+	err := r.db.Where("project_id = ?", projectID).
+		Order("id ASC").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("get schedules by project %d: %w", projectID, err)
+	}
+	return schedules, nil
+}
+
+// GetDue 获取已到触发时间的启用计划
+func (r *executionScheduleRepository) GetDue(now time.Time) ([]*models.ExecutionSchedule, error) {
+	var schedules []*models.ExecutionSchedule
+	err := r.db.Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("get due schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// Update 更新执行计划字段
+func (r *executionScheduleRepository) Update(id uint, updates map[string]interface{}) error {
+	result := r.db.Model(&models.ExecutionSchedule{}).
+		Where("id = ?", id).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update schedule %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Claim 以乐观锁抢占一次触发：版本号匹配时更新并递增版本，返回是否抢占成功
+// 多个服务实例同时处理同一计划时只有一个能成功
+func (r *executionScheduleRepository) Claim(id uint, version int, updates map[string]interface{}) (bool, error) {
+	values := make(map[string]interface{}, len(updates)+1)
+	for k, v := range updates {
+		values[k] = v
+	}
+	values["version"] = gorm.Expr("version + 1")
+
+	result := r.db.Model(&models.ExecutionSchedule{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(values)
+	if result.Error != nil {
+		return false, fmt.Errorf("claim schedule %d: %w", id, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Delete 删除执行计划
+func (r *executionScheduleRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.ExecutionSchedule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("delete schedule %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteByTaskUUID 删除任务的所有执行计划
+func (r *executionScheduleRepository) DeleteByTaskUUID(taskUUID string) error {
+	err := r.db.Where("task_uuid = ?", taskUUID).Delete(&models.ExecutionSchedule{}).Error
+	if err != nil {
+		return fmt.Errorf("delete schedules by task_uuid %s: %w", taskUUID, err)
+	}
+	return nil
+}
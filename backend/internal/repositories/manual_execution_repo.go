@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"fmt"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ManualExecutionRepository 手工执行会话仓储接口
+type ManualExecutionRepository interface {
+	CreateSession(session *models.ManualExecutionSession, steps []*models.ManualStepResult) error
+	GetSessionByID(id uint) (*models.ManualExecutionSession, error)
+	GetLatestSession(caseResultID uint) (*models.ManualExecutionSession, error)
+	GetActiveSessionsByTester(taskUUID string, testerID uint) ([]*models.ManualExecutionSession, error)
+	GetSessionsByTaskUUID(taskUUID string) ([]*models.ManualExecutionSession, error)
+	UpdateSession(session *models.ManualExecutionSession) error
+	GetSteps(sessionID uint) ([]*models.ManualStepResult, error)
+	UpdateStep(sessionID uint, stepIndex int, updates map[string]interface{}) error
+}
+
+type manualExecutionRepository struct {
+	db *gorm.DB
+}
+
+// NewManualExecutionRepository 创建手工执行会话仓储实例
+func NewManualExecutionRepository(db *gorm.DB) ManualExecutionRepository {
+	return &manualExecutionRepository{db: db}
+}
+
+// CreateSession 创建会话及其步骤(使用事务)
+func (r *manualExecutionRepository) CreateSession(session *models.ManualExecutionSession, steps []*models.ManualStepResult) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			return nil
+		}
+		for _, step := range steps {
+			step.SessionID = session.ID
+		}
+		return tx.Create(&steps).Error
+	})
+	if err != nil {
+		return fmt.Errorf("create manual session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionByID 根据ID获取会话
+func (r *manualExecutionRepository) GetSessionByID(id uint) (*models.ManualExecutionSession, error) {
+	var session models.ManualExecutionSession
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetLatestSession 获取用例结果最近一次的会话
+func (r *manualExecutionRepository) GetLatestSession(caseResultID uint) (*models.ManualExecutionSession, error) {
+	var session models.ManualExecutionSession
+	err := r.db.Where("case_result_id = ?", caseResultID).
+		Order("id DESC").
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveSessionsByTester 获取测试人员在任务中正在进行的会话
+func (r *manualExecutionRepository) GetActiveSessionsByTester(taskUUID string, testerID uint) ([]*models.ManualExecutionSession, error) {
+	var sessions []*models.ManualExecutionSession
+	err := r.db.Where("task_uuid = ? AND tester_id = ? AND status = ?", taskUUID, testerID, models.ManualSessionStatusInProgress).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("get active sessions by tester %d: %w", testerID, err)
+	}
+	return sessions, nil
+}
+
+// GetSessionsByTaskUUID 获取任务的所有会话
+func (r *manualExecutionRepository) GetSessionsByTaskUUID(taskUUID string) ([]*models.ManualExecutionSession, error) {
+	var sessions []*models.ManualExecutionSession
+	err := r.db.Where("task_uuid = ?", taskUUID).
+		Order("id ASC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("get sessions by task_uuid %s: %w", taskUUID, err)
+	}
+	return sessions, nil
+}
+
+// UpdateSession 保存会话
+func (r *manualExecutionRepository) UpdateSession(session *models.ManualExecutionSession) error {
+	if err := r.db.Save(session).Error; err != nil {
+		return fmt.Errorf("update manual session %d: %w", session.ID, err)
+	}
+	return nil
+}
+
+// GetSteps 获取会话的步骤结果(按步骤顺序)
+func (r *manualExecutionRepository) GetSteps(sessionID uint) ([]*models.ManualStepResult, error) {
+	var steps []*models.ManualStepResult
+	err := r.db.Where("session_id = ?", sessionID).
+		Order("step_index ASC").
+		Find(&steps).Error
+	if err != nil {
+		return nil, fmt.Errorf("get steps by session %d: %w", sessionID, err)
+	}
+	return steps, nil
+}
+
+// UpdateStep 更新单个步骤结果
+func (r *manualExecutionRepository) UpdateStep(sessionID uint, stepIndex int, updates map[string]interface{}) error {
+	result := r.db.Model(&models.ManualStepResult{}).
+		Where("session_id = ? AND step_index = ?", sessionID, stepIndex).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update step %d of session %d: %w", stepIndex, sessionID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
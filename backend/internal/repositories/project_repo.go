@@ -32,7 +32,7 @@ func (r *projectRepository) FindProjectsByUserID(userID uint) ([]models.Project,
 	var projects []models.Project
 	err := r.db.
 		Table("projects").
-		Select("projects.id, projects.name, projects.description, projects.status, projects.owner_id, projects.created_at, projects.updated_at, projects.deleted_at, users.nickname as owner_name").
+		Select("projects.id, projects.name, projects.description, projects.status, projects.owner_id, projects.script_executor, projects.created_at, projects.updated_at, projects.deleted_at, users.nickname as owner_name").
 		Joins("JOIN project_members ON project_members.project_id = projects.id").
 		Joins("LEFT JOIN users ON users.id = projects.owner_id").
 		Where("project_members.user_id = ?", userID).
@@ -68,7 +68,7 @@ func (r *projectRepository) UpdateName(id uint, name string) (*models.Project, e
 	return &project, err
 }
 
-// Update 更新项目字段(支持 name/description/status/owner_id)
+// Update 更新项目字段(支持 name/description/status/owner_id/script_executor)
 func (r *projectRepository) Update(id uint, updates map[string]interface{}) (*models.Project, error) {
 	err := r.db.Model(&models.Project{}).
 		Where("id = ?", id).
@@ -84,7 +84,7 @@ func (r *projectRepository) GetByID(id uint) (*models.Project, error) {
 	var project models.Project
 	err := r.db.
 		Table("projects").
-		Select("projects.id, projects.name, projects.description, projects.status, projects.owner_id, projects.created_at, projects.updated_at, projects.deleted_at, users.nickname as owner_name").
+		Select("projects.id, projects.name, projects.description, projects.status, projects.owner_id, projects.script_executor, projects.created_at, projects.updated_at, projects.deleted_at, users.nickname as owner_name").
 		Joins("LEFT JOIN users ON users.id = projects.owner_id").
 		Where("projects.id = ?", id).
 		Scan(&project).Error
@@ -128,11 +128,37 @@ func (r *projectRepository) DeleteWithCascade(id uint) error {
 			return err
 		}
 		if len(taskUUIDs) > 0 {
+			// 删除用例执行记录
+			if err := tx.Where("task_uuid IN ?", taskUUIDs).Delete(&models.ExecutionAttempt{}).Error; err != nil {
+				return err
+			}
 			// 删除执行用例结果
 			if err := tx.Unscoped().Where("task_uuid IN ?", taskUUIDs).Delete(&models.ExecutionCaseResult{}).Error; err != nil {
 				return err
 			}
 		}
+		// 删除手工执行步骤结果及会话
+		sessionIDs := tx.Model(&models.ManualExecutionSession{}).Select("id").Where("project_id = ?", id)
+		if err := tx.Where("session_id IN (?)", sessionIDs).Delete(&models.ManualStepResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&models.ManualExecutionSession{}).Error; err != nil {
+			return err
+		}
+		// 删除执行产物记录(产物文件由服务层删除)
+		if err := tx.Where("project_id = ?", id).Delete(&models.ExecutionArtifact{}).Error; err != nil {
+			return err
+		}
+		// 删除执行批次、定时执行计划和执行环境
+		if err := tx.Where("project_id = ?", id).Delete(&models.ExecutionRun{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&models.ExecutionSchedule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&models.EnvironmentProfile{}).Error; err != nil {
+			return err
+		}
 		// 删除执行任务
 		if err := tx.Unscoped().Where("project_id = ?", id).Delete(&models.ExecutionTask{}).Error; err != nil {
 			return err
@@ -169,6 +195,10 @@ func (r *projectRepository) DeleteWithCascade(id uint) error {
 			if err := tx.Unscoped().Where("defect_id IN ?", defectIDs).Delete(&models.DefectComment{}).Error; err != nil {
 				return err
 			}
+			// 删除缺陷变更记录
+			if err := tx.Where("defect_id IN ?", defectIDs).Delete(&models.DefectHistory{}).Error; err != nil {
+				return err
+			}
 		}
 		// 删除缺陷
 		if err := tx.Unscoped().Where("project_id = ?", id).Delete(&models.Defect{}).Error; err != nil {
@@ -181,6 +211,17 @@ func (r *projectRepository) DeleteWithCascade(id uint) error {
 		if err := tx.Unscoped().Where("project_id = ?", id).Delete(&models.DefectPhase{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("project_id = ?", id).Delete(&models.DefectWorkflow{}).Error; err != nil {
+			return err
+		}
+		// 删除缺陷与执行结果的关联
+		if err := tx.Where("project_id = ?", id).Delete(&models.DefectCaseLink{}).Error; err != nil {
+			return err
+		}
+		// 删除保存的缺陷筛选
+		if err := tx.Where("project_id = ?", id).Delete(&models.DefectSavedFilter{}).Error; err != nil {
+			return err
+		}
 
 		// 9. 删除需求管理相关
 		if err := tx.Unscoped().Where("project_id = ?", id).Delete(&models.RequirementItem{}).Error; err != nil {
@@ -0,0 +1,550 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 断言类型
+const (
+	AssertionTypeStatus          = "status"
+	AssertionTypeJSONPath        = "json_path"
+	AssertionTypeBodyRegex       = "body_regex"
+	AssertionTypeHeader          = "header"
+	AssertionTypeJSONSchema      = "json_schema"
+	AssertionTypeMaxResponseTime = "max_response_time"
+)
+
+// 断言比较运算符
+var assertionOps = map[string]bool{
+	"eq": true, "ne": true,
+	"contains": true, "not_contains": true,
+	"regex":  true,
+	"exists": true, "not_exists": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+}
+
+// APIAssertion 接口用例的单条断言，按JSON数组存储在用例的 assertions 字段
+//
+//	[{"type":"status","expected":200},
+//	 {"type":"json_path","path":"data.items.0.id","op":"eq","expected":1},
+//	 {"type":"body_regex","pattern":"\"ok\"\\s*:\\s*true"},
+//	 {"type":"header","name":"Content-Type","op":"contains","expected":"json"},
+//	 {"type":"json_schema","schema":{"type":"object","required":["data"]}},
+//	 {"type":"max_response_time","max_ms":500}]
+type APIAssertion struct {
+	Type     string          `json:"type"`
+	Path     string          `json:"path,omitempty"`     // json_path: GJSON风格路径，如 data.items.0.id、data.items.#
+	Name     string          `json:"name,omitempty"`     // header: 响应头名称
+	Op       string          `json:"op,omitempty"`       // 默认 eq
+	Expected interface{}     `json:"expected,omitempty"` // 期望值
+	Pattern  string          `json:"pattern,omitempty"`  // body_regex: 正则表达式
+	Schema   json.RawMessage `json:"schema,omitempty"`   // json_schema: JSON Schema 子集
+	MaxMs    int             `json:"max_ms,omitempty"`   // max_response_time: 最大响应时间(毫秒)
+}
+
+// AssertionResult 单条断言的执行结果
+type AssertionResult struct {
+	Index    int    `json:"index"`
+	Type     string `json:"type"`
+	Target   string `json:"target,omitempty"` // json_path 路径或 header 名称
+	Passed   bool   `json:"passed"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// ParseAPIAssertions 解析并校验断言JSON，空文本返回nil
+func ParseAPIAssertions(text string) ([]APIAssertion, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var assertions []APIAssertion
+	if err := json.Unmarshal([]byte(text), &assertions); err != nil {
+		return nil, fmt.Errorf("invalid assertions json: %w", err)
+	}
+
+	for i, a := range assertions {
+		if a.Op != "" && !assertionOps[a.Op] {
+			return nil, fmt.Errorf("assertion #%d: unsupported op %q", i+1, a.Op)
+		}
+		switch a.Type {
+		case AssertionTypeStatus:
+			if a.Expected == nil {
+				return nil, fmt.Errorf("assertion #%d: status requires expected", i+1)
+			}
+		case AssertionTypeJSONPath:
+			if a.Path == "" {
+				return nil, fmt.Errorf("assertion #%d: json_path requires path", i+1)
+			}
+		case AssertionTypeBodyRegex:
+			if _, err := regexp.Compile(a.Pattern); err != nil || a.Pattern == "" {
+				return nil, fmt.Errorf("assertion #%d: body_regex requires a valid pattern", i+1)
+			}
+		case AssertionTypeHeader:
+			if a.Name == "" {
+				return nil, fmt.Errorf("assertion #%d: header requires name", i+1)
+			}
+		case AssertionTypeJSONSchema:
+			var schema map[string]interface{}
+			if err := json.Unmarshal(a.Schema, &schema); err != nil {
+				return nil, fmt.Errorf("assertion #%d: json_schema requires an object schema", i+1)
+			}
+		case AssertionTypeMaxResponseTime:
+			if a.MaxMs <= 0 {
+				return nil, fmt.Errorf("assertion #%d: max_response_time requires max_ms > 0", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("assertion #%d: unsupported type %q", i+1, a.Type)
+		}
+	}
+	return assertions, nil
+}
+
+// NormalizeAPIAssertions 将请求中的断言(JSON字符串或数组)规范化为存储文本并校验
+func NormalizeAPIAssertions(raw interface{}) (string, error) {
+	var text string
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		text = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid assertions: %w", err)
+		}
+		text = string(data)
+	}
+	if _, err := ParseAPIAssertions(text); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// EvaluateAPIAssertions 对接口响应逐条执行断言
+func EvaluateAPIAssertions(assertions []APIAssertion, resp *APIResponse) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+
+	// 响应体只解析一次，非JSON时 bodyErr 记录原因
+	var body interface{}
+	bodyErr := json.Unmarshal([]byte(resp.Body), &body)
+
+	for i, a := range assertions {
+		r := AssertionResult{Index: i + 1, Type: a.Type}
+		op := a.Op
+		if op == "" {
+			op = "eq"
+		}
+
+		switch a.Type {
+		case AssertionTypeStatus:
+			r.Expected = formatAssertionValue(a.Expected)
+			r.Actual = strconv.Itoa(resp.StatusCode)
+			r.Passed, r.Message = compareAssertionValue(op, float64(resp.StatusCode), true, a.Expected)
+
+		case AssertionTypeJSONPath:
+			r.Target = a.Path
+			r.Expected = formatAssertionValue(a.Expected)
+			if bodyErr != nil {
+				r.Message = "response body is not valid json"
+				break
+			}
+			value, found := lookupJSONPath(body, a.Path)
+			if found {
+				r.Actual = formatAssertionValue(value)
+			}
+			r.Passed, r.Message = compareAssertionValue(op, value, found, a.Expected)
+
+		case AssertionTypeBodyRegex:
+			r.Expected = a.Pattern
+			re, err := regexp.Compile(a.Pattern)
+			if err != nil {
+				r.Message = err.Error()
+				break
+			}
+			r.Passed = re.MatchString(resp.Body)
+			if !r.Passed {
+				r.Message = "body does not match pattern"
+			}
+
+		case AssertionTypeHeader:
+			r.Target = a.Name
+			r.Expected = formatAssertionValue(a.Expected)
+			values := resp.Header.Values(a.Name)
+			found := len(values) > 0
+			var actual interface{}
+			if found {
+				actual = strings.Join(values, ", ")
+				r.Actual = actual.(string)
+			}
+			r.Passed, r.Message = compareAssertionValue(op, actual, found, a.Expected)
+
+		case AssertionTypeJSONSchema:
+			if bodyErr != nil {
+				r.Message = "response body is not valid json"
+				break
+			}
+			var schema map[string]interface{}
+			if err := json.Unmarshal(a.Schema, &schema); err != nil {
+				r.Message = "invalid schema"
+				break
+			}
+			violations := validateJSONSchema(schema, body, "$")
+			r.Passed = len(violations) == 0
+			if !r.Passed {
+				r.Message = strings.Join(violations, "; ")
+			}
+
+		case AssertionTypeMaxResponseTime:
+			r.Expected = fmt.Sprintf("<= %dms", a.MaxMs)
+			r.Actual = fmt.Sprintf("%dms", resp.LatencyMs)
+			r.Passed = resp.LatencyMs <= a.MaxMs
+			if !r.Passed {
+				r.Message = "response time exceeded"
+			}
+
+		default:
+			r.Message = fmt.Sprintf("unsupported type %q", a.Type)
+		}
+
+		results = append(results, r)
+	}
+	return results
+}
+
+// summarizeAssertionFailures 汇总失败断言，写入用例备注
+func summarizeAssertionFailures(results []AssertionResult) string {
+	var parts []string
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		desc := fmt.Sprintf("#%d %s", r.Index, r.Type)
+		if r.Target != "" {
+			desc += " " + r.Target
+		}
+		detail := r.Message
+		if r.Expected != "" || r.Actual != "" {
+			detail = fmt.Sprintf("expected %s, actual %s", r.Expected, r.Actual)
+			if r.Message != "" {
+				detail += " (" + r.Message + ")"
+			}
+		}
+		parts = append(parts, desc+": "+detail)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// lookupJSONPath 按GJSON风格路径取值
+// 支持 "a.b.0.c"、"$.a.b[0]"，"#" 返回数组长度
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.TrimPrefix(path, ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return doc, true
+	}
+
+	current := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[seg]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			if seg == "#" {
+				current = float64(len(node))
+				continue
+			}
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			current = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// compareAssertionValue 按运算符比较实际值和期望值，返回是否通过及失败原因
+func compareAssertionValue(op string, actual interface{}, found bool, expected interface{}) (bool, string) {
+	switch op {
+	case "exists":
+		if found {
+			return true, ""
+		}
+		return false, "value not found"
+	case "not_exists":
+		if !found {
+			return true, ""
+		}
+		return false, "value exists"
+	}
+
+	if !found {
+		return false, "value not found"
+	}
+
+	switch op {
+	case "eq":
+		if assertionValuesEqual(actual, expected) {
+			return true, ""
+		}
+		return false, "not equal"
+	case "ne":
+		if !assertionValuesEqual(actual, expected) {
+			return true, ""
+		}
+		return false, "equal"
+	case "contains", "not_contains":
+		contains := assertionContains(actual, expected)
+		if contains == (op == "contains") {
+			return true, ""
+		}
+		if contains {
+			return false, "value contains expected"
+		}
+		return false, "value does not contain expected"
+	case "regex":
+		re, err := regexp.Compile(formatAssertionValue(expected))
+		if err != nil {
+			return false, err.Error()
+		}
+		if re.MatchString(formatAssertionValue(actual)) {
+			return true, ""
+		}
+		return false, "value does not match pattern"
+	case "gt", "gte", "lt", "lte":
+		a, okA := toAssertionNumber(actual)
+		e, okE := toAssertionNumber(expected)
+		if !okA || !okE {
+			return false, "value is not a number"
+		}
+		var passed bool
+		switch op {
+		case "gt":
+			passed = a > e
+		case "gte":
+			passed = a >= e
+		case "lt":
+			passed = a < e
+		case "lte":
+			passed = a <= e
+		}
+		if passed {
+			return true, ""
+		}
+		return false, fmt.Sprintf("not %s", op)
+	}
+	return false, fmt.Sprintf("unsupported op %q", op)
+}
+
+// assertionValuesEqual 数值按数值比较，其余按JSON结构比较，字符串与标量按文本比较
+func assertionValuesEqual(actual, expected interface{}) bool {
+	if a, ok := toAssertionNumber(actual); ok {
+		if e, ok := toAssertionNumber(expected); ok {
+			return a == e
+		}
+	}
+	if reflect.DeepEqual(actual, expected) {
+		return true
+	}
+	switch actual.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return formatAssertionValue(actual) == formatAssertionValue(expected)
+}
+
+// assertionContains 数组判断是否含有元素，其余按文本包含判断
+func assertionContains(actual, expected interface{}) bool {
+	if arr, ok := actual.([]interface{}); ok {
+		for _, item := range arr {
+			if assertionValuesEqual(item, expected) {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(formatAssertionValue(actual), formatAssertionValue(expected))
+}
+
+// toAssertionNumber 将JSON数值或数字字符串转换为float64
+func toAssertionNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// formatAssertionValue 断言值的文本形式
+func formatAssertionValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1e15 {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// validateJSONSchema 按JSON Schema子集校验，返回违规描述
+// 支持 type/enum/const/required/properties/additionalProperties/items/
+// minItems/maxItems/minLength/maxLength/minimum/maximum/pattern
+func validateJSONSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var violations []string
+
+	if t, ok := schema["type"]; ok {
+		if !matchSchemaType(t, value) {
+			return []string{fmt.Sprintf("%s: expected type %s", path, formatAssertionValue(t))}
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, e := range enum {
+			if assertionValuesEqual(value, e) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			violations = append(violations, fmt.Sprintf("%s: value not in enum", path))
+		}
+	}
+	if c, ok := schema["const"]; ok && !assertionValuesEqual(value, c) {
+		violations = append(violations, fmt.Sprintf("%s: expected const %s", path, formatAssertionValue(c)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				key, _ := r.(string)
+				if _, exists := v[key]; !exists {
+					violations = append(violations, fmt.Sprintf("%s: missing required property %q", path, key))
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for key, sub := range props {
+			subSchema, ok := sub.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if child, exists := v[key]; exists {
+				violations = append(violations, validateJSONSchema(subSchema, child, path+"."+key)...)
+			}
+		}
+		if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+			for key := range v {
+				if _, declared := props[key]; !declared {
+					violations = append(violations, fmt.Sprintf("%s: unexpected property %q", path, key))
+				}
+			}
+		}
+
+	case []interface{}:
+		if min, ok := toAssertionNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			violations = append(violations, fmt.Sprintf("%s: expected at least %s items", path, formatAssertionValue(min)))
+		}
+		if max, ok := toAssertionNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			violations = append(violations, fmt.Sprintf("%s: expected at most %s items", path, formatAssertionValue(max)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				violations = append(violations, validateJSONSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := toAssertionNumber(schema["minLength"]); ok && length < min {
+			violations = append(violations, fmt.Sprintf("%s: shorter than %s", path, formatAssertionValue(min)))
+		}
+		if max, ok := toAssertionNumber(schema["maxLength"]); ok && length > max {
+			violations = append(violations, fmt.Sprintf("%s: longer than %s", path, formatAssertionValue(max)))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				violations = append(violations, fmt.Sprintf("%s: does not match pattern", path))
+			}
+		}
+
+	case float64:
+		if min, ok := toAssertionNumber(schema["minimum"]); ok && v < min {
+			violations = append(violations, fmt.Sprintf("%s: less than minimum %s", path, formatAssertionValue(min)))
+		}
+		if max, ok := toAssertionNumber(schema["maximum"]); ok && v > max {
+			violations = append(violations, fmt.Sprintf("%s: greater than maximum %s", path, formatAssertionValue(max)))
+		}
+	}
+
+	return violations
+}
+
+// matchSchemaType 判断值是否符合schema的type(字符串或字符串数组)
+func matchSchemaType(t interface{}, value interface{}) bool {
+	if types, ok := t.([]interface{}); ok {
+		for _, item := range types {
+			if matchSchemaType(item, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	name, _ := t.(string)
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAPIAssertions_Validation 校验断言类型和必填字段
+func TestParseAPIAssertions_Validation(t *testing.T) {
+	assertions, err := ParseAPIAssertions("")
+	require.NoError(t, err)
+	assert.Nil(t, assertions)
+
+	assertions, err = ParseAPIAssertions(`[{"type":"status","expected":200},{"type":"max_response_time","max_ms":500}]`)
+	require.NoError(t, err)
+	assert.Len(t, assertions, 2)
+
+	invalid := []string{
+		`{"type":"status"}`,
+		`[{"type":"status"}]`,
+		`[{"type":"json_path"}]`,
+		`[{"type":"body_regex","pattern":"("}]`,
+		`[{"type":"header"}]`,
+		`[{"type":"json_schema","schema":[]}]`,
+		`[{"type":"max_response_time"}]`,
+		`[{"type":"unknown"}]`,
+		`[{"type":"status","op":"like","expected":200}]`,
+	}
+	for _, text := range invalid {
+		_, err := ParseAPIAssertions(text)
+		assert.Error(t, err, text)
+	}
+}
+
+// TestEvaluateAPIAssertions 各类型断言的判定结果
+func TestEvaluateAPIAssertions(t *testing.T) {
+	assertions, err := ParseAPIAssertions(`[
+		{"type":"status","expected":200},
+		{"type":"json_path","path":"data.items.0.id","expected":1},
+		{"type":"json_path","path":"$.data.items[1].name","op":"contains","expected":"ob"},
+		{"type":"json_path","path":"data.items.#","op":"gte","expected":2},
+		{"type":"json_path","path":"data.missing","op":"not_exists"},
+		{"type":"body_regex","pattern":"\"ok\"\\s*:\\s*true"},
+		{"type":"header","name":"Content-Type","op":"contains","expected":"json"},
+		{"type":"json_schema","schema":{"type":"object","required":["ok","data"],"properties":{"data":{"type":"object","properties":{"items":{"type":"array","minItems":1,"items":{"type":"object","required":["id"]}}}}}}},
+		{"type":"max_response_time","max_ms":500}
+	]`)
+	require.NoError(t, err)
+
+	resp := &APIResponse{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       `{"ok": true, "data": {"items": [{"id": 1, "name": "alice"}, {"id": 2, "name": "bob"}]}}`,
+		LatencyMs:  120,
+	}
+	results := EvaluateAPIAssertions(assertions, resp)
+	require.Len(t, results, len(assertions))
+	for _, r := range results {
+		assert.True(t, r.Passed, "assertion #%d %s: %s", r.Index, r.Type, r.Message)
+	}
+	assert.Empty(t, summarizeAssertionFailures(results))
+
+	resp.StatusCode = 500
+	resp.LatencyMs = 800
+	resp.Body = `{"ok": false, "data": {"items": []}}`
+	results = EvaluateAPIAssertions(assertions, resp)
+
+	failed := map[int]bool{}
+	for _, r := range results {
+		if !r.Passed {
+			failed[r.Index] = true
+		}
+	}
+	assert.Equal(t, map[int]bool{1: true, 2: true, 3: true, 4: true, 6: true, 8: true, 9: true}, failed)
+	summary := summarizeAssertionFailures(results)
+	assert.Contains(t, summary, "#1 status: expected 200, actual 500")
+	assert.Contains(t, summary, "#9 max_response_time")
+}
+
+// TestEvaluateAPIAssertions_NonJSONBody 响应体非JSON时json_path断言失败
+func TestEvaluateAPIAssertions_NonJSONBody(t *testing.T) {
+	assertions := []APIAssertion{{Type: AssertionTypeJSONPath, Path: "id", Op: "exists"}}
+	results := EvaluateAPIAssertions(assertions, &APIResponse{StatusCode: 200, Body: "<html></html>"})
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, "response body is not valid json", results[0].Message)
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"webtest/internal/models"
+)
+
+// 变量提取来源
+const (
+	ExtractionSourceJSONPath = "json_path"
+	ExtractionSourceHeader   = "header"
+	ExtractionSourceRegex    = "regex"
+)
+
+// extractionVarPattern 提取变量名规则，与 ${VAR} 占位符保持一致
+var extractionVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// APIExtraction 从接口响应中提取批次变量的规则，按JSON数组存储在用例的 extractions 字段
+// 提取出的变量在同一执行批次中对后续用例可见，以 ${var} 引用
+//
+//	[{"var":"token","source":"json_path","path":"data.token"},
+//	 {"var":"location","source":"header","name":"Location"},
+//	 {"var":"order_id","source":"regex","pattern":"\"orderId\":\\s*\"(\\w+)\"","group":1}]
+type APIExtraction struct {
+	Var     string `json:"var"`
+	Source  string `json:"source"`
+	Path    string `json:"path,omitempty"`    // json_path: GJSON风格路径
+	Name    string `json:"name,omitempty"`    // header: 响应头名称
+	Pattern string `json:"pattern,omitempty"` // regex: 作用于响应体的正则
+	Group   int    `json:"group,omitempty"`   // regex: 捕获组序号，默认有捕获组时取第1组
+}
+
+// ParseAPIExtractions 解析并校验提取规则JSON，空文本返回nil
+func ParseAPIExtractions(text string) ([]APIExtraction, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	var extractions []APIExtraction
+	if err := json.Unmarshal([]byte(text), &extractions); err != nil {
+		return nil, fmt.Errorf("invalid extractions json: %w", err)
+	}
+
+	for i, e := range extractions {
+		if !extractionVarPattern.MatchString(e.Var) {
+			return nil, fmt.Errorf("extraction #%d: invalid var name %q", i+1, e.Var)
+		}
+		switch e.Source {
+		case ExtractionSourceJSONPath:
+			if e.Path == "" {
+				return nil, fmt.Errorf("extraction #%d: json_path requires path", i+1)
+			}
+		case ExtractionSourceHeader:
+			if e.Name == "" {
+				return nil, fmt.Errorf("extraction #%d: header requires name", i+1)
+			}
+		case ExtractionSourceRegex:
+			re, err := regexp.Compile(e.Pattern)
+			if err != nil || e.Pattern == "" {
+				return nil, fmt.Errorf("extraction #%d: regex requires a valid pattern", i+1)
+			}
+			if e.Group < 0 || e.Group > re.NumSubexp() {
+				return nil, fmt.Errorf("extraction #%d: group %d out of range", i+1, e.Group)
+			}
+		default:
+			return nil, fmt.Errorf("extraction #%d: unsupported source %q", i+1, e.Source)
+		}
+	}
+	return extractions, nil
+}
+
+// NormalizeAPIExtractions 将请求中的提取规则(JSON字符串或数组)规范化为存储文本并校验
+func NormalizeAPIExtractions(raw interface{}) (string, error) {
+	var text string
+	switch v := raw.(type) {
+	case nil:
+		return "", nil
+	case string:
+		text = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid extractions: %w", err)
+		}
+		text = string(data)
+	}
+	if _, err := ParseAPIExtractions(text); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// ExtractAPIVariables 按规则从响应中提取变量，返回提取成功的变量和失败原因
+func ExtractAPIVariables(extractions []APIExtraction, resp *APIResponse) (map[string]string, []string) {
+	values := make(map[string]string, len(extractions))
+	var failures []string
+
+	var body interface{}
+	bodyErr := json.Unmarshal([]byte(resp.Body), &body)
+
+	for _, e := range extractions {
+		switch e.Source {
+		case ExtractionSourceJSONPath:
+			if bodyErr != nil {
+				failures = append(failures, fmt.Sprintf("%s: response body is not valid json", e.Var))
+				continue
+			}
+			value, found := lookupJSONPath(body, e.Path)
+			if !found || value == nil {
+				failures = append(failures, fmt.Sprintf("%s: path %s not found", e.Var, e.Path))
+				continue
+			}
+			values[e.Var] = formatAssertionValue(value)
+
+		case ExtractionSourceHeader:
+			value := resp.Header.Get(e.Name)
+			if value == "" {
+				failures = append(failures, fmt.Sprintf("%s: header %s not found", e.Var, e.Name))
+				continue
+			}
+			values[e.Var] = value
+
+		case ExtractionSourceRegex:
+			re, err := regexp.Compile(e.Pattern)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", e.Var, err))
+				continue
+			}
+			match := re.FindStringSubmatch(resp.Body)
+			if match == nil {
+				failures = append(failures, fmt.Sprintf("%s: pattern not matched", e.Var))
+				continue
+			}
+			group := e.Group
+			if group == 0 && len(match) > 1 {
+				group = 1
+			}
+			values[e.Var] = match[group]
+		}
+	}
+	return values, failures
+}
+
+// mergeRunVariables 合并批次变量和任务变量，批次变量在前以优先替换
+func mergeRunVariables(variables []*models.UserDefinedVariable, runVars map[string]string) []*models.UserDefinedVariable {
+	if len(runVars) == 0 {
+		return variables
+	}
+	keys := make([]string, 0, len(runVars))
+	for k := range runVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	merged := make([]*models.UserDefinedVariable, 0, len(runVars)+len(variables))
+	for _, k := range keys {
+		merged = append(merged, &models.UserDefinedVariable{VarKey: k, VarValue: runVars[k]})
+	}
+	return append(merged, variables...)
+}
+
+// maskedVariablesJSON 将变量脱敏后序列化，用于在报告中展示
+func maskedVariablesJSON(values map[string]string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	masked := make(map[string]string, len(values))
+	for k, v := range values {
+		masked[k] = maskValue(k, v)
+	}
+	data, err := json.Marshal(masked)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExtractAPIVariables 支持json_path/header/regex三种提取来源
+func TestExtractAPIVariables(t *testing.T) {
+	extractions, err := ParseAPIExtractions(`[
+		{"var":"token","source":"json_path","path":"data.token"},
+		{"var":"userId","source":"json_path","path":"$.data.users[0].id"},
+		{"var":"location","source":"header","name":"Location"},
+		{"var":"order_id","source":"regex","pattern":"order-(\\d+)"},
+		{"var":"missing","source":"json_path","path":"data.none"}
+	]`)
+	require.NoError(t, err)
+
+	resp := &APIResponse{
+		StatusCode: 201,
+		Header:     http.Header{"Location": []string{"/orders/42"}},
+		Body:       `{"data": {"token": "abc123", "users": [{"id": 7}], "ref": "order-42"}}`,
+	}
+	values, failures := ExtractAPIVariables(extractions, resp)
+	assert.Equal(t, map[string]string{
+		"token":    "abc123",
+		"userId":   "7",
+		"location": "/orders/42",
+		"order_id": "42",
+	}, values)
+	assert.Equal(t, []string{"missing: path data.none not found"}, failures)
+
+	assert.JSONEq(t, `{"token":"ab***","userId":"7","location":"/orders/42","order_id":"42"}`, maskedVariablesJSON(values))
+
+	_, err = ParseAPIExtractions(`[{"var":"bad-name","source":"header","name":"X"}]`)
+	assert.Error(t, err)
+	_, err = ParseAPIExtractions(`[{"var":"id","source":"regex","pattern":"(\\d+)","group":2}]`)
+	assert.Error(t, err)
+}
+
+// TestMergeRunVariables_Precedence 批次提取的变量优先于同名任务变量
+func TestMergeRunVariables_Precedence(t *testing.T) {
+	s := &executionTaskService{}
+	variables := []*models.UserDefinedVariable{
+		{VarKey: "BASE_URL", VarValue: "http://localhost"},
+		{VarKey: "TOKEN", VarValue: "static"},
+	}
+	merged := mergeRunVariables(variables, map[string]string{"TOKEN": "extracted", "orderId": "42"})
+
+	result := s.replaceVariables("${BASE_URL}/orders/${orderId}?t=${TOKEN}", merged)
+	assert.Equal(t, "http://localhost/orders/42?t=extracted", result)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxAPIResponseBody 记录到用例结果中的响应体上限(字节)
+const maxAPIResponseBody = 64 * 1024
+
+// APIRequest 结构化的接口请求(来自 ApiTestCase 的 URL/Method/Header/Body)
+type APIRequest struct {
+	Method string
+	URL    string
+	Header string // JSON对象或多行 "Key: Value"
+	Body   string
+}
+
+// APIResponse 接口响应
+type APIResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+	LatencyMs  int
+	Truncated  bool // 响应体超过上限被截断
+}
+
+// APIRunner 基于 net/http 的接口用例执行器，不依赖 playwright-executor
+type APIRunner struct {
+	httpClient *http.Client
+}
+
+// NewAPIRunner 创建接口执行器实例
+func NewAPIRunner(timeout time.Duration) *APIRunner {
+	return &APIRunner{
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Do 发送请求并读取响应，非2xx状态码不视为错误，由调用方判定结果
+func (r *APIRunner) Do(ctx context.Context, apiReq APIRequest) (*APIResponse, error) {
+	method := strings.ToUpper(strings.TrimSpace(apiReq.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	url := strings.TrimSpace(apiReq.URL)
+	if url == "" {
+		return nil, fmt.Errorf("url is empty")
+	}
+
+	headers, err := parseHeaderText(apiReq.Header)
+	if err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+
+	var body io.Reader
+	if apiReq.Body != "" {
+		body = strings.NewReader(apiReq.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	for key, values := range headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if apiReq.Body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(apiReq.Body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	startTime := time.Now()
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAPIResponseBody+1))
+	latency := int(time.Since(startTime).Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	result := &APIResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		LatencyMs:  latency,
+	}
+	if len(data) > maxAPIResponseBody {
+		data = data[:maxAPIResponseBody]
+		result.Truncated = true
+	}
+	result.Body = string(data)
+	return result, nil
+}
+
+// parseHeaderText 解析用例中的请求头文本
+// 支持 JSON 对象 {"Key": "Value"} 和每行一个 "Key: Value" 两种写法
+func parseHeaderText(text string) (http.Header, error) {
+	headers := http.Header{}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return headers, nil
+	}
+
+	if strings.HasPrefix(text, "{") {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return nil, fmt.Errorf("invalid json header: %w", err)
+		}
+		for key, value := range obj {
+			headers.Add(key, fmt.Sprintf("%v", value))
+		}
+		return headers, nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid header line: %q", line)
+		}
+		headers.Add(strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:]))
+	}
+	return headers, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseHeaderText 支持JSON和多行两种请求头写法
+func TestParseHeaderText(t *testing.T) {
+	headers, err := parseHeaderText(`{"Authorization": "Bearer abc", "X-Count": 3}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer abc", headers.Get("Authorization"))
+	assert.Equal(t, "3", headers.Get("X-Count"))
+
+	headers, err = parseHeaderText("Content-Type: application/json\n\nX-Token: a:b\n")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "a:b", headers.Get("X-Token"))
+
+	_, err = parseHeaderText("no-colon-here")
+	assert.Error(t, err)
+}
+
+// TestAPIRunner_Do 发送结构化请求并记录状态码和响应体
+func TestAPIRunner_Do(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, `{"name":"a"}`, string(body))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	runner := NewAPIRunner(5 * time.Second)
+	resp, err := runner.Do(context.Background(), APIRequest{
+		Method: "post",
+		URL:    server.URL + "/users",
+		Body:   `{"name":"a"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, `{"id":1}`, resp.Body)
+	assert.False(t, resp.Truncated)
+}
+
+// TestExecuteAPICase_SubstitutesVariables URL/Header/Body均做变量替换，4xx判定为NG
+func TestExecuteAPICase_SubstitutesVariables(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	s := &executionTaskService{executors: newAPIExecutorRegistry(), execSlots: make(chan struct{}, 1)}
+	task := &models.ExecutionTask{ExecutionType: "api"}
+	variables := []*models.UserDefinedVariable{
+		{VarKey: "BASE_URL", VarValue: server.URL},
+		{VarKey: "TOKEN", VarValue: "secret-token"},
+		{VarKey: "USER", VarValue: "alice"},
+	}
+
+	c := &models.ExecutionCaseResult{
+		ID:     1,
+		Method: "PUT",
+		URL:    "${BASE_URL}/users/1",
+		Header: "Authorization: Bearer ${TOKEN}",
+		Body:   `{"name":"${USER}"}`,
+	}
+	exec, err := s.executeAPICase(context.Background(), task, c, variables, "en", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", c.TestResult)
+	assert.Equal(t, http.StatusOK, c.StatusCode)
+	assert.Equal(t, `{"name":"alice"}`, c.ActualResponse)
+	assert.NotEmpty(t, c.ResponseTime)
+	assert.Empty(t, exec.ErrorMessage)
+
+	c = &models.ExecutionCaseResult{ID: 2, Method: "GET", URL: "${BASE_URL}/users/1"}
+	exec, err = s.executeAPICase(context.Background(), task, c, variables, "en", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "NG", c.TestResult)
+	assert.Equal(t, http.StatusUnauthorized, c.StatusCode)
+	assert.Contains(t, exec.ErrorMessage, "401")
+}
+
+// newAPIExecutorRegistry 只注册内置接口执行器的注册表
+func newAPIExecutorRegistry() *ExecutorRegistry {
+	r := NewExecutorRegistry()
+	r.Register(NewNativeAPIExecutor(NewAPIRunner(5 * time.Second)))
+	return r
+}
@@ -122,6 +122,12 @@ func (s *apiTestCaseService) CreateCase(projectID uint, userID uint, testCase *m
 	if err := testCase.Validate(); err != nil {
 		return nil, fmt.Errorf("validate case data: %w", err)
 	}
+	if _, err := ParseAPIAssertions(testCase.Assertions); err != nil {
+		return nil, fmt.Errorf("断言格式错误: %w", err)
+	}
+	if _, err := ParseAPIExtractions(testCase.Extractions); err != nil {
+		return nil, fmt.Errorf("提取规则格式错误: %w", err)
+	}
 
 	// 获取当前类型的最大display_order
 	cases, _, err := s.repo.List(projectID, testCase.CaseType, 0, 1)
@@ -244,6 +250,20 @@ func (s *apiTestCaseService) InsertCase(projectID uint, userID uint, caseType st
 	if response, ok := caseData["response"].(string); ok {
 		newCase.Response = response
 	}
+	if raw, ok := caseData["assertions"]; ok {
+		assertions, err := NormalizeAPIAssertions(raw)
+		if err != nil {
+			return nil, fmt.Errorf("断言格式错误: %w", err)
+		}
+		newCase.Assertions = assertions
+	}
+	if raw, ok := caseData["extractions"]; ok {
+		extractions, err := NormalizeAPIExtractions(raw)
+		if err != nil {
+			return nil, fmt.Errorf("提取规则格式错误: %w", err)
+		}
+		newCase.Extractions = extractions
+	}
 	if scriptCode, ok := caseData["script_code"].(string); ok {
 		newCase.ScriptCode = scriptCode
 	}
@@ -351,6 +371,22 @@ func (s *apiTestCaseService) UpdateCase(projectID uint, userID uint, caseID stri
 		return errors.New("无项目访问权限")
 	}
 
+	// 断言和提取规则支持字符串或数组，统一存为JSON文本
+	if raw, ok := updates["assertions"]; ok {
+		assertions, err := NormalizeAPIAssertions(raw)
+		if err != nil {
+			return fmt.Errorf("断言格式错误: %w", err)
+		}
+		updates["assertions"] = assertions
+	}
+	if raw, ok := updates["extractions"]; ok {
+		extractions, err := NormalizeAPIExtractions(raw)
+		if err != nil {
+			return fmt.Errorf("提取规则格式错误: %w", err)
+		}
+		updates["extractions"] = extractions
+	}
+
 	// 更新用例
 	if err := s.repo.Update(caseID, updates); err != nil {
 		return fmt.Errorf("update case: %w", err)
@@ -21,18 +21,22 @@ type DefectAttachmentService interface {
 	Download(id uint) (*models.DefectAttachment, io.ReadCloser, error)
 	Delete(id uint) error
 	ListByDefectID(defectID string) ([]*models.DefectAttachment, error)
+	// AttachExecutionArtifacts 将用例结果最近一次执行的产物复制为缺陷附件
+	AttachExecutionArtifacts(defectID string, projectID uint, userID uint, caseResultID uint) ([]*models.DefectAttachment, error)
 }
 
 type defectAttachmentService struct {
-	repo        repositories.DefectAttachmentRepository
-	storagePath string
+	repo         repositories.DefectAttachmentRepository
+	artifactRepo repositories.ExecutionArtifactRepository
+	storagePath  string
 }
 
 // NewDefectAttachmentService 创建缺陷附件服务实例
-func NewDefectAttachmentService(repo repositories.DefectAttachmentRepository, storagePath string) DefectAttachmentService {
+func NewDefectAttachmentService(repo repositories.DefectAttachmentRepository, artifactRepo repositories.ExecutionArtifactRepository, storagePath string) DefectAttachmentService {
 	return &defectAttachmentService{
-		repo:        repo,
-		storagePath: storagePath,
+		repo:         repo,
+		artifactRepo: artifactRepo,
+		storagePath:  storagePath,
 	}
 }
 
@@ -170,3 +174,85 @@ func (s *defectAttachmentService) ListByDefectID(defectID string) ([]*models.Def
 	}
 	return attachments, nil
 }
+
+// AttachExecutionArtifacts 将用例结果最近一次执行的产物复制为缺陷附件
+// 文件复制到缺陷附件目录，删除执行任务不影响已附加的附件
+func (s *defectAttachmentService) AttachExecutionArtifacts(defectID string, projectID uint, userID uint, caseResultID uint) ([]*models.DefectAttachment, error) {
+	artifacts, err := s.artifactRepo.GetByCaseResultID(caseResultID)
+	if err != nil {
+		return nil, fmt.Errorf("get execution artifacts: %w", err)
+	}
+
+	// 只取最近一次执行的产物(按attempt_id倒序排列)
+	var latest []*models.ExecutionArtifact
+	for _, a := range artifacts {
+		if a.ProjectID != projectID {
+			return nil, errors.New("execution artifacts not found")
+		}
+		if len(latest) > 0 && a.AttemptID != latest[0].AttemptID {
+			break
+		}
+		latest = append(latest, a)
+	}
+	if len(latest) == 0 {
+		return nil, errors.New("execution artifacts not found")
+	}
+
+	relPath := filepath.Join("defects", fmt.Sprintf("%d", projectID), defectID)
+	fullDir := filepath.Join(s.storagePath, relPath)
+	if err := os.MkdirAll(fullDir, 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	attachments := make([]*models.DefectAttachment, 0, len(latest))
+	for _, a := range latest {
+		storageName := fmt.Sprintf("%d_%d_%s", timestamp, a.ID, a.FileName)
+		fullPath := filepath.Join(fullDir, storageName)
+		size, err := copyFile(filepath.Join(s.storagePath, a.FilePath), fullPath)
+		if err != nil {
+			log.Printf("[Attachment From Artifact Failed] artifact_id=%d, error=%v", a.ID, err)
+			continue
+		}
+
+		attachment := &models.DefectAttachment{
+			DefectID:   defectID,
+			FileName:   a.FileName,
+			FilePath:   filepath.Join(relPath, storageName),
+			FileSize:   size,
+			MimeType:   a.MimeType,
+			UploadedBy: userID,
+		}
+		if err := s.repo.Create(attachment); err != nil {
+			os.Remove(fullPath)
+			return attachments, fmt.Errorf("create attachment record: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	log.Printf("[Attachment From Artifact] defect_id=%s, case_result_id=%d, count=%d", defectID, caseResultID, len(attachments))
+	return attachments, nil
+}
+
+// copyFile 复制文件，返回写入的字节数
+func copyFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return 0, err
+	}
+	return size, nil
+}
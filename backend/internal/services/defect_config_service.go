@@ -1,8 +1,10 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
 
@@ -24,21 +26,28 @@ type DefectConfigService interface {
 	UpdatePhase(id uint, req *models.DefectPhaseUpdateRequest) error
 	DeletePhase(id uint) error
 	ListPhases(projectID uint) ([]*models.DefectPhase, error)
+
+	// 工作流管理
+	GetWorkflow(projectID uint) (*models.DefectWorkflowResponse, error)
+	UpdateWorkflow(projectID uint, userID uint, req *models.DefectWorkflowUpdateRequest) (*models.DefectWorkflowResponse, error)
 }
 
 type defectConfigService struct {
-	subjectRepo repositories.DefectSubjectRepository
-	phaseRepo   repositories.DefectPhaseRepository
+	subjectRepo  repositories.DefectSubjectRepository
+	phaseRepo    repositories.DefectPhaseRepository
+	workflowRepo repositories.DefectWorkflowRepository
 }
 
 // NewDefectConfigService 创建缺陷配置服务实例
 func NewDefectConfigService(
 	subjectRepo repositories.DefectSubjectRepository,
 	phaseRepo repositories.DefectPhaseRepository,
+	workflowRepo repositories.DefectWorkflowRepository,
 ) DefectConfigService {
 	return &defectConfigService{
-		subjectRepo: subjectRepo,
-		phaseRepo:   phaseRepo,
+		subjectRepo:  subjectRepo,
+		phaseRepo:    phaseRepo,
+		workflowRepo: workflowRepo,
 	}
 }
 
@@ -237,3 +246,56 @@ func (s *defectConfigService) ListPhases(projectID uint) ([]*models.DefectPhase,
 	}
 	return phases, nil
 }
+
+// ========== 工作流管理 ==========
+
+// GetWorkflow 获取项目的缺陷工作流(未配置时返回默认工作流)
+func (s *defectConfigService) GetWorkflow(projectID uint) (*models.DefectWorkflowResponse, error) {
+	transitions, isDefault, err := loadDefectTransitions(s.workflowRepo, projectID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]models.DefectStatus, 0, len(models.ValidDefectStatuses))
+	for _, status := range models.ValidDefectStatuses {
+		if status != models.DefectStatusActive {
+			statuses = append(statuses, status)
+		}
+	}
+	return &models.DefectWorkflowResponse{
+		ProjectID:   projectID,
+		IsDefault:   isDefault,
+		Statuses:    statuses,
+		Fields:      models.DefectTransitionFields,
+		Transitions: transitions,
+	}, nil
+}
+
+// UpdateWorkflow 保存项目的缺陷工作流，transitions为空时恢复默认工作流
+func (s *defectConfigService) UpdateWorkflow(projectID uint, userID uint, req *models.DefectWorkflowUpdateRequest) (*models.DefectWorkflowResponse, error) {
+	if len(req.Transitions) == 0 {
+		if err := s.workflowRepo.DeleteByProjectID(projectID); err != nil {
+			return nil, err
+		}
+		log.Printf("[Defect Workflow Reset] project_id=%d, user_id=%d", projectID, userID)
+		return s.GetWorkflow(projectID)
+	}
+
+	if err := validateDefectTransitions(req.Transitions); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(req.Transitions)
+	if err != nil {
+		return nil, fmt.Errorf("marshal transitions: %w", err)
+	}
+	workflow := &models.DefectWorkflow{
+		ProjectID:   projectID,
+		Transitions: string(data),
+		UpdatedBy:   userID,
+	}
+	if err := s.workflowRepo.Save(workflow); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[Defect Workflow Update] project_id=%d, user_id=%d, transitions=%d", projectID, userID, len(req.Transitions))
+	return s.GetWorkflow(projectID)
+}
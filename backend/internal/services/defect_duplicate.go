@@ -0,0 +1,441 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 缺陷重复检测：标题/描述分词后计算 Jaccard 相似度
+// 英文和数字按单词切分，中日韩文字按相邻二字切分(单字时取单字)
+
+const (
+	duplicateTitleWeight       = 0.7 // 双方都有描述时标题相似度的权重
+	duplicateDescriptionWeight = 0.3
+	maxDuplicateCandidates     = 10
+)
+
+// defectTokens 将文本切分为词集合
+func defectTokens(text string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	var word, cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens[string(word)] = struct{}{}
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens[string(cjk)] = struct{}{}
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens[string(cjk[i:i+2])] = struct{}{}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJKRune(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// jaccard 计算两个词集合的 Jaccard 相似度
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// duplicateEntry 参与重复检测的缺陷及其分词结果
+type duplicateEntry struct {
+	defect      *models.Defect
+	title       map[string]struct{}
+	description map[string]struct{}
+}
+
+func newDuplicateEntry(d *models.Defect) *duplicateEntry {
+	return &duplicateEntry{
+		defect:      d,
+		title:       defectTokens(d.Title),
+		description: defectTokens(d.Description),
+	}
+}
+
+// similarity 计算两个缺陷的相似度；不满足 Case ID/组件 过滤条件时返回0
+func (e *duplicateEntry) similarity(other *duplicateEntry, opts models.DefectDuplicateOptions) float64 {
+	if opts.SameCaseID && (e.defect.CaseID == "" || e.defect.CaseID != other.defect.CaseID) {
+		return 0
+	}
+	if opts.SameComponent && (e.defect.Component == "" || e.defect.Component != other.defect.Component) {
+		return 0
+	}
+	score := jaccard(e.title, other.title)
+	if len(e.description) > 0 && len(other.description) > 0 {
+		score = duplicateTitleWeight*score + duplicateDescriptionWeight*jaccard(e.description, other.description)
+	}
+	return math.Round(score*100) / 100
+}
+
+// duplicatePool 一次检测中的候选缺陷集合
+type duplicatePool struct {
+	entries []*duplicateEntry
+}
+
+func newDuplicatePool(defects []*models.Defect) *duplicatePool {
+	pool := &duplicatePool{entries: make([]*duplicateEntry, 0, len(defects))}
+	for _, d := range defects {
+		pool.add(d)
+	}
+	return pool
+}
+
+func (p *duplicatePool) add(d *models.Defect) {
+	p.entries = append(p.entries, newDuplicateEntry(d))
+}
+
+// match 返回与缺陷相似度达到阈值的候选(按相似度降序)
+func (p *duplicatePool) match(d *models.Defect, opts models.DefectDuplicateOptions) []models.DefectDuplicateCandidate {
+	target := newDuplicateEntry(d)
+	threshold := duplicateThreshold(opts)
+	var candidates []models.DefectDuplicateCandidate
+	for _, e := range p.entries {
+		if d.ID != "" && e.defect.ID == d.ID {
+			continue
+		}
+		if score := target.similarity(e, opts); score >= threshold {
+			candidates = append(candidates, newDuplicateCandidate(e.defect, score))
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+	return candidates
+}
+
+func duplicateThreshold(opts models.DefectDuplicateOptions) float64 {
+	if opts.Threshold <= 0 || opts.Threshold > 1 {
+		return models.DefaultDefectDuplicateThreshold
+	}
+	return opts.Threshold
+}
+
+func newDuplicateCandidate(d *models.Defect, score float64) models.DefectDuplicateCandidate {
+	return models.DefectDuplicateCandidate{
+		ID:        d.ID,
+		DefectID:  d.DefectID,
+		Title:     d.Title,
+		Status:    d.Status,
+		CaseID:    d.CaseID,
+		Component: d.Component,
+		Score:     score,
+	}
+}
+
+// FindDuplicates 查找与缺陷疑似重复的未关闭缺陷
+func (s *defectService) FindDuplicates(projectID uint, defect *models.Defect, opts models.DefectDuplicateOptions) ([]models.DefectDuplicateCandidate, error) {
+	defects, err := s.repo.ListOpenByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return newDuplicatePool(defects).match(defect, opts), nil
+}
+
+// ListDuplicateGroups 将项目中未关闭的缺陷按相似度聚类为疑似重复分组
+func (s *defectService) ListDuplicateGroups(projectID uint, opts models.DefectDuplicateOptions) ([]models.DefectDuplicateGroup, error) {
+	defects, err := s.repo.ListOpenByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	pool := newDuplicatePool(defects)
+	threshold := duplicateThreshold(opts)
+
+	// 并查集：相似度达到阈值的缺陷归入同一组
+	parent := make([]int, len(pool.entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	best := make([]float64, len(pool.entries))
+	for i := 0; i < len(pool.entries); i++ {
+		for j := i + 1; j < len(pool.entries); j++ {
+			score := pool.entries[i].similarity(pool.entries[j], opts)
+			if score < threshold {
+				continue
+			}
+			parent[find(i)] = find(j)
+			best[i] = math.Max(best[i], score)
+			best[j] = math.Max(best[j], score)
+		}
+	}
+
+	members := make(map[int][]int)
+	var roots []int
+	for i := range pool.entries {
+		if best[i] == 0 {
+			continue
+		}
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], i)
+	}
+
+	groups := make([]models.DefectDuplicateGroup, 0, len(roots))
+	for _, root := range roots {
+		var group models.DefectDuplicateGroup
+		for _, i := range members[root] {
+			group.Defects = append(group.Defects, newDuplicateCandidate(pool.entries[i].defect, best[i]))
+			group.Score = math.Max(group.Score, best[i])
+		}
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Score > groups[j].Score
+	})
+	return groups, nil
+}
+
+// Merge 将来源缺陷合并到目标缺陷
+// 来源缺陷的非空字段补充到目标缺陷的空字段，关联的执行结果转移到目标缺陷，来源缺陷标记为 Rejected
+// 写入前校验全部来源缺陷，每个来源缺陷的合并在同一事务中完成
+func (s *defectService) Merge(projectID uint, userID uint, req *models.DefectMergeRequest) (*models.Defect, error) {
+	target, err := s.getProjectDefect(projectID, req.TargetDefectID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(req.SourceDefectIDs))
+	var sources []*models.Defect
+	for _, sourceID := range req.SourceDefectIDs {
+		if sourceID == target.DefectID {
+			return nil, errors.New("cannot merge a defect into itself")
+		}
+		if seen[sourceID] {
+			continue
+		}
+		seen[sourceID] = true
+		source, err := s.getProjectDefect(projectID, sourceID)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	for _, source := range sources {
+		if err := s.mergeOne(source, target, userID); err != nil {
+			return nil, err
+		}
+		if target, err = s.GetByID(target.ID); err != nil {
+			return nil, err
+		}
+		log.Printf("[Defect Merge] user_id=%d, source=%s, target=%s", userID, source.DefectID, target.DefectID)
+	}
+	return target, nil
+}
+
+// mergeOne 合并单个来源缺陷：补充目标字段、转移关联、标记来源为 Rejected
+func (s *defectService) mergeOne(source, target *models.Defect, userID uint) error {
+	m := &repositories.DefectMerge{TargetID: target.ID, SourceID: source.ID}
+
+	if fill := blankFieldUpdates(target, source); fill != nil {
+		updates, histories, err := s.prepareUpdate(target, userID, fill, models.DefectChangeSourceMerge)
+		if err != nil {
+			return fmt.Errorf("merge fields of %s: %w", source.DefectID, err)
+		}
+		m.TargetUpdates, m.TargetHistories = updates, histories
+	}
+
+	rejected := string(models.DefectStatusRejected)
+	resolution := "Duplicate of " + target.DefectID
+	updates, histories, err := s.prepareUpdate(source, userID, &models.DefectUpdateRequest{Status: &rejected, Resolution: &resolution}, models.DefectChangeSourceMerge)
+	if err != nil {
+		return fmt.Errorf("reject merged defect %s: %w", source.DefectID, err)
+	}
+	if updates == nil {
+		// 来源缺陷已是 Rejected 且备注相同时只更新 updated_by
+		updates = map[string]interface{}{"updated_by": userID}
+	}
+	m.SourceUpdates, m.SourceHistories = updates, histories
+
+	links, err := s.linkRepo.ListByDefectID(source.ID)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		m.Links = append(m.Links, &models.DefectCaseLink{
+			DefectID:     target.ID,
+			DefectNo:     target.DefectID,
+			ProjectID:    target.ProjectID,
+			CaseResultID: link.CaseResultID,
+			TaskUUID:     link.TaskUUID,
+			CaseID:       link.CaseID,
+			CaseNum:      link.CaseNum,
+			CreatedBy:    userID,
+		})
+	}
+
+	if err := s.linkRepo.MergeDefect(m); err != nil {
+		return fmt.Errorf("merge defect %s: %w", source.DefectID, err)
+	}
+	return nil
+}
+
+// getProjectDefect 按显示ID获取项目内的缺陷
+func (s *defectService) getProjectDefect(projectID uint, defectID string) (*models.Defect, error) {
+	defect, err := s.repo.GetByDefectID(defectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("defect not found")
+		}
+		return nil, fmt.Errorf("get defect: %w", err)
+	}
+	if defect.ProjectID != projectID {
+		return nil, errors.New("defect not found")
+	}
+	return defect, nil
+}
+
+// blankFieldUpdates 用来源缺陷的非空字段补充目标缺陷的空字段，无可补充字段时返回nil
+func blankFieldUpdates(target, source *models.Defect) *models.DefectUpdateRequest {
+	req := &models.DefectUpdateRequest{}
+	changed := false
+	fill := func(dst **string, current, value string) {
+		if current == "" && value != "" {
+			v := value
+			*dst = &v
+			changed = true
+		}
+	}
+	fill(&req.Subject, target.Subject, source.Subject)
+	fill(&req.Description, target.Description, source.Description)
+	fill(&req.RecoveryMethod, target.RecoveryMethod, source.RecoveryMethod)
+	fill(&req.Type, target.Type, source.Type)
+	fill(&req.Frequency, target.Frequency, source.Frequency)
+	fill(&req.DetectedVersion, target.DetectedVersion, source.DetectedVersion)
+	fill(&req.Phase, target.Phase, source.Phase)
+	fill(&req.CaseID, target.CaseID, source.CaseID)
+	fill(&req.RecoveryRank, target.RecoveryRank, source.RecoveryRank)
+	fill(&req.DetectionTeam, target.DetectionTeam, source.DetectionTeam)
+	fill(&req.Location, target.Location, source.Location)
+	fill(&req.Component, target.Component, source.Component)
+	fill(&req.Models, target.Models, source.Models)
+	if !changed {
+		return nil
+	}
+	return req
+}
+
+// importDuplicateChecker 导入时按重复处理模式检测疑似重复行
+// 候选包括项目中未关闭的缺陷和本次导入新建的缺陷
+type importDuplicateChecker struct {
+	s      *defectService
+	opts   models.DefectImportOptions
+	pool   *duplicatePool
+	userID uint
+}
+
+// newImportDuplicateChecker 创建导入重复检测器，模式为none时返回nil
+func (s *defectService) newImportDuplicateChecker(projectID, userID uint, opts models.DefectImportOptions) (*importDuplicateChecker, error) {
+	if opts.DuplicateMode == models.DefectDuplicateModeNone {
+		return nil, nil
+	}
+	if !models.IsValidDefectDuplicateMode(opts.DuplicateMode) {
+		return nil, fmt.Errorf("invalid duplicate mode: %s", opts.DuplicateMode)
+	}
+	defects, err := s.repo.ListOpenByProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &importDuplicateChecker{s: s, opts: opts, pool: newDuplicatePool(defects), userID: userID}, nil
+}
+
+// handle 检测导入行是否疑似重复，已跳过或合并时返回true
+func (c *importDuplicateChecker) handle(row int, req *models.DefectCreateRequest, result *models.ImportResult) bool {
+	if c == nil {
+		return false
+	}
+	incoming := &models.Defect{
+		Title: req.Title, Subject: req.Subject, Description: req.Description, RecoveryMethod: req.RecoveryMethod,
+		Type: req.Type, Frequency: req.Frequency, DetectedVersion: req.DetectedVersion, Phase: req.Phase,
+		CaseID: req.CaseID, RecoveryRank: req.RecoveryRank, DetectionTeam: req.DetectionTeam,
+		Location: req.Location, Component: req.Component, Models: req.Models,
+	}
+	candidates := c.pool.match(incoming, c.opts.Duplicate)
+	if len(candidates) == 0 {
+		return false
+	}
+	best := candidates[0]
+	dup := models.ImportDuplicate{Row: row, Title: req.Title, DuplicateOf: best.DefectID, Score: best.Score, Action: "skipped"}
+
+	if c.opts.DuplicateMode == models.DefectDuplicateModeMerge {
+		existing, err := c.s.GetByID(best.ID)
+		if err != nil {
+			log.Printf("[Defect Import] row %d merge target %s not found: %v", row, best.DefectID, err)
+			return false
+		}
+		if fill := blankFieldUpdates(existing, incoming); fill != nil {
+			if err := c.s.Update(existing.ID, c.userID, fill, models.DefectChangeSourceImport); err != nil {
+				log.Printf("[Defect Import] row %d merge into %s failed: %v", row, best.DefectID, err)
+				return false
+			}
+		}
+		dup.Action = "merged"
+		result.MergedCount++
+	} else {
+		result.SkippedCount++
+	}
+	result.Duplicates = append(result.Duplicates, dup)
+	log.Printf("[Defect Import] row %d %s as duplicate of %s (score=%.2f)", row, dup.Action, best.DefectID, best.Score)
+	return true
+}
+
+// added 将本次导入新建的缺陷加入候选
+func (c *importDuplicateChecker) added(d *models.Defect) {
+	if c != nil && d != nil {
+		c.pool.add(d)
+	}
+}
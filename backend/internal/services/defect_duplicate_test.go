@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefectTokens(t *testing.T) {
+	assert.Equal(t, map[string]struct{}{"login": {}, "500": {}, "登录": {}, "录失": {}, "失败": {}, "画": {}},
+		defectTokens("Login 500: 登录失败 / 画"))
+
+	a := newDuplicateEntry(&models.Defect{Title: "登录页面输入错误密码后无提示"})
+	b := newDuplicateEntry(&models.Defect{Title: "登录页面输入错误密码无提示"})
+	c := newDuplicateEntry(&models.Defect{Title: "导出CSV缺少表头"})
+	assert.GreaterOrEqual(t, a.similarity(b, models.DefectDuplicateOptions{}), models.DefaultDefectDuplicateThreshold)
+	assert.Less(t, a.similarity(c, models.DefectDuplicateOptions{}), 0.1)
+	assert.Zero(t, a.similarity(b, models.DefectDuplicateOptions{SameCaseID: true}))
+}
+
+func TestDefectService_DuplicatesGroupAndMerge(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{},
+		&models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectCaseLink{})
+	s := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		repositories.NewDefectWorkflowRepository(db), repositories.NewProjectMemberRepository(db),
+		repositories.NewDefectHistoryRepository(db), repositories.NewDefectCommentRepository(db),
+		repositories.NewDefectCaseLinkRepository(db)).(*defectService)
+
+	target, err := s.Create(1, 1, &models.DefectCreateRequest{Title: "Login fails with wrong password"})
+	require.NoError(t, err)
+	dup, err := s.Create(1, 1, &models.DefectCreateRequest{Title: "login fails with a wrong password", Component: "auth"})
+	require.NoError(t, err)
+	_, err = s.Create(1, 1, &models.DefectCreateRequest{Title: "Export CSV is missing header row"})
+	require.NoError(t, err)
+
+	candidates, err := s.FindDuplicates(1, dup, models.DefectDuplicateOptions{})
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, target.DefectID, candidates[0].DefectID)
+
+	groups, err := s.ListDuplicateGroups(1, models.DefectDuplicateOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].Defects, 2)
+
+	// 导入 skip 模式：与已有缺陷或本次已导入行重复的行被跳过
+	checker, err := s.newImportDuplicateChecker(1, 1, models.DefectImportOptions{DuplicateMode: models.DefectDuplicateModeSkip})
+	require.NoError(t, err)
+	result := &models.ImportResult{}
+	assert.True(t, checker.handle(1, &models.DefectCreateRequest{Title: "Login fails with wrong password!"}, result))
+	assert.False(t, checker.handle(2, &models.DefectCreateRequest{Title: "Dashboard chart overlaps legend"}, result))
+	checker.added(&models.Defect{ID: "new", DefectID: "000099", Title: "Dashboard chart overlaps legend"})
+	assert.True(t, checker.handle(3, &models.DefectCreateRequest{Title: "dashboard chart overlaps the legend"}, result))
+	assert.Equal(t, 2, result.SkippedCount)
+	assert.Equal(t, "000099", result.Duplicates[1].DuplicateOf)
+
+	_, err = s.Merge(1, 1, &models.DefectMergeRequest{TargetDefectID: target.DefectID, SourceDefectIDs: []string{target.DefectID}})
+	assert.EqualError(t, err, "cannot merge a defect into itself")
+
+	// 写入前校验全部来源缺陷：任一来源无效时不修改任何缺陷
+	_, err = s.Merge(1, 1, &models.DefectMergeRequest{TargetDefectID: target.DefectID, SourceDefectIDs: []string{dup.DefectID, "999999"}})
+	assert.EqualError(t, err, "defect not found")
+	_, err = s.Merge(1, 1, &models.DefectMergeRequest{TargetDefectID: target.DefectID, SourceDefectIDs: []string{dup.DefectID, target.DefectID}})
+	assert.EqualError(t, err, "cannot merge a defect into itself")
+	unchanged, err := s.GetByID(dup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dup.Status, unchanged.Status)
+
+	require.NoError(t, s.linkRepo.Create(&models.DefectCaseLink{DefectID: dup.ID, DefectNo: dup.DefectID, ProjectID: 1,
+		CaseResultID: 7, TaskUUID: "task-1", CaseID: "case-7", CreatedBy: 1}))
+
+	// 重复的来源缺陷ID只合并一次
+	merged, err := s.Merge(1, 1, &models.DefectMergeRequest{TargetDefectID: target.DefectID, SourceDefectIDs: []string{dup.DefectID, dup.DefectID}})
+	require.NoError(t, err)
+	assert.Equal(t, "auth", merged.Component)
+	links, err := s.linkRepo.ListByDefectID(target.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, uint(7), links[0].CaseResultID)
+	links, err = s.linkRepo.ListByDefectID(dup.ID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+	source, err := s.GetByID(dup.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(models.DefectStatusRejected), source.Status)
+	assert.Equal(t, "Duplicate of "+target.DefectID, source.Resolution)
+
+	// 合并后来源缺陷不再参与重复分组
+	groups, err = s.ListDuplicateGroups(1, models.DefectDuplicateOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
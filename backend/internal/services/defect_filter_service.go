@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// DefectFilterService 保存的缺陷筛选服务接口
+type DefectFilterService interface {
+	List(projectID uint, userID uint) ([]*models.DefectSavedFilter, error)
+	Create(projectID uint, userID uint, role string, req *models.DefectSavedFilterRequest) (*models.DefectSavedFilter, error)
+	Update(projectID uint, userID uint, role string, id uint, req *models.DefectSavedFilterRequest) (*models.DefectSavedFilter, error)
+	Delete(projectID uint, userID uint, role string, id uint) error
+	Apply(projectID uint, userID uint, params *models.DefectListParams) error
+}
+
+type defectFilterService struct {
+	repo repositories.DefectSavedFilterRepository
+}
+
+// NewDefectFilterService 创建保存的缺陷筛选服务实例
+func NewDefectFilterService(repo repositories.DefectSavedFilterRepository) DefectFilterService {
+	return &defectFilterService{repo: repo}
+}
+
+// List 获取用户可见的筛选
+func (s *defectFilterService) List(projectID uint, userID uint) ([]*models.DefectSavedFilter, error) {
+	return s.repo.ListVisible(projectID, userID)
+}
+
+// Create 保存筛选，项目范围的筛选只有项目经理可以创建
+func (s *defectFilterService) Create(projectID uint, userID uint, role string, req *models.DefectSavedFilterRequest) (*models.DefectSavedFilter, error) {
+	filter := &models.DefectSavedFilter{ProjectID: projectID, UserID: userID}
+	if err := s.fill(filter, userID, role, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(filter); err != nil {
+		return nil, err
+	}
+	log.Printf("[Defect Filter Create] project_id=%d, user_id=%d, filter_id=%d, scope=%s", projectID, userID, filter.ID, filter.Scope)
+	return filter, nil
+}
+
+// Update 修改筛选
+func (s *defectFilterService) Update(projectID uint, userID uint, role string, id uint, req *models.DefectSavedFilterRequest) (*models.DefectSavedFilter, error) {
+	filter, err := s.getEditable(projectID, userID, role, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fill(filter, userID, role, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Delete 删除筛选
+func (s *defectFilterService) Delete(projectID uint, userID uint, role string, id uint) error {
+	if _, err := s.getEditable(projectID, userID, role, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// Apply 将列表参数中引用的筛选(ID或名称)合并到查询语句和排序
+// 同时指定 q 时与筛选条件取交集；未指定排序时使用筛选的排序
+func (s *defectFilterService) Apply(projectID uint, userID uint, params *models.DefectListParams) error {
+	if params.Filter == "" {
+		return nil
+	}
+	filter, err := s.resolve(projectID, userID, params.Filter)
+	if err != nil {
+		return err
+	}
+	params.Query = models.CombineDefectQueries(filter.Query, params.Query)
+	if params.Sort == "" {
+		params.Sort = filter.Sort
+	}
+	return nil
+}
+
+// resolve 按ID或名称查找用户可见的筛选，同名时个人筛选优先
+func (s *defectFilterService) resolve(projectID uint, userID uint, ref string) (*models.DefectSavedFilter, error) {
+	filters, err := s.repo.ListVisible(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := strconv.ParseUint(ref, 10, 32)
+	var matched *models.DefectSavedFilter
+	for _, f := range filters {
+		if uint64(f.ID) == id {
+			return f, nil
+		}
+		if f.Name == ref && (matched == nil || f.Scope == models.DefectFilterScopeUser) {
+			matched = f
+		}
+	}
+	if matched == nil {
+		return nil, errors.New("defect filter not found")
+	}
+	return matched, nil
+}
+
+// getEditable 获取可修改的筛选：个人筛选限创建人，项目筛选限项目经理
+func (s *defectFilterService) getEditable(projectID uint, userID uint, role string, id uint) (*models.DefectSavedFilter, error) {
+	filter, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("defect filter not found")
+		}
+		return nil, fmt.Errorf("get defect filter: %w", err)
+	}
+	if filter.ProjectID != projectID {
+		return nil, errors.New("defect filter not found")
+	}
+	if filter.Scope == models.DefectFilterScopeProject {
+		if role != constants.RoleProjectManager {
+			return nil, errors.New("only project managers can manage project filters")
+		}
+	} else if filter.UserID != userID {
+		return nil, errors.New("defect filter not found")
+	}
+	return filter, nil
+}
+
+// fill 校验请求并写入筛选字段
+func (s *defectFilterService) fill(filter *models.DefectSavedFilter, userID uint, role string, req *models.DefectSavedFilterRequest) error {
+	scope := req.Scope
+	if scope == "" {
+		scope = models.DefectFilterScopeUser
+	}
+	if scope == models.DefectFilterScopeProject && role != constants.RoleProjectManager {
+		return errors.New("only project managers can manage project filters")
+	}
+	if _, err := ParseDefectQuery(req.Query); err != nil {
+		return err
+	}
+	if _, err := ParseDefectSort(req.Sort); err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	exists, err := s.repo.ExistsByName(filter.ProjectID, scope, userID, name, filter.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("defect filter name already exists")
+	}
+
+	filter.Name = name
+	filter.Query = strings.TrimSpace(req.Query)
+	filter.Sort = strings.TrimSpace(req.Sort)
+	filter.Scope = scope
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefectService_UpdateRecordsHistoryAndTimeline(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{},
+		&models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectComment{}, &models.DefectCaseLink{})
+	require.NoError(t, db.Create(&models.User{ID: 1, Username: "tester", Nickname: "测试员", Password: "x"}).Error)
+	require.NoError(t, db.Create(&models.ProjectMember{ProjectID: 1, UserID: 1, Role: constants.RoleProjectMember}).Error)
+	defect := &models.Defect{DefectID: "000001", ProjectID: 1, Title: "登录失败", Priority: "B", CreatedBy: 1, DetectedBy: "tester"}
+	require.NoError(t, db.Create(defect).Error)
+
+	historyRepo := repositories.NewDefectHistoryRepository(db)
+	commentRepo := repositories.NewDefectCommentRepository(db)
+	s := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		repositories.NewDefectWorkflowRepository(db), repositories.NewProjectMemberRepository(db), historyRepo, commentRepo,
+		repositories.NewDefectCaseLinkRepository(db))
+
+	// 未变化的字段不记录，同一次更新共享批次ID
+	require.NoError(t, s.Update(defect.ID, 1, &models.DefectUpdateRequest{
+		Title: strPtr("登录失败"), Priority: strPtr("A"), Assignee: strPtr("dev"),
+	}, models.DefectChangeSourceMCP))
+	histories, err := historyRepo.ListByDefectID(defect.ID)
+	require.NoError(t, err)
+	require.Len(t, histories, 2)
+	assert.Equal(t, histories[0].ChangeSetID, histories[1].ChangeSetID)
+	for _, h := range histories {
+		assert.Equal(t, models.DefectChangeSourceMCP, h.Source)
+		assert.Equal(t, "测试员", h.ActorName)
+		if h.Field == "priority" {
+			assert.Equal(t, "B", h.OldValue)
+			assert.Equal(t, "A", h.NewValue)
+		}
+	}
+
+	// 导入同样受工作流限制(New -> Closed 默认不允许)，允许的变更记录来源
+	var workflowErr *DefectWorkflowError
+	assert.ErrorAs(t, s.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("Closed")}, models.DefectChangeSourceImport), &workflowErr)
+	require.NoError(t, s.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("New"), Severity: strPtr("Critical")}, models.DefectChangeSourceImport))
+
+	require.NoError(t, commentRepo.Create(&models.DefectComment{DefectID: defect.ID, Content: "已复现", CreatedBy: 1}))
+
+	timeline, err := s.GetTimeline(defect.ID)
+	require.NoError(t, err)
+	require.Len(t, timeline, 4)
+	assert.Equal(t, "created", timeline[0].Type)
+	assert.Equal(t, "change", timeline[1].Type)
+	assert.Len(t, timeline[1].Changes, 2)
+	assert.Equal(t, models.DefectChangeSourceImport, timeline[2].Source)
+	assert.Equal(t, []models.DefectFieldChange{{Field: "severity", OldValue: defect.Severity, NewValue: "Critical"}}, timeline[2].Changes)
+	assert.Equal(t, "comment", timeline[3].Type)
+	assert.Equal(t, "测试员", timeline[3].ActorName)
+
+	// 导入时被工作流拦截的行计入失败并返回原因
+	row := make([]string, len(csvHeaders)+1)
+	row[0], row[1], row[5], row[6], row[7], row[18] = "000001", "登录失败", "A", "Critical", "Functional", "Closed"
+	csvData := strings.Join(append([]string{"Defect ID"}, csvHeaders...), ",") + "\n" + strings.Join(row, ",") + "\n"
+	result, err := s.Import(1, 1, strings.NewReader(csvData), models.DefectImportOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, result.SuccessCount)
+	assert.Equal(t, 1, result.FailCount)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 1, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Reason, "status transition from New to Closed is not allowed")
+}
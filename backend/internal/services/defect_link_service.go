@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// DefectLinkService 缺陷与执行结果关联服务接口
+type DefectLinkService interface {
+	CreateFromCaseResult(projectID uint, userID uint, req *models.DefectFromResultRequest) (*models.Defect, error)
+	LinkCaseResults(projectID uint, userID uint, defect *models.Defect, caseResultIDs []uint) ([]*models.DefectCaseLink, error)
+	UnlinkCaseResult(defect *models.Defect, caseResultID uint) error
+	ListByDefect(defectID string) ([]*models.DefectCaseLink, error)
+}
+
+type defectLinkService struct {
+	defectService     DefectService
+	attachmentService DefectAttachmentService
+	resultRepo        repositories.ExecutionCaseResultRepository
+	taskRepo          repositories.ExecutionTaskRepository
+	phaseRepo         repositories.DefectPhaseRepository
+	linkRepo          repositories.DefectCaseLinkRepository
+}
+
+// NewDefectLinkService 创建缺陷关联服务实例
+func NewDefectLinkService(
+	defectService DefectService,
+	attachmentService DefectAttachmentService,
+	resultRepo repositories.ExecutionCaseResultRepository,
+	taskRepo repositories.ExecutionTaskRepository,
+	phaseRepo repositories.DefectPhaseRepository,
+	linkRepo repositories.DefectCaseLinkRepository,
+) DefectLinkService {
+	return &defectLinkService{
+		defectService:     defectService,
+		attachmentService: attachmentService,
+		resultRepo:        resultRepo,
+		taskRepo:          taskRepo,
+		phaseRepo:         phaseRepo,
+		linkRepo:          linkRepo,
+	}
+}
+
+// CreateFromCaseResult 从NG执行结果提交缺陷并建立关联
+// 标题、步骤、期望结果取自用例快照，发现版本取自任务的测试版本，测试阶段匹配任务名中包含的项目阶段
+func (s *defectLinkService) CreateFromCaseResult(projectID uint, userID uint, req *models.DefectFromResultRequest) (*models.Defect, error) {
+	result, task, err := s.getCaseResult(projectID, req.CaseResultID)
+	if err != nil {
+		return nil, err
+	}
+	if result.TestResult != "NG" {
+		return nil, errors.New("only NG results can be filed as defects")
+	}
+
+	lang := req.Language
+	if lang == "" {
+		lang = task.DisplayLanguage
+	}
+	content := caseResultContent(result, lang)
+
+	createReq := &models.DefectCreateRequest{
+		Title:           req.Title,
+		SubjectID:       req.SubjectID,
+		Subject:         req.Subject,
+		Description:     buildDefectDescription(req.Description, result, content),
+		Priority:        req.Priority,
+		Severity:        req.Severity,
+		Type:            req.Type,
+		Frequency:       req.Frequency,
+		DetectedVersion: req.DetectedVersion,
+		PhaseID:         req.PhaseID,
+		Phase:           req.Phase,
+		CaseID:          caseResultNum(result),
+	}
+	if createReq.Title == "" {
+		createReq.Title = truncateRunes(content.title, 200)
+	}
+	if createReq.DetectedVersion == "" {
+		createReq.DetectedVersion = task.TestVersion
+	}
+	if createReq.PhaseID == nil && createReq.Phase == "" {
+		createReq.Phase = s.matchPhase(projectID, task.TaskName)
+	}
+
+	defect, err := s.defectService.CreateLinked(projectID, userID, createReq, result)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.AttachArtifacts {
+		if _, err := s.attachmentService.AttachExecutionArtifacts(defect.ID, projectID, userID, result.ID); err != nil {
+			log.Printf("[Defect From Result] attach artifacts skipped: defect_id=%s, case_result_id=%d, error=%v", defect.DefectID, result.ID, err)
+		}
+	}
+
+	log.Printf("[Defect From Result] user_id=%d, defect_id=%s, case_result_id=%d, task_uuid=%s", userID, defect.DefectID, result.ID, task.TaskUUID)
+	return defect, nil
+}
+
+// LinkCaseResults 将执行结果关联到已有缺陷(已关联的忽略)
+func (s *defectLinkService) LinkCaseResults(projectID uint, userID uint, defect *models.Defect, caseResultIDs []uint) ([]*models.DefectCaseLink, error) {
+	for _, id := range caseResultIDs {
+		result, _, err := s.getCaseResult(projectID, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.linkRepo.Create(newDefectCaseLink(defect, result, userID)); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("[Defect Link] user_id=%d, defect_id=%s, case_result_ids=%v", userID, defect.DefectID, caseResultIDs)
+	return s.linkRepo.ListByDefectID(defect.ID)
+}
+
+// UnlinkCaseResult 取消缺陷与执行结果的关联
+func (s *defectLinkService) UnlinkCaseResult(defect *models.Defect, caseResultID uint) error {
+	if err := s.linkRepo.Delete(defect.ID, caseResultID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("defect case link not found")
+		}
+		return err
+	}
+	log.Printf("[Defect Unlink] defect_id=%s, case_result_id=%d", defect.DefectID, caseResultID)
+	return nil
+}
+
+// ListByDefect 查询缺陷关联的执行结果
+func (s *defectLinkService) ListByDefect(defectID string) ([]*models.DefectCaseLink, error) {
+	return s.linkRepo.ListByDefectID(defectID)
+}
+
+// getCaseResult 获取执行结果及其任务，并校验属于该项目
+func (s *defectLinkService) getCaseResult(projectID uint, caseResultID uint) (*models.ExecutionCaseResult, *models.ExecutionTask, error) {
+	result, err := s.resultRepo.GetByID(caseResultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("execution case result not found")
+		}
+		return nil, nil, fmt.Errorf("get execution case result: %w", err)
+	}
+	task, err := s.taskRepo.GetByUUID(result.TaskUUID)
+	if err != nil || task.ProjectID != projectID {
+		return nil, nil, errors.New("execution case result not found")
+	}
+	return result, task, nil
+}
+
+// matchPhase 返回名称包含在任务名中的项目测试阶段(取最长匹配)
+func (s *defectLinkService) matchPhase(projectID uint, taskName string) string {
+	phases, err := s.phaseRepo.ListByProjectID(projectID)
+	if err != nil {
+		return ""
+	}
+	matched := ""
+	for _, p := range phases {
+		if p.Name != "" && strings.Contains(taskName, p.Name) && len(p.Name) > len(matched) {
+			matched = p.Name
+		}
+	}
+	return matched
+}
+
+func newDefectCaseLink(defect *models.Defect, result *models.ExecutionCaseResult, userID uint) *models.DefectCaseLink {
+	return &models.DefectCaseLink{
+		DefectID:     defect.ID,
+		DefectNo:     defect.DefectID,
+		ProjectID:    defect.ProjectID,
+		CaseResultID: result.ID,
+		TaskUUID:     result.TaskUUID,
+		CaseID:       result.CaseID,
+		CaseNum:      caseResultNum(result),
+		CreatedBy:    userID,
+	}
+}
+
+// caseResultNum 用例的显示编号：优先用户自定义CaseID，否则用序号
+func caseResultNum(result *models.ExecutionCaseResult) string {
+	if result.CaseNum != "" {
+		return result.CaseNum
+	}
+	return fmt.Sprintf("%d", result.DisplayID)
+}
+
+// caseContent 按语言选取的用例快照内容
+type caseContent struct {
+	title          string
+	precondition   string
+	steps          string
+	expectedResult string
+}
+
+// caseResultContent 按语言选取用例快照，指定语言为空时依次回退到中/日/英
+func caseResultContent(r *models.ExecutionCaseResult, lang string) caseContent {
+	pick := func(cn, jp, en string) string {
+		values := map[string]string{"cn": cn, "jp": jp, "en": en}
+		if v := values[lang]; v != "" {
+			return v
+		}
+		for _, v := range []string{cn, jp, en} {
+			if v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	var parts []string
+	if r.CaseType == "api" {
+		parts = []string{r.Method, r.URL}
+	} else {
+		parts = []string{
+			pick(r.ScreenCN, r.ScreenJP, r.ScreenEN),
+			pick(r.FunctionCN, r.FunctionJP, r.FunctionEN),
+			pick(r.MajorFunctionCN, r.MajorFunctionJP, r.MajorFunctionEN),
+			pick(r.MiddleFunctionCN, r.MiddleFunctionJP, r.MiddleFunctionEN),
+			pick(r.MinorFunctionCN, r.MinorFunctionJP, r.MinorFunctionEN),
+		}
+	}
+	var titleParts []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			titleParts = append(titleParts, p)
+		}
+	}
+
+	return caseContent{
+		title:          fmt.Sprintf("[%s] %s", caseResultNum(r), strings.Join(titleParts, " / ")),
+		precondition:   pick(r.PreconditionCN, r.PreconditionJP, r.PreconditionEN),
+		steps:          pick(r.TestStepsCN, r.TestStepsJP, r.TestStepsEN),
+		expectedResult: pick(r.ExpectedResultCN, r.ExpectedResultJP, r.ExpectedResultEN),
+	}
+}
+
+// buildDefectDescription 拼接缺陷描述：补充说明、前置条件、步骤、期望结果、实际结果
+func buildDefectDescription(extra string, r *models.ExecutionCaseResult, content caseContent) string {
+	actual := r.Remark
+	if r.CaseType == "api" && r.ActualResponse != "" {
+		actual = strings.TrimSpace(fmt.Sprintf("HTTP %d\n%s\n%s", r.StatusCode, r.ActualResponse, r.Remark))
+	}
+
+	var sections []string
+	if extra = strings.TrimSpace(extra); extra != "" {
+		sections = append(sections, extra)
+	}
+	for _, sec := range []struct{ label, value string }{
+		{"Precondition", content.precondition},
+		{"Steps", content.steps},
+		{"Expected Result", content.expectedResult},
+		{"Actual Result", actual},
+	} {
+		if v := strings.TrimSpace(sec.value); v != "" {
+			sections = append(sections, fmt.Sprintf("【%s】\n%s", sec.label, v))
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max])
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"testing"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefectLinkService_CreateFromResultAndRetest(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{},
+		&models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectCaseLink{}, &models.DefectPhase{})
+	require.NoError(t, db.Create(&models.ProjectMember{ProjectID: 1, UserID: 1, Role: constants.RoleProjectMember}).Error)
+	require.NoError(t, db.Create(&models.DefectPhase{ProjectID: 1, Name: "ST"}).Error)
+
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "ST 第1轮", ExecutionType: "manual", TestVersion: "v1.2", DisplayLanguage: "cn", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	ng := &models.ExecutionCaseResult{
+		TaskUUID: task.TaskUUID, CaseID: "case-1", CaseNum: "TC-001", CaseType: "overall", TestResult: "NG",
+		MajorFunctionCN: "登录", MinorFunctionCN: "密码错误", TestStepsCN: "1. 输入错误密码", ExpectedResultCN: "提示密码错误",
+		Remark: "页面无响应", UpdatedBy: 1,
+	}
+	other := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-2", DisplayID: 2, CaseType: "overall", TestResult: "NG", UpdatedBy: 1}
+	ok := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-3", CaseType: "overall", TestResult: "OK", UpdatedBy: 1}
+	for _, r := range []*models.ExecutionCaseResult{ng, other, ok} {
+		require.NoError(t, db.Create(r).Error)
+	}
+
+	resultRepo := repositories.NewExecutionCaseResultRepository(db)
+	taskRepo := repositories.NewExecutionTaskRepository(db)
+	linkRepo := repositories.NewDefectCaseLinkRepository(db)
+	defectService := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		repositories.NewDefectWorkflowRepository(db), repositories.NewProjectMemberRepository(db),
+		repositories.NewDefectHistoryRepository(db), repositories.NewDefectCommentRepository(db), linkRepo)
+	s := NewDefectLinkService(defectService, nil, resultRepo, taskRepo, repositories.NewDefectPhaseRepository(db), linkRepo)
+
+	_, err := s.CreateFromCaseResult(1, 1, &models.DefectFromResultRequest{CaseResultID: ok.ID})
+	assert.EqualError(t, err, "only NG results can be filed as defects")
+
+	defect, err := s.CreateFromCaseResult(1, 1, &models.DefectFromResultRequest{CaseResultID: ng.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "[TC-001] 登录 / 密码错误", defect.Title)
+	assert.Equal(t, "v1.2", defect.DetectedVersion)
+	assert.Equal(t, "ST", defect.Phase)
+	assert.Contains(t, defect.Description, "1. 输入错误密码")
+	assert.Contains(t, defect.Description, "页面无响应")
+
+	// 多个执行结果关联同一缺陷，BugID同步为缺陷显示ID
+	links, err := s.LinkCaseResults(1, 1, defect, []uint{other.ID, ng.ID})
+	require.NoError(t, err)
+	assert.Len(t, links, 2)
+	saved, err := resultRepo.GetByID(other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, defect.DefectID, saved.BugID)
+
+	// 关闭缺陷后，下一个执行任务中的关联用例标记为需复测(只标记一次)
+	require.NoError(t, db.Model(&models.Defect{}).Where("id = ?", defect.ID).Update("status", "Resolved").Error)
+	require.NoError(t, defectService.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("Closed")}, models.DefectChangeSourceWeb))
+
+	resultService := NewExecutionCaseResultService(resultRepo, taskRepo, nil, nil, nil, nil, linkRepo)
+	requests := []SaveCaseResultRequest{
+		{CaseID: "case-1", TestResult: "NR"}, {CaseID: "case-2", TestResult: "NR"}, {CaseID: "case-3", TestResult: "NR"},
+	}
+	var flagged []string
+	for i := 0; i < 2; i++ {
+		next := &models.ExecutionTask{ProjectID: 1, TaskName: "ST 第2轮", ExecutionType: "manual", CreatedBy: 1}
+		require.NoError(t, db.Create(next).Error)
+		require.NoError(t, resultService.SaveCaseResults(next.TaskUUID, 1, requests))
+
+		results, err := resultRepo.GetByTaskUUID(next.TaskUUID)
+		require.NoError(t, err)
+		for _, r := range results {
+			if r.RetestRequired {
+				assert.Equal(t, defect.DefectID, r.RetestDefectID)
+				flagged = append(flagged, r.CaseID)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"case-1", "case-2"}, flagged)
+
+	require.NoError(t, s.UnlinkCaseResult(defect, other.ID))
+	saved, err = resultRepo.GetByID(other.ID)
+	require.NoError(t, err)
+	assert.Empty(t, saved.BugID)
+}
+
+// TestDefectCaseLinks_RemovedWithCaseResults 清空任务执行结果时一并删除缺陷关联
+func TestDefectCaseLinks_RemovedWithCaseResults(t *testing.T) {
+	db := newTestDB(t, &models.DefectCaseLink{}, &models.ManualExecutionSession{}, &models.ManualStepResult{})
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "ST 第1轮", ExecutionType: "manual", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	ng := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "overall", TestResult: "NG", UpdatedBy: 1}
+	require.NoError(t, db.Create(ng).Error)
+
+	linkRepo := repositories.NewDefectCaseLinkRepository(db)
+	require.NoError(t, linkRepo.Create(&models.DefectCaseLink{DefectID: "defect-1", DefectNo: "000001", ProjectID: 1,
+		CaseResultID: ng.ID, TaskUUID: task.TaskUUID, CaseID: ng.CaseID, CreatedBy: 1}))
+
+	resultService := NewExecutionCaseResultService(repositories.NewExecutionCaseResultRepository(db),
+		repositories.NewExecutionTaskRepository(db), nil, nil, nil, nil, linkRepo)
+	require.NoError(t, resultService.ClearTaskResults(task.TaskUUID))
+
+	links, err := linkRepo.ListByDefectID("defect-1")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+// TestDefectService_DeleteRemovesLinks 删除缺陷时一并删除关联、变更记录并清空执行结果的BugID
+func TestDefectService_DeleteRemovesLinks(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{},
+		&models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectCaseLink{})
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "ST 第1轮", ExecutionType: "manual", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	ng := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "overall", TestResult: "NG", UpdatedBy: 1}
+	require.NoError(t, db.Create(ng).Error)
+	defect := &models.Defect{ID: "defect-1", DefectID: "000001", ProjectID: 1, Title: "登录失败", CreatedBy: 1}
+	require.NoError(t, db.Create(defect).Error)
+
+	linkRepo := repositories.NewDefectCaseLinkRepository(db)
+	require.NoError(t, linkRepo.Create(&models.DefectCaseLink{DefectID: defect.ID, DefectNo: defect.DefectID, ProjectID: 1,
+		CaseResultID: ng.ID, TaskUUID: task.TaskUUID, CaseID: ng.CaseID, CreatedBy: 1}))
+	require.NoError(t, db.Create(&models.DefectHistory{DefectID: defect.ID, ProjectID: 1, ChangeSetID: "change-1",
+		Field: "status", OldValue: "New", NewValue: "InProgress", ActorID: 1, Source: models.DefectChangeSourceWeb}).Error)
+
+	s := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		repositories.NewDefectWorkflowRepository(db), repositories.NewProjectMemberRepository(db),
+		repositories.NewDefectHistoryRepository(db), repositories.NewDefectCommentRepository(db), linkRepo)
+	require.NoError(t, s.Delete(defect.ID))
+
+	links, err := linkRepo.ListByDefectID(defect.ID)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+	saved, err := repositories.NewExecutionCaseResultRepository(db).GetByID(ng.ID)
+	require.NoError(t, err)
+	assert.Empty(t, saved.BugID)
+	histories, err := repositories.NewDefectHistoryRepository(db).ListByDefectID(defect.ID)
+	require.NoError(t, err)
+	assert.Empty(t, histories)
+	assert.EqualError(t, s.Delete(defect.ID), "defect not found")
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 缺陷查询语言
+//
+//	expr  := and ("or" and)*
+//	and   := unary ("and" unary)*
+//	unary := "not" unary | "(" expr ")" | cond
+//	cond  := field op value | field ["not"] "in" "(" value ("," value)* ")"
+//	op    := = | != | > | >= | < | <= | ~ (包含) | !~ (不包含)
+//
+// 例: severity in (Critical,Major) and component=Payment and status!=Closed and created>2026-09-01
+// 值含空格或特殊字符时用单/双引号包裹；关键字和字段名不区分大小写。
+// 日期字段只给出日期时按整天比较，如 created>2026-09-01 表示 9月2日及以后。
+
+// DefectQueryError 查询语句解析错误
+type DefectQueryError struct {
+	Pos     int // 出错位置(字符偏移)
+	Message string
+}
+
+func (e *DefectQueryError) Error() string {
+	return fmt.Sprintf("invalid query at %d: %s", e.Pos, e.Message)
+}
+
+type defectFieldKind int
+
+const (
+	defectFieldString defectFieldKind = iota
+	defectFieldNumber
+	defectFieldTime
+)
+
+type defectQueryField struct {
+	column string
+	kind   defectFieldKind
+}
+
+// defectQueryFields 可查询/排序的字段(查询名 -> 列)
+var defectQueryFields = map[string]defectQueryField{
+	"id":               {"defect_id", defectFieldString},
+	"defect_id":        {"defect_id", defectFieldString},
+	"title":            {"title", defectFieldString},
+	"subject":          {"subject", defectFieldString},
+	"module":           {"subject", defectFieldString},
+	"description":      {"description", defectFieldString},
+	"recovery_method":  {"recovery_method", defectFieldString},
+	"priority":         {"priority", defectFieldString},
+	"severity":         {"severity", defectFieldString},
+	"type":             {"type", defectFieldString},
+	"frequency":        {"frequency", defectFieldString},
+	"detected_version": {"detected_version", defectFieldString},
+	"phase":            {"phase", defectFieldString},
+	"case_id":          {"case_id", defectFieldString},
+	"assignee":         {"assignee", defectFieldString},
+	"recovery_rank":    {"recovery_rank", defectFieldString},
+	"detection_team":   {"detection_team", defectFieldString},
+	"location":         {"location", defectFieldString},
+	"fix_version":      {"fix_version", defectFieldString},
+	"sqa_memo":         {"sqa_memo", defectFieldString},
+	"component":        {"component", defectFieldString},
+	"resolution":       {"resolution", defectFieldString},
+	"models":           {"models", defectFieldString},
+	"detected_by":      {"detected_by", defectFieldString},
+	"status":           {"status", defectFieldString},
+	"created_by":       {"created_by", defectFieldNumber},
+	"updated_by":       {"updated_by", defectFieldNumber},
+	"created":          {"created_at", defectFieldTime},
+	"created_at":       {"created_at", defectFieldTime},
+	"updated":          {"updated_at", defectFieldTime},
+	"updated_at":       {"updated_at", defectFieldTime},
+}
+
+// DefectQuery 解析后的查询条件(参数化SQL)
+type DefectQuery struct {
+	Where string
+	Args  []interface{}
+}
+
+// ParseDefectQuery 解析查询语句，空语句返回nil
+func ParseDefectQuery(input string) (*DefectQuery, error) {
+	tokens, err := lexDefectQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &defectQueryParser{tokens: tokens, end: len([]rune(input))}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != nil {
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	return q, nil
+}
+
+// ParseDefectSort 解析排序("-created,priority"，前缀-表示降序)，为空时按创建时间降序
+// 末尾追加 id 作为唯一排序键，保证分页结果稳定
+func ParseDefectSort(input string) (string, error) {
+	if strings.TrimSpace(input) == "" {
+		return "created_at DESC, id", nil
+	}
+	var orders []string
+	for _, part := range strings.Split(input, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		dir := "ASC"
+		if strings.HasPrefix(name, "-") {
+			name, dir = strings.TrimSpace(name[1:]), "DESC"
+		}
+		field, ok := defectQueryFields[name]
+		if !ok {
+			return "", &DefectQueryError{Message: fmt.Sprintf("unknown sort field %q", name)}
+		}
+		orders = append(orders, field.column+" "+dir)
+	}
+	return strings.Join(append(orders, "id"), ", "), nil
+}
+
+type defectQueryTokenKind int
+
+const (
+	tokWord defectQueryTokenKind = iota // 字段名/关键字/未加引号的值
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type defectQueryToken struct {
+	kind defectQueryTokenKind
+	text string
+	pos  int
+}
+
+func lexDefectQuery(input string) ([]defectQueryToken, error) {
+	var tokens []defectQueryToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, defectQueryToken{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, defectQueryToken{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, defectQueryToken{tokComma, ",", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &DefectQueryError{Pos: start, Message: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, defectQueryToken{tokString, sb.String(), start})
+		case strings.ContainsRune("=!<>~", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '!' && runes[i+1] == '~')) {
+				op += string(runes[i+1])
+			}
+			if op == "!" {
+				return nil, &DefectQueryError{Pos: start, Message: "unknown operator \"!\""}
+			}
+			i += len([]rune(op))
+			tokens = append(tokens, defectQueryToken{tokOp, op, start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()=!<>~,\"'", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, defectQueryToken{tokWord, string(runes[start:i]), start})
+		}
+	}
+	return tokens, nil
+}
+
+type defectQueryParser struct {
+	tokens []defectQueryToken
+	pos    int
+	end    int
+}
+
+func (p *defectQueryParser) peek() *defectQueryToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *defectQueryParser) next() (*defectQueryToken, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, &DefectQueryError{Pos: p.end, Message: "unexpected end of query"}
+	}
+	p.pos++
+	return tok, nil
+}
+
+// keyword 当前token是指定关键字时消费并返回true
+func (p *defectQueryParser) keyword(word string) bool {
+	if tok := p.peek(); tok != nil && tok.kind == tokWord && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *defectQueryParser) parseOr() (*DefectQuery, error) {
+	return p.parseJoined("or", " OR ", p.parseAnd)
+}
+
+func (p *defectQueryParser) parseAnd() (*DefectQuery, error) {
+	return p.parseJoined("and", " AND ", p.parseUnary)
+}
+
+func (p *defectQueryParser) parseJoined(word, sep string, operand func() (*DefectQuery, error)) (*DefectQuery, error) {
+	q, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	parts, args := []string{q.Where}, q.Args
+	for p.keyword(word) {
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, next.Where)
+		args = append(args, next.Args...)
+	}
+	if len(parts) == 1 {
+		return q, nil
+	}
+	return &DefectQuery{Where: "(" + strings.Join(parts, sep) + ")", Args: args}, nil
+}
+
+func (p *defectQueryParser) parseUnary() (*DefectQuery, error) {
+	if p.keyword("not") {
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &DefectQuery{Where: "NOT " + q.Where, Args: q.Args}, nil
+	}
+	if tok := p.peek(); tok != nil && tok.kind == tokLParen {
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, ")"); err != nil {
+			return nil, err
+		}
+		return &DefectQuery{Where: "(" + q.Where + ")", Args: q.Args}, nil
+	}
+	return p.parseCond()
+}
+
+func (p *defectQueryParser) expect(kind defectQueryTokenKind, text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.kind != kind {
+		return &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("expected %q, got %q", text, tok.text)}
+	}
+	return nil
+}
+
+func (p *defectQueryParser) parseCond() (*DefectQuery, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != tokWord {
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("expected field, got %q", tok.text)}
+	}
+	field, ok := defectQueryFields[strings.ToLower(tok.text)]
+	if !ok {
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("unknown field %q", tok.text)}
+	}
+
+	negate := p.keyword("not")
+	if p.keyword("in") {
+		return p.parseIn(field, negate)
+	}
+	if negate {
+		return nil, &DefectQueryError{Pos: tok.pos, Message: "expected \"in\" after \"not\""}
+	}
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if opTok.kind != tokOp {
+		return nil, &DefectQueryError{Pos: opTok.pos, Message: fmt.Sprintf("expected operator, got %q", opTok.text)}
+	}
+	valTok, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return compareDefectField(field, opTok.text, valTok)
+}
+
+func (p *defectQueryParser) value() (*defectQueryToken, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind != tokWord && tok.kind != tokString {
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("expected value, got %q", tok.text)}
+	}
+	return tok, nil
+}
+
+func (p *defectQueryParser) parseIn(field defectQueryField, negate bool) (*DefectQuery, error) {
+	if err := p.expect(tokLParen, "("); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		tok, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := defectFieldValue(field, tok)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		sep, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if sep.kind == tokRParen {
+			break
+		}
+		if sep.kind != tokComma {
+			return nil, &DefectQueryError{Pos: sep.pos, Message: fmt.Sprintf("expected \",\" or \")\", got %q", sep.text)}
+		}
+	}
+	op := "IN"
+	if negate {
+		op = "NOT IN"
+	}
+	return &DefectQuery{Where: fmt.Sprintf("%s %s ?", field.column, op), Args: []interface{}{values}}, nil
+}
+
+// defectLikeEscaper 转义 LIKE 通配符，使 ~ 按字面包含匹配
+var defectLikeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// compareDefectField 生成单个比较条件
+func compareDefectField(field defectQueryField, op string, tok *defectQueryToken) (*DefectQuery, error) {
+	col := field.column
+	if op == "~" || op == "!~" {
+		if field.kind != defectFieldString {
+			return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("operator %q only applies to text fields", op)}
+		}
+		like := "LIKE"
+		if op == "!~" {
+			like = "NOT LIKE"
+		}
+		return &DefectQuery{Where: fmt.Sprintf(`%s %s ? ESCAPE '\'`, col, like), Args: []interface{}{"%" + defectLikeEscaper.Replace(tok.text) + "%"}}, nil
+	}
+
+	// 只给出日期时按整天比较
+	if field.kind == defectFieldTime {
+		if day, err := time.ParseInLocation("2006-01-02", tok.text, time.Local); err == nil {
+			next := day.AddDate(0, 0, 1)
+			switch op {
+			case "=":
+				return &DefectQuery{Where: fmt.Sprintf("(%s >= ? AND %s < ?)", col, col), Args: []interface{}{day, next}}, nil
+			case "!=":
+				return &DefectQuery{Where: fmt.Sprintf("(%s < ? OR %s >= ?)", col, col), Args: []interface{}{day, next}}, nil
+			case ">":
+				return &DefectQuery{Where: col + " >= ?", Args: []interface{}{next}}, nil
+			case ">=":
+				return &DefectQuery{Where: col + " >= ?", Args: []interface{}{day}}, nil
+			case "<":
+				return &DefectQuery{Where: col + " < ?", Args: []interface{}{day}}, nil
+			case "<=":
+				return &DefectQuery{Where: col + " < ?", Args: []interface{}{next}}, nil
+			}
+		}
+	}
+
+	switch op {
+	case "=", "!=", ">", ">=", "<", "<=":
+	default:
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("unknown operator %q", op)}
+	}
+	if op == "!=" {
+		op = "<>"
+	}
+	v, err := defectFieldValue(field, tok)
+	if err != nil {
+		return nil, err
+	}
+	return &DefectQuery{Where: fmt.Sprintf("%s %s ?", col, op), Args: []interface{}{v}}, nil
+}
+
+// defectFieldValue 按字段类型转换值
+func defectFieldValue(field defectQueryField, tok *defectQueryToken) (interface{}, error) {
+	switch field.kind {
+	case defectFieldNumber:
+		n, err := strconv.ParseUint(tok.text, 10, 64)
+		if err != nil {
+			return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("%q is not a number", tok.text)}
+		}
+		return n, nil
+	case defectFieldTime:
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, tok.text, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return nil, &DefectQueryError{Pos: tok.pos, Message: fmt.Sprintf("%q is not a date", tok.text)}
+	}
+	return tok.text, nil
+}
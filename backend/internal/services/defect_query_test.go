@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDefectQuery(t *testing.T) {
+	q, err := ParseDefectQuery(`severity in (Critical,Major) and component=Payment`)
+	require.NoError(t, err)
+	assert.Equal(t, "(severity IN ? AND component = ?)", q.Where)
+	assert.Equal(t, []interface{}{[]interface{}{"Critical", "Major"}, "Payment"}, q.Args)
+
+	q, err = ParseDefectQuery(`not (status=Closed or title~"登录")`)
+	require.NoError(t, err)
+	assert.Equal(t, `NOT ((status = ? OR title LIKE ? ESCAPE '\'))`, q.Where)
+	assert.Equal(t, []interface{}{"Closed", "%登录%"}, q.Args)
+
+	// LIKE 通配符按字面匹配
+	q, err = ParseDefectQuery(`title~100%_x`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{`%100\%\_x%`}, q.Args)
+
+	// 只给日期时按整天比较
+	q, err = ParseDefectQuery(`created>2026-09-01`)
+	require.NoError(t, err)
+	assert.Equal(t, "created_at >= ?", q.Where)
+	require.Len(t, q.Args, 1)
+	assert.True(t, q.Args[0].(time.Time).Equal(time.Date(2026, 9, 2, 0, 0, 0, 0, time.Local)))
+
+	q, err = ParseDefectQuery("  ")
+	require.NoError(t, err)
+	assert.Nil(t, q)
+
+	_, err = ParseDefectQuery(`foo=1`)
+	assert.EqualError(t, err, `invalid query at 0: unknown field "foo"`)
+	_, err = ParseDefectQuery(`(status=New`)
+	assert.EqualError(t, err, "invalid query at 11: unexpected end of query")
+
+	order, err := ParseDefectSort("-priority,created")
+	require.NoError(t, err)
+	assert.Equal(t, "priority DESC, created_at ASC, id", order)
+	_, err = ParseDefectSort("bogus")
+	assert.EqualError(t, err, `invalid query at 0: unknown sort field "bogus"`)
+}
+
+func TestDefectService_ListWithQueryAndSavedFilter(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{},
+		&models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectCaseLink{}, &models.DefectSavedFilter{})
+	s := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		repositories.NewDefectWorkflowRepository(db), repositories.NewProjectMemberRepository(db),
+		repositories.NewDefectHistoryRepository(db), repositories.NewDefectCommentRepository(db),
+		repositories.NewDefectCaseLinkRepository(db))
+	filters := NewDefectFilterService(repositories.NewDefectSavedFilterRepository(db))
+
+	for _, req := range []models.DefectCreateRequest{
+		{Title: "Pay timeout", Severity: "Critical", Priority: "B", Component: "Payment"},
+		{Title: "Pay rounding", Severity: "Minor", Priority: "A", Component: "Payment"},
+		{Title: "Login crash", Severity: "Critical", Priority: "A", Component: "Auth"},
+	} {
+		_, err := s.Create(1, 1, &req)
+		require.NoError(t, err)
+	}
+
+	result, err := s.List(1, models.DefectListParams{Query: "component=Payment or severity=Critical", Sort: "priority,-title"})
+	require.NoError(t, err)
+	require.Len(t, result.Defects, 3)
+	assert.Equal(t, "Pay rounding", result.Defects[0].Title)
+	assert.Equal(t, "Login crash", result.Defects[1].Title)
+	assert.Equal(t, int64(3), result.Total)
+
+	result, err = s.List(1, models.DefectListParams{Query: "title~_a"})
+	require.NoError(t, err)
+	assert.Zero(t, result.Total)
+
+	_, err = s.List(1, models.DefectListParams{Query: "severity=="})
+	var queryErr *DefectQueryError
+	assert.ErrorAs(t, err, &queryErr)
+
+	// 项目筛选只有项目经理可以创建
+	_, err = filters.Create(1, 2, constants.RoleProjectMember, &models.DefectSavedFilterRequest{Name: "payment", Query: "component=Payment", Scope: models.DefectFilterScopeProject})
+	assert.EqualError(t, err, "only project managers can manage project filters")
+	shared, err := filters.Create(1, 1, constants.RoleProjectManager, &models.DefectSavedFilterRequest{Name: "payment", Query: "component=Payment", Sort: "-priority", Scope: models.DefectFilterScopeProject})
+	require.NoError(t, err)
+	_, err = filters.Create(1, 1, constants.RoleProjectManager, &models.DefectSavedFilterRequest{Name: "payment", Scope: models.DefectFilterScopeProject})
+	assert.EqualError(t, err, "defect filter name already exists")
+	_, err = filters.Create(1, 2, constants.RoleProjectMember, &models.DefectSavedFilterRequest{Name: "mine", Query: "severity="})
+	assert.ErrorAs(t, err, &queryErr)
+	_, err = filters.Create(1, 2, constants.RoleProjectMember, &models.DefectSavedFilterRequest{Name: "mine", Query: "severity=Critical"})
+	require.NoError(t, err)
+
+	visible, err := filters.List(1, 3)
+	require.NoError(t, err)
+	assert.Len(t, visible, 1)
+	assert.EqualError(t, filters.Delete(1, 2, constants.RoleProjectMember, shared.ID), "only project managers can manage project filters")
+
+	// 按名称引用筛选，与 q 取交集并沿用筛选的排序
+	params := models.DefectListParams{Filter: "payment", Query: "severity=Critical"}
+	require.NoError(t, filters.Apply(1, 2, &params))
+	assert.Equal(t, "-priority", params.Sort)
+	result, err = s.List(1, params)
+	require.NoError(t, err)
+	require.Len(t, result.Defects, 1)
+	assert.Equal(t, "Pay timeout", result.Defects[0].Title)
+
+	assert.EqualError(t, filters.Apply(1, 3, &models.DefectListParams{Filter: "mine"}), "defect filter not found")
+}
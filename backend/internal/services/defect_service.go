@@ -8,11 +8,14 @@ import (
 	"html"
 	"io"
 	"log"
+	"sort"
 	"strings"
 	"time"
+	"webtest/internal/constants"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
 
+	"github.com/google/uuid"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 )
@@ -21,32 +24,59 @@ import (
 type DefectService interface {
 	// CRUD
 	Create(projectID uint, userID uint, req *models.DefectCreateRequest) (*models.Defect, error)
+	CreateLinked(projectID uint, userID uint, req *models.DefectCreateRequest, result *models.ExecutionCaseResult) (*models.Defect, error)
 	GetByID(id string) (*models.Defect, error)
 	GetByDefectID(defectID string) (*models.Defect, error)
-	Update(id string, userID uint, req *models.DefectUpdateRequest) error
+	Update(id string, userID uint, req *models.DefectUpdateRequest, source string) error
 	Delete(id string) error
 
+	// 变更记录
+	GetTimeline(id string) ([]models.DefectTimelineEntry, error)
+
 	// 列表
-	List(projectID uint, status, keyword string, page, size int) (*models.DefectListResponse, error)
+	List(projectID uint, params models.DefectListParams) (*models.DefectListResponse, error)
+
+	// 重复检测
+	FindDuplicates(projectID uint, defect *models.Defect, opts models.DefectDuplicateOptions) ([]models.DefectDuplicateCandidate, error)
+	ListDuplicateGroups(projectID uint, opts models.DefectDuplicateOptions) ([]models.DefectDuplicateGroup, error)
+	Merge(projectID uint, userID uint, req *models.DefectMergeRequest) (*models.Defect, error)
 
 	// 导入导出
 	GenerateTemplate(format string) ([]byte, error)
-	ImportWithFormat(projectID uint, userID uint, reader io.Reader, isXLSX bool) (*models.ImportResult, error)
-	Import(projectID uint, userID uint, reader io.Reader) (*models.ImportResult, error)
+	ImportWithFormat(projectID uint, userID uint, reader io.Reader, isXLSX bool, opts models.DefectImportOptions) (*models.ImportResult, error)
+	Import(projectID uint, userID uint, reader io.Reader, opts models.DefectImportOptions) (*models.ImportResult, error)
 	Export(projectID uint) ([]byte, error)
 	ExportWithFormat(projectID uint, format string) ([]byte, error)
 }
 
 type defectService struct {
-	repo     repositories.DefectRepository
-	userRepo repositories.UserRepository
+	repo         repositories.DefectRepository
+	userRepo     repositories.UserRepository
+	workflowRepo repositories.DefectWorkflowRepository
+	memberRepo   repositories.ProjectMemberRepository
+	historyRepo  repositories.DefectHistoryRepository
+	commentRepo  repositories.DefectCommentRepository
+	linkRepo     repositories.DefectCaseLinkRepository
 }
 
 // NewDefectService 创建缺陷服务实例
-func NewDefectService(repo repositories.DefectRepository, userRepo repositories.UserRepository) DefectService {
+func NewDefectService(
+	repo repositories.DefectRepository,
+	userRepo repositories.UserRepository,
+	workflowRepo repositories.DefectWorkflowRepository,
+	memberRepo repositories.ProjectMemberRepository,
+	historyRepo repositories.DefectHistoryRepository,
+	commentRepo repositories.DefectCommentRepository,
+	linkRepo repositories.DefectCaseLinkRepository,
+) DefectService {
 	return &defectService{
-		repo:     repo,
-		userRepo: userRepo,
+		repo:         repo,
+		userRepo:     userRepo,
+		workflowRepo: workflowRepo,
+		memberRepo:   memberRepo,
+		historyRepo:  historyRepo,
+		commentRepo:  commentRepo,
+		linkRepo:     linkRepo,
 	}
 }
 
@@ -66,6 +96,35 @@ func decodeHTMLEntities(text string) string {
 
 // Create 创建缺陷
 func (s *defectService) Create(projectID uint, userID uint, req *models.DefectCreateRequest) (*models.Defect, error) {
+	defect, err := s.newDefect(projectID, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.Create(defect); err != nil {
+		return nil, fmt.Errorf("create defect: %w", err)
+	}
+
+	log.Printf("[Defect Create] user_id=%d, project_id=%d, defect_id=%s, created_at=%v", userID, projectID, defect.DefectID, defect.CreatedAt)
+	return defect, nil
+}
+
+// CreateLinked 创建缺陷并关联执行结果，两者在同一事务中写入
+func (s *defectService) CreateLinked(projectID uint, userID uint, req *models.DefectCreateRequest, result *models.ExecutionCaseResult) (*models.Defect, error) {
+	defect, err := s.newDefect(projectID, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	defect.ID = uuid.New().String()
+	if err = s.linkRepo.CreateWithDefect(defect, newDefectCaseLink(defect, result, userID)); err != nil {
+		return nil, fmt.Errorf("create defect: %w", err)
+	}
+
+	log.Printf("[Defect Create] user_id=%d, project_id=%d, defect_id=%s, case_result_id=%d", userID, projectID, defect.DefectID, result.ID)
+	return defect, nil
+}
+
+// newDefect 校验创建请求并构建缺陷(分配DefectID，尚未写入)
+func (s *defectService) newDefect(projectID uint, userID uint, req *models.DefectCreateRequest) (*models.Defect, error) {
 	// 验证优先级
 	if req.Priority != "" && !models.IsValidDefectPriority(req.Priority) {
 		return nil, errors.New("invalid priority value")
@@ -177,11 +236,6 @@ func (s *defectService) Create(projectID uint, userID uint, req *models.DefectCr
 		}
 	}
 
-	if err = s.repo.Create(defect); err != nil {
-		return nil, fmt.Errorf("create defect: %w", err)
-	}
-
-	log.Printf("[Defect Create] user_id=%d, project_id=%d, defect_id=%s, created_at=%v", userID, projectID, defect.DefectID, defect.CreatedAt)
 	return defect, nil
 }
 
@@ -209,8 +263,9 @@ func (s *defectService) GetByDefectID(defectID string) (*models.Defect, error) {
 	return defect, nil
 }
 
-// Update 更新缺陷
-func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateRequest) error {
+// Update 更新缺陷，变更的字段按来源(source)记录到变更历史
+// 除合并(merge)由项目经理操作不校验工作流外，其余来源(含导入)均按工作流校验状态流转
+func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateRequest, source string) error {
 	// 先获取缺陷以得到projectID
 	defect, err := s.repo.GetByID(id)
 	if err != nil {
@@ -220,12 +275,35 @@ func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateR
 		return fmt.Errorf("get defect: %w", err)
 	}
 
+	updates, histories, err := s.prepareUpdate(defect, userID, req, source)
+	if err != nil {
+		return err
+	}
+	if updates == nil {
+		return nil
+	}
+
+	if err := s.repo.UpdateWithHistory(id, updates, histories); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("defect not found")
+		}
+		return fmt.Errorf("update defect: %w", err)
+	}
+
+	s.syncRetestPending(defect, updates)
+
+	log.Printf("[Defect Update] user_id=%d, defect_id=%s, source=%s, changed=%d, fields=%v", userID, id, source, len(histories), updates)
+	return nil
+}
+
+// prepareUpdate 校验更新请求，构建更新字段及变更记录(尚未写入)，没有需要更新的字段时返回nil
+func (s *defectService) prepareUpdate(defect *models.Defect, userID uint, req *models.DefectUpdateRequest, source string) (map[string]interface{}, []*models.DefectHistory, error) {
 	// 构建更新字段
 	updates := make(map[string]interface{})
 
 	if req.Title != nil {
 		if *req.Title == "" {
-			return errors.New("title cannot be empty")
+			return nil, nil, errors.New("title cannot be empty")
 		}
 		updates["title"] = *req.Title
 	}
@@ -252,19 +330,19 @@ func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateR
 	}
 	if req.Priority != nil {
 		if !models.IsValidDefectPriority(*req.Priority) {
-			return errors.New("invalid priority value")
+			return nil, nil, errors.New("invalid priority value")
 		}
 		updates["priority"] = *req.Priority
 	}
 	if req.Severity != nil {
 		if !models.IsValidDefectSeverity(*req.Severity) {
-			return errors.New("invalid severity value")
+			return nil, nil, errors.New("invalid severity value")
 		}
 		updates["severity"] = *req.Severity
 	}
 	if req.Type != nil {
 		if *req.Type != "" && !models.IsValidDefectType(*req.Type) {
-			return errors.New("invalid type value")
+			return nil, nil, errors.New("invalid type value")
 		}
 		updates["type"] = *req.Type
 	}
@@ -323,7 +401,13 @@ func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateR
 	}
 	if req.Status != nil {
 		if !models.IsValidDefectStatus(*req.Status) {
-			return errors.New("invalid status value")
+			return nil, nil, errors.New("invalid status value")
+		}
+		// 合并由项目经理发起，被合并的缺陷直接标记为 Rejected，不受工作流约束；其余来源(含导入)均校验
+		if source != models.DefectChangeSourceMerge {
+			if err := s.checkTransition(defect, userID, req); err != nil {
+				return nil, nil, err
+			}
 		}
 		oldStatus := defect.Status
 		updates["status"] = *req.Status
@@ -333,18 +417,164 @@ func (s *defectService) Update(id string, userID uint, req *models.DefectUpdateR
 	updates["updated_by"] = userID
 
 	if len(updates) == 1 { // 只有updated_by
-		return nil
+		return nil, nil, nil
 	}
 
-	if err := s.repo.Update(id, updates); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("defect not found")
+	return updates, s.buildHistories(defect, updates, userID, source), nil
+}
+
+// buildHistories 对比更新前后的字段值，生成变更记录(值未变化的字段不记录)
+func (s *defectService) buildHistories(defect *models.Defect, updates map[string]interface{}, userID uint, source string) []*models.DefectHistory {
+	before := defectFieldValues(defect)
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		if field != "updated_by" {
+			fields = append(fields, field)
 		}
-		return fmt.Errorf("update defect: %w", err)
 	}
+	sort.Strings(fields)
 
-	log.Printf("[Defect Update] user_id=%d, defect_id=%s, fields=%v", userID, id, updates)
-	return nil
+	actorName := ""
+	if user, err := s.userRepo.FindByID(userID); err == nil && user != nil {
+		actorName = user.Nickname
+	}
+	changeSetID := uuid.New().String()
+
+	var histories []*models.DefectHistory
+	for _, field := range fields {
+		newValue := fmt.Sprint(updates[field])
+		if before[field] == newValue {
+			continue
+		}
+		histories = append(histories, &models.DefectHistory{
+			DefectID:    defect.ID,
+			ProjectID:   defect.ProjectID,
+			ChangeSetID: changeSetID,
+			Field:       field,
+			OldValue:    before[field],
+			NewValue:    newValue,
+			ActorID:     userID,
+			ActorName:   actorName,
+			Source:      source,
+		})
+	}
+	return histories
+}
+
+// syncRetestPending 缺陷关闭时标记关联用例待复测，重新打开时取消标记
+func (s *defectService) syncRetestPending(defect *models.Defect, updates map[string]interface{}) {
+	status, ok := updates["status"].(string)
+	if !ok || status == defect.Status {
+		return
+	}
+	closed := string(models.DefectStatusClosed)
+	if status != closed && defect.Status != closed {
+		return
+	}
+	if err := s.linkRepo.SetRetestPending(defect.ID, status == closed); err != nil {
+		log.Printf("[Defect Retest Warning] defect_id=%s, error=%v", defect.DefectID, err)
+	}
+}
+
+// defectFieldValues 缺陷可更新字段的当前值(按数据库列名)
+func defectFieldValues(d *models.Defect) map[string]string {
+	return map[string]string{
+		"title":            d.Title,
+		"subject":          d.Subject,
+		"description":      d.Description,
+		"recovery_method":  d.RecoveryMethod,
+		"priority":         d.Priority,
+		"severity":         d.Severity,
+		"type":             d.Type,
+		"frequency":        d.Frequency,
+		"detected_version": d.DetectedVersion,
+		"phase":            d.Phase,
+		"case_id":          d.CaseID,
+		"assignee":         d.Assignee,
+		"recovery_rank":    d.RecoveryRank,
+		"detection_team":   d.DetectionTeam,
+		"location":         d.Location,
+		"fix_version":      d.FixVersion,
+		"sqa_memo":         d.SQAMemo,
+		"component":        d.Component,
+		"resolution":       d.Resolution,
+		"models":           d.Models,
+		"detected_by":      d.DetectedBy,
+		"status":           d.Status,
+	}
+}
+
+// GetTimeline 获取缺陷时间线：创建、字段变更(按批次合并)和说明，按时间升序
+func (s *defectService) GetTimeline(id string) ([]models.DefectTimelineEntry, error) {
+	defect, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.historyRepo.ListByDefectID(id)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.commentRepo.ListByDefectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := []models.DefectTimelineEntry{{
+		Type:      "created",
+		Time:      defect.CreatedAt,
+		ActorID:   defect.CreatedBy,
+		ActorName: defect.DetectedBy,
+	}}
+
+	changeSets := make(map[string]int)
+	for _, h := range histories {
+		change := models.DefectFieldChange{Field: h.Field, OldValue: h.OldValue, NewValue: h.NewValue}
+		if idx, ok := changeSets[h.ChangeSetID]; ok {
+			timeline[idx].Changes = append(timeline[idx].Changes, change)
+			continue
+		}
+		changeSets[h.ChangeSetID] = len(timeline)
+		timeline = append(timeline, models.DefectTimelineEntry{
+			Type:      "change",
+			Time:      h.CreatedAt,
+			ActorID:   h.ActorID,
+			ActorName: h.ActorName,
+			Source:    h.Source,
+			Changes:   []models.DefectFieldChange{change},
+		})
+	}
+
+	for _, c := range comments {
+		timeline = append(timeline, models.DefectTimelineEntry{
+			Type:      "comment",
+			Time:      c.CreatedAt,
+			ActorID:   c.CreatedBy,
+			ActorName: c.CreatedByUser.Nickname,
+			Comment:   c,
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline, nil
+}
+
+// checkTransition 按项目工作流和用户的项目角色校验状态变更
+func (s *defectService) checkTransition(defect *models.Defect, userID uint, req *models.DefectUpdateRequest) error {
+	transitions, _, err := loadDefectTransitions(s.workflowRepo, defect.ProjectID)
+	if err != nil {
+		return err
+	}
+	role, err := s.memberRepo.GetMemberRole(defect.ProjectID, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("get member role: %w", err)
+	}
+	// 系统管理员不一定是项目成员，不受流转规则的角色限制
+	if user, err := s.userRepo.FindByID(userID); err == nil && user != nil && user.Role == constants.RoleSystemAdmin {
+		role = constants.RoleSystemAdmin
+	}
+	return checkDefectTransition(transitions, defect, req, role)
 }
 
 // Delete 删除缺陷
@@ -358,7 +588,8 @@ func (s *defectService) Delete(id string) error {
 		return fmt.Errorf("get defect: %w", err)
 	}
 
-	if err := s.repo.Delete(id); err != nil {
+	// 缺陷与其执行结果关联、变更记录在同一事务中删除，并清空执行结果中指向该缺陷的BugID
+	if err := s.linkRepo.DeleteDefect(id); err != nil {
 		return fmt.Errorf("delete defect: %w", err)
 	}
 
@@ -367,8 +598,9 @@ func (s *defectService) Delete(id string) error {
 }
 
 // List 分页查询缺陷列表
-func (s *defectService) List(projectID uint, status, keyword string, page, size int) (*models.DefectListResponse, error) {
+func (s *defectService) List(projectID uint, params models.DefectListParams) (*models.DefectListResponse, error) {
 	// 参数校验
+	page, size := params.Page, params.Size
 	if page < 1 {
 		page = 1
 	}
@@ -379,11 +611,24 @@ func (s *defectService) List(projectID uint, status, keyword string, page, size
 	}
 
 	// 验证状态
-	if status != "" && !models.IsValidDefectStatus(status) {
+	if params.Status != "" && !models.IsValidDefectStatus(params.Status) {
 		return nil, errors.New("invalid status value")
 	}
 
-	defects, total, err := s.repo.List(projectID, status, keyword, page, size)
+	// 解析查询语句和排序
+	listQuery := repositories.DefectListQuery{Status: params.Status, Keyword: params.Keyword, Page: page, Size: size}
+	cond, err := ParseDefectQuery(params.Query)
+	if err != nil {
+		return nil, err
+	}
+	if cond != nil {
+		listQuery.Where, listQuery.Args = cond.Where, cond.Args
+	}
+	if listQuery.Order, err = ParseDefectSort(params.Sort); err != nil {
+		return nil, err
+	}
+
+	defects, total, err := s.repo.List(projectID, listQuery)
 	if err != nil {
 		return nil, fmt.Errorf("list defects: %w", err)
 	}
@@ -596,15 +841,15 @@ func (s *defectService) generateXLSXTemplate() ([]byte, error) {
 }
 
 // ImportWithFormat 根据格式导入缺陷
-func (s *defectService) ImportWithFormat(projectID uint, userID uint, reader io.Reader, isXLSX bool) (*models.ImportResult, error) {
+func (s *defectService) ImportWithFormat(projectID uint, userID uint, reader io.Reader, isXLSX bool, opts models.DefectImportOptions) (*models.ImportResult, error) {
 	if isXLSX {
-		return s.importXLSX(projectID, userID, reader)
+		return s.importXLSX(projectID, userID, reader, opts)
 	}
-	return s.Import(projectID, userID, reader)
+	return s.Import(projectID, userID, reader, opts)
 }
 
 // importXLSX 直接导入 XLSX 文件
-func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader) (*models.ImportResult, error) {
+func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader, opts models.DefectImportOptions) (*models.ImportResult, error) {
 	// 读取 XLSX 文件
 	data, err := io.ReadAll(reader)
 	if err != nil {
@@ -644,6 +889,10 @@ func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader
 		FailCount:    0,
 		Errors:       []models.ImportError{},
 	}
+	dupChecker, err := s.newImportDuplicateChecker(projectID, userID, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// 检测说明行：如果第2行包含 "(Required)" 或 "(Optional)" 则认为存在说明行
 	startRowIdx := 1 // 默认从第2行(索引1)开始处理数据
@@ -782,9 +1031,11 @@ func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader
 					DetectedBy:      &req.DetectedBy,
 					Status:          &req.Status,
 				}
-				err := s.Update(existingDefect.ID, userID, updateReq)
+				err := s.Update(existingDefect.ID, userID, updateReq, models.DefectChangeSourceImport)
 				if err != nil {
 					log.Printf("[Defect Import XLSX DEBUG] Row %d (Excel row %d) UPDATE FAILED: Defect ID=%s, error=%v", dataRowNum, excelRowNum, defectID, err)
+					result.FailCount++
+					result.Errors = append(result.Errors, models.ImportError{Row: dataRowNum, Reason: fmt.Sprintf("defect %s: %v", defectID, err)})
 					continue
 				}
 				log.Printf("[Defect Import XLSX DEBUG] Row %d (Excel row %d) UPDATED: Defect ID=%s", dataRowNum, excelRowNum, defectID)
@@ -793,13 +1044,19 @@ func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader
 			}
 		}
 
+		// 疑似重复行按重复处理模式跳过或合并
+		if dupChecker.handle(dataRowNum, req, result) {
+			continue
+		}
+
 		// 创建新缺陷
-		_, err := s.Create(projectID, userID, req)
+		created, err := s.Create(projectID, userID, req)
 		if err != nil {
 			log.Printf("[Defect Import XLSX DEBUG] Row %d (Excel row %d) CREATE FAILED: %v", dataRowNum, excelRowNum, err)
 			continue
 		}
 		log.Printf("[Defect Import XLSX DEBUG] Row %d (Excel row %d) CREATED", dataRowNum, excelRowNum)
+		dupChecker.added(created)
 		result.SuccessCount++
 	}
 
@@ -808,7 +1065,7 @@ func (s *defectService) importXLSX(projectID uint, userID uint, reader io.Reader
 }
 
 // Import 导入缺陷（CSV格式 - 支持有无说明行）
-func (s *defectService) Import(projectID uint, userID uint, reader io.Reader) (*models.ImportResult, error) {
+func (s *defectService) Import(projectID uint, userID uint, reader io.Reader, opts models.DefectImportOptions) (*models.ImportResult, error) {
 	// 读取所有内容以检测和移除BOM
 	data, err := io.ReadAll(reader)
 	if err != nil {
@@ -848,6 +1105,11 @@ func (s *defectService) Import(projectID uint, userID uint, reader io.Reader) (*
 	}
 	log.Printf("[Defect Import CSV DEBUG] Headers validated, starting to process data rows")
 
+	dupChecker, err := s.newImportDuplicateChecker(projectID, userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// 清理重复的 defect_id（防止主键冲突）
 	s.repo.CleanupDuplicateDefects(projectID, "000001")
 	log.Printf("[Defect Import CSV] Cleaned up duplicate defect IDs before importing")
@@ -1000,9 +1262,11 @@ func (s *defectService) Import(projectID uint, userID uint, reader io.Reader) (*
 					DetectedBy:      &req.DetectedBy,
 					Status:          &req.Status,
 				}
-				err := s.Update(existingDefect.ID, userID, updateReq)
+				err := s.Update(existingDefect.ID, userID, updateReq, models.DefectChangeSourceImport)
 				if err != nil {
 					log.Printf("[Defect Import CSV DEBUG] Row %d (Excel row %d) UPDATE FAILED: Defect ID=%s, error=%v", dataRowNum, excelRowNum, defectID, err)
+					result.FailCount++
+					result.Errors = append(result.Errors, models.ImportError{Row: dataRowNum, Reason: fmt.Sprintf("defect %s: %v", defectID, err)})
 					continue
 				}
 				log.Printf("[Defect Import CSV DEBUG] Row %d (Excel row %d) UPDATED: Defect ID=%s", dataRowNum, excelRowNum, defectID)
@@ -1011,14 +1275,20 @@ func (s *defectService) Import(projectID uint, userID uint, reader io.Reader) (*
 			}
 		}
 
+		// 疑似重复行按重复处理模式跳过或合并
+		if dupChecker.handle(dataRowNum, req, result) {
+			continue
+		}
+
 		// 创建新缺陷
-		_, err := s.Create(projectID, userID, req)
+		created, err := s.Create(projectID, userID, req)
 		if err != nil {
 			log.Printf("[Defect Import CSV DEBUG] Row %d (Excel row %d) CREATE FAILED: %v", dataRowNum, excelRowNum, err)
 			continue
 		}
 
 		log.Printf("[Defect Import CSV DEBUG] Row %d (Excel row %d) CREATED", dataRowNum, excelRowNum)
+		dupChecker.added(created)
 		result.SuccessCount++
 	}
 
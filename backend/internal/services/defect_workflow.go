@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// DefectWorkflowError 缺陷工作流校验错误，Code 为 constants 中定义的错误码
+type DefectWorkflowError struct {
+	Code    int
+	Message string
+}
+
+func (e *DefectWorkflowError) Error() string {
+	return e.Message
+}
+
+func newDefectWorkflowError(code int, format string, args ...interface{}) error {
+	return &DefectWorkflowError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// loadDefectTransitions 获取项目的流转规则，项目未配置时返回默认规则(isDefault=true)
+func loadDefectTransitions(repo repositories.DefectWorkflowRepository, projectID uint) ([]models.DefectTransition, bool, error) {
+	workflow, err := repo.GetByProjectID(projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.DefaultDefectTransitions(), true, nil
+		}
+		return nil, false, fmt.Errorf("get defect workflow: %w", err)
+	}
+
+	var transitions []models.DefectTransition
+	if err := json.Unmarshal([]byte(workflow.Transitions), &transitions); err != nil {
+		return nil, false, fmt.Errorf("parse defect workflow of project %d: %w", projectID, err)
+	}
+	return transitions, false, nil
+}
+
+// validateDefectTransitions 校验工作流定义
+func validateDefectTransitions(transitions []models.DefectTransition) error {
+	seen := make(map[string]bool)
+	for _, t := range transitions {
+		for _, status := range []string{t.From, t.To} {
+			if !models.IsValidDefectStatus(status) || status == string(models.DefectStatusActive) {
+				return newDefectWorkflowError(constants.ErrDefectWorkflowInvalid, "invalid status in workflow: %s", status)
+			}
+		}
+		if t.From == t.To {
+			return newDefectWorkflowError(constants.ErrDefectWorkflowInvalid, "transition from %s to itself is not needed", t.From)
+		}
+		key := t.From + "->" + t.To
+		if seen[key] {
+			return newDefectWorkflowError(constants.ErrDefectWorkflowInvalid, "duplicate transition: %s", key)
+		}
+		seen[key] = true
+
+		for _, field := range t.RequiredFields {
+			if !models.IsValidDefectTransitionField(field) {
+				return newDefectWorkflowError(constants.ErrDefectWorkflowInvalid, "invalid required field in %s: %s", key, field)
+			}
+		}
+		for _, role := range t.Roles {
+			if role != constants.RoleProjectManager && role != constants.RoleProjectMember {
+				return newDefectWorkflowError(constants.ErrDefectWorkflowInvalid, "invalid role in %s: %s", key, role)
+			}
+		}
+	}
+	return nil
+}
+
+// checkDefectTransition 按工作流校验缺陷状态变更
+// 旧数据的Active状态按InProgress处理；状态未变化时不做校验；系统管理员不受角色限制
+func checkDefectTransition(transitions []models.DefectTransition, defect *models.Defect, req *models.DefectUpdateRequest, role string) error {
+	to := *req.Status
+	if to == defect.Status {
+		return nil
+	}
+	from := defect.Status
+	if from == string(models.DefectStatusActive) {
+		from = string(models.DefectStatusInProgress)
+	}
+
+	var rule *models.DefectTransition
+	for i := range transitions {
+		if transitions[i].From == from && transitions[i].To == to {
+			rule = &transitions[i]
+			break
+		}
+	}
+	if rule == nil {
+		return newDefectWorkflowError(constants.ErrDefectTransitionNotAllowed, "status transition from %s to %s is not allowed", from, to)
+	}
+
+	if len(rule.Roles) > 0 && role != constants.RoleSystemAdmin {
+		allowed := false
+		for _, r := range rule.Roles {
+			if r == role {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return newDefectWorkflowError(constants.ErrDefectTransitionForbidden, "status transition from %s to %s requires role: %s", from, to, strings.Join(rule.Roles, ", "))
+		}
+	}
+
+	var missing []string
+	for _, field := range rule.RequiredFields {
+		if strings.TrimSpace(defectTransitionFieldValue(defect, req, field)) == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return newDefectWorkflowError(constants.ErrDefectTransitionFieldMissing, "status transition from %s to %s requires fields: %s", from, to, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// defectTransitionFieldValue 获取更新后的字段值(请求中提供的值优先)
+func defectTransitionFieldValue(defect *models.Defect, req *models.DefectUpdateRequest, field string) string {
+	pick := func(updated *string, current string) string {
+		if updated != nil {
+			return *updated
+		}
+		return current
+	}
+	switch field {
+	case "resolution":
+		return pick(req.Resolution, defect.Resolution)
+	case "fix_version":
+		return pick(req.FixVersion, defect.FixVersion)
+	case "assignee":
+		return pick(req.Assignee, defect.Assignee)
+	case "component":
+		return pick(req.Component, defect.Component)
+	case "sqa_memo":
+		return pick(req.SQAMemo, defect.SQAMemo)
+	case "recovery_method":
+		return pick(req.RecoveryMethod, defect.RecoveryMethod)
+	case "location":
+		return pick(req.Location, defect.Location)
+	case "models":
+		return pick(req.Models, defect.Models)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"webtest/internal/constants"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string { return &s }
+
+func workflowErrCode(t *testing.T, err error) int {
+	var wfErr *DefectWorkflowError
+	require.True(t, errors.As(err, &wfErr), "expected DefectWorkflowError, got %v", err)
+	return wfErr.Code
+}
+
+func TestCheckDefectTransition_Default(t *testing.T) {
+	transitions := models.DefaultDefectTransitions()
+	require.NoError(t, validateDefectTransitions(transitions))
+
+	defect := &models.Defect{Status: "New"}
+	err := checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Closed")}, constants.RoleProjectManager)
+	assert.Equal(t, constants.ErrDefectTransitionNotAllowed, workflowErrCode(t, err))
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("New")}, ""))
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Confirmed")}, constants.RoleProjectMember))
+
+	// 旧数据的Active按InProgress处理，解决时要求解决方案和修复版本
+	defect = &models.Defect{Status: "Active", FixVersion: "1.2.0"}
+	err = checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Resolved")}, constants.RoleProjectMember)
+	assert.Equal(t, constants.ErrDefectTransitionFieldMissing, workflowErrCode(t, err))
+	assert.Contains(t, err.Error(), "resolution")
+	assert.NotContains(t, err.Error(), "fix_version")
+	assert.NoError(t, checkDefectTransition(transitions, defect,
+		&models.DefectUpdateRequest{Status: strPtr("Resolved"), Resolution: strPtr("修正空指针")}, constants.RoleProjectMember))
+
+	// 关闭后只有项目经理可以重新打开
+	defect = &models.Defect{Status: "Closed"}
+	err = checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Reopened")}, constants.RoleProjectMember)
+	assert.Equal(t, constants.ErrDefectTransitionForbidden, workflowErrCode(t, err))
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Reopened")}, constants.RoleProjectManager))
+	// 系统管理员不受角色限制
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("Reopened")}, constants.RoleSystemAdmin))
+
+	// 新缺陷可直接开始处理
+	defect = &models.Defect{Status: "New"}
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("InProgress")}, constants.RoleProjectMember))
+}
+
+func TestPermissiveDefectTransitions_SeedExistingProjects(t *testing.T) {
+	transitions := models.PermissiveDefectTransitions()
+	require.NoError(t, validateDefectTransitions(transitions))
+	defect := &models.Defect{Status: "Closed"}
+	assert.NoError(t, checkDefectTransition(transitions, defect, &models.DefectUpdateRequest{Status: strPtr("New")}, ""))
+
+	db := newTestDB(t, &models.Project{}, &models.DefectWorkflow{})
+	for _, name := range []string{"old-1", "old-2"} {
+		require.NoError(t, db.Create(&models.Project{Name: name}).Error)
+	}
+	repo := repositories.NewDefectWorkflowRepository(db)
+	require.NoError(t, repo.Save(&models.DefectWorkflow{ProjectID: 1, Transitions: "[]"}))
+
+	seeded, err := repo.SeedMissing(`[{"from":"New","to":"Closed"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), seeded)
+	workflow, err := repo.GetByProjectID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", workflow.Transitions)
+	_, err = repo.GetByProjectID(2)
+	assert.NoError(t, err)
+}
+
+func TestValidateDefectTransitions(t *testing.T) {
+	cases := []models.DefectTransition{
+		{From: "New", To: "Done"},
+		{From: "New", To: "New"},
+		{From: "New", To: "Closed", RequiredFields: []string{"title"}},
+		{From: "New", To: "Closed", Roles: []string{"system_admin"}},
+	}
+	for _, c := range cases {
+		err := validateDefectTransitions([]models.DefectTransition{c})
+		assert.Equal(t, constants.ErrDefectWorkflowInvalid, workflowErrCode(t, err), "%+v", c)
+	}
+	err := validateDefectTransitions([]models.DefectTransition{{From: "New", To: "Closed"}, {From: "New", To: "Closed"}})
+	assert.Equal(t, constants.ErrDefectWorkflowInvalid, workflowErrCode(t, err))
+}
+
+func TestDefectService_UpdateFollowsProjectWorkflow(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{}, &models.Defect{}, &models.DefectAttachment{}, &models.DefectWorkflow{}, &models.DefectHistory{}, &models.DefectCaseLink{})
+	require.NoError(t, db.Create(&models.ProjectMember{ProjectID: 1, UserID: 1, Role: constants.RoleProjectMember}).Error)
+	defect := &models.Defect{DefectID: "000001", ProjectID: 1, Title: "登录失败", CreatedBy: 1}
+	require.NoError(t, db.Create(defect).Error)
+
+	workflowRepo := repositories.NewDefectWorkflowRepository(db)
+	config := NewDefectConfigService(nil, nil, workflowRepo)
+	s := NewDefectService(repositories.NewDefectRepository(db), repositories.NewUserRepository(db),
+		workflowRepo, repositories.NewProjectMemberRepository(db),
+		repositories.NewDefectHistoryRepository(db), repositories.NewDefectCommentRepository(db),
+		repositories.NewDefectCaseLinkRepository(db))
+
+	// 自定义工作流：New 可直接关闭但需要填写SQA备注
+	_, err := config.UpdateWorkflow(1, 1, &models.DefectWorkflowUpdateRequest{Transitions: []models.DefectTransition{
+		{From: "New", To: "Closed", RequiredFields: []string{"sqa_memo"}},
+	}})
+	require.NoError(t, err)
+
+	err = s.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("Confirmed")}, models.DefectChangeSourceWeb)
+	assert.Equal(t, constants.ErrDefectTransitionNotAllowed, workflowErrCode(t, err))
+	err = s.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("Closed")}, models.DefectChangeSourceWeb)
+	assert.Equal(t, constants.ErrDefectTransitionFieldMissing, workflowErrCode(t, err))
+	require.NoError(t, s.Update(defect.ID, 1, &models.DefectUpdateRequest{Status: strPtr("Closed"), SQAMemo: strPtr("重复提交")}, models.DefectChangeSourceWeb))
+
+	saved, err := s.GetByID(defect.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Closed", saved.Status)
+
+	// 清空规则后恢复默认工作流
+	workflow, err := config.UpdateWorkflow(1, 1, &models.DefectWorkflowUpdateRequest{})
+	require.NoError(t, err)
+	assert.True(t, workflow.IsDefault)
+	assert.NotContains(t, workflow.Statuses, models.DefectStatusActive)
+}
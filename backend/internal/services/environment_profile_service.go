@@ -0,0 +1,256 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// CreateEnvironmentProfileRequest 创建执行环境请求
+type CreateEnvironmentProfileRequest struct {
+	Name           string            `json:"name" binding:"required,max=50"`
+	Description    string            `json:"description" binding:"max=500"`
+	Variables      map[string]string `json:"variables"` // 覆盖任务的自定义变量
+	Browser        string            `json:"browser" binding:"omitempty,oneof=chromium firefox webkit"`
+	Device         string            `json:"device" binding:"max=100"`
+	ViewportWidth  int               `json:"viewport_width" binding:"min=0,max=7680"`
+	ViewportHeight int               `json:"viewport_height" binding:"min=0,max=4320"`
+}
+
+// UpdateEnvironmentProfileRequest 更新执行环境请求(未传字段保持不变)
+type UpdateEnvironmentProfileRequest struct {
+	Name           *string            `json:"name" binding:"omitempty,max=50"`
+	Description    *string            `json:"description" binding:"omitempty,max=500"`
+	Variables      *map[string]string `json:"variables"`
+	Browser        *string            `json:"browser" binding:"omitempty,oneof=chromium firefox webkit"`
+	Device         *string            `json:"device" binding:"omitempty,max=100"`
+	ViewportWidth  *int               `json:"viewport_width" binding:"omitempty,min=0,max=7680"`
+	ViewportHeight *int               `json:"viewport_height" binding:"omitempty,min=0,max=4320"`
+}
+
+// EnvironmentProfileView 执行环境(变量以对象形式返回)
+type EnvironmentProfileView struct {
+	ID             uint              `json:"id"`
+	ProjectID      uint              `json:"project_id"`
+	Name           string            `json:"name"`
+	Description    string            `json:"description"`
+	Variables      map[string]string `json:"variables"`
+	Browser        string            `json:"browser"`
+	Device         string            `json:"device"`
+	ViewportWidth  int               `json:"viewport_width"`
+	ViewportHeight int               `json:"viewport_height"`
+	CreatedBy      uint              `json:"created_by"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// EnvironmentProfileService 执行环境服务接口
+type EnvironmentProfileService interface {
+	ListProfiles(projectID uint) ([]*EnvironmentProfileView, error)
+	CreateProfile(projectID uint, userID uint, req CreateEnvironmentProfileRequest) (*EnvironmentProfileView, error)
+	UpdateProfile(projectID uint, profileID uint, req UpdateEnvironmentProfileRequest) (*EnvironmentProfileView, error)
+	DeleteProfile(projectID uint, profileID uint) error
+}
+
+type environmentProfileService struct {
+	repo repositories.EnvironmentProfileRepository
+}
+
+// NewEnvironmentProfileService 创建执行环境服务实例
+func NewEnvironmentProfileService(repo repositories.EnvironmentProfileRepository) EnvironmentProfileService {
+	return &environmentProfileService{repo: repo}
+}
+
+// ListProfiles 获取项目的执行环境
+func (s *environmentProfileService) ListProfiles(projectID uint) ([]*EnvironmentProfileView, error) {
+	profiles, err := s.repo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list environment profiles: %w", err)
+	}
+	views := make([]*EnvironmentProfileView, 0, len(profiles))
+	for _, p := range profiles {
+		views = append(views, newEnvironmentProfileView(p))
+	}
+	return views, nil
+}
+
+// CreateProfile 创建执行环境，名称在项目内唯一
+func (s *environmentProfileService) CreateProfile(projectID uint, userID uint, req CreateEnvironmentProfileRequest) (*EnvironmentProfileView, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("执行环境名称不能为空")
+	}
+	if err := validateViewport(req.ViewportWidth, req.ViewportHeight); err != nil {
+		return nil, err
+	}
+	exists, err := s.repo.ExistsByName(projectID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("执行环境名称已存在")
+	}
+	variables, err := encodeProfileVariables(req.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &models.EnvironmentProfile{
+		ProjectID:      projectID,
+		Name:           name,
+		Description:    req.Description,
+		Variables:      variables,
+		Browser:        req.Browser,
+		Device:         strings.TrimSpace(req.Device),
+		ViewportWidth:  req.ViewportWidth,
+		ViewportHeight: req.ViewportHeight,
+		CreatedBy:      userID,
+	}
+	if err := s.repo.Create(profile); err != nil {
+		return nil, err
+	}
+	fmt.Printf("[EnvProfile] 创建执行环境: id=%d, project_id=%d, name=%s\n", profile.ID, projectID, name)
+	return newEnvironmentProfileView(profile), nil
+}
+
+// UpdateProfile 更新执行环境
+func (s *environmentProfileService) UpdateProfile(projectID uint, profileID uint, req UpdateEnvironmentProfileRequest) (*EnvironmentProfileView, error) {
+	profile, err := s.getProjectProfile(projectID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("执行环境名称不能为空")
+		}
+		if name != profile.Name {
+			exists, err := s.repo.ExistsByName(projectID, name, profileID)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, errors.New("执行环境名称已存在")
+			}
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Variables != nil {
+		variables, err := encodeProfileVariables(*req.Variables)
+		if err != nil {
+			return nil, err
+		}
+		updates["variables"] = variables
+	}
+	if req.Browser != nil {
+		updates["browser"] = *req.Browser
+	}
+	if req.Device != nil {
+		updates["device"] = strings.TrimSpace(*req.Device)
+	}
+	width, height := profile.ViewportWidth, profile.ViewportHeight
+	if req.ViewportWidth != nil {
+		width = *req.ViewportWidth
+		updates["viewport_width"] = width
+	}
+	if req.ViewportHeight != nil {
+		height = *req.ViewportHeight
+		updates["viewport_height"] = height
+	}
+	if err := validateViewport(width, height); err != nil {
+		return nil, err
+	}
+
+	if len(updates) > 0 {
+		if err := s.repo.Update(profileID, updates); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := s.repo.GetByID(profileID)
+	if err != nil {
+		return nil, fmt.Errorf("reload environment profile: %w", err)
+	}
+	return newEnvironmentProfileView(updated), nil
+}
+
+// DeleteProfile 删除执行环境(已执行的批次保留环境名称)
+func (s *environmentProfileService) DeleteProfile(projectID uint, profileID uint) error {
+	if _, err := s.getProjectProfile(projectID, profileID); err != nil {
+		return err
+	}
+	return s.repo.Delete(profileID)
+}
+
+// getProjectProfile 获取执行环境并校验归属
+func (s *environmentProfileService) getProjectProfile(projectID uint, profileID uint) (*models.EnvironmentProfile, error) {
+	profile, err := s.repo.GetByID(profileID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行环境不存在")
+		}
+		return nil, fmt.Errorf("get environment profile: %w", err)
+	}
+	if profile.ProjectID != projectID {
+		return nil, errors.New("执行环境不属于该项目")
+	}
+	return profile, nil
+}
+
+// validateViewport 视口宽高需同时设置或同时为0
+func validateViewport(width, height int) error {
+	if (width > 0) != (height > 0) {
+		return errors.New("视口宽高需同时设置")
+	}
+	return nil
+}
+
+// encodeProfileVariables 将变量覆盖序列化为JSON，变量名不能为空
+func encodeProfileVariables(variables map[string]string) (string, error) {
+	if len(variables) == 0 {
+		return "", nil
+	}
+	for key := range variables {
+		if strings.TrimSpace(key) == "" {
+			return "", errors.New("变量名不能为空")
+		}
+	}
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return "", fmt.Errorf("marshal profile variables: %w", err)
+	}
+	return string(data), nil
+}
+
+func newEnvironmentProfileView(p *models.EnvironmentProfile) *EnvironmentProfileView {
+	view := &EnvironmentProfileView{
+		ID:             p.ID,
+		ProjectID:      p.ProjectID,
+		Name:           p.Name,
+		Description:    p.Description,
+		Variables:      map[string]string{},
+		Browser:        p.Browser,
+		Device:         p.Device,
+		ViewportWidth:  p.ViewportWidth,
+		ViewportHeight: p.ViewportHeight,
+		CreatedBy:      p.CreatedBy,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if p.Variables != "" {
+		if err := json.Unmarshal([]byte(p.Variables), &view.Variables); err != nil {
+			fmt.Printf("[EnvProfile] ⚠️ 解析环境变量失败: id=%d, error=%v\n", p.ID, err)
+		}
+	}
+	return view
+}
@@ -33,12 +33,18 @@ type ExcelService interface {
 	GetProjectName(projectID uint) (string, error)
 	// 导出手工用例多语言模版 (CN/JP/EN三个空xlsx文件打包成zip)
 	ExportManualCaseTemplate() ([]byte, string, error)
+	// 导出执行任务的结果报告(概要/用例集统计/NG一览/用例明细)
+	ExportExecutionReport(projectID uint, taskUUID string) ([]byte, string, error)
+	// 导出两个执行任务的对比结果
+	ExportTaskComparison(projectID uint, baseTaskUUID string, targetTaskUUID string) ([]byte, string, error)
 }
 
 type excelService struct {
 	caseRepo    repositories.ManualTestCaseRepository
 	projectRepo repositories.ProjectRepository
 	ecrRepo     repositories.ExecutionCaseResultRepository
+	taskRepo    repositories.ExecutionTaskRepository
+	defectRepo  repositories.DefectRepository
 }
 
 // NewExcelService 创建Excel服务实例
@@ -46,11 +52,15 @@ func NewExcelService(
 	caseRepo repositories.ManualTestCaseRepository,
 	projectRepo repositories.ProjectRepository,
 	ecrRepo repositories.ExecutionCaseResultRepository,
+	taskRepo repositories.ExecutionTaskRepository,
+	defectRepo repositories.DefectRepository,
 ) ExcelService {
 	return &excelService{
 		caseRepo:    caseRepo,
 		projectRepo: projectRepo,
 		ecrRepo:     ecrRepo,
+		taskRepo:    taskRepo,
+		defectRepo:  defectRepo,
 	}
 }
 
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// unsafeFileChars 产物文件名中不允许的字符
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// artifactDir 任务产物的存储目录(相对存储根目录)
+func artifactDir(projectID uint, taskUUID string) string {
+	return filepath.Join(projectArtifactDir(projectID), taskUUID)
+}
+
+// projectArtifactDir 项目执行产物的相对存储目录
+func projectArtifactDir(projectID uint) string {
+	return filepath.Join("execution-artifacts", fmt.Sprintf("%d", projectID))
+}
+
+// saveArtifacts 将执行器返回的产物写入存储目录并创建记录，单个产物失败只记录日志
+func (s *executionTaskService) saveArtifacts(c *models.ExecutionCaseResult, attemptID uint, artifacts []ExecutorArtifact, actx attemptContext) {
+	if len(artifacts) == 0 {
+		return
+	}
+
+	relDir := filepath.Join(artifactDir(actx.ProjectID, c.TaskUUID), fmt.Sprintf("%d", c.ID))
+	fullDir := filepath.Join(s.storagePath, relDir)
+	if err := os.MkdirAll(fullDir, 0755); err != nil {
+		fmt.Printf("[saveArtifacts] ❌ 创建目录失败: %v\n", err)
+		return
+	}
+
+	for i, a := range artifacts {
+		data, err := base64.StdEncoding.DecodeString(a.Data)
+		if err != nil {
+			fmt.Printf("[saveArtifacts] ❌ 产物解码失败: case_result_id=%d, name=%s, error=%v\n", c.ID, a.Name, err)
+			continue
+		}
+
+		fileName := unsafeFileChars.ReplaceAllString(filepath.Base(a.Name), "_")
+		if fileName == "" || fileName == "." {
+			fileName = a.Type
+		}
+		storageName := fmt.Sprintf("%d_%d_%s", attemptID, i, fileName)
+		if err := os.WriteFile(filepath.Join(fullDir, storageName), data, 0644); err != nil {
+			fmt.Printf("[saveArtifacts] ❌ 写入产物失败: case_result_id=%d, name=%s, error=%v\n", c.ID, a.Name, err)
+			continue
+		}
+
+		mimeType := a.MimeType
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		artifact := &models.ExecutionArtifact{
+			ProjectID:    actx.ProjectID,
+			TaskUUID:     c.TaskUUID,
+			CaseResultID: c.ID,
+			AttemptID:    attemptID,
+			RunUUID:      actx.RunUUID,
+			ArtifactType: a.Type,
+			FileName:     fileName,
+			FilePath:     filepath.Join(relDir, storageName),
+			FileSize:     int64(len(data)),
+			MimeType:     mimeType,
+		}
+		if err := s.artifactRepo.Create(artifact); err != nil {
+			fmt.Printf("[saveArtifacts] ❌ 写入产物记录失败: %v\n", err)
+			os.Remove(filepath.Join(fullDir, storageName))
+		}
+	}
+}
+
+// ListArtifacts 获取用例结果的执行产物(最新执行在前)
+func (s *executionTaskService) ListArtifacts(projectID uint, taskUUID string, caseResultID uint) ([]*models.ExecutionArtifact, error) {
+	if _, err := s.getTaskCaseResult(projectID, taskUUID, caseResultID); err != nil {
+		return nil, err
+	}
+	artifacts, err := s.artifactRepo.GetByCaseResultID(caseResultID)
+	if err != nil {
+		return nil, fmt.Errorf("list artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+// OpenArtifact 打开执行产物文件用于下载，调用方负责关闭
+func (s *executionTaskService) OpenArtifact(projectID uint, taskUUID string, caseResultID uint, artifactID uint) (*models.ExecutionArtifact, io.ReadCloser, error) {
+	if _, err := s.getTaskCaseResult(projectID, taskUUID, caseResultID); err != nil {
+		return nil, nil, err
+	}
+	artifact, err := s.artifactRepo.GetByID(artifactID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("产物不存在")
+		}
+		return nil, nil, fmt.Errorf("get artifact: %w", err)
+	}
+	if artifact.CaseResultID != caseResultID {
+		return nil, nil, errors.New("产物不存在")
+	}
+
+	file, err := os.Open(filepath.Join(s.storagePath, artifact.FilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, errors.New("产物文件不存在")
+		}
+		return nil, nil, fmt.Errorf("open artifact file: %w", err)
+	}
+	return artifact, file, nil
+}
+
+// removeTaskArtifacts 删除任务的产物记录和文件(删除任务时调用)
+func (s *executionTaskService) removeTaskArtifacts(projectID uint, taskUUID string) error {
+	if err := s.artifactRepo.DeleteByTaskUUID(taskUUID); err != nil {
+		return err
+	}
+	fullDir := filepath.Join(s.storagePath, artifactDir(projectID, taskUUID))
+	if err := os.RemoveAll(fullDir); err != nil {
+		fmt.Printf("[removeTaskArtifacts] ⚠️ 删除产物目录失败: %s, error=%v\n", fullDir, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArtifactRepo 内存中的产物仓储
+type fakeArtifactRepo struct {
+	artifacts []*models.ExecutionArtifact
+}
+
+func (r *fakeArtifactRepo) Create(a *models.ExecutionArtifact) error {
+	a.ID = uint(len(r.artifacts) + 1)
+	r.artifacts = append(r.artifacts, a)
+	return nil
+}
+func (r *fakeArtifactRepo) GetByID(id uint) (*models.ExecutionArtifact, error) {
+	return r.artifacts[id-1], nil
+}
+func (r *fakeArtifactRepo) GetByCaseResultID(caseResultID uint) ([]*models.ExecutionArtifact, error) {
+	return r.artifacts, nil
+}
+func (r *fakeArtifactRepo) GetByAttemptID(attemptID uint) ([]*models.ExecutionArtifact, error) {
+	return r.artifacts, nil
+}
+func (r *fakeArtifactRepo) GetBySessionID(sessionID uint) ([]*models.ExecutionArtifact, error) {
+	return r.artifacts, nil
+}
+func (r *fakeArtifactRepo) AssignSessionAttempt(sessionID uint, attemptID uint) error { return nil }
+func (r *fakeArtifactRepo) DeleteByTaskUUID(taskUUID string) error                    { return nil }
+
+// TestPlaywrightExecutorClient_ReturnsArtifactsOnFailure 脚本失败时保留执行器返回的产物
+func TestPlaywrightExecutorClient_ReturnsArtifactsOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ExecuteRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.NotNil(t, req.Artifacts)
+		assert.True(t, req.Artifacts.ScreenshotOnFailure)
+
+		_ = json.NewEncoder(w).Encode(ExecuteResponse{
+			Success: false,
+			Error:   "locator not found",
+			Artifacts: []ExecutorArtifact{
+				{Type: models.ArtifactTypeScreenshot, Name: "failure.png", MimeType: "image/png", Data: base64.StdEncoding.EncodeToString([]byte("png"))},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := NewPlaywrightExecutorClient(PlaywrightExecutorConfig{ExecutorURL: server.URL, ExecuteTimeout: 5 * time.Second, MaxRetries: 1})
+	result, err := client.Execute(context.Background(), "async (page) => {}")
+	require.Error(t, err)
+	require.NotNil(t, result)
+	require.Len(t, result.Artifacts, 1)
+	assert.Equal(t, "failure.png", result.Artifacts[0].Name)
+}
+
+// TestPlaywrightExecutorClient_ScriptErrorNotRetried 脚本执行失败不重试，直接返回脚本错误
+func TestPlaywrightExecutorClient_ScriptErrorNotRetried(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(ExecuteResponse{Success: false, Error: "locator not found"})
+	}))
+	defer server.Close()
+
+	client := NewPlaywrightExecutorClient(PlaywrightExecutorConfig{ExecutorURL: server.URL, ExecuteTimeout: 5 * time.Second, MaxRetries: 3})
+	_, err := client.Execute(context.Background(), "async (page) => {}")
+	var scriptErr *ScriptExecutionError
+	require.ErrorAs(t, err, &scriptErr)
+	assert.NotContains(t, err.Error(), "retries")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// TestSaveArtifacts 产物写入存储目录并记录相对路径
+func TestSaveArtifacts(t *testing.T) {
+	repo := &fakeArtifactRepo{}
+	s := &executionTaskService{artifactRepo: repo, storagePath: t.TempDir()}
+	c := &models.ExecutionCaseResult{ID: 7, TaskUUID: "task-1"}
+
+	s.saveArtifacts(c, 3, []ExecutorArtifact{
+		{Type: models.ArtifactTypeScreenshot, Name: "../failure.png", MimeType: "image/png", Data: base64.StdEncoding.EncodeToString([]byte("png-bytes"))},
+		{Type: models.ArtifactTypeConsoleLog, Name: "console.log", Data: "not base64!"},
+	}, attemptContext{ProjectID: 1, RunUUID: "run-1"})
+
+	require.Len(t, repo.artifacts, 1)
+	a := repo.artifacts[0]
+	assert.Equal(t, uint(3), a.AttemptID)
+	assert.Equal(t, "failure.png", a.FileName)
+	assert.Equal(t, int64(len("png-bytes")), a.FileSize)
+	assert.Equal(t, filepath.Join("execution-artifacts", "1", "task-1", "7", "3_0_failure.png"), a.FilePath)
+
+	data, err := os.ReadFile(filepath.Join(s.storagePath, a.FilePath))
+	require.NoError(t, err)
+	assert.Equal(t, "png-bytes", string(data))
+}
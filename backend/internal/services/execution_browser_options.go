@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+)
+
+// storageStateRedacted 记录在结果中的 storageState 占位(原值含Cookie等登录态，不落库)
+var storageStateRedacted = json.RawMessage(`"***"`)
+
+// ValidateBrowserOptions 校验用例集/任务上配置的浏览器设置
+func ValidateBrowserOptions(o *BrowserOptions) error {
+	if o == nil {
+		return nil
+	}
+	switch o.Browser {
+	case "", "chromium", "firefox", "webkit":
+	default:
+		return errors.New("不支持的浏览器类型")
+	}
+	if o.Viewport != nil && (o.Viewport.Width <= 0 || o.Viewport.Height <= 0) {
+		return errors.New("视口宽高需同时设置")
+	}
+	if len(o.StorageState) > 0 {
+		trimmed := bytes.TrimSpace(o.StorageState)
+		var state map[string]json.RawMessage
+		if len(trimmed) == 0 || trimmed[0] != '{' || json.Unmarshal(trimmed, &state) != nil {
+			return errors.New("storageState必须是JSON对象")
+		}
+	}
+	for name := range o.ExtraHTTPHeaders {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("请求头名称不能为空")
+		}
+	}
+	return nil
+}
+
+// isEmptyBrowserOptions 是否未设置任何项
+func isEmptyBrowserOptions(o *BrowserOptions) bool {
+	return o == nil || (o.Browser == "" && o.Headless == nil && o.Device == "" && o.Viewport == nil &&
+		o.Locale == "" && o.TimezoneID == "" && len(o.StorageState) == 0 && len(o.ExtraHTTPHeaders) == 0)
+}
+
+// EncodeBrowserOptions 校验并序列化浏览器设置，未设置任何项时返回空串(清除配置)
+func EncodeBrowserOptions(o *BrowserOptions) (string, error) {
+	if err := ValidateBrowserOptions(o); err != nil {
+		return "", err
+	}
+	if isEmptyBrowserOptions(o) {
+		return "", nil
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return "", fmt.Errorf("marshal browser options: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeBrowserOptions 解析已保存的浏览器设置，解析失败时记录日志并视为未设置
+func decodeBrowserOptions(data string, source string) *BrowserOptions {
+	if data == "" {
+		return nil
+	}
+	var o BrowserOptions
+	if err := json.Unmarshal([]byte(data), &o); err != nil {
+		fmt.Printf("[BrowserOptions] ⚠️ 解析%s浏览器设置失败: %v\n", source, err)
+		return nil
+	}
+	return &o
+}
+
+// mergeBrowserOptions 按顺序合并浏览器设置，后者已设置的项覆盖前者，请求头按名称合并
+func mergeBrowserOptions(layers ...*BrowserOptions) *BrowserOptions {
+	merged := &BrowserOptions{}
+	for _, o := range layers {
+		if o == nil {
+			continue
+		}
+		if o.Browser != "" {
+			merged.Browser = o.Browser
+		}
+		if o.Headless != nil {
+			headless := *o.Headless
+			merged.Headless = &headless
+		}
+		if o.Device != "" {
+			merged.Device = o.Device
+		}
+		if o.Viewport != nil {
+			viewport := *o.Viewport
+			merged.Viewport = &viewport
+		}
+		if o.Locale != "" {
+			merged.Locale = o.Locale
+		}
+		if o.TimezoneID != "" {
+			merged.TimezoneID = o.TimezoneID
+		}
+		if len(o.StorageState) > 0 {
+			merged.StorageState = o.StorageState
+		}
+		if len(o.ExtraHTTPHeaders) > 0 {
+			if merged.ExtraHTTPHeaders == nil {
+				merged.ExtraHTTPHeaders = make(map[string]string, len(o.ExtraHTTPHeaders))
+			}
+			for k, v := range o.ExtraHTTPHeaders {
+				merged.ExtraHTTPHeaders[k] = v
+			}
+		}
+	}
+	if isEmptyBrowserOptions(merged) {
+		return nil
+	}
+	return merged
+}
+
+// recordedBrowserOptions 生成记录在执行结果中的浏览器设置(JSON)
+// storageState 只记录是否设置，认证类请求头按 maskValue 规则脱敏
+func recordedBrowserOptions(o *BrowserOptions) string {
+	if isEmptyBrowserOptions(o) {
+		return ""
+	}
+	recorded := *o
+	if len(recorded.StorageState) > 0 {
+		recorded.StorageState = storageStateRedacted
+	}
+	if len(o.ExtraHTTPHeaders) > 0 {
+		recorded.ExtraHTTPHeaders = make(map[string]string, len(o.ExtraHTTPHeaders))
+		for k, v := range o.ExtraHTTPHeaders {
+			lower := strings.ToLower(k)
+			if strings.Contains(lower, "authorization") || strings.Contains(lower, "cookie") {
+				v = "***"
+			}
+			recorded.ExtraHTTPHeaders[k] = maskValue(k, v)
+		}
+	}
+	data, err := json.Marshal(&recorded)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// browserResolver 计算批次内每条用例实际使用的浏览器设置
+// 优先级：任务设置 < 用例集设置 < 批次设置(矩阵执行的执行环境)
+type browserResolver struct {
+	groupRepo *repositories.CaseGroupRepository
+	projectID uint
+	task      *BrowserOptions
+	run       *BrowserOptions
+
+	mu     sync.Mutex
+	groups map[string]*BrowserOptions // 用例集设置缓存，key 为 ID 或 类型/名称
+}
+
+func (s *executionTaskService) newBrowserResolver(task *models.ExecutionTask, run *BrowserOptions) *browserResolver {
+	return &browserResolver{
+		groupRepo: s.caseGroupRepo,
+		projectID: task.ProjectID,
+		task:      decodeBrowserOptions(task.BrowserOptions, "任务"),
+		run:       run,
+		groups:    make(map[string]*BrowserOptions),
+	}
+}
+
+// forCase 返回用例实际使用的浏览器设置，均未设置时返回nil(执行器默认)
+func (r *browserResolver) forCase(c *models.ExecutionCaseResult) *BrowserOptions {
+	return mergeBrowserOptions(r.task, r.group(c), r.run)
+}
+
+// group 获取用例所属用例集的浏览器设置，结果按用例集缓存
+func (r *browserResolver) group(c *models.ExecutionCaseResult) *BrowserOptions {
+	if r.groupRepo == nil || (c.CaseGroupID == 0 && c.CaseGroupName == "") {
+		return nil
+	}
+	key := fmt.Sprintf("%s/%s", c.CaseType, c.CaseGroupName)
+	if c.CaseGroupID > 0 {
+		key = fmt.Sprintf("#%d", c.CaseGroupID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if o, ok := r.groups[key]; ok {
+		return o
+	}
+	var (
+		group *models.CaseGroup
+		err   error
+	)
+	if c.CaseGroupID > 0 {
+		group, err = r.groupRepo.GetByID(c.CaseGroupID)
+	} else {
+		group, err = r.groupRepo.GetByName(r.projectID, c.CaseType, c.CaseGroupName)
+	}
+	var o *BrowserOptions
+	if err != nil {
+		fmt.Printf("[BrowserOptions] ⚠️ 获取用例集失败: key=%s, error=%v\n", key, err)
+	} else if group != nil {
+		o = decodeBrowserOptions(group.BrowserOptions, "用例集")
+	}
+	r.groups[key] = o
+	return o
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeBrowserOptions(t *testing.T) {
+	headless := false
+	task := &BrowserOptions{Browser: "chromium", Locale: "zh-CN", ExtraHTTPHeaders: map[string]string{"X-Env": "task", "X-Task": "1"}}
+	group := &BrowserOptions{Device: "iPhone 13", Headless: &headless, ExtraHTTPHeaders: map[string]string{"X-Env": "group"}}
+	run := &BrowserOptions{Browser: "webkit", Viewport: &Viewport{Width: 800, Height: 600}}
+
+	merged := mergeBrowserOptions(task, group, nil, run)
+	require.NotNil(t, merged)
+	assert.Equal(t, "webkit", merged.Browser)
+	assert.Equal(t, "iPhone 13", merged.Device)
+	assert.Equal(t, "zh-CN", merged.Locale)
+	require.NotNil(t, merged.Headless)
+	assert.False(t, *merged.Headless)
+	assert.Equal(t, &Viewport{Width: 800, Height: 600}, merged.Viewport)
+	assert.Equal(t, map[string]string{"X-Env": "group", "X-Task": "1"}, merged.ExtraHTTPHeaders)
+	// 合并结果不影响原设置
+	assert.Equal(t, "task", task.ExtraHTTPHeaders["X-Env"])
+
+	assert.Nil(t, mergeBrowserOptions(nil, &BrowserOptions{}))
+}
+
+func TestRecordedBrowserOptions(t *testing.T) {
+	o := &BrowserOptions{
+		Browser:          "firefox",
+		StorageState:     json.RawMessage(`{"cookies":[{"name":"sid","value":"secret"}],"origins":[]}`),
+		ExtraHTTPHeaders: map[string]string{"Authorization": "Bearer abc", "X-Trace": "on"},
+	}
+	var recorded BrowserOptions
+	require.NoError(t, json.Unmarshal([]byte(recordedBrowserOptions(o)), &recorded))
+	assert.Equal(t, "firefox", recorded.Browser)
+	assert.JSONEq(t, `"***"`, string(recorded.StorageState))
+	assert.Equal(t, "***", recorded.ExtraHTTPHeaders["Authorization"])
+	assert.Equal(t, "on", recorded.ExtraHTTPHeaders["X-Trace"])
+	assert.Equal(t, "", recordedBrowserOptions(nil))
+}
+
+// TestExecuteTask_SavesBrowserOptions 执行Web用例后用例结果记录实际使用的浏览器设置
+func TestExecuteTask_SavesBrowserOptions(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+	project := &models.Project{Name: "demo", ScriptExecutor: ExecutorFake}
+	require.NoError(t, db.Create(project).Error)
+	task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "browser", ExecutionType: "automation", Concurrency: 1,
+		BrowserOptions: `{"browser":"firefox","extraHTTPHeaders":{"Authorization":"Bearer abc"}}`, CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	ecr := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1", ScriptCode: "await page.goto('/');", UpdatedBy: 1}
+	require.NoError(t, db.Create(ecr).Error)
+
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	run, err := s.ExecuteTask(project.ID, 1, task.TaskUUID)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		detail, err := s.GetRun(project.ID, task.TaskUUID, run.RunUUID)
+		return err == nil && detail.Status == models.ExecutionRunStatusFinished
+	}, 5*time.Second, 20*time.Millisecond)
+
+	var saved models.ExecutionCaseResult
+	require.NoError(t, db.First(&saved, ecr.ID).Error)
+	var recorded BrowserOptions
+	require.NoError(t, json.Unmarshal([]byte(saved.BrowserOptions), &recorded))
+	assert.Equal(t, "firefox", recorded.Browser)
+	assert.Equal(t, "***", recorded.ExtraHTTPHeaders["Authorization"])
+}
+
+func TestEncodeBrowserOptions_Validation(t *testing.T) {
+	_, err := EncodeBrowserOptions(&BrowserOptions{Browser: "edge"})
+	assert.EqualError(t, err, "不支持的浏览器类型")
+	_, err = EncodeBrowserOptions(&BrowserOptions{Viewport: &Viewport{Width: 800}})
+	assert.EqualError(t, err, "视口宽高需同时设置")
+	_, err = EncodeBrowserOptions(&BrowserOptions{StorageState: json.RawMessage(`"state.json"`)})
+	assert.EqualError(t, err, "storageState必须是JSON对象")
+
+	data, err := EncodeBrowserOptions(&BrowserOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "", data)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
 )
@@ -72,8 +73,10 @@ type SaveCaseResultRequest struct {
 // ExecutionCaseResultService 测试执行用例结果服务接口
 type ExecutionCaseResultService interface {
 	GetCaseResults(taskUUID string) ([]*models.ExecutionCaseResult, error)
+	GetCaseResultsByAssignee(taskUUID string, assigneeID uint) ([]*models.ExecutionCaseResult, error)
 	SaveCaseResults(taskUUID string, userID uint, requests []SaveCaseResultRequest) error
-	GetStatistics(taskUUID string) (map[string]int, error)
+	GetStatistics(taskUUID string) (*CaseResultStatistics, error)
+	AssignCases(projectID uint, taskUUID string, req AssignCasesRequest) (int64, error)
 	InitTaskResults(taskUUID string, projectID uint, executionType string, userID uint, caseGroupID uint, caseGroupName string) error
 	ClearTaskResults(taskUUID string) error
 	UpdateSingleResult(id uint, result string, comment string, userID uint) error
@@ -106,12 +109,41 @@ type UpdateResultResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// AssignCasesRequest 批量分配用例请求
+// 范围条件(用例ID列表/用例集/大功能)至少指定一个，多个条件同时生效
+type AssignCasesRequest struct {
+	AssigneeID    uint   `json:"assignee_id"` // 0表示取消分配
+	CaseResultIDs []uint `json:"case_result_ids"`
+	CaseGroupName string `json:"case_group_name"`
+	MajorFunction string `json:"major_function"` // 匹配任一语言的大功能
+}
+
+// AssigneeProgress 单个负责人的执行进度(assignee_id为0表示未分配的用例)
+type AssigneeProgress struct {
+	*repositories.AssigneeStatistic
+	Executed     int     `json:"executed"`
+	ProgressRate float64 `json:"progress_rate"` // 已执行占比(%)
+	PassRate     float64 `json:"pass_rate"`     // 已执行用例的通过率(%)
+}
+
+// CaseResultStatistics 任务的用例统计信息
+type CaseResultStatistics struct {
+	Total      int                 `json:"total"`
+	NRCount    int                 `json:"nr_count"`
+	OKCount    int                 `json:"ok_count"`
+	NGCount    int                 `json:"ng_count"`
+	BlockCount int                 `json:"block_count"`
+	Assignees  []*AssigneeProgress `json:"assignees"`
+}
+
 type executionCaseResultService struct {
 	repo       repositories.ExecutionCaseResultRepository
 	taskRepo   repositories.ExecutionTaskRepository
 	manualRepo repositories.ManualTestCaseRepository
 	autoRepo   repositories.AutoTestCaseRepository
 	apiRepo    repositories.ApiTestCaseRepository
+	memberRepo repositories.ProjectMemberRepository
+	linkRepo   repositories.DefectCaseLinkRepository
 }
 
 // NewExecutionCaseResultService 创建服务实例
@@ -121,6 +153,8 @@ func NewExecutionCaseResultService(
 	manualRepo repositories.ManualTestCaseRepository,
 	autoRepo repositories.AutoTestCaseRepository,
 	apiRepo repositories.ApiTestCaseRepository,
+	memberRepo repositories.ProjectMemberRepository,
+	linkRepo repositories.DefectCaseLinkRepository,
 ) ExecutionCaseResultService {
 	return &executionCaseResultService{
 		repo:       repo,
@@ -128,6 +162,8 @@ func NewExecutionCaseResultService(
 		manualRepo: manualRepo,
 		autoRepo:   autoRepo,
 		apiRepo:    apiRepo,
+		memberRepo: memberRepo,
+		linkRepo:   linkRepo,
 	}
 }
 
@@ -140,6 +176,15 @@ func (s *executionCaseResultService) GetCaseResults(taskUUID string) ([]*models.
 	return results, nil
 }
 
+// GetCaseResultsByAssignee 获取任务中分配给指定成员的执行结果(assigneeID为0时返回未分配的用例)
+func (s *executionCaseResultService) GetCaseResultsByAssignee(taskUUID string, assigneeID uint) ([]*models.ExecutionCaseResult, error) {
+	results, err := s.repo.GetByTaskUUIDAndAssignee(taskUUID, assigneeID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results for task %s assignee %d: %w", taskUUID, assigneeID, err)
+	}
+	return results, nil
+}
+
 // SaveCaseResults 保存或更新执行结果
 func (s *executionCaseResultService) SaveCaseResults(taskUUID string, userID uint, requests []SaveCaseResultRequest) error {
 	if len(requests) == 0 {
@@ -225,16 +270,119 @@ func (s *executionCaseResultService) SaveCaseResults(taskUUID string, userID uin
 		return fmt.Errorf("batch upsert %d results: %w", len(results), err)
 	}
 
+	s.flagRetestCases(task, results)
 	return nil
 }
 
-// GetStatistics 获取任务的统计信息
-func (s *executionCaseResultService) GetStatistics(taskUUID string) (map[string]int, error) {
+// flagRetestCases 将关联缺陷已关闭的用例在本任务中标记为需复测
+// 标记失败不影响执行结果的保存
+func (s *executionCaseResultService) flagRetestCases(task *models.ExecutionTask, results []*models.ExecutionCaseResult) {
+	caseIDs := make([]string, 0, len(results))
+	for _, r := range results {
+		caseIDs = append(caseIDs, r.CaseID)
+	}
+	links, err := s.linkRepo.ListPendingRetest(task.ProjectID, caseIDs)
+	if err != nil {
+		log.Printf("[Retest Flag Failed] task_uuid=%s, error=%v", task.TaskUUID, err)
+		return
+	}
+
+	// 缺陷在当前任务中提交时不算"下一个任务"
+	var pending []*models.DefectCaseLink
+	for _, link := range links {
+		if link.TaskUUID != task.TaskUUID {
+			pending = append(pending, link)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	if err := s.linkRepo.ScheduleRetest(pending, task.TaskUUID); err != nil {
+		log.Printf("[Retest Flag Failed] task_uuid=%s, error=%v", task.TaskUUID, err)
+		return
+	}
+	log.Printf("[Retest Flag] task_uuid=%s, cases=%d", task.TaskUUID, len(pending))
+}
+
+// GetStatistics 获取任务的统计信息(含按负责人的执行进度)
+func (s *executionCaseResultService) GetStatistics(taskUUID string) (*CaseResultStatistics, error) {
 	stats, err := s.repo.GetStatistics(taskUUID)
 	if err != nil {
 		return nil, fmt.Errorf("get statistics for task %s: %w", taskUUID, err)
 	}
-	return stats, nil
+	assignees, err := s.repo.GetAssigneeStatistics(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get assignee statistics for task %s: %w", taskUUID, err)
+	}
+
+	result := &CaseResultStatistics{
+		Total:      stats["total"],
+		NRCount:    stats["nr_count"],
+		OKCount:    stats["ok_count"],
+		NGCount:    stats["ng_count"],
+		BlockCount: stats["block_count"],
+		Assignees:  make([]*AssigneeProgress, 0, len(assignees)),
+	}
+	for _, a := range assignees {
+		result.Assignees = append(result.Assignees, newAssigneeProgress(a))
+	}
+	return result, nil
+}
+
+// AssignCases 将范围内的用例分配给项目成员，返回分配的用例数
+func (s *executionCaseResultService) AssignCases(projectID uint, taskUUID string, req AssignCasesRequest) (int64, error) {
+	task, err := s.taskRepo.GetByUUID(taskUUID)
+	if err != nil {
+		return 0, errors.New("任务不存在")
+	}
+	if task.ProjectID != projectID {
+		return 0, errors.New("任务不属于该项目")
+	}
+	if len(req.CaseResultIDs) == 0 && req.CaseGroupName == "" && req.MajorFunction == "" {
+		return 0, errors.New("请指定分配范围")
+	}
+
+	assigneeName := ""
+	if req.AssigneeID > 0 {
+		members, err := s.memberRepo.FindMembersWithUser(projectID)
+		if err != nil {
+			return 0, fmt.Errorf("find members of project %d: %w", projectID, err)
+		}
+		found := false
+		for _, m := range members {
+			if m.UserID == req.AssigneeID {
+				assigneeName = m.Nickname
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, errors.New("负责人不是项目成员")
+		}
+	}
+
+	scope := repositories.CaseAssignScope{
+		CaseResultIDs: req.CaseResultIDs,
+		CaseGroupName: req.CaseGroupName,
+		MajorFunction: req.MajorFunction,
+	}
+	count, err := s.repo.AssignCases(taskUUID, scope, req.AssigneeID, assigneeName)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// newAssigneeProgress 根据负责人的用例统计计算执行进度和通过率
+func newAssigneeProgress(stat *repositories.AssigneeStatistic) *AssigneeProgress {
+	p := &AssigneeProgress{AssigneeStatistic: stat, Executed: stat.Total - stat.NRCount}
+	if stat.Total > 0 {
+		p.ProgressRate = math.Round(float64(p.Executed)*1000/float64(stat.Total)) / 10
+	}
+	if p.Executed > 0 {
+		p.PassRate = math.Round(float64(stat.OKCount)*1000/float64(p.Executed)) / 10
+	}
+	return p
 }
 
 // InitTaskResults 初始化任务执行结果
@@ -242,7 +390,7 @@ func (s *executionCaseResultService) GetStatistics(taskUUID string) (map[string]
 // caseGroupName: 可选，指定用例集名称（如果caseGroupID>0时会查询对应名称）
 func (s *executionCaseResultService) InitTaskResults(taskUUID string, projectID uint, executionType string, userID uint, caseGroupID uint, caseGroupName string) error {
 	// 验证任务存在
-	_, err := s.taskRepo.GetByUUID(taskUUID)
+	task, err := s.taskRepo.GetByUUID(taskUUID)
 	if err != nil {
 		return fmt.Errorf("task %s not found: %w", taskUUID, err)
 	}
@@ -296,6 +444,7 @@ func (s *executionCaseResultService) InitTaskResults(taskUUID string, projectID
 		return fmt.Errorf("batch create %d default results: %w", len(results), err)
 	}
 
+	s.flagRetestCases(task, results)
 	return nil
 }
 
@@ -508,13 +657,15 @@ func (s *executionCaseResultService) buildExecutionResultsForApi(taskUUID string
 			TestResult:    "NR",
 			UpdatedBy:     userID,
 			// API用例字段快照
-			Screen:     testCase.Screen,
-			URL:        testCase.URL,
-			Header:     testCase.Header,
-			Method:     testCase.Method,
-			Body:       testCase.Body,
-			Response:   testCase.Response,
-			ScriptCode: testCase.ScriptCode,
+			Screen:      testCase.Screen,
+			URL:         testCase.URL,
+			Header:      testCase.Header,
+			Method:      testCase.Method,
+			Body:        testCase.Body,
+			Response:    testCase.Response,
+			Assertions:  testCase.Assertions,
+			Extractions: testCase.Extractions,
+			ScriptCode:  testCase.ScriptCode,
 		}
 		results = append(results, result)
 	}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionCaseResult_AssignCases(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ProjectMember{})
+	for i, name := range []string{"alice", "bob"} {
+		user := &models.User{Username: name, Nickname: name, Password: "x", Role: "project_member"}
+		require.NoError(t, db.Create(user).Error)
+		require.NoError(t, db.Create(&models.ProjectMember{ProjectID: 1, UserID: user.ID, Role: "project_member"}).Error)
+		require.Equal(t, uint(i+1), user.ID)
+	}
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "manual", ExecutionType: "manual", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	cases := []struct{ group, major, result string }{
+		{"login", "认证", "OK"}, {"login", "认证", "NG"}, {"login", "会话", "NR"}, {"order", "下单", "NR"},
+	}
+	var ids []uint
+	for i, c := range cases {
+		ecr := &models.ExecutionCaseResult{
+			TaskUUID: task.TaskUUID, CaseID: fmt.Sprintf("case-%d", i), CaseType: "overall", CaseGroupName: c.group,
+			MajorFunctionCN: c.major, TestResult: c.result, UpdatedBy: 1,
+		}
+		require.NoError(t, db.Create(ecr).Error)
+		ids = append(ids, ecr.ID)
+	}
+
+	repo := repositories.NewExecutionCaseResultRepository(db)
+	s := NewExecutionCaseResultService(repo, repositories.NewExecutionTaskRepository(db), nil, nil, nil,
+		repositories.NewProjectMemberRepository(db), repositories.NewDefectCaseLinkRepository(db))
+
+	count, err := s.AssignCases(1, task.TaskUUID, AssignCasesRequest{AssigneeID: 1, CaseGroupName: "login", MajorFunction: "认证"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	count, err = s.AssignCases(1, task.TaskUUID, AssignCasesRequest{AssigneeID: 2, CaseResultIDs: []uint{ids[2]}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = s.AssignCases(1, task.TaskUUID, AssignCasesRequest{AssigneeID: 2})
+	assert.EqualError(t, err, "请指定分配范围")
+	_, err = s.AssignCases(1, task.TaskUUID, AssignCasesRequest{AssigneeID: 9, CaseGroupName: "order"})
+	assert.EqualError(t, err, "负责人不是项目成员")
+	_, err = s.AssignCases(2, task.TaskUUID, AssignCasesRequest{AssigneeID: 1, CaseGroupName: "order"})
+	assert.EqualError(t, err, "任务不属于该项目")
+
+	mine, err := s.GetCaseResultsByAssignee(task.TaskUUID, 1)
+	require.NoError(t, err)
+	require.Len(t, mine, 2)
+	assert.Equal(t, "alice", mine[0].AssigneeName)
+
+	stats, err := s.GetStatistics(task.TaskUUID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Total)
+	require.Len(t, stats.Assignees, 3)
+	unassigned, alice, bob := stats.Assignees[0], stats.Assignees[1], stats.Assignees[2]
+	assert.Equal(t, uint(0), unassigned.AssigneeID)
+	assert.Equal(t, 1, unassigned.Total)
+	assert.Equal(t, 2, alice.Executed)
+	assert.Equal(t, 100.0, alice.ProgressRate)
+	assert.Equal(t, 50.0, alice.PassRate)
+	assert.Equal(t, "bob", bob.AssigneeName)
+	assert.Equal(t, 0.0, bob.ProgressRate)
+
+	// 取消分配
+	count, err = s.AssignCases(1, task.TaskUUID, AssignCasesRequest{CaseResultIDs: []uint{ids[2]}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	unassignedCases, err := s.GetCaseResultsByAssignee(task.TaskUUID, 0)
+	require.NoError(t, err)
+	assert.Len(t, unassignedCases, 2)
+}
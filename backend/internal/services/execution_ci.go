@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"webtest/internal/models"
+)
+
+// ciWaitPollInterval 等待批次结束时的轮询间隔
+const ciWaitPollInterval = time.Second
+
+// CITriggerRequest CI流水线触发执行请求
+type CITriggerRequest struct {
+	TestVersion    string            `json:"test_version" binding:"omitempty,max=50"`
+	TestEnv        string            `json:"test_env" binding:"omitempty,max=100"`
+	Variables      map[string]string `json:"variables"`                                          // 覆盖任务变量(仅本批次有效)
+	Wait           bool              `json:"wait"`                                               // 等待批次结束后返回结果
+	TimeoutSeconds int               `json:"timeout_seconds" binding:"omitempty,min=1,max=3600"` // 等待超时，默认600秒
+}
+
+// RunReportCase 执行结果报告中的单条用例
+type RunReportCase struct {
+	CaseResultID uint   `json:"case_result_id"`
+	DisplayID    uint   `json:"display_id"`
+	CaseNum      string `json:"case_num"`
+	Name         string `json:"name"`
+	CaseGroup    string `json:"case_group"`
+	TestResult   string `json:"test_result"`
+	DurationMs   int    `json:"duration_ms"`
+	Message      string `json:"message,omitempty"` // 失败或Block原因
+	RetryCount   int    `json:"retry_count"`
+	Flaky        bool   `json:"flaky"`
+}
+
+// RunReport 执行批次的机器可读结果汇总，可渲染为JSON/JUnit XML/TAP
+type RunReport struct {
+	RunUUID      string          `json:"run_uuid"`
+	TaskUUID     string          `json:"task_uuid"`
+	TaskName     string          `json:"task_name"`
+	TestVersion  string          `json:"test_version"`
+	TestEnv      string          `json:"test_env"`
+	Status       string          `json:"status"`
+	TriggerType  string          `json:"trigger_type"`
+	Passed       bool            `json:"passed"` // 批次已结束且无NG、无异常
+	Total        int             `json:"total"`
+	OKCount      int             `json:"ok_count"`
+	NGCount      int             `json:"ng_count"`
+	BlockCount   int             `json:"block_count"`
+	NRCount      int             `json:"nr_count"`
+	FlakyCount   int             `json:"flaky_count"`
+	DurationMs   int64           `json:"duration_ms"`
+	ErrorMessage string          `json:"error_message,omitempty"`
+	StartedAt    *time.Time      `json:"started_at"`
+	FinishedAt   *time.Time      `json:"finished_at"`
+	Cases        []RunReportCase `json:"cases"`
+}
+
+// TriggerCIRun 供CI流水线触发任务执行：记录测试版本/环境，按请求覆盖变量后创建执行批次
+func (s *executionTaskService) TriggerCIRun(projectID uint, userID uint, taskUUID string, req CITriggerRequest) (*models.ExecutionRun, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	for key := range req.Variables {
+		if strings.TrimSpace(key) == "" {
+			return nil, errors.New("变量名不能为空")
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.TestVersion != "" {
+		updates["test_version"] = req.TestVersion
+	}
+	if req.TestEnv != "" {
+		updates["test_env"] = req.TestEnv
+	}
+
+	// 版本/环境在确认没有执行中批次后写入，被拒绝的触发不影响正在执行的批次
+	return s.startTaskRun(projectID, userID, taskUUID, runOptions{
+		TriggerType:       models.ExecutionRunTriggerCI,
+		VariableOverrides: req.Variables,
+		TaskUpdates:       updates,
+	})
+}
+
+// WaitRun 等待执行批次结束；ctx 超时或取消时返回批次当前状态
+func (s *executionTaskService) WaitRun(ctx context.Context, projectID uint, taskUUID string, runUUID string) (*models.ExecutionRun, error) {
+	for {
+		run, err := s.getTaskRun(projectID, taskUUID, runUUID)
+		if err != nil {
+			return nil, err
+		}
+		if !run.IsActive() {
+			return run, nil
+		}
+		select {
+		case <-ctx.Done():
+			return run, nil
+		case <-time.After(ciWaitPollInterval):
+		}
+	}
+}
+
+// GetRunReport 根据任务的用例结果生成批次结果报告
+// 用例结果只保留最新一次执行，因此仅支持任务最近一次执行批次
+func (s *executionTaskService) GetRunReport(projectID uint, taskUUID string, runUUID string) (*RunReport, error) {
+	run, err := s.getTaskRun(projectID, taskUUID, runUUID)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := s.runRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	if len(runs) > 0 && runs[0].RunUUID != runUUID {
+		return nil, errors.New("执行批次不是任务的最近一次执行")
+	}
+
+	task, err := s.repo.GetByUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results: %w", err)
+	}
+	return buildRunReport(task, run, cases), nil
+}
+
+// buildRunReport 汇总用例结果，NR计为未执行
+func buildRunReport(task *models.ExecutionTask, run *models.ExecutionRun, cases []*models.ExecutionCaseResult) *RunReport {
+	report := &RunReport{
+		RunUUID:      run.RunUUID,
+		TaskUUID:     task.TaskUUID,
+		TaskName:     task.TaskName,
+		TestVersion:  task.TestVersion,
+		TestEnv:      task.TestEnv,
+		Status:       run.Status,
+		TriggerType:  run.TriggerType,
+		Total:        len(cases),
+		ErrorMessage: run.ErrorMessage,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+		Cases:        make([]RunReportCase, 0, len(cases)),
+	}
+	if run.StartedAt != nil && run.FinishedAt != nil {
+		report.DurationMs = run.FinishedAt.Sub(*run.StartedAt).Milliseconds()
+	}
+
+	for _, c := range cases {
+		durationMs, _ := strconv.Atoi(c.ResponseTime)
+		rc := RunReportCase{
+			CaseResultID: c.ID,
+			DisplayID:    c.DisplayID,
+			CaseNum:      c.CaseNum,
+			Name:         reportCaseName(c),
+			CaseGroup:    c.CaseGroupName,
+			TestResult:   c.TestResult,
+			DurationMs:   durationMs,
+			RetryCount:   c.RetryCount,
+			Flaky:        c.Flaky,
+		}
+		switch c.TestResult {
+		case "OK":
+			report.OKCount++
+		case "NG":
+			report.NGCount++
+			rc.Message = c.Remark
+		case "Block":
+			report.BlockCount++
+			rc.Message = c.Remark
+		default:
+			report.NRCount++
+		}
+		if c.Flaky {
+			report.FlakyCount++
+		}
+		report.Cases = append(report.Cases, rc)
+	}
+
+	report.Passed = run.Status == models.ExecutionRunStatusFinished && run.ErrorMessage == "" && report.NGCount == 0
+	return report
+}
+
+// reportCaseName 用例在报告中的名称：编号 + 画面/功能(API用例为请求方法和URL)
+func reportCaseName(c *models.ExecutionCaseResult) string {
+	parts := []string{}
+	if c.CaseNum != "" {
+		parts = append(parts, c.CaseNum)
+	} else {
+		parts = append(parts, fmt.Sprintf("#%d", c.DisplayID))
+	}
+	if c.Method != "" || c.URL != "" {
+		parts = append(parts, strings.TrimSpace(c.Method+" "+c.URL))
+	} else {
+		for _, text := range []string{
+			firstNonEmpty(c.ScreenCN, c.ScreenJP, c.ScreenEN, c.Screen),
+			firstNonEmpty(c.FunctionCN, c.FunctionJP, c.FunctionEN),
+		} {
+			if text != "" {
+				parts = append(parts, text)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// JUnit XML 结构(Jenkins/GitLab 通用格式)
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// RenderJUnitReport 将报告渲染为JUnit XML：NG为failure，Block/NR为skipped
+func RenderJUnitReport(report *RunReport) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:    report.TaskName,
+		Tests:   report.Total,
+		Skipped: report.BlockCount + report.NRCount,
+		Time:    junitSeconds(report.DurationMs),
+		Properties: []junitProperty{
+			{Name: "run_uuid", Value: report.RunUUID},
+			{Name: "test_version", Value: report.TestVersion},
+			{Name: "test_env", Value: report.TestEnv},
+			{Name: "status", Value: report.Status},
+		},
+		Cases: make([]junitTestCase, 0, len(report.Cases)),
+	}
+	if report.StartedAt != nil {
+		suite.Timestamp = report.StartedAt.UTC().Format("2006-01-02T15:04:05")
+	}
+	if report.ErrorMessage != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "error_message", Value: report.ErrorMessage})
+	}
+
+	for _, rc := range report.Cases {
+		className := report.TaskName
+		if rc.CaseGroup != "" {
+			className += "." + rc.CaseGroup
+		}
+		tc := junitTestCase{
+			Name:      rc.Name,
+			ClassName: className,
+			Time:      junitSeconds(int64(rc.DurationMs)),
+		}
+		switch rc.TestResult {
+		case "OK":
+		case "NG":
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: firstLine(rc.Message), Type: "NG", Text: rc.Message}
+		case "Block":
+			tc.Skipped = &junitMessage{Message: firstLine(rc.Message)}
+		default:
+			tc.Skipped = &junitMessage{Message: "未执行"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	doc := junitTestSuites{
+		Name:     report.TaskName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal junit: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// RenderTAPReport 将报告渲染为TAP version 13：NG为not ok，Block/NR为SKIP
+func RenderTAPReport(report *RunReport) string {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(report.Cases))
+	for i, rc := range report.Cases {
+		name := tapEscape(rc.Name)
+		switch rc.TestResult {
+		case "OK":
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, name)
+		case "NG":
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, name)
+			if rc.Message != "" {
+				b.WriteString("  ---\n  message: |\n")
+				for _, line := range strings.Split(rc.Message, "\n") {
+					b.WriteString("    " + line + "\n")
+				}
+				b.WriteString("  ...\n")
+			}
+		case "Block":
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, name, tapEscape(firstLine(rc.Message)))
+		default:
+			fmt.Fprintf(&b, "ok %d - %s # SKIP 未执行\n", i+1, name)
+		}
+	}
+	return b.String()
+}
+
+// junitSeconds 毫秒转换为JUnit使用的秒数
+func junitSeconds(ms int64) string {
+	return strconv.FormatFloat(float64(ms)/1000, 'f', 3, 64)
+}
+
+// firstLine 返回多行文本的第一行
+func firstLine(text string) string {
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		return text[:idx]
+	}
+	return text
+}
+
+// tapEscape 转义TAP描述中的 # 和换行，避免被解析为指令
+func tapEscape(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	return strings.ReplaceAll(text, "#", "\\#")
+}
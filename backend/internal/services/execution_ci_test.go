@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRunReport() *RunReport {
+	started := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	finished := started.Add(1500 * time.Millisecond)
+	task := &models.ExecutionTask{TaskUUID: "task-1", TaskName: "冒烟测试", TestVersion: "v1.2.0", TestEnv: "staging"}
+	run := &models.ExecutionRun{
+		RunUUID:     "run-1",
+		Status:      models.ExecutionRunStatusFinished,
+		TriggerType: models.ExecutionRunTriggerCI,
+		StartedAt:   &started,
+		FinishedAt:  &finished,
+	}
+	cases := []*models.ExecutionCaseResult{
+		{ID: 1, DisplayID: 1, CaseNum: "TC-001", ScreenCN: "登录", FunctionCN: "正常登录", TestResult: "OK", ResponseTime: "820", Flaky: true, RetryCount: 1},
+		{ID: 2, DisplayID: 2, Method: "GET", URL: "/api/users", TestResult: "NG", Remark: "期望状态码200，实际500\n详细信息"},
+		{ID: 3, DisplayID: 3, CaseNum: "TC-003", TestResult: "Block", Remark: "无脚本代码"},
+		{ID: 4, DisplayID: 4, CaseNum: "TC-#4", TestResult: "NR"},
+	}
+	return buildRunReport(task, run, cases)
+}
+
+// TestBuildRunReport 按用例结果汇总，有NG时不通过
+func TestBuildRunReport(t *testing.T) {
+	report := sampleRunReport()
+
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 1, report.OKCount)
+	assert.Equal(t, 1, report.NGCount)
+	assert.Equal(t, 1, report.BlockCount)
+	assert.Equal(t, 1, report.NRCount)
+	assert.Equal(t, 1, report.FlakyCount)
+	assert.Equal(t, int64(1500), report.DurationMs)
+	assert.False(t, report.Passed)
+	assert.Equal(t, "TC-001 登录 正常登录", report.Cases[0].Name)
+	assert.Equal(t, "#2 GET /api/users", report.Cases[1].Name)
+	assert.Equal(t, 820, report.Cases[0].DurationMs)
+}
+
+// TestRenderJUnitReport NG为failure，Block/NR为skipped
+func TestRenderJUnitReport(t *testing.T) {
+	data, err := RenderJUnitReport(sampleRunReport())
+	require.NoError(t, err)
+
+	var doc junitTestSuites
+	require.NoError(t, xml.Unmarshal(data, &doc))
+	assert.Equal(t, 4, doc.Tests)
+	assert.Equal(t, 1, doc.Failures)
+	assert.Equal(t, 2, doc.Skipped)
+	require.Len(t, doc.Suites, 1)
+	suite := doc.Suites[0]
+	assert.Equal(t, "1.500", suite.Time)
+	assert.Nil(t, suite.Cases[0].Failure)
+	require.NotNil(t, suite.Cases[1].Failure)
+	assert.Equal(t, "期望状态码200，实际500", suite.Cases[1].Failure.Message)
+	assert.NotNil(t, suite.Cases[2].Skipped)
+	assert.Contains(t, string(data), `<property name="test_version" value="v1.2.0"></property>`)
+}
+
+// TestRenderTAPReport 输出TAP version 13
+func TestRenderTAPReport(t *testing.T) {
+	tap := RenderTAPReport(sampleRunReport())
+	lines := strings.Split(tap, "\n")
+
+	assert.Equal(t, "TAP version 13", lines[0])
+	assert.Equal(t, "1..4", lines[1])
+	assert.Equal(t, "ok 1 - TC-001 登录 正常登录", lines[2])
+	assert.Equal(t, "not ok 2 - \\#2 GET /api/users", lines[3])
+	assert.Contains(t, tap, "    期望状态码200，实际500\n    详细信息\n  ...\n")
+	assert.Contains(t, tap, "ok 3 - TC-003 # SKIP 无脚本代码\n")
+	assert.Contains(t, tap, "ok 4 - TC-\\#4 # SKIP 未执行\n")
+}
+
+// TestTriggerCIRun_ConflictKeepsVersion 任务执行中时拒绝CI触发，不修改任务的测试版本/环境
+func TestTriggerCIRun_ConflictKeepsVersion(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+	project := &models.Project{Name: "demo", ScriptExecutor: ExecutorFake}
+	require.NoError(t, db.Create(project).Error)
+	task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "ci", ExecutionType: "automation", TestVersion: "v1", TestEnv: "staging", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	require.NoError(t, db.Create(&models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1",
+		ScriptCode: "await page.goto('/');", UpdatedBy: 1}).Error)
+	require.NoError(t, db.Create(&models.ExecutionRun{TaskUUID: task.TaskUUID, ProjectID: project.ID, Status: models.ExecutionRunStatusRunning}).Error)
+
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	_, err := s.TriggerCIRun(project.ID, 1, task.TaskUUID, CITriggerRequest{TestVersion: "v2", TestEnv: "prod"})
+	assert.EqualError(t, err, "任务正在执行中")
+
+	var saved models.ExecutionTask
+	require.NoError(t, db.Where("task_uuid = ?", task.TaskUUID).First(&saved).Error)
+	assert.Equal(t, "v1", saved.TestVersion)
+	assert.Equal(t, "staging", saved.TestEnv)
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"sync"
+	"time"
+	"webtest/internal/models"
+)
+
+// 执行进度事件类型
+const (
+	ExecutionEventRunStarted   = "run_started"
+	ExecutionEventCaseStarted  = "case_started"
+	ExecutionEventCaseFinished = "case_finished"
+	ExecutionEventRunFinished  = "run_finished"
+)
+
+// 每个任务保留的最近事件数，新连接的观察者和轮询方据此补发
+const executionEventBacklog = 500
+
+// subscriberBuffer 单个订阅者的事件缓冲，缓冲满时丢弃新事件(订阅方可按seq发现缺口后重新拉取)
+const subscriberBuffer = 64
+
+// ExecutionEvent 执行任务的进度事件
+// Seq 在同一任务内单调递增，服务重启后从1重新计数
+type ExecutionEvent struct {
+	Seq          int64     `json:"seq"`
+	Type         string    `json:"type"`
+	TaskUUID     string    `json:"task_uuid"`
+	RunUUID      string    `json:"run_uuid,omitempty"`    // 单条用例执行时为空
+	Environment  string    `json:"environment,omitempty"` // 矩阵执行时的执行环境名称
+	CaseResultID uint      `json:"case_result_id,omitempty"`
+	DisplayID    uint      `json:"display_id,omitempty"`
+	CaseNum      string    `json:"case_num,omitempty"`
+	TestResult   string    `json:"test_result,omitempty"` // case_finished: OK/NG/Block
+	ResponseTime string    `json:"response_time,omitempty"`
+	RetryCount   int       `json:"retry_count,omitempty"`
+	Flaky        bool      `json:"flaky,omitempty"`
+	Remark       string    `json:"remark,omitempty"`
+	Status       string    `json:"status,omitempty"` // run_finished: finished/cancelled
+	Total        int       `json:"total,omitempty"`
+	OKCount      int       `json:"ok_count"`
+	NGCount      int       `json:"ng_count"`
+	BlockCount   int       `json:"block_count"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// taskEventStream 单个任务的事件序列和订阅者
+type taskEventStream struct {
+	seq         int64
+	backlog     []ExecutionEvent
+	subscribers map[chan ExecutionEvent]struct{}
+}
+
+// ExecutionEventHub 按任务分发执行进度事件
+// 同一任务的所有观察者收到相同的事件序列
+type ExecutionEventHub struct {
+	mu      sync.Mutex
+	streams map[string]*taskEventStream
+}
+
+// NewExecutionEventHub 创建事件分发中心
+func NewExecutionEventHub() *ExecutionEventHub {
+	return &ExecutionEventHub{streams: make(map[string]*taskEventStream)}
+}
+
+// stream 获取任务的事件序列，调用方需持有 mu
+func (h *ExecutionEventHub) stream(taskUUID string) *taskEventStream {
+	st, ok := h.streams[taskUUID]
+	if !ok {
+		st = &taskEventStream{subscribers: make(map[chan ExecutionEvent]struct{})}
+		h.streams[taskUUID] = st
+	}
+	return st
+}
+
+// Publish 发布事件：分配序号、写入补发缓冲并推送给所有订阅者
+func (h *ExecutionEventHub) Publish(event ExecutionEvent) ExecutionEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	st := h.stream(event.TaskUUID)
+	st.seq++
+	event.Seq = st.seq
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	st.backlog = append(st.backlog, event)
+	if len(st.backlog) > executionEventBacklog {
+		st.backlog = st.backlog[len(st.backlog)-executionEventBacklog:]
+	}
+	for ch := range st.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return event
+}
+
+// Since 返回序号大于 since 的缓冲事件
+func (h *ExecutionEventHub) Since(taskUUID string, since int64) []ExecutionEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sinceLocked(taskUUID, since)
+}
+
+func (h *ExecutionEventHub) sinceLocked(taskUUID string, since int64) []ExecutionEvent {
+	events := make([]ExecutionEvent, 0)
+	st, ok := h.streams[taskUUID]
+	if !ok {
+		return events
+	}
+	for _, ev := range st.backlog {
+		if ev.Seq > since {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// Subscribe 订阅任务事件，返回序号大于 since 的缓冲事件和后续事件通道
+// 补发与订阅在同一把锁内完成，不会遗漏或重复事件；使用完毕后必须调用 unsubscribe
+func (h *ExecutionEventHub) Subscribe(taskUUID string, since int64) ([]ExecutionEvent, <-chan ExecutionEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	backlog := h.sinceLocked(taskUUID, since)
+	ch := make(chan ExecutionEvent, subscriberBuffer)
+	st := h.stream(taskUUID)
+	st.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(st.subscribers, ch)
+		})
+	}
+	return backlog, ch, unsubscribe
+}
+
+// Forget 丢弃任务的事件缓冲并断开订阅者(删除任务时调用)
+func (h *ExecutionEventHub) Forget(taskUUID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st, ok := h.streams[taskUUID]
+	if !ok {
+		return
+	}
+	for ch := range st.subscribers {
+		close(ch)
+	}
+	delete(h.streams, taskUUID)
+}
+
+// caseEvent 由用例结果生成事件
+func caseEvent(eventType string, runUUID string, c *models.ExecutionCaseResult) ExecutionEvent {
+	ev := ExecutionEvent{
+		Type:         eventType,
+		TaskUUID:     c.TaskUUID,
+		RunUUID:      runUUID,
+		CaseResultID: c.ID,
+		DisplayID:    c.DisplayID,
+		CaseNum:      c.CaseNum,
+	}
+	if eventType == ExecutionEventCaseFinished {
+		ev.TestResult = c.TestResult
+		ev.ResponseTime = c.ResponseTime
+		ev.RetryCount = c.RetryCount
+		ev.Flaky = c.Flaky
+		ev.Remark = firstLine(c.Remark)
+	}
+	return ev
+}
+
+// runEvent 由执行批次生成事件
+func runEvent(eventType string, run *models.ExecutionRun) ExecutionEvent {
+	return ExecutionEvent{
+		Type:         eventType,
+		TaskUUID:     run.TaskUUID,
+		RunUUID:      run.RunUUID,
+		Environment:  run.EnvProfileName,
+		Status:       run.Status,
+		Total:        run.Total,
+		OKCount:      run.OKCount,
+		NGCount:      run.NGCount,
+		BlockCount:   run.BlockCount,
+		ErrorMessage: run.ErrorMessage,
+	}
+}
+
+// ListEvents 获取任务序号大于 since 的进度事件(供轮询方和MCP客户端使用)
+func (s *executionTaskService) ListEvents(projectID uint, taskUUID string, since int64) ([]ExecutionEvent, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	return s.events.Since(taskUUID, since), nil
+}
+
+// SubscribeEvents 订阅任务的进度事件(SSE)，返回补发事件、事件通道和取消订阅函数
+func (s *executionTaskService) SubscribeEvents(projectID uint, taskUUID string, since int64) ([]ExecutionEvent, <-chan ExecutionEvent, func(), error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, nil, nil, err
+	}
+	backlog, ch, unsubscribe := s.events.Subscribe(taskUUID, since)
+	return backlog, ch, unsubscribe, nil
+}
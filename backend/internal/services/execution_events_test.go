@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionEventHub_SubscribersShareStream(t *testing.T) {
+	hub := NewExecutionEventHub()
+	hub.Publish(ExecutionEvent{Type: ExecutionEventRunStarted, TaskUUID: "t1"})
+	hub.Publish(ExecutionEvent{Type: ExecutionEventCaseStarted, TaskUUID: "t1", CaseResultID: 1})
+	hub.Publish(ExecutionEvent{Type: ExecutionEventRunStarted, TaskUUID: "t2"})
+
+	backlogA, chA, unsubscribeA := hub.Subscribe("t1", 0)
+	defer unsubscribeA()
+	backlogB, chB, unsubscribeB := hub.Subscribe("t1", 1)
+	defer unsubscribeB()
+
+	require.Len(t, backlogA, 2)
+	require.Len(t, backlogB, 1)
+	assert.Equal(t, int64(2), backlogB[0].Seq)
+
+	ev := hub.Publish(ExecutionEvent{Type: ExecutionEventCaseFinished, TaskUUID: "t1", CaseResultID: 1, TestResult: "OK"})
+	assert.Equal(t, int64(3), ev.Seq)
+	for _, ch := range []<-chan ExecutionEvent{chA, chB} {
+		select {
+		case got := <-ch:
+			assert.Equal(t, ev, got)
+		case <-time.After(time.Second):
+			t.Fatal("subscriber did not receive event")
+		}
+	}
+
+	assert.Len(t, hub.Since("t2", 0), 1)
+	assert.Empty(t, hub.Since("t1", 3))
+}
+
+func TestExecutionEventHub_BacklogAndForget(t *testing.T) {
+	hub := NewExecutionEventHub()
+	for i := 0; i < executionEventBacklog+10; i++ {
+		hub.Publish(ExecutionEvent{Type: ExecutionEventCaseStarted, TaskUUID: "t1"})
+	}
+	events := hub.Since("t1", 0)
+	require.Len(t, events, executionEventBacklog)
+	assert.Equal(t, int64(11), events[0].Seq)
+
+	_, ch, unsubscribe := hub.Subscribe("t1", 0)
+	hub.Forget("t1")
+	_, ok := <-ch
+	assert.False(t, ok, "channel should be closed after Forget")
+	unsubscribe()
+	assert.Empty(t, hub.Since("t1", 0))
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SetProjectExecutorRequest 设置项目执行器请求，script_executor 为空时恢复默认
+type SetProjectExecutorRequest struct {
+	ScriptExecutor string `json:"script_executor" binding:"max=30"`
+}
+
+// ProjectExecutorSelection 项目的执行器设置及各类用例实际使用的执行器
+type ProjectExecutorSelection struct {
+	ProjectID      uint              `json:"project_id"`
+	ScriptExecutor string            `json:"script_executor"` // 项目指定的执行器，空为默认
+	Effective      map[string]string `json:"effective"`       // 执行类型/用例类别 -> 执行器名称
+}
+
+// resolveExecutor 选择执行任务用例的执行器(项目设置优先，其次为任务类型的默认执行器)
+func (s *executionTaskService) resolveExecutor(task *models.ExecutionTask, kind string) (ScriptExecutor, error) {
+	if s.executors == nil {
+		return nil, errors.New("未配置执行器")
+	}
+	projectExecutor := ""
+	if s.projectRepo != nil {
+		project, err := s.projectRepo.GetByID(task.ProjectID)
+		if err != nil {
+			fmt.Printf("[resolveExecutor] ⚠️ 获取项目失败，使用默认执行器: project_id=%d, error=%v\n", task.ProjectID, err)
+		} else if project != nil {
+			projectExecutor = project.ScriptExecutor
+		}
+	}
+	return s.executors.Resolve(projectExecutor, task.ExecutionType, kind)
+}
+
+// ListExecutors 获取已注册执行器的能力和健康状态
+func (s *executionTaskService) ListExecutors(ctx context.Context) []ExecutorStatus {
+	if s.executors == nil {
+		return []ExecutorStatus{}
+	}
+	return s.executors.Status(ctx)
+}
+
+// GetExecutorPool 获取远程执行器节点池的各节点状态和吞吐量
+func (s *executionTaskService) GetExecutorPool() (*ExecutorPoolStatus, error) {
+	if s.executors != nil {
+		if e, ok := s.executors.Get(ExecutorRemote); ok {
+			if remote, ok := e.(*remoteScriptExecutor); ok {
+				return remote.Pool().Status(), nil
+			}
+		}
+	}
+	return nil, errors.New("未配置远程执行器")
+}
+
+// GetProjectExecutor 获取项目的执行器设置
+func (s *executionTaskService) GetProjectExecutor(projectID uint) (*ProjectExecutorSelection, error) {
+	project, err := s.getProject(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.projectExecutorSelection(project), nil
+}
+
+// SetProjectExecutor 设置项目的执行器，执行器需已注册
+func (s *executionTaskService) SetProjectExecutor(projectID uint, req SetProjectExecutorRequest) (*ProjectExecutorSelection, error) {
+	name := strings.TrimSpace(req.ScriptExecutor)
+	if name != "" {
+		if _, ok := s.executors.Get(name); !ok {
+			return nil, errors.New("执行器不存在")
+		}
+	}
+	if _, err := s.getProject(projectID); err != nil {
+		return nil, err
+	}
+	project, err := s.projectRepo.Update(projectID, map[string]interface{}{"script_executor": name})
+	if err != nil {
+		return nil, fmt.Errorf("update project executor: %w", err)
+	}
+	fmt.Printf("[SetProjectExecutor] 项目执行器已更新: project_id=%d, executor=%q\n", projectID, name)
+	return s.projectExecutorSelection(project), nil
+}
+
+// getProject 获取项目(不存在时返回"项目不存在")
+func (s *executionTaskService) getProject(projectID uint) (*models.Project, error) {
+	project, err := s.projectRepo.GetByID(projectID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("项目不存在")
+		}
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	if project.ID == 0 {
+		return nil, errors.New("项目不存在")
+	}
+	return project, nil
+}
+
+// projectExecutorSelection 计算项目各类用例实际使用的执行器
+func (s *executionTaskService) projectExecutorSelection(project *models.Project) *ProjectExecutorSelection {
+	selection := &ProjectExecutorSelection{
+		ProjectID:      project.ID,
+		ScriptExecutor: project.ScriptExecutor,
+		Effective:      make(map[string]string),
+	}
+	targets := []struct{ key, executionType, kind string }{
+		{"automation", "automation", CaseKindScript},
+		{"api_script", "api", CaseKindScript},
+		{"api_request", "api", CaseKindAPI},
+	}
+	for _, t := range targets {
+		if e, err := s.executors.Resolve(project.ScriptExecutor, t.executionType, t.kind); err == nil {
+			selection.Effective[t.key] = e.Name()
+		}
+	}
+	return selection
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+)
+
+// RunCaseDiff 两个批次中同一用例的结果对比
+type RunCaseDiff struct {
+	CaseResultID     uint   `json:"case_result_id"`
+	DisplayID        uint   `json:"display_id"`
+	CaseNum          string `json:"case_num"`
+	BaseResult       string `json:"base_result"`   // 批次内最后一次执行结果，未执行为空
+	TargetResult     string `json:"target_result"` // 批次内最后一次执行结果，未执行为空
+	BaseDurationMs   int    `json:"base_duration_ms"`
+	TargetDurationMs int    `json:"target_duration_ms"`
+	Changed          bool   `json:"changed"`
+}
+
+// RunComparison 同一任务两个批次的对比结果
+type RunComparison struct {
+	BaseRun      *models.ExecutionRun `json:"base_run"`
+	TargetRun    *models.ExecutionRun `json:"target_run"`
+	ChangedCount int                  `json:"changed_count"`
+	Cases        []RunCaseDiff        `json:"cases"`
+}
+
+// FlakyCaseReport 不稳定用例排行中的一项
+type FlakyCaseReport struct {
+	repositories.CaseFlakyStat
+	FlakyRate  float64 `json:"flaky_rate"` // 不稳定次数/执行次数
+	CaseNum    string  `json:"case_num"`
+	ScreenCN   string  `json:"screen_cn"`
+	FunctionCN string  `json:"function_cn"`
+}
+
+// 不稳定用例排行的默认条数和上限
+const (
+	defaultFlakyCaseLimit = 20
+	maxFlakyCaseLimit     = 100
+)
+
+// ListAttempts 获取用例结果的执行记录
+func (s *executionTaskService) ListAttempts(projectID uint, taskUUID string, caseResultID uint) ([]*models.ExecutionAttempt, error) {
+	if _, err := s.getTaskCaseResult(projectID, taskUUID, caseResultID); err != nil {
+		return nil, err
+	}
+
+	attempts, err := s.attemptRepo.GetByCaseResultID(caseResultID)
+	if err != nil {
+		return nil, fmt.Errorf("list attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// CompareRuns 对比同一任务的两个执行批次，按用例ID逐条比较
+// 重新导入用例后用例结果ID会变化，因此以执行记录中的用例ID匹配
+func (s *executionTaskService) CompareRuns(projectID uint, taskUUID string, baseRunUUID string, targetRunUUID string) (*RunComparison, error) {
+	baseRun, err := s.getTaskRun(projectID, taskUUID, baseRunUUID)
+	if err != nil {
+		return nil, err
+	}
+	targetRun, err := s.getTaskRun(projectID, taskUUID, targetRunUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	baseAttempts, err := s.latestAttemptsByCaseID(baseRunUUID)
+	if err != nil {
+		return nil, err
+	}
+	targetAttempts, err := s.latestAttemptsByCaseID(targetRunUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results: %w", err)
+	}
+
+	comparison := &RunComparison{
+		BaseRun:   baseRun,
+		TargetRun: targetRun,
+		Cases:     make([]RunCaseDiff, 0, len(cases)),
+	}
+	for _, c := range cases {
+		diff := RunCaseDiff{
+			CaseResultID: c.ID,
+			DisplayID:    c.DisplayID,
+			CaseNum:      c.CaseNum,
+		}
+		if a, ok := baseAttempts[c.CaseID]; ok {
+			diff.BaseResult = a.TestResult
+			diff.BaseDurationMs = a.DurationMs
+		}
+		if a, ok := targetAttempts[c.CaseID]; ok {
+			diff.TargetResult = a.TestResult
+			diff.TargetDurationMs = a.DurationMs
+		}
+		diff.Changed = diff.BaseResult != diff.TargetResult
+		if diff.Changed {
+			comparison.ChangedCount++
+		}
+		comparison.Cases = append(comparison.Cases, diff)
+	}
+	return comparison, nil
+}
+
+// latestAttemptsByCase 获取批次内每条用例的最后一次执行记录
+func (s *executionTaskService) latestAttemptsByCase(runUUID string) (map[uint]*models.ExecutionAttempt, error) {
+	attempts, err := s.attemptRepo.GetByRunUUID(runUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get run attempts: %w", err)
+	}
+	latest := make(map[uint]*models.ExecutionAttempt, len(attempts))
+	for _, a := range attempts {
+		// 按执行时间升序，后出现的覆盖先出现的
+		latest[a.CaseResultID] = a
+	}
+	return latest, nil
+}
+
+// latestAttemptsByCaseID 获取批次内每个用例ID的最后一次执行记录，用于跨批次对比
+func (s *executionTaskService) latestAttemptsByCaseID(runUUID string) (map[string]*models.ExecutionAttempt, error) {
+	attempts, err := s.attemptRepo.GetByRunUUID(runUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get run attempts: %w", err)
+	}
+	latest := make(map[string]*models.ExecutionAttempt, len(attempts))
+	for _, a := range attempts {
+		latest[a.CaseID] = a
+	}
+	return latest, nil
+}
+
+// ListFlakyCases 统计项目所有执行任务中重试后才通过的用例，按不稳定次数排行
+func (s *executionTaskService) ListFlakyCases(projectID uint, limit int) ([]*FlakyCaseReport, error) {
+	if limit <= 0 {
+		limit = defaultFlakyCaseLimit
+	}
+	if limit > maxFlakyCaseLimit {
+		limit = maxFlakyCaseLimit
+	}
+
+	stats, err := s.attemptRepo.FlakyStatsByProject(projectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list flaky cases: %w", err)
+	}
+
+	reports := make([]*FlakyCaseReport, 0, len(stats))
+	for _, stat := range stats {
+		report := &FlakyCaseReport{CaseFlakyStat: stat}
+		if stat.Executions > 0 {
+			report.FlakyRate = float64(stat.FlakyCount) / float64(stat.Executions)
+		}
+		// 用例信息取最新的执行结果快照
+		if results, err := s.ecrRepo.GetByCaseID(stat.CaseID); err == nil && len(results) > 0 {
+			report.CaseNum = results[0].CaseNum
+			report.ScreenCN = results[0].ScreenCN
+			report.FunctionCN = results[0].FunctionCN
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"webtest/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MatrixRunRequest 矩阵执行请求：任务在每个执行环境下各执行一次
+type MatrixRunRequest struct {
+	ProfileIDs []uint `json:"profile_ids" binding:"required,min=1,max=20"`
+}
+
+// MatrixEnvSummary 单个执行环境的批次统计
+type MatrixEnvSummary struct {
+	EnvProfileID   uint    `json:"env_profile_id"`
+	EnvProfileName string  `json:"env_profile_name"`
+	RunUUID        string  `json:"run_uuid"`
+	Status         string  `json:"status"`
+	Total          int     `json:"total"`
+	CompletedCount int     `json:"completed_count"`
+	OKCount        int     `json:"ok_count"`
+	NGCount        int     `json:"ng_count"`
+	BlockCount     int     `json:"block_count"`
+	NRCount        int     `json:"nr_count"`
+	PassRate       float64 `json:"pass_rate"` // OK / Total，百分比保留1位小数
+}
+
+// MatrixCaseResult 用例在单个执行环境下的结果
+type MatrixCaseResult struct {
+	EnvProfileName string `json:"env_profile_name"`
+	TestResult     string `json:"test_result"` // OK/NG/Block/NR
+	DurationMs     int    `json:"duration_ms"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+}
+
+// MatrixCaseRow 用例在所有执行环境下的结果(与 Environments 顺序一致)
+type MatrixCaseRow struct {
+	CaseResultID uint                `json:"case_result_id"`
+	DisplayID    uint                `json:"display_id"`
+	CaseNum      string              `json:"case_num"`
+	Screen       string              `json:"screen"`
+	Results      []*MatrixCaseResult `json:"results"`
+	Overall      string              `json:"overall"`    // 跨环境汇总结果
+	Consistent   bool                `json:"consistent"` // 所有环境结果一致
+}
+
+// MatrixCrossSummary 跨环境汇总
+type MatrixCrossSummary struct {
+	Total           int `json:"total"`
+	AllOK           int `json:"all_ok"`          // 所有环境均通过
+	AllNG           int `json:"all_ng"`          // 所有环境均失败
+	EnvSpecificFail int `json:"env_specific_ng"` // 仅部分环境失败，多为环境相关问题
+	Inconsistent    int `json:"inconsistent"`    // 各环境结果不一致(含Block/NR)
+	Pending         int `json:"pending"`         // 尚有环境未执行完成
+}
+
+// MatrixRunSummary 一次矩阵执行的结果
+type MatrixRunSummary struct {
+	MatrixUUID   string              `json:"matrix_uuid"`
+	TaskUUID     string              `json:"task_uuid"`
+	Finished     bool                `json:"finished"` // 所有环境批次均已结束
+	Environments []*MatrixEnvSummary `json:"environments"`
+	Summary      MatrixCrossSummary  `json:"summary"`
+	Cases        []*MatrixCaseRow    `json:"cases"`
+}
+
+// StartMatrixRun 在多个执行环境下并行执行任务，每个环境创建一个执行批次
+// 各批次共享同一 matrix_uuid，执行槽由服务器范围的并发上限统一控制
+func (s *executionTaskService) StartMatrixRun(projectID uint, userID uint, taskUUID string, req MatrixRunRequest) ([]*models.ExecutionRun, error) {
+	fmt.Printf("[MatrixRun] 开始矩阵执行: projectID=%d, userID=%d, taskUUID=%s, profiles=%v\n", projectID, userID, taskUUID, req.ProfileIDs)
+
+	task, cases, err := s.loadRunnableCases(projectID, taskUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := s.loadProjectProfiles(projectID, req.ProfileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	if _, err := s.runRepo.GetActiveByTaskUUID(taskUUID); err == nil {
+		return nil, errors.New("任务正在执行中")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("get active run: %w", err)
+	}
+
+	// 矩阵批次不直接写入用例结果，全部环境结束后汇总写回
+	if err := s.ecrRepo.ResetTestResults(taskUUID, executableCaseIDs(task, cases)); err != nil {
+		return nil, fmt.Errorf("reset case results: %w", err)
+	}
+
+	matrixUUID := uuid.New().String()
+	executedBy := s.getUserName(userID)
+	runs := make([]*models.ExecutionRun, 0, len(profiles))
+	for _, profile := range profiles {
+		run := &models.ExecutionRun{
+			TaskUUID:          taskUUID,
+			ProjectID:         projectID,
+			Status:            models.ExecutionRunStatusQueued,
+			Total:             len(cases),
+			TriggeredBy:       userID,
+			TriggerType:       models.ExecutionRunTriggerManual,
+			ExecutedBy:        executedBy,
+			MatrixUUID:        matrixUUID,
+			EnvProfileID:      profile.ID,
+			EnvProfileName:    profile.Name,
+			VariableOverrides: profile.Variables,
+		}
+		if browser := profileBrowserOptions(profile); browser != nil {
+			data, err := json.Marshal(browser)
+			if err != nil {
+				return nil, fmt.Errorf("marshal browser options: %w", err)
+			}
+			run.BrowserOptions = string(data)
+		}
+		s.leaseRun(run)
+		if err := s.runRepo.Create(run); err != nil {
+			return nil, fmt.Errorf("create run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	for _, run := range runs {
+		s.startRunLocked(run)
+	}
+	fmt.Printf("[MatrixRun] 矩阵执行已创建: matrix_uuid=%s, runs=%d\n", matrixUUID, len(runs))
+	return runs, nil
+}
+
+// loadProjectProfiles 按请求顺序加载执行环境(去重)，并校验归属
+func (s *executionTaskService) loadProjectProfiles(projectID uint, ids []uint) ([]*models.EnvironmentProfile, error) {
+	unique := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	profiles, err := s.profileRepo.GetByIDs(unique)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.EnvironmentProfile, len(profiles))
+	for _, p := range profiles {
+		byID[p.ID] = p
+	}
+	ordered := make([]*models.EnvironmentProfile, 0, len(unique))
+	for _, id := range unique {
+		p, ok := byID[id]
+		if !ok {
+			return nil, errors.New("执行环境不存在")
+		}
+		if p.ProjectID != projectID {
+			return nil, errors.New("执行环境不属于该项目")
+		}
+		ordered = append(ordered, p)
+	}
+	return ordered, nil
+}
+
+// profileBrowserOptions 由执行环境生成发送给执行器的浏览器设置，未设置任何项时返回nil
+func profileBrowserOptions(p *models.EnvironmentProfile) *BrowserOptions {
+	opts := &BrowserOptions{Browser: p.Browser, Device: p.Device}
+	if p.ViewportWidth > 0 && p.ViewportHeight > 0 {
+		opts.Viewport = &Viewport{Width: p.ViewportWidth, Height: p.ViewportHeight}
+	}
+	if opts.Browser == "" && opts.Device == "" && opts.Viewport == nil {
+		return nil
+	}
+	return opts
+}
+
+// completeMatrix 矩阵内所有批次结束后，将各环境结果汇总写回用例结果
+// 多个批次几乎同时结束时可能重复汇总，结果相同
+func (s *executionTaskService) completeMatrix(matrixUUID string) {
+	runs, err := s.runRepo.GetByMatrixUUID(matrixUUID)
+	if err != nil {
+		fmt.Printf("[completeMatrix] ❌ 读取矩阵批次失败: matrix_uuid=%s, error=%v\n", matrixUUID, err)
+		return
+	}
+	for _, run := range runs {
+		if run.Status == models.ExecutionRunStatusQueued || run.Status == models.ExecutionRunStatusRunning {
+			return
+		}
+	}
+	if len(runs) == 0 {
+		return
+	}
+
+	summary, err := s.buildMatrixSummary(runs[0].TaskUUID, matrixUUID, runs)
+	if err != nil {
+		fmt.Printf("[completeMatrix] ❌ 汇总矩阵结果失败: matrix_uuid=%s, error=%v\n", matrixUUID, err)
+		return
+	}
+	for _, row := range summary.Cases {
+		if row.Overall == "NR" && row.Consistent {
+			// 各环境均未执行(不可执行用例或已取消)，保留用例结果
+			continue
+		}
+		updates := map[string]interface{}{
+			"test_result": row.Overall,
+			"remark":      matrixRemark(row.Results),
+		}
+		if err := s.ecrRepo.UpdateResult(row.CaseResultID, updates); err != nil {
+			fmt.Printf("[completeMatrix] ❌ 写回用例结果失败: case_result_id=%d, error=%v\n", row.CaseResultID, err)
+		}
+	}
+	fmt.Printf("[completeMatrix] ✅ 矩阵执行完成: matrix_uuid=%s, all_ok=%d, env_specific_ng=%d\n",
+		matrixUUID, summary.Summary.AllOK, summary.Summary.EnvSpecificFail)
+}
+
+// GetMatrixRun 获取矩阵执行的各环境统计和按用例对齐的结果
+func (s *executionTaskService) GetMatrixRun(projectID uint, taskUUID string, matrixUUID string) (*MatrixRunSummary, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	runs, err := s.runRepo.GetByMatrixUUID(matrixUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 || runs[0].TaskUUID != taskUUID {
+		return nil, errors.New("矩阵执行不存在")
+	}
+	return s.buildMatrixSummary(taskUUID, matrixUUID, runs)
+}
+
+// buildMatrixSummary 读取各批次的最后执行记录并组装矩阵结果
+func (s *executionTaskService) buildMatrixSummary(taskUUID string, matrixUUID string, runs []*models.ExecutionRun) (*MatrixRunSummary, error) {
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results: %w", err)
+	}
+	attemptsByRun := make(map[string]map[uint]*models.ExecutionAttempt, len(runs))
+	for _, run := range runs {
+		latest, err := s.latestAttemptsByCase(run.RunUUID)
+		if err != nil {
+			return nil, err
+		}
+		attemptsByRun[run.RunUUID] = latest
+	}
+	return assembleMatrixSummary(taskUUID, matrixUUID, runs, cases, attemptsByRun), nil
+}
+
+// assembleMatrixSummary 按环境和用例组装矩阵结果(纯函数，便于测试)
+func assembleMatrixSummary(
+	taskUUID string,
+	matrixUUID string,
+	runs []*models.ExecutionRun,
+	cases []*models.ExecutionCaseResult,
+	attemptsByRun map[string]map[uint]*models.ExecutionAttempt,
+) *MatrixRunSummary {
+	summary := &MatrixRunSummary{
+		MatrixUUID:   matrixUUID,
+		TaskUUID:     taskUUID,
+		Finished:     true,
+		Environments: make([]*MatrixEnvSummary, 0, len(runs)),
+		Cases:        make([]*MatrixCaseRow, 0, len(cases)),
+	}
+	envByRun := make(map[string]*MatrixEnvSummary, len(runs))
+	for _, run := range runs {
+		env := &MatrixEnvSummary{
+			EnvProfileID:   run.EnvProfileID,
+			EnvProfileName: run.EnvProfileName,
+			RunUUID:        run.RunUUID,
+			Status:         run.Status,
+			Total:          len(cases),
+			CompletedCount: run.CompletedCount,
+		}
+		if run.Status == models.ExecutionRunStatusQueued || run.Status == models.ExecutionRunStatusRunning {
+			summary.Finished = false
+		}
+		summary.Environments = append(summary.Environments, env)
+		envByRun[run.RunUUID] = env
+	}
+
+	sorted := make([]*models.ExecutionCaseResult, len(cases))
+	copy(sorted, cases)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].DisplayID < sorted[j].DisplayID })
+
+	for _, c := range sorted {
+		row := &MatrixCaseRow{
+			CaseResultID: c.ID,
+			DisplayID:    c.DisplayID,
+			CaseNum:      c.CaseNum,
+			Screen:       c.Screen,
+			Results:      make([]*MatrixCaseResult, 0, len(runs)),
+		}
+		results := make([]string, 0, len(runs))
+		for _, run := range runs {
+			result := &MatrixCaseResult{EnvProfileName: run.EnvProfileName, TestResult: "NR"}
+			if a, ok := attemptsByRun[run.RunUUID][c.ID]; ok {
+				result.TestResult = a.TestResult
+				result.DurationMs = a.DurationMs
+				result.ErrorMessage = firstLine(a.ErrorMessage)
+			}
+			env := envByRun[run.RunUUID]
+			switch result.TestResult {
+			case "OK":
+				env.OKCount++
+			case "NG":
+				env.NGCount++
+			case "Block":
+				env.BlockCount++
+			default:
+				env.NRCount++
+			}
+			row.Results = append(row.Results, result)
+			results = append(results, result.TestResult)
+		}
+		row.Overall = aggregateMatrixResult(results)
+		row.Consistent = allEqual(results)
+		summary.Cases = append(summary.Cases, row)
+		countMatrixCase(&summary.Summary, results)
+	}
+
+	for _, env := range summary.Environments {
+		if env.Total > 0 {
+			env.PassRate = float64(env.OKCount*1000/env.Total) / 10
+		}
+	}
+	return summary
+}
+
+// aggregateMatrixResult 跨环境汇总结果：任一环境NG即NG，其次Block；全部OK为OK，否则NR
+func aggregateMatrixResult(results []string) string {
+	hasBlock := false
+	allOK := len(results) > 0
+	for _, r := range results {
+		switch r {
+		case "NG":
+			return "NG"
+		case "Block":
+			hasBlock = true
+		}
+		if r != "OK" {
+			allOK = false
+		}
+	}
+	if hasBlock {
+		return "Block"
+	}
+	if allOK {
+		return "OK"
+	}
+	return "NR"
+}
+
+// countMatrixCase 累计单条用例的跨环境统计
+func countMatrixCase(sum *MatrixCrossSummary, results []string) {
+	sum.Total++
+	var ok, ng, nr int
+	for _, r := range results {
+		switch r {
+		case "OK":
+			ok++
+		case "NG":
+			ng++
+		case "Block":
+		default:
+			nr++
+		}
+	}
+	switch {
+	case nr > 0:
+		sum.Pending++
+	case ok == len(results):
+		sum.AllOK++
+	case ng == len(results):
+		sum.AllNG++
+	case ng > 0 && ok > 0:
+		sum.EnvSpecificFail++
+	}
+	if !allEqual(results) {
+		sum.Inconsistent++
+	}
+}
+
+// matrixRemark 生成写回用例结果的备注，如 "staging=OK, uat=NG"
+func matrixRemark(results []*MatrixCaseResult) string {
+	parts := make([]string, 0, len(results))
+	for _, r := range results {
+		parts = append(parts, fmt.Sprintf("%s=%s", r.EnvProfileName, r.TestResult))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func allEqual(values []string) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i] != values[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssembleMatrixSummary(t *testing.T) {
+	runs := []*models.ExecutionRun{
+		{RunUUID: "r1", EnvProfileID: 1, EnvProfileName: "staging", Status: models.ExecutionRunStatusFinished},
+		{RunUUID: "r2", EnvProfileID: 2, EnvProfileName: "uat", Status: models.ExecutionRunStatusRunning},
+	}
+	cases := []*models.ExecutionCaseResult{
+		{ID: 12, DisplayID: 2, CaseNum: "TC-2"},
+		{ID: 11, DisplayID: 1, CaseNum: "TC-1"},
+		{ID: 13, DisplayID: 3, CaseNum: "TC-3"},
+	}
+	attempts := map[string]map[uint]*models.ExecutionAttempt{
+		"r1": {
+			11: {TestResult: "OK", DurationMs: 120},
+			12: {TestResult: "OK"},
+			13: {TestResult: "NG", ErrorMessage: "timeout\nstack"},
+		},
+		"r2": {
+			11: {TestResult: "OK"},
+			12: {TestResult: "NG"},
+		},
+	}
+
+	summary := assembleMatrixSummary("task", "matrix", runs, cases, attempts)
+
+	assert.False(t, summary.Finished)
+	require.Len(t, summary.Cases, 3)
+	assert.Equal(t, uint(11), summary.Cases[0].CaseResultID)
+	assert.Equal(t, "OK", summary.Cases[0].Overall)
+	assert.True(t, summary.Cases[0].Consistent)
+	assert.Equal(t, "NG", summary.Cases[1].Overall)
+	assert.False(t, summary.Cases[1].Consistent)
+	assert.Equal(t, "NG", summary.Cases[2].Overall)
+	assert.Equal(t, "timeout", summary.Cases[2].Results[0].ErrorMessage)
+	assert.Equal(t, "NR", summary.Cases[2].Results[1].TestResult)
+
+	assert.Equal(t, MatrixCrossSummary{Total: 3, AllOK: 1, EnvSpecificFail: 1, Inconsistent: 2, Pending: 1}, summary.Summary)
+	assert.Equal(t, 66.6, summary.Environments[0].PassRate)
+	assert.Equal(t, 1, summary.Environments[1].NRCount)
+	assert.Equal(t, "staging=OK, uat=NG", matrixRemark(summary.Cases[1].Results))
+}
+
+func TestAggregateMatrixResult(t *testing.T) {
+	assert.Equal(t, "NG", aggregateMatrixResult([]string{"Block", "NG", "OK"}))
+	assert.Equal(t, "Block", aggregateMatrixResult([]string{"OK", "Block"}))
+	assert.Equal(t, "OK", aggregateMatrixResult([]string{"OK", "OK"}))
+	assert.Equal(t, "NR", aggregateMatrixResult([]string{"OK", "NR"}))
+}
@@ -0,0 +1,555 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"webtest/internal/models"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// reportLabels 执行报告中的文字(中/日/英)
+var reportLabels = map[string][3]string{
+	"sheet_summary":   {"概要", "サマリー", "Summary"},
+	"sheet_groups":    {"用例集统计", "ケース集計", "Case Groups"},
+	"sheet_ng":        {"NG一览", "NG一覧", "NG List"},
+	"sheet_cases":     {"用例明细", "ケース明細", "Case Details"},
+	"title":           {"测试执行报告", "テスト実行レポート", "Test Execution Report"},
+	"task_name":       {"任务名称", "タスク名", "Task Name"},
+	"execution_type":  {"执行类型", "実行種別", "Execution Type"},
+	"test_version":    {"测试版本", "テストバージョン", "Test Version"},
+	"test_env":        {"测试环境", "テスト環境", "Test Environment"},
+	"executor":        {"执行人", "実行者", "Executor"},
+	"start_date":      {"开始日期", "開始日", "Start Date"},
+	"end_date":        {"结束日期", "終了日", "End Date"},
+	"test_date":       {"测试日期", "テスト日", "Test Date"},
+	"total":           {"用例总数", "ケース総数", "Total"},
+	"pass_rate":       {"通过率", "合格率", "Pass Rate"},
+	"executed_rate":   {"执行率", "実施率", "Executed Rate"},
+	"avg_response":    {"平均响应时间(ms)", "平均応答時間(ms)", "Avg Response Time (ms)"},
+	"max_response":    {"最大响应时间(ms)", "最大応答時間(ms)", "Max Response Time (ms)"},
+	"block_reasons":   {"Block原因", "Block理由", "Block Reasons"},
+	"reason":          {"原因", "理由", "Reason"},
+	"count":           {"件数", "件数", "Count"},
+	"case_group":      {"用例集", "ケース集", "Case Group"},
+	"no_group":        {"(未分组)", "(未分類)", "(Ungrouped)"},
+	"no_reason":       {"(未填写)", "(未記入)", "(Not specified)"},
+	"no":              {"No.", "No.", "No."},
+	"case_id":         {"用例编号", "ケースID", "Case ID"},
+	"case_content":    {"用例内容", "ケース内容", "Case"},
+	"test_result":     {"测试结果", "テスト結果", "Result"},
+	"response_time":   {"响应时间(ms)", "応答時間(ms)", "Response Time (ms)"},
+	"retry_count":     {"重试次数", "リトライ回数", "Retries"},
+	"flaky":           {"不稳定", "不安定", "Flaky"},
+	"bug_id":          {"缺陷ID", "バグID", "Bug ID"},
+	"defect_title":    {"缺陷标题", "バグタイトル", "Defect Title"},
+	"defect_status":   {"缺陷状态", "バグステータス", "Defect Status"},
+	"remark":          {"备注", "備考", "Remark"},
+	"type_manual":     {"手工测试", "手動テスト", "Manual"},
+	"type_automation": {"自动化测试", "自動テスト", "Automation"},
+	"type_api":        {"接口测试", "APIテスト", "API"},
+
+	// 任务对比
+	"sheet_compare":   {"对比结果", "比較結果", "Comparison"},
+	"compare_title":   {"执行任务对比", "実行タスク比較", "Execution Task Comparison"},
+	"base":            {"基准", "基準", "Base"},
+	"target":          {"目标", "対象", "Target"},
+	"category":        {"分类", "分類", "Category"},
+	"base_result":     {"基准结果", "基準結果", "Base Result"},
+	"target_result":   {"目标结果", "対象結果", "Target Result"},
+	"base_response":   {"基准响应时间(ms)", "基準応答時間(ms)", "Base Response (ms)"},
+	"target_response": {"目标响应时间(ms)", "対象応答時間(ms)", "Target Response (ms)"},
+	"response_delta":  {"响应时间差(ms)", "応答時間差(ms)", "Response Delta (ms)"},
+	"newly_failing":   {"新增失败", "新規NG", "Newly Failing"},
+	"newly_passing":   {"新增通过", "NG解消", "Newly Passing"},
+	"still_failing":   {"持续失败", "継続NG", "Still Failing"},
+	"added":           {"新增用例", "追加ケース", "Added"},
+	"removed":         {"移除用例", "削除ケース", "Removed"},
+	"changed":         {"结果变化", "結果変化", "Changed"},
+	"unchanged":       {"无变化", "変化なし", "Unchanged"},
+}
+
+// reportLabel 按显示语言取报告文字，all 时三种语言并列
+func reportLabel(key string, lang string) string {
+	texts, ok := reportLabels[key]
+	if !ok {
+		return key
+	}
+	switch strings.ToLower(lang) {
+	case "jp":
+		return texts[1]
+	case "en":
+		return texts[2]
+	case "all":
+		return strings.Join(texts[:], " / ")
+	default:
+		return texts[0]
+	}
+}
+
+// reportSheetName 工作表名称，all 时使用英文(工作表名最长31个字符)
+func reportSheetName(key string, lang string) string {
+	if strings.ToLower(lang) == "all" {
+		return reportLabels[key][2]
+	}
+	return reportLabel(key, lang)
+}
+
+// reportCount 结果计数
+type reportCount struct {
+	Total, OK, NG, Block, NR int
+}
+
+func (c *reportCount) add(result string) {
+	c.Total++
+	switch result {
+	case "OK":
+		c.OK++
+	case "NG":
+		c.NG++
+	case "Block":
+		c.Block++
+	default:
+		c.NR++
+	}
+}
+
+// passRate OK占用例总数的比例
+func (c reportCount) passRate() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.OK) / float64(c.Total)
+}
+
+// executedRate 已有结果(OK/NG/Block)的比例
+func (c reportCount) executedRate() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Total-c.NR) / float64(c.Total)
+}
+
+// reportGroupStat 用例集统计
+type reportGroupStat struct {
+	Name string
+	reportCount
+}
+
+// reportReason Block原因及件数
+type reportReason struct {
+	Reason string
+	Count  int
+}
+
+// executionReportData 执行报告的统计数据
+type executionReportData struct {
+	Counts        reportCount
+	Groups        []reportGroupStat
+	BlockReasons  []reportReason
+	AvgResponseMs int
+	MaxResponseMs int
+}
+
+// buildExecutionReportData 统计用例结果：总数、用例集分组(按首次出现顺序)、Block原因(按件数倒序)、响应时间
+func buildExecutionReportData(cases []*models.ExecutionCaseResult) *executionReportData {
+	data := &executionReportData{}
+	groupIndex := make(map[string]int)
+	reasonCount := make(map[string]int)
+	var respTotal, respCount int
+
+	for _, c := range cases {
+		data.Counts.add(c.TestResult)
+
+		idx, ok := groupIndex[c.CaseGroupName]
+		if !ok {
+			idx = len(data.Groups)
+			groupIndex[c.CaseGroupName] = idx
+			data.Groups = append(data.Groups, reportGroupStat{Name: c.CaseGroupName})
+		}
+		data.Groups[idx].add(c.TestResult)
+
+		if c.TestResult == "Block" {
+			reasonCount[strings.TrimSpace(firstLine(c.Remark))]++
+		}
+		if ms, err := strconv.Atoi(c.ResponseTime); err == nil && ms > 0 {
+			respTotal += ms
+			respCount++
+			if ms > data.MaxResponseMs {
+				data.MaxResponseMs = ms
+			}
+		}
+	}
+
+	for reason, count := range reasonCount {
+		data.BlockReasons = append(data.BlockReasons, reportReason{Reason: reason, Count: count})
+	}
+	sort.Slice(data.BlockReasons, func(i, j int) bool {
+		if data.BlockReasons[i].Count != data.BlockReasons[j].Count {
+			return data.BlockReasons[i].Count > data.BlockReasons[j].Count
+		}
+		return data.BlockReasons[i].Reason < data.BlockReasons[j].Reason
+	})
+	if respCount > 0 {
+		data.AvgResponseMs = respTotal / respCount
+	}
+	return data
+}
+
+// reportCaseContent 按任务类型和显示语言生成用例内容
+// 手工用例为大/中/小功能，自动化用例为画面/功能，API用例为请求方法和URL
+func reportCaseContent(executionType string, c *models.ExecutionCaseResult, lang string) string {
+	pick := func(cn, jp, en string) string {
+		switch strings.ToLower(lang) {
+		case "jp":
+			return jp
+		case "en":
+			return en
+		case "all":
+			var parts []string
+			for _, v := range []string{cn, jp, en} {
+				if v != "" {
+					parts = append(parts, v)
+				}
+			}
+			return strings.Join(parts, "\n")
+		default:
+			return cn
+		}
+	}
+	joinNonEmpty := func(sep string, values ...string) string {
+		var parts []string
+		for _, v := range values {
+			if strings.TrimSpace(v) != "" {
+				parts = append(parts, v)
+			}
+		}
+		return strings.Join(parts, sep)
+	}
+
+	switch executionType {
+	case "manual":
+		return joinNonEmpty(" > ",
+			pick(c.MajorFunctionCN, c.MajorFunctionJP, c.MajorFunctionEN),
+			pick(c.MiddleFunctionCN, c.MiddleFunctionJP, c.MiddleFunctionEN),
+			pick(c.MinorFunctionCN, c.MinorFunctionJP, c.MinorFunctionEN))
+	case "api":
+		return joinNonEmpty(" ", c.Screen, c.Method, c.URL)
+	default:
+		return joinNonEmpty(" > ",
+			pick(c.ScreenCN, c.ScreenJP, c.ScreenEN),
+			pick(c.FunctionCN, c.FunctionJP, c.FunctionEN))
+	}
+}
+
+// ExportExecutionReport 导出执行任务的结果报告(概要/用例集统计/NG一览/用例明细四个工作表)
+// 文字按任务的显示语言(cn/jp/en/all)输出
+func (s *excelService) ExportExecutionReport(projectID uint, taskUUID string) ([]byte, string, error) {
+	task, err := s.taskRepo.GetByUUID(taskUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("任务不存在")
+		}
+		return nil, "", fmt.Errorf("get task by uuid: %w", err)
+	}
+	if task.ProjectID != projectID {
+		return nil, "", errors.New("任务不属于该项目")
+	}
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get case results: %w", err)
+	}
+
+	lang := task.DisplayLanguage
+	if lang == "" {
+		lang = "cn"
+	}
+	data := buildExecutionReportData(cases)
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+	})
+	titleStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	percentStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 10}) // 0.00%
+	wrapStyle, _ := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Vertical: "top", WrapText: true}})
+
+	writeHeader := func(sheet string, row int, keys []string) {
+		for i, key := range keys {
+			cell := fmt.Sprintf("%s%d", columnName(i), row)
+			f.SetCellValue(sheet, cell, reportLabel(key, lang))
+		}
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("%s%d", columnName(len(keys)-1), row), headerStyle)
+	}
+	groupName := func(name string) string {
+		if name == "" {
+			return reportLabel("no_group", lang)
+		}
+		return name
+	}
+
+	// 1. 概要
+	summarySheet := reportSheetName("sheet_summary", lang)
+	f.SetSheetName("Sheet1", summarySheet)
+	f.SetCellValue(summarySheet, "A1", reportLabel("title", lang))
+	f.SetCellStyle(summarySheet, "A1", "A1", titleStyle)
+	formatDate := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+	info := []struct {
+		key   string
+		value interface{}
+	}{
+		{"task_name", task.TaskName},
+		{"execution_type", reportLabel("type_"+task.ExecutionType, lang)},
+		{"test_version", task.TestVersion},
+		{"test_env", task.TestEnv},
+		{"executor", task.Executor},
+		{"start_date", formatDate(task.StartDate)},
+		{"end_date", formatDate(task.EndDate)},
+		{"test_date", formatDate(task.TestDate)},
+		{"total", data.Counts.Total},
+		{"OK", data.Counts.OK},
+		{"NG", data.Counts.NG},
+		{"Block", data.Counts.Block},
+		{"NR", data.Counts.NR},
+		{"pass_rate", data.Counts.passRate()},
+		{"executed_rate", data.Counts.executedRate()},
+		{"avg_response", data.AvgResponseMs},
+		{"max_response", data.MaxResponseMs},
+	}
+	row := 3
+	for _, item := range info {
+		f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), reportLabel(item.key, lang))
+		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), item.value)
+		if item.key == "pass_rate" || item.key == "executed_rate" {
+			f.SetCellStyle(summarySheet, fmt.Sprintf("B%d", row), fmt.Sprintf("B%d", row), percentStyle)
+		}
+		row++
+	}
+	row++
+	f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), reportLabel("block_reasons", lang))
+	f.SetCellStyle(summarySheet, fmt.Sprintf("A%d", row), fmt.Sprintf("A%d", row), titleStyle)
+	row++
+	writeHeader(summarySheet, row, []string{"reason", "count"})
+	for _, reason := range data.BlockReasons {
+		row++
+		text := reason.Reason
+		if text == "" {
+			text = reportLabel("no_reason", lang)
+		}
+		f.SetCellValue(summarySheet, fmt.Sprintf("A%d", row), text)
+		f.SetCellValue(summarySheet, fmt.Sprintf("B%d", row), reason.Count)
+	}
+	f.SetColWidth(summarySheet, "A", "A", 28)
+	f.SetColWidth(summarySheet, "B", "B", 40)
+
+	// 2. 用例集统计
+	groupSheet := reportSheetName("sheet_groups", lang)
+	f.NewSheet(groupSheet)
+	writeHeader(groupSheet, 1, []string{"case_group", "total", "OK", "NG", "Block", "NR", "pass_rate"})
+	for i, g := range data.Groups {
+		r := i + 2
+		f.SetSheetRow(groupSheet, fmt.Sprintf("A%d", r), &[]interface{}{groupName(g.Name), g.Total, g.OK, g.NG, g.Block, g.NR, g.passRate()})
+		f.SetCellStyle(groupSheet, fmt.Sprintf("G%d", r), fmt.Sprintf("G%d", r), percentStyle)
+	}
+	f.SetColWidth(groupSheet, "A", "A", 30)
+	f.SetColWidth(groupSheet, "B", "G", 12)
+
+	// 3. NG一览(关联缺陷的标题和状态)
+	ngSheet := reportSheetName("sheet_ng", lang)
+	f.NewSheet(ngSheet)
+	writeHeader(ngSheet, 1, []string{"no", "case_id", "case_group", "case_content", "remark", "bug_id", "defect_title", "defect_status"})
+	r := 2
+	for _, c := range cases {
+		if c.TestResult != "NG" {
+			continue
+		}
+		title, status := s.lookupDefect(projectID, c.BugID)
+		f.SetSheetRow(ngSheet, fmt.Sprintf("A%d", r), &[]interface{}{
+			c.DisplayID, c.CaseNum, groupName(c.CaseGroupName), reportCaseContent(task.ExecutionType, c, lang),
+			c.Remark, c.BugID, title, status,
+		})
+		r++
+	}
+	if r > 2 {
+		f.SetCellStyle(ngSheet, "A2", fmt.Sprintf("H%d", r-1), wrapStyle)
+	}
+	for col, width := range map[string]float64{"A": 8, "B": 14, "C": 20, "D": 40, "E": 40, "F": 12, "G": 30, "H": 12} {
+		f.SetColWidth(ngSheet, col, col, width)
+	}
+
+	// 4. 用例明细(含响应时间)
+	caseSheet := reportSheetName("sheet_cases", lang)
+	f.NewSheet(caseSheet)
+	writeHeader(caseSheet, 1, []string{"no", "case_id", "case_group", "case_content", "test_result", "response_time", "retry_count", "flaky", "bug_id", "remark"})
+	for i, c := range cases {
+		var responseTime interface{} = ""
+		if ms, err := strconv.Atoi(c.ResponseTime); err == nil {
+			responseTime = ms
+		}
+		flaky := ""
+		if c.Flaky {
+			flaky = "✓"
+		}
+		f.SetSheetRow(caseSheet, fmt.Sprintf("A%d", i+2), &[]interface{}{
+			c.DisplayID, c.CaseNum, groupName(c.CaseGroupName), reportCaseContent(task.ExecutionType, c, lang),
+			c.TestResult, responseTime, c.RetryCount, flaky, c.BugID, c.Remark,
+		})
+	}
+	if len(cases) > 0 {
+		f.SetCellStyle(caseSheet, "A2", fmt.Sprintf("J%d", len(cases)+1), wrapStyle)
+	}
+	for col, width := range map[string]float64{"A": 8, "B": 14, "C": 20, "D": 40, "E": 10, "F": 14, "G": 10, "H": 8, "I": 12, "J": 40} {
+		f.SetColWidth(caseSheet, col, col, width)
+	}
+
+	f.SetActiveSheet(0)
+	buffer, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, "", fmt.Errorf("write buffer: %w", err)
+	}
+
+	projectName, _ := s.GetProjectName(projectID)
+	filename := fmt.Sprintf("%s_%s_ExecutionReport_%s.xlsx", projectName, task.TaskName, time.Now().Format("20060102_150405"))
+	return buffer.Bytes(), filename, nil
+}
+
+// lookupDefect 查询NG用例关联缺陷的标题和状态，缺陷不存在或不属于该项目时返回空
+func (s *excelService) lookupDefect(projectID uint, bugID string) (string, string) {
+	if s.defectRepo == nil || strings.TrimSpace(bugID) == "" {
+		return "", ""
+	}
+	defect, err := s.defectRepo.GetByDefectID(strings.TrimSpace(bugID))
+	if err != nil || defect.ProjectID != projectID {
+		return "", ""
+	}
+	return defect.Title, defect.Status
+}
+
+// ExportTaskComparison 导出两个执行任务的对比结果(概要/对比结果两个工作表)
+// 文字按目标任务的显示语言输出，回归用例(新增失败)以红色标示
+func (s *excelService) ExportTaskComparison(projectID uint, baseTaskUUID string, targetTaskUUID string) ([]byte, string, error) {
+	cmp, err := loadTaskComparison(s.taskRepo, s.ecrRepo, projectID, baseTaskUUID, targetTaskUUID)
+	if err != nil {
+		return nil, "", err
+	}
+	target, err := s.taskRepo.GetByUUID(targetTaskUUID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get task by uuid: %w", err)
+	}
+	lang := target.DisplayLanguage
+	if lang == "" {
+		lang = "cn"
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#D3D3D3"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+	})
+	titleStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	percentStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 10})
+	wrapStyle, _ := f.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Vertical: "top", WrapText: true}})
+	regressionStyle, _ := f.NewStyle(&excelize.Style{
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#F8CBAD"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+
+	// 1. 概要
+	summarySheet := reportSheetName("sheet_summary", lang)
+	f.SetSheetName("Sheet1", summarySheet)
+	f.SetCellValue(summarySheet, "A1", reportLabel("compare_title", lang))
+	f.SetCellStyle(summarySheet, "A1", "A1", titleStyle)
+	f.SetSheetRow(summarySheet, "A3", &[]interface{}{"", reportLabel("base", lang), reportLabel("target", lang)})
+	f.SetCellStyle(summarySheet, "A3", "C3", headerStyle)
+	sides := []struct {
+		key          string
+		base, target interface{}
+	}{
+		{"task_name", cmp.Base.TaskName, cmp.Target.TaskName},
+		{"test_version", cmp.Base.TestVersion, cmp.Target.TestVersion},
+		{"test_env", cmp.Base.TestEnv, cmp.Target.TestEnv},
+		{"total", cmp.Base.Total, cmp.Target.Total},
+		{"OK", cmp.Base.OKCount, cmp.Target.OKCount},
+		{"NG", cmp.Base.NGCount, cmp.Target.NGCount},
+		{"Block", cmp.Base.BlockCount, cmp.Target.BlockCount},
+		{"NR", cmp.Base.NRCount, cmp.Target.NRCount},
+		{"pass_rate", cmp.Base.PassRate, cmp.Target.PassRate},
+	}
+	row := 4
+	for _, side := range sides {
+		f.SetSheetRow(summarySheet, fmt.Sprintf("A%d", row), &[]interface{}{reportLabel(side.key, lang), side.base, side.target})
+		if side.key == "pass_rate" {
+			f.SetCellStyle(summarySheet, fmt.Sprintf("B%d", row), fmt.Sprintf("C%d", row), percentStyle)
+		}
+		row++
+	}
+	row++
+	f.SetSheetRow(summarySheet, fmt.Sprintf("A%d", row), &[]interface{}{reportLabel("category", lang), reportLabel("count", lang)})
+	f.SetCellStyle(summarySheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	for _, category := range compareCategoryOrder {
+		row++
+		f.SetSheetRow(summarySheet, fmt.Sprintf("A%d", row), &[]interface{}{reportLabel(category, lang), cmp.Summary[category]})
+	}
+	f.SetColWidth(summarySheet, "A", "A", 24)
+	f.SetColWidth(summarySheet, "B", "C", 30)
+
+	// 2. 对比结果
+	compareSheet := reportSheetName("sheet_compare", lang)
+	f.NewSheet(compareSheet)
+	headers := []string{"category", "case_id", "case_group", "case_content", "base_result", "target_result",
+		"base_response", "target_response", "response_delta", "bug_id"}
+	for i, key := range headers {
+		f.SetCellValue(compareSheet, fmt.Sprintf("%s1", columnName(i)), reportLabel(key, lang))
+	}
+	f.SetCellStyle(compareSheet, "A1", fmt.Sprintf("%s1", columnName(len(headers)-1)), headerStyle)
+	optionalInt := func(v *int) interface{} {
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	for i, diff := range cmp.Cases {
+		r := i + 2
+		f.SetSheetRow(compareSheet, fmt.Sprintf("A%d", r), &[]interface{}{
+			reportLabel(diff.Category, lang), diff.CaseNum, diff.CaseGroupName, diff.CaseName,
+			diff.BaseResult, diff.TargetResult,
+			optionalInt(diff.BaseResponseMs), optionalInt(diff.TargetResponseMs), optionalInt(diff.ResponseDeltaMs),
+			firstNonEmpty(diff.TargetBugID, diff.BaseBugID),
+		})
+		style := wrapStyle
+		if diff.Category == CompareNewlyFailing {
+			style = regressionStyle
+		}
+		f.SetCellStyle(compareSheet, fmt.Sprintf("A%d", r), fmt.Sprintf("J%d", r), style)
+	}
+	for col, width := range map[string]float64{"A": 14, "B": 14, "C": 20, "D": 40, "E": 10, "F": 10, "G": 14, "H": 14, "I": 14, "J": 12} {
+		f.SetColWidth(compareSheet, col, col, width)
+	}
+
+	f.SetActiveSheet(0)
+	buffer, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, "", fmt.Errorf("write buffer: %w", err)
+	}
+
+	projectName, _ := s.GetProjectName(projectID)
+	filename := fmt.Sprintf("%s_%s_vs_%s_Comparison_%s.xlsx", projectName, cmp.Base.TaskName, cmp.Target.TaskName, time.Now().Format("20060102_150405"))
+	return buffer.Bytes(), filename, nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildExecutionReportData(t *testing.T) {
+	cases := []*models.ExecutionCaseResult{
+		{CaseGroupName: "登录", TestResult: "OK", ResponseTime: "120"},
+		{CaseGroupName: "登录", TestResult: "NG", ResponseTime: "380", BugID: "BUG-1"},
+		{CaseGroupName: "", TestResult: "Block", Remark: "环境未就绪\n详细日志略"},
+		{CaseGroupName: "订单", TestResult: "Block", Remark: "环境未就绪"},
+		{CaseGroupName: "订单", TestResult: "Block", Remark: ""},
+		{CaseGroupName: "订单", TestResult: "NR"},
+	}
+
+	data := buildExecutionReportData(cases)
+
+	assert.Equal(t, reportCount{Total: 6, OK: 1, NG: 1, Block: 3, NR: 1}, data.Counts)
+	assert.InDelta(t, 1.0/6, data.Counts.passRate(), 1e-9)
+	assert.InDelta(t, 5.0/6, data.Counts.executedRate(), 1e-9)
+	require.Len(t, data.Groups, 3)
+	assert.Equal(t, "登录", data.Groups[0].Name)
+	assert.Equal(t, 2, data.Groups[0].Total)
+	assert.Equal(t, "订单", data.Groups[2].Name)
+	assert.Equal(t, 2, data.Groups[2].Block)
+	assert.Equal(t, []reportReason{{Reason: "环境未就绪", Count: 2}, {Reason: "", Count: 1}}, data.BlockReasons)
+	assert.Equal(t, 250, data.AvgResponseMs)
+	assert.Equal(t, 380, data.MaxResponseMs)
+}
+
+func TestReportCaseContent(t *testing.T) {
+	manual := &models.ExecutionCaseResult{MajorFunctionCN: "用户管理", MajorFunctionJP: "ユーザー管理", MinorFunctionCN: "新增"}
+	assert.Equal(t, "用户管理 > 新增", reportCaseContent("manual", manual, "cn"))
+	assert.Equal(t, "ユーザー管理", reportCaseContent("manual", manual, "jp"))
+	assert.Equal(t, "用户管理\nユーザー管理 > 新增", reportCaseContent("manual", manual, "all"))
+
+	api := &models.ExecutionCaseResult{Method: "GET", URL: "/api/users"}
+	assert.Equal(t, "GET /api/users", reportCaseContent("api", api, "en"))
+
+	assert.Equal(t, "Pass Rate", reportLabel("pass_rate", "en"))
+	assert.Equal(t, "通过率 / 合格率 / Pass Rate", reportLabel("pass_rate", "all"))
+	assert.Equal(t, "Summary", reportSheetName("sheet_summary", "all"))
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// scheduleTickInterval 调度器检查到期计划的间隔
+const scheduleTickInterval = 30 * time.Second
+
+// 即将执行/历史列表的默认条数和上限
+const (
+	defaultScheduleListLimit = 20
+	maxScheduleListLimit     = 200
+)
+
+// CreateScheduleRequest 创建定时执行计划请求
+type CreateScheduleRequest struct {
+	CronExpr string `json:"cron_expr" binding:"required,max=100"` // 5段Cron表达式或 @daily 等宏
+	Enabled  *bool  `json:"enabled"`                              // 默认启用
+}
+
+// UpdateScheduleRequest 更新定时执行计划请求
+type UpdateScheduleRequest struct {
+	CronExpr *string `json:"cron_expr" binding:"omitempty,max=100"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// UpcomingScheduledRun 即将触发的定时执行
+type UpcomingScheduledRun struct {
+	ScheduleID uint      `json:"schedule_id"`
+	TaskUUID   string    `json:"task_uuid"`
+	TaskName   string    `json:"task_name"`
+	CronExpr   string    `json:"cron_expr"`
+	FireAt     time.Time `json:"fire_at"`
+}
+
+// ExecutionScheduleService 定时执行计划服务，内置调度器按Cron表达式触发执行任务
+type ExecutionScheduleService interface {
+	ListSchedules(projectID uint) ([]*models.ExecutionSchedule, error)
+	CreateSchedule(projectID uint, userID uint, taskUUID string, req CreateScheduleRequest) (*models.ExecutionSchedule, error)
+	UpdateSchedule(projectID uint, scheduleID uint, req UpdateScheduleRequest) (*models.ExecutionSchedule, error)
+	DeleteSchedule(projectID uint, scheduleID uint) error
+	ListUpcoming(projectID uint, limit int) ([]UpcomingScheduledRun, error)
+	ListHistory(projectID uint, limit int) ([]*models.ExecutionRun, error)
+
+	// Start 启动后台调度器；Stop 停止调度器(不影响已创建的执行批次)
+	Start()
+	Stop()
+}
+
+type executionScheduleService struct {
+	repo        repositories.ExecutionScheduleRepository
+	taskRepo    repositories.ExecutionTaskRepository
+	runRepo     repositories.ExecutionRunRepository
+	taskService ExecutionTaskService // 创建执行批次
+
+	instanceID string // 本服务实例标识，记录在计划的最近触发实例中
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	startOnce  sync.Once
+	stopOnce   sync.Once
+}
+
+// NewExecutionScheduleService 创建定时执行计划服务实例
+func NewExecutionScheduleService(
+	repo repositories.ExecutionScheduleRepository,
+	taskRepo repositories.ExecutionTaskRepository,
+	runRepo repositories.ExecutionRunRepository,
+	taskService ExecutionTaskService,
+) ExecutionScheduleService {
+	return &executionScheduleService{
+		repo:        repo,
+		taskRepo:    taskRepo,
+		runRepo:     runRepo,
+		taskService: taskService,
+		instanceID:  serviceInstanceID(),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}
+}
+
+// ListSchedules 获取项目的定时执行计划
+func (s *executionScheduleService) ListSchedules(projectID uint) ([]*models.ExecutionSchedule, error) {
+	schedules, err := s.repo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// CreateSchedule 为自动化/API执行任务创建定时执行计划
+func (s *executionScheduleService) CreateSchedule(projectID uint, userID uint, taskUUID string, req CreateScheduleRequest) (*models.ExecutionSchedule, error) {
+	task, err := s.getProjectTask(projectID, taskUUID)
+	if err != nil {
+		return nil, err
+	}
+	if task.ExecutionType == "manual" {
+		return nil, errors.New("手工测试类型不支持定时执行")
+	}
+	cron, err := parseScheduleCron(req.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &models.ExecutionSchedule{
+		ProjectID: projectID,
+		TaskUUID:  taskUUID,
+		CronExpr:  strings.TrimSpace(req.CronExpr),
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedBy: userID,
+	}
+	if schedule.Enabled {
+		schedule.NextRunAt = nextFireTime(cron, time.Now())
+	}
+	if err := s.repo.Create(schedule); err != nil {
+		return nil, fmt.Errorf("create schedule: %w", err)
+	}
+	if !schedule.Enabled {
+		// enabled 字段有默认值，停用时需显式更新
+		if err := s.repo.Update(schedule.ID, map[string]interface{}{"enabled": false}); err != nil {
+			return nil, fmt.Errorf("disable schedule: %w", err)
+		}
+	}
+	fmt.Printf("[Schedule] 创建执行计划: id=%d, task_uuid=%s, cron=%s\n", schedule.ID, taskUUID, schedule.CronExpr)
+	return schedule, nil
+}
+
+// UpdateSchedule 更新Cron表达式或启停状态，并重新计算下次触发时间
+func (s *executionScheduleService) UpdateSchedule(projectID uint, scheduleID uint, req UpdateScheduleRequest) (*models.ExecutionSchedule, error) {
+	schedule, err := s.getProjectSchedule(projectID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	cronExpr := schedule.CronExpr
+	if req.CronExpr != nil {
+		cronExpr = strings.TrimSpace(*req.CronExpr)
+	}
+	enabled := schedule.Enabled
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	cron, err := parseScheduleCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"cron_expr":   cronExpr,
+		"enabled":     enabled,
+		"next_run_at": nil,
+	}
+	if enabled {
+		updates["next_run_at"] = nextFireTime(cron, time.Now())
+	}
+	if err := s.repo.Update(scheduleID, updates); err != nil {
+		return nil, fmt.Errorf("update schedule: %w", err)
+	}
+	return s.repo.GetByID(scheduleID)
+}
+
+// DeleteSchedule 删除定时执行计划，已触发的执行批次保留
+func (s *executionScheduleService) DeleteSchedule(projectID uint, scheduleID uint) error {
+	if _, err := s.getProjectSchedule(projectID, scheduleID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(scheduleID); err != nil {
+		return fmt.Errorf("delete schedule: %w", err)
+	}
+	return nil
+}
+
+// ListUpcoming 按时间顺序列出项目中即将触发的定时执行
+func (s *executionScheduleService) ListUpcoming(projectID uint, limit int) ([]UpcomingScheduledRun, error) {
+	limit = clampScheduleLimit(limit)
+	schedules, err := s.repo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+
+	upcoming := make([]UpcomingScheduledRun, 0, limit)
+	for _, schedule := range schedules {
+		if !schedule.Enabled || schedule.NextRunAt == nil {
+			continue
+		}
+		cron, err := ParseCron(schedule.CronExpr)
+		if err != nil {
+			continue
+		}
+		taskName := ""
+		if task, err := s.taskRepo.GetByUUID(schedule.TaskUUID); err == nil {
+			taskName = task.TaskName
+		}
+
+		// 每个计划最多展开 limit 次，合并后再截取
+		fireAt := schedule.NextRunAt.In(time.Local)
+		for i := 0; i < limit && !fireAt.IsZero(); i++ {
+			upcoming = append(upcoming, UpcomingScheduledRun{
+				ScheduleID: schedule.ID,
+				TaskUUID:   schedule.TaskUUID,
+				TaskName:   taskName,
+				CronExpr:   schedule.CronExpr,
+				FireAt:     fireAt,
+			})
+			fireAt = cron.Next(fireAt)
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].FireAt.Before(upcoming[j].FireAt)
+	})
+	if len(upcoming) > limit {
+		upcoming = upcoming[:limit]
+	}
+	return upcoming, nil
+}
+
+// ListHistory 获取项目中由定时计划触发的执行批次
+func (s *executionScheduleService) ListHistory(projectID uint, limit int) ([]*models.ExecutionRun, error) {
+	runs, err := s.runRepo.GetScheduledByProject(projectID, clampScheduleLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("list scheduled runs: %w", err)
+	}
+	return runs, nil
+}
+
+// Start 启动后台调度器，启动时立即检查一次(服务停机期间错过的计划补触发一次)
+func (s *executionScheduleService) Start() {
+	s.startOnce.Do(func() {
+		fmt.Printf("[Scheduler] 调度器启动: instance=%s, interval=%v\n", s.instanceID, scheduleTickInterval)
+		go func() {
+			defer close(s.doneCh)
+			ticker := time.NewTicker(scheduleTickInterval)
+			defer ticker.Stop()
+
+			s.tick(time.Now())
+			for {
+				select {
+				case now := <-ticker.C:
+					s.tick(now)
+				case <-s.stopCh:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止后台调度器并等待当前检查结束
+func (s *executionScheduleService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+	})
+	s.startOnce.Do(func() { close(s.doneCh) }) // 未启动时无需等待
+	<-s.doneCh
+}
+
+// tick 触发所有到期的计划
+func (s *executionScheduleService) tick(now time.Time) {
+	due, err := s.repo.GetDue(now.UTC())
+	if err != nil {
+		fmt.Printf("[Scheduler] ❌ 查询到期计划失败: %v\n", err)
+		return
+	}
+	for _, schedule := range due {
+		s.fire(schedule, now)
+	}
+}
+
+// fire 抢占并触发一次计划
+// 先以乐观锁推进下次触发时间，抢占成功的实例才执行，保证多实例部署时每次只触发一次
+func (s *executionScheduleService) fire(schedule *models.ExecutionSchedule, now time.Time) {
+	claim := map[string]interface{}{
+		"last_run_at":   now.UTC(),
+		"last_fired_by": s.instanceID,
+		"last_run_uuid": "",
+		"last_error":    "",
+	}
+	cron, err := ParseCron(schedule.CronExpr)
+	if err != nil {
+		// 表达式已失效(如手工修改数据库)，停用计划
+		claim["enabled"] = false
+		claim["next_run_at"] = nil
+		claim["last_error"] = fmt.Sprintf("Cron表达式格式错误: %v", err)
+	} else {
+		// 从当前时间计算下次触发，停机期间错过的多次触发只补一次
+		claim["next_run_at"] = nextFireTime(cron, now)
+	}
+
+	claimed, err := s.repo.Claim(schedule.ID, schedule.Version, claim)
+	if err != nil {
+		fmt.Printf("[Scheduler] ❌ 抢占计划失败: id=%d, error=%v\n", schedule.ID, err)
+		return
+	}
+	if !claimed || cron == nil {
+		return
+	}
+
+	fmt.Printf("[Scheduler] 触发执行计划: id=%d, task_uuid=%s, cron=%s\n", schedule.ID, schedule.TaskUUID, schedule.CronExpr)
+	run, err := s.runSchedule(schedule)
+	result := map[string]interface{}{}
+	if err != nil {
+		fmt.Printf("[Scheduler] ❌ 定时执行失败: id=%d, error=%v\n", schedule.ID, err)
+		result["last_error"] = err.Error()
+	} else {
+		result["last_run_uuid"] = run.RunUUID
+	}
+	if err := s.repo.Update(schedule.ID, result); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("[Scheduler] ❌ 更新计划触发结果失败: id=%d, error=%v\n", schedule.ID, err)
+	}
+}
+
+// runSchedule 创建执行批次，与手动执行相同在 startTaskRun 中检查执行中批次并原地重置用例结果
+// 任务已删除时一并删除计划；任务仍在执行中时跳过本次触发
+func (s *executionScheduleService) runSchedule(schedule *models.ExecutionSchedule) (*models.ExecutionRun, error) {
+	task, err := s.taskRepo.GetByUUID(schedule.TaskUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if delErr := s.repo.Delete(schedule.ID); delErr != nil {
+				fmt.Printf("[Scheduler] ❌ 删除失效计划失败: id=%d, error=%v\n", schedule.ID, delErr)
+			}
+			return nil, errors.New("任务不存在")
+		}
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+
+	return s.taskService.ExecuteScheduledTask(task.ProjectID, schedule.CreatedBy, task.TaskUUID, schedule.ID)
+}
+
+// getProjectTask 获取任务并校验项目归属
+func (s *executionScheduleService) getProjectTask(projectID uint, taskUUID string) (*models.ExecutionTask, error) {
+	task, err := s.taskRepo.GetByUUID(taskUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("任务不存在")
+		}
+		return nil, fmt.Errorf("get task by uuid: %w", err)
+	}
+	if task.ProjectID != projectID {
+		return nil, errors.New("任务不属于该项目")
+	}
+	return task, nil
+}
+
+// getProjectSchedule 获取执行计划并校验项目归属
+func (s *executionScheduleService) getProjectSchedule(projectID uint, scheduleID uint) (*models.ExecutionSchedule, error) {
+	schedule, err := s.repo.GetByID(scheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行计划不存在")
+		}
+		return nil, fmt.Errorf("get schedule: %w", err)
+	}
+	if schedule.ProjectID != projectID {
+		return nil, errors.New("执行计划不属于该项目")
+	}
+	return schedule, nil
+}
+
+// parseScheduleCron 解析Cron表达式，并要求在查找范围内能够触发
+func parseScheduleCron(expr string) (*CronSchedule, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Cron表达式格式错误: %v", err)
+	}
+	if cron.Next(time.Now()).IsZero() {
+		return nil, errors.New("Cron表达式格式错误: 不会触发")
+	}
+	return cron, nil
+}
+
+// nextFireTime 按服务器时区计算下次触发时间，以UTC保存
+func nextFireTime(cron *CronSchedule, now time.Time) *time.Time {
+	next := cron.Next(now.In(time.Local))
+	if next.IsZero() {
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// clampScheduleLimit 限制列表条数
+func clampScheduleLimit(limit int) int {
+	if limit <= 0 {
+		return defaultScheduleListLimit
+	}
+	if limit > maxScheduleListLimit {
+		return maxScheduleListLimit
+	}
+	return limit
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 任务对比中用例的分类
+const (
+	CompareNewlyFailing = "newly_failing" // 基准非NG，目标NG
+	CompareNewlyPassing = "newly_passing" // 基准NG，目标OK
+	CompareStillFailing = "still_failing" // 基准和目标均为NG
+	CompareAdded        = "added"         // 仅目标任务中存在
+	CompareRemoved      = "removed"       // 仅基准任务中存在
+	CompareChanged      = "changed"       // 其他结果变化(如OK→Block)
+	CompareUnchanged    = "unchanged"     // 结果相同
+)
+
+// compareCategoryOrder 对比结果的排序优先级，回归问题排在最前
+var compareCategoryOrder = []string{
+	CompareNewlyFailing, CompareStillFailing, CompareChanged, CompareNewlyPassing,
+	CompareAdded, CompareRemoved, CompareUnchanged,
+}
+
+// TaskCompareSide 参与对比的任务概况
+type TaskCompareSide struct {
+	TaskUUID      string  `json:"task_uuid"`
+	TaskName      string  `json:"task_name"`
+	ExecutionType string  `json:"execution_type"`
+	TestVersion   string  `json:"test_version"`
+	TestEnv       string  `json:"test_env"`
+	Total         int     `json:"total"`
+	OKCount       int     `json:"ok_count"`
+	NGCount       int     `json:"ng_count"`
+	BlockCount    int     `json:"block_count"`
+	NRCount       int     `json:"nr_count"`
+	PassRate      float64 `json:"pass_rate"` // OK/总数
+}
+
+// TaskCaseDiff 两个任务中同一用例(按CaseID匹配)的结果对比
+type TaskCaseDiff struct {
+	CaseID           string `json:"case_id"`
+	CaseNum          string `json:"case_num"`
+	CaseGroupName    string `json:"case_group_name"`
+	CaseName         string `json:"case_name"`
+	Category         string `json:"category"`
+	BaseResult       string `json:"base_result"`   // 仅目标任务中存在时为空
+	TargetResult     string `json:"target_result"` // 仅基准任务中存在时为空
+	BaseResponseMs   *int   `json:"base_response_ms,omitempty"`
+	TargetResponseMs *int   `json:"target_response_ms,omitempty"`
+	ResponseDeltaMs  *int   `json:"response_delta_ms,omitempty"` // 目标-基准，两侧均有响应时间时计算
+	BaseBugID        string `json:"base_bug_id,omitempty"`
+	TargetBugID      string `json:"target_bug_id,omitempty"`
+}
+
+// TaskComparison 两个执行任务的对比结果
+type TaskComparison struct {
+	Base    TaskCompareSide `json:"base"`
+	Target  TaskCompareSide `json:"target"`
+	Summary map[string]int  `json:"summary"` // 分类 -> 用例数
+	Cases   []TaskCaseDiff  `json:"cases"`   // 按分类优先级排序
+}
+
+// CompareTasks 对比两个执行任务的用例结果(如同一用例集在两个测试版本上的执行)
+func (s *executionTaskService) CompareTasks(projectID uint, baseTaskUUID string, targetTaskUUID string) (*TaskComparison, error) {
+	return loadTaskComparison(s.repo, s.ecrRepo, projectID, baseTaskUUID, targetTaskUUID)
+}
+
+// loadTaskComparison 读取两个任务及其用例结果并生成对比，供接口和Excel导出共用
+func loadTaskComparison(
+	taskRepo repositories.ExecutionTaskRepository,
+	ecrRepo repositories.ExecutionCaseResultRepository,
+	projectID uint, baseTaskUUID string, targetTaskUUID string,
+) (*TaskComparison, error) {
+	if baseTaskUUID == targetTaskUUID {
+		return nil, errors.New("不能对比同一个任务")
+	}
+
+	loadTask := func(taskUUID string) (*models.ExecutionTask, []*models.ExecutionCaseResult, error) {
+		task, err := taskRepo.GetByUUID(taskUUID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil, errors.New("任务不存在")
+			}
+			return nil, nil, fmt.Errorf("get task by uuid: %w", err)
+		}
+		if task.ProjectID != projectID {
+			return nil, nil, errors.New("任务不属于该项目")
+		}
+		cases, err := ecrRepo.GetByTaskUUID(taskUUID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get case results: %w", err)
+		}
+		return task, cases, nil
+	}
+
+	baseTask, baseCases, err := loadTask(baseTaskUUID)
+	if err != nil {
+		return nil, err
+	}
+	targetTask, targetCases, err := loadTask(targetTaskUUID)
+	if err != nil {
+		return nil, err
+	}
+	if baseTask.ExecutionType != targetTask.ExecutionType {
+		return nil, errors.New("执行类型不一致，无法对比")
+	}
+	return compareTaskResults(baseTask, baseCases, targetTask, targetCases), nil
+}
+
+// compareTaskResults 按CaseID匹配两个任务的用例结果并分类
+// 用例名称按目标任务的显示语言生成，手工用例不计算响应时间差
+func compareTaskResults(
+	baseTask *models.ExecutionTask, baseCases []*models.ExecutionCaseResult,
+	targetTask *models.ExecutionTask, targetCases []*models.ExecutionCaseResult,
+) *TaskComparison {
+	lang := targetTask.DisplayLanguage
+	withTiming := targetTask.ExecutionType != "manual"
+
+	comparison := &TaskComparison{
+		Base:    newTaskCompareSide(baseTask, baseCases),
+		Target:  newTaskCompareSide(targetTask, targetCases),
+		Summary: make(map[string]int, len(compareCategoryOrder)),
+		Cases:   make([]TaskCaseDiff, 0, len(targetCases)),
+	}
+	for _, category := range compareCategoryOrder {
+		comparison.Summary[category] = 0
+	}
+
+	baseByID := make(map[string]*models.ExecutionCaseResult, len(baseCases))
+	for _, c := range baseCases {
+		baseByID[c.CaseID] = c
+	}
+	matched := make(map[string]bool, len(targetCases))
+
+	newDiff := func(c *models.ExecutionCaseResult, executionType string) TaskCaseDiff {
+		return TaskCaseDiff{
+			CaseID:        c.CaseID,
+			CaseNum:       c.CaseNum,
+			CaseGroupName: c.CaseGroupName,
+			CaseName:      reportCaseContent(executionType, c, lang),
+		}
+	}
+
+	for _, t := range targetCases {
+		diff := newDiff(t, targetTask.ExecutionType)
+		diff.TargetResult = t.TestResult
+		diff.TargetBugID = t.BugID
+		if withTiming {
+			diff.TargetResponseMs = parseResponseMs(t.ResponseTime)
+		}
+
+		b, ok := baseByID[t.CaseID]
+		if !ok {
+			diff.Category = CompareAdded
+		} else {
+			matched[t.CaseID] = true
+			diff.BaseResult = b.TestResult
+			diff.BaseBugID = b.BugID
+			diff.Category = classifyCaseChange(b.TestResult, t.TestResult)
+			if withTiming {
+				diff.BaseResponseMs = parseResponseMs(b.ResponseTime)
+				if diff.BaseResponseMs != nil && diff.TargetResponseMs != nil {
+					delta := *diff.TargetResponseMs - *diff.BaseResponseMs
+					diff.ResponseDeltaMs = &delta
+				}
+			}
+		}
+		comparison.Cases = append(comparison.Cases, diff)
+	}
+	for _, b := range baseCases {
+		if matched[b.CaseID] {
+			continue
+		}
+		diff := newDiff(b, baseTask.ExecutionType)
+		diff.Category = CompareRemoved
+		diff.BaseResult = b.TestResult
+		diff.BaseBugID = b.BugID
+		if withTiming {
+			diff.BaseResponseMs = parseResponseMs(b.ResponseTime)
+		}
+		comparison.Cases = append(comparison.Cases, diff)
+	}
+
+	// 按分类优先级稳定排序，同一分类内保持目标任务的用例顺序
+	ordered := make([]TaskCaseDiff, 0, len(comparison.Cases))
+	for _, category := range compareCategoryOrder {
+		for _, diff := range comparison.Cases {
+			if diff.Category == category {
+				ordered = append(ordered, diff)
+				comparison.Summary[category]++
+			}
+		}
+	}
+	comparison.Cases = ordered
+	return comparison
+}
+
+// classifyCaseChange 根据基准和目标的测试结果判断用例分类
+func classifyCaseChange(base string, target string) string {
+	switch {
+	case base == "NG" && target == "NG":
+		return CompareStillFailing
+	case target == "NG":
+		return CompareNewlyFailing
+	case base == "NG" && target == "OK":
+		return CompareNewlyPassing
+	case base == target:
+		return CompareUnchanged
+	default:
+		return CompareChanged
+	}
+}
+
+// parseResponseMs 解析用例结果中的响应时间(毫秒)，未记录时返回nil
+func parseResponseMs(value string) *int {
+	ms, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || ms < 0 {
+		return nil
+	}
+	return &ms
+}
+
+// newTaskCompareSide 汇总任务的结果统计
+func newTaskCompareSide(task *models.ExecutionTask, cases []*models.ExecutionCaseResult) TaskCompareSide {
+	var counts reportCount
+	for _, c := range cases {
+		counts.add(c.TestResult)
+	}
+	return TaskCompareSide{
+		TaskUUID:      task.TaskUUID,
+		TaskName:      task.TaskName,
+		ExecutionType: task.ExecutionType,
+		TestVersion:   task.TestVersion,
+		TestEnv:       task.TestEnv,
+		Total:         counts.Total,
+		OKCount:       counts.OK,
+		NGCount:       counts.NG,
+		BlockCount:    counts.Block,
+		NRCount:       counts.NR,
+		PassRate:      counts.passRate(),
+	}
+}
+
+// RenderComparisonMarkdown 将任务对比结果渲染为Markdown，可直接作为AI报告的输入或内容
+func RenderComparisonMarkdown(cmp *TaskComparison) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 执行任务对比: %s → %s\n\n", cmp.Base.TaskName, cmp.Target.TaskName)
+
+	b.WriteString("| 项目 | 基准 | 目标 |\n|---|---|---|\n")
+	rows := [][3]string{
+		{"任务", cmp.Base.TaskName, cmp.Target.TaskName},
+		{"测试版本", cmp.Base.TestVersion, cmp.Target.TestVersion},
+		{"测试环境", cmp.Base.TestEnv, cmp.Target.TestEnv},
+		{"用例总数", strconv.Itoa(cmp.Base.Total), strconv.Itoa(cmp.Target.Total)},
+		{"OK/NG/Block/NR",
+			fmt.Sprintf("%d/%d/%d/%d", cmp.Base.OKCount, cmp.Base.NGCount, cmp.Base.BlockCount, cmp.Base.NRCount),
+			fmt.Sprintf("%d/%d/%d/%d", cmp.Target.OKCount, cmp.Target.NGCount, cmp.Target.BlockCount, cmp.Target.NRCount)},
+		{"通过率", fmt.Sprintf("%.1f%%", cmp.Base.PassRate*100), fmt.Sprintf("%.1f%%", cmp.Target.PassRate*100)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", row[0], markdownCell(row[1]), markdownCell(row[2]))
+	}
+
+	b.WriteString("\n## 分类统计\n\n| 分类 | 用例数 |\n|---|---|\n")
+	for _, category := range compareCategoryOrder {
+		fmt.Fprintf(&b, "| %s | %d |\n", category, cmp.Summary[category])
+	}
+
+	for _, category := range compareCategoryOrder {
+		if category == CompareUnchanged || cmp.Summary[category] == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n| 用例编号 | 用例集 | 用例 | 基准结果 | 目标结果 | 响应时间差(ms) | 缺陷ID |\n|---|---|---|---|---|---|---|\n", category)
+		for _, diff := range cmp.Cases {
+			if diff.Category != category {
+				continue
+			}
+			delta := ""
+			if diff.ResponseDeltaMs != nil {
+				delta = fmt.Sprintf("%+d", *diff.ResponseDeltaMs)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(diff.CaseNum), markdownCell(diff.CaseGroupName), markdownCell(diff.CaseName),
+				diff.BaseResult, diff.TargetResult, delta, markdownCell(firstNonEmpty(diff.TargetBugID, diff.BaseBugID)))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell 转义表格单元格中的竖线和换行
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareTaskResults_Classification(t *testing.T) {
+	baseTask := &models.ExecutionTask{TaskUUID: "b", TaskName: "v1.0", ExecutionType: "api", TestVersion: "1.0"}
+	targetTask := &models.ExecutionTask{TaskUUID: "t", TaskName: "v1.1", ExecutionType: "api", TestVersion: "1.1"}
+	baseCases := []*models.ExecutionCaseResult{
+		{CaseID: "c1", TestResult: "OK", ResponseTime: "100", Method: "GET", URL: "/a"},
+		{CaseID: "c2", TestResult: "NG", ResponseTime: "200"},
+		{CaseID: "c3", TestResult: "NG", BugID: "BUG-3"},
+		{CaseID: "c4", TestResult: "OK"},
+		{CaseID: "c5", TestResult: "OK"},
+		{CaseID: "c6", TestResult: "OK", ResponseTime: "50"},
+	}
+	targetCases := []*models.ExecutionCaseResult{
+		{CaseID: "c1", TestResult: "NG", ResponseTime: "350", Method: "GET", URL: "/a"},
+		{CaseID: "c2", TestResult: "OK", ResponseTime: "150"},
+		{CaseID: "c3", TestResult: "NG"},
+		{CaseID: "c4", TestResult: "Block"},
+		{CaseID: "c6", TestResult: "OK", ResponseTime: "80"},
+		{CaseID: "c7", TestResult: "NR"},
+	}
+
+	cmp := compareTaskResults(baseTask, baseCases, targetTask, targetCases)
+
+	assert.Equal(t, map[string]int{
+		CompareNewlyFailing: 1, CompareStillFailing: 1, CompareChanged: 1, CompareNewlyPassing: 1,
+		CompareAdded: 1, CompareRemoved: 1, CompareUnchanged: 1,
+	}, cmp.Summary)
+	require.Len(t, cmp.Cases, 7)
+
+	first := cmp.Cases[0]
+	assert.Equal(t, "c1", first.CaseID)
+	assert.Equal(t, CompareNewlyFailing, first.Category)
+	assert.Equal(t, "GET /a", first.CaseName)
+	require.NotNil(t, first.ResponseDeltaMs)
+	assert.Equal(t, 250, *first.ResponseDeltaMs)
+
+	assert.Equal(t, CompareStillFailing, cmp.Cases[1].Category)
+	assert.Equal(t, "BUG-3", cmp.Cases[1].BaseBugID)
+	assert.Equal(t, "c4", cmp.Cases[2].CaseID)
+	assert.Equal(t, "c5", cmp.Cases[5].CaseID)
+	assert.Equal(t, CompareRemoved, cmp.Cases[5].Category)
+	assert.Empty(t, cmp.Cases[5].TargetResult)
+
+	assert.Equal(t, 2, cmp.Base.NGCount)
+	assert.InDelta(t, 2.0/6, cmp.Target.PassRate, 1e-9)
+}
+
+func TestCompareTaskResults_ManualHasNoTiming(t *testing.T) {
+	task := &models.ExecutionTask{ExecutionType: "manual"}
+	cmp := compareTaskResults(task,
+		[]*models.ExecutionCaseResult{{CaseID: "c1", TestResult: "OK", ResponseTime: "10"}},
+		task,
+		[]*models.ExecutionCaseResult{{CaseID: "c1", TestResult: "OK", ResponseTime: "20"}})
+
+	require.Len(t, cmp.Cases, 1)
+	assert.Nil(t, cmp.Cases[0].ResponseDeltaMs)
+	assert.Equal(t, CompareUnchanged, cmp.Cases[0].Category)
+}
+
+func TestRenderComparisonMarkdown(t *testing.T) {
+	task := &models.ExecutionTask{TaskName: "v1", ExecutionType: "api"}
+	cmp := compareTaskResults(task,
+		[]*models.ExecutionCaseResult{{CaseID: "c1", CaseNum: "API-1", TestResult: "OK", ResponseTime: "100"}},
+		&models.ExecutionTask{TaskName: "v2", ExecutionType: "api"},
+		[]*models.ExecutionCaseResult{{CaseID: "c1", CaseNum: "API-1", TestResult: "NG", ResponseTime: "90", Method: "GET", URL: "/a|b"}})
+
+	md := RenderComparisonMarkdown(cmp)
+	assert.Contains(t, md, "# 执行任务对比: v1 → v2")
+	assert.Contains(t, md, "## newly_failing")
+	assert.Contains(t, md, "| API-1 |  | GET /a\\|b | OK | NG | -10 |  |")
+	assert.False(t, strings.Contains(md, "## unchanged"))
+}
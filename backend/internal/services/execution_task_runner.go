@@ -0,0 +1,1080 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"webtest/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// cancelWaitTimeout 取消批次时等待后台协程退出的最长时间
+const cancelWaitTimeout = 10 * time.Second
+
+// 批次租约：持有实例定期续期，实例退出后租约到期，由其他实例(或重启后的实例)接管
+const (
+	runLeaseDuration      = time.Minute
+	runLeaseRenewInterval = runLeaseDuration / 3
+)
+
+// serviceInstanceID 本服务实例标识(主机名-进程号)
+func serviceInstanceID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// activeRun 本进程内正在执行的批次句柄
+type activeRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	running map[uint]bool // 正在执行的用例结果ID(并发执行时可能有多条)
+}
+
+// setRunning 标记用例是否正在执行
+func (h *activeRun) setRunning(caseResultID uint, running bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if running {
+		h.running[caseResultID] = true
+	} else {
+		delete(h.running, caseResultID)
+	}
+}
+
+// isRunning 用例是否正在执行
+func (h *activeRun) isRunning(caseResultID uint) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.running[caseResultID]
+}
+
+// RunCaseProgress 执行批次中单条用例的进度
+type RunCaseProgress struct {
+	CaseResultID  uint   `json:"case_result_id"`
+	DisplayID     uint   `json:"display_id"`
+	CaseNum       string `json:"case_num"`
+	TestResult    string `json:"test_result"`
+	ResponseTime  string `json:"response_time"`
+	ExtractedVars string `json:"extracted_vars,omitempty"` // 本用例提取的变量(脱敏)
+	Running       bool   `json:"running"`
+}
+
+// ExecutionRunDetail 执行批次详情(含用例当前结果)
+type ExecutionRunDetail struct {
+	*models.ExecutionRun
+	Summary *ExecuteTaskResult `json:"summary,omitempty"` // 批次结束后的统计
+	Cases   []RunCaseProgress  `json:"cases"`
+}
+
+// ListRuns 获取任务的执行批次列表
+func (s *executionTaskService) ListRuns(projectID uint, taskUUID string) ([]*models.ExecutionRun, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	runs, err := s.runRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetRun 获取执行批次详情，供前端轮询进度
+func (s *executionTaskService) GetRun(projectID uint, taskUUID string, runUUID string) (*ExecutionRunDetail, error) {
+	run, err := s.getTaskRun(projectID, taskUUID, runUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results: %w", err)
+	}
+
+	s.runsMu.Lock()
+	handle := s.activeRuns[runUUID]
+	s.runsMu.Unlock()
+
+	detail := &ExecutionRunDetail{
+		ExecutionRun: run,
+		Cases:        make([]RunCaseProgress, 0, len(cases)),
+	}
+	if !run.IsActive() && run.FinishedAt != nil {
+		detail.Summary = &ExecuteTaskResult{
+			Total:      run.Total,
+			OKCount:    run.OKCount,
+			NGCount:    run.NGCount,
+			BlockCount: run.BlockCount,
+			ExecutedAt: *run.FinishedAt,
+			ExecutedBy: run.ExecutedBy,
+		}
+	}
+	for _, c := range cases {
+		detail.Cases = append(detail.Cases, RunCaseProgress{
+			CaseResultID:  c.ID,
+			DisplayID:     c.DisplayID,
+			CaseNum:       c.CaseNum,
+			TestResult:    c.TestResult,
+			ResponseTime:  c.ResponseTime,
+			ExtractedVars: c.ExtractedVars,
+			Running:       handle != nil && handle.isRunning(c.ID),
+		})
+	}
+	return detail, nil
+}
+
+// CancelRun 取消执行批次
+// 本进程内的批次通过context取消并等待后台协程收尾；其他情况直接标记为已取消
+func (s *executionTaskService) CancelRun(projectID uint, userID uint, taskUUID string, runUUID string) (*models.ExecutionRun, error) {
+	run, err := s.getTaskRun(projectID, taskUUID, runUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !run.IsActive() {
+		return nil, errors.New("执行批次已结束")
+	}
+	fmt.Printf("[CancelRun] 取消执行批次: run_uuid=%s, userID=%d\n", runUUID, userID)
+
+	s.runsMu.Lock()
+	handle, ok := s.activeRuns[runUUID]
+	s.runsMu.Unlock()
+
+	if ok {
+		handle.cancel()
+		select {
+		case <-handle.done:
+		case <-time.After(cancelWaitTimeout):
+			fmt.Printf("[CancelRun] ⚠️ 等待批次退出超时: run_uuid=%s\n", runUUID)
+		}
+	} else {
+		s.finishRun(runUUID, models.ExecutionRunStatusCancelled, "")
+	}
+
+	return s.runRepo.GetByUUID(runUUID)
+}
+
+// ResumeRuns 恢复服务重启前未完成的执行批次，继续执行仍为NR的用例
+// 只恢复本实例以条件更新接管到租约的批次，并启动后台续租：
+// 定期续期本实例持有的租约，同时接管其他实例退出后租约到期的批次
+func (s *executionTaskService) ResumeRuns() error {
+	if err := s.claimOrphanRuns(); err != nil {
+		return err
+	}
+	s.leaseOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(runLeaseRenewInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				s.renewRunLeases()
+				if err := s.claimOrphanRuns(); err != nil {
+					fmt.Printf("[ResumeRuns] ❌ 接管执行批次失败: %v\n", err)
+				}
+			}
+		}()
+	})
+	return nil
+}
+
+// claimOrphanRuns 接管租约已到期的未完成批次并在本实例继续执行
+func (s *executionTaskService) claimOrphanRuns() error {
+	runs, err := s.runRepo.GetActive()
+	if err != nil {
+		return fmt.Errorf("get active runs: %w", err)
+	}
+
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	now := time.Now()
+	for _, run := range runs {
+		if _, ok := s.activeRuns[run.RunUUID]; ok {
+			continue
+		}
+		if run.LeaseUntil != nil && run.LeaseUntil.After(now) {
+			continue
+		}
+		until := now.Add(runLeaseDuration)
+		claimed, err := s.runRepo.ClaimLease(run.RunUUID, s.instanceID, now, until)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		run.Owner = s.instanceID
+		run.LeaseUntil = &until
+		fmt.Printf("[ResumeRuns] 恢复执行批次: run_uuid=%s, task_uuid=%s, instance=%s\n", run.RunUUID, run.TaskUUID, s.instanceID)
+		s.startRunLocked(run)
+	}
+	return nil
+}
+
+// renewRunLeases 续期本实例正在执行的批次租约
+func (s *executionTaskService) renewRunLeases() {
+	s.runsMu.Lock()
+	runUUIDs := make([]string, 0, len(s.activeRuns))
+	for runUUID := range s.activeRuns {
+		runUUIDs = append(runUUIDs, runUUID)
+	}
+	s.runsMu.Unlock()
+
+	if err := s.runRepo.RenewLeases(runUUIDs, s.instanceID, time.Now().Add(runLeaseDuration)); err != nil {
+		fmt.Printf("[ResumeRuns] ❌ 续期批次租约失败: %v\n", err)
+	}
+}
+
+// leaseRun 标记新建批次由本实例持有
+func (s *executionTaskService) leaseRun(run *models.ExecutionRun) {
+	until := time.Now().Add(runLeaseDuration)
+	run.Owner = s.instanceID
+	run.LeaseUntil = &until
+}
+
+// startRunLocked 在后台协程中执行批次，调用方需持有 runsMu
+func (s *executionTaskService) startRunLocked(run *models.ExecutionRun) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := &activeRun{cancel: cancel, done: make(chan struct{}), running: make(map[uint]bool)}
+	s.activeRuns[run.RunUUID] = handle
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Printf("[processRun] ❌ 批次异常: run_uuid=%s, panic=%v\n", run.RunUUID, r)
+				s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, fmt.Sprintf("panic: %v", r))
+			}
+			s.runsMu.Lock()
+			delete(s.activeRuns, run.RunUUID)
+			s.runsMu.Unlock()
+			cancel()
+			close(handle.done)
+		}()
+		s.processRun(ctx, run, handle)
+	}()
+}
+
+// stopTaskRuns 取消任务在本进程内的所有批次(删除任务前调用)
+func (s *executionTaskService) stopTaskRuns(taskUUID string) {
+	runs, err := s.runRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return
+	}
+	s.stopRuns(runs)
+}
+
+// StopProjectRuns 取消项目在本进程内的所有批次(删除项目前调用)
+func (s *executionTaskService) StopProjectRuns(projectID uint) {
+	runs, err := s.runRepo.GetActive()
+	if err != nil {
+		return
+	}
+	var projectRuns []*models.ExecutionRun
+	for _, run := range runs {
+		if run.ProjectID == projectID {
+			projectRuns = append(projectRuns, run)
+		}
+	}
+	s.stopRuns(projectRuns)
+}
+
+// stopRuns 取消本进程内正在执行的批次并等待后台协程退出
+func (s *executionTaskService) stopRuns(runs []*models.ExecutionRun) {
+	var waits []chan struct{}
+	s.runsMu.Lock()
+	for _, run := range runs {
+		if handle, ok := s.activeRuns[run.RunUUID]; ok {
+			handle.cancel()
+			waits = append(waits, handle.done)
+		}
+	}
+	s.runsMu.Unlock()
+
+	for _, done := range waits {
+		select {
+		case <-done:
+		case <-time.After(cancelWaitTimeout):
+		}
+	}
+}
+
+// processRun 批次主循环：跳过已有结果的用例，按任务并发数并行执行仍为NR的用例
+// 统计按用例顺序从 outcomes 汇总，与执行完成顺序无关
+// 用例定义了变量提取时按 DisplayID 顺序串行执行，提取的变量对后续用例可见
+func (s *executionTaskService) processRun(ctx context.Context, run *models.ExecutionRun, handle *activeRun) {
+	startedAt := time.Now()
+	if run.StartedAt != nil {
+		startedAt = *run.StartedAt
+	}
+	s.updateRun(run.RunUUID, map[string]interface{}{
+		"status":     models.ExecutionRunStatusRunning,
+		"started_at": startedAt,
+	})
+
+	task, err := s.repo.GetByUUID(run.TaskUUID)
+	if err != nil {
+		s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, fmt.Sprintf("get task: %v", err))
+		return
+	}
+	cases, err := s.ecrRepo.GetByTaskUUID(run.TaskUUID)
+	if err != nil {
+		s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, fmt.Sprintf("get case results: %v", err))
+		return
+	}
+	started := runEvent(ExecutionEventRunStarted, run)
+	started.Status = models.ExecutionRunStatusRunning
+	started.Total = len(cases)
+	s.events.Publish(started)
+
+	variables := s.loadTaskVariables(task, "processRun")
+	if run.VariableOverrides != "" {
+		// 触发时指定的变量覆盖优先于任务变量，提取的批次变量仍然最优先
+		var overrides map[string]string
+		if err := json.Unmarshal([]byte(run.VariableOverrides), &overrides); err != nil {
+			fmt.Printf("[processRun] ⚠️ 还原变量覆盖失败: %v\n", err)
+		}
+		variables = mergeRunVariables(variables, overrides)
+	}
+	browsers := s.newBrowserResolver(task, decodeBrowserOptions(run.BrowserOptions, "批次"))
+	lang := task.DisplayLanguage
+	if lang == "" {
+		lang = "cn"
+	}
+	// 矩阵执行的批次共享同一组用例结果，结果只记录在本批次的执行记录中，全部环境结束后汇总写回
+	matrix := run.MatrixUUID != ""
+	actx := attemptContext{
+		ProjectID:      run.ProjectID,
+		RunUUID:        run.RunUUID,
+		UserID:         run.TriggeredBy,
+		ExecutorName:   run.ExecutedBy,
+		SkipCaseResult: matrix,
+	}
+	if matrix {
+		latest, err := s.latestAttemptsByCase(run.RunUUID)
+		if err != nil {
+			s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, err.Error())
+			return
+		}
+		for _, c := range cases {
+			c.TestResult = "NR"
+			if a, ok := latest[c.ID]; ok {
+				c.TestResult = a.TestResult
+			}
+		}
+	}
+
+	// 批次变量：恢复执行时从批次记录中还原已提取的变量
+	runVars := make(map[string]string)
+	if run.RunVariables != "" {
+		if err := json.Unmarshal([]byte(run.RunVariables), &runVars); err != nil {
+			fmt.Printf("[processRun] ⚠️ 还原批次变量失败: %v\n", err)
+		}
+	}
+
+	// 执行前检查变量：引用了未定义变量的用例不发送给执行器，直接记为Block
+	lint := lintVariables(caseResultLintInputs(task, cases), mergeRunVariables(variables, runVars))
+	undefinedByCase := make(map[uint][]string)
+	for _, lc := range lint.Cases {
+		if len(lc.Undefined) > 0 {
+			undefinedByCase[lc.CaseResultID] = lc.Undefined
+		}
+	}
+	if !lint.Valid {
+		fmt.Printf("[processRun] ⚠️ 未定义变量: %v\n", lint.Undefined)
+	}
+
+	// 恢复执行时，已有结果的用例直接计入统计；无脚本用例记为Block
+	outcomes := make([]string, len(cases))
+	var pending []int
+	for i, c := range cases {
+		switch {
+		case !isCaseExecutable(task, c):
+			// 不可执行用例不重置，计为Block但保留原结果
+			outcomes[i] = "Block"
+		case c.TestResult == "OK" || c.TestResult == "NG" || c.TestResult == "Block":
+			outcomes[i] = c.TestResult
+		case len(undefinedByCase[c.ID]) > 0:
+			remark := s.getUndefinedRemarkByLang(lang, undefinedByCase[c.ID])
+			if matrix {
+				now := time.Now()
+				c.TestResult = "Block"
+				c.Remark = remark
+				s.recordAttempt(c, &caseExecution{ErrorMessage: remark, StartedAt: now, FinishedAt: now}, actx)
+			} else if err := s.blockCase(c, remark); err != nil {
+				fmt.Printf("[processRun] ❌ 标记Block失败: case_result_id=%d, error=%v\n", c.ID, err)
+			}
+			s.events.Publish(caseEvent(ExecutionEventCaseFinished, run.RunUUID, c))
+			outcomes[i] = "Block"
+		default:
+			pending = append(pending, i)
+		}
+	}
+
+	// mu 串行化结果写库与进度更新，避免并发写同一批次记录
+	var mu sync.Mutex
+	progress := func(currentCaseID uint) {
+		stats := summarizeOutcomes(outcomes)
+		s.updateRun(run.RunUUID, map[string]interface{}{
+			"total":           len(cases),
+			"completed_count": stats.OKCount + stats.NGCount + stats.BlockCount,
+			"ok_count":        stats.OKCount,
+			"ng_count":        stats.NGCount,
+			"block_count":     stats.BlockCount,
+			"current_case_id": currentCaseID,
+		})
+	}
+	progress(0)
+
+	workers := s.runConcurrency(task, len(pending))
+	if hasExtractions(task, cases) && workers > 1 {
+		// 后续用例依赖前序用例提取的变量，必须按顺序执行
+		workers = 1
+		fmt.Printf("[processRun] 用例定义了变量提取，改为串行执行\n")
+	}
+	fmt.Printf("[processRun] 待执行 %d/%d 个用例，并发数: %d\n", len(pending), len(cases), workers)
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var runErr error
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				c := cases[i]
+				handle.setRunning(c.ID, true)
+				s.events.Publish(caseEvent(ExecutionEventCaseStarted, run.RunUUID, c))
+				mu.Lock()
+				progress(c.ID)
+				caseVars := mergeRunVariables(variables, runVars)
+				mu.Unlock()
+
+				caseCtx := actx
+				caseCtx.VariableSnapshot = variableSnapshot(caseVars)
+				exec, execErr := s.executeWithRetry(runCtx, task, c, caseVars, browsers.forCase(c), lang, "processRun", func(failed *caseExecution) {
+					mu.Lock()
+					s.recordAttempt(c, failed, caseCtx)
+					mu.Unlock()
+				})
+				handle.setRunning(c.ID, false)
+				if execErr != nil {
+					// 被取消的用例保持NR，下次执行或恢复时重新执行
+					continue
+				}
+
+				mu.Lock()
+				if len(exec.Extracted) > 0 {
+					for k, v := range exec.Extracted {
+						runVars[k] = v
+					}
+					s.saveRunVariables(run.RunUUID, runVars)
+				}
+				if err := s.saveCaseResult(c, exec, caseCtx); err != nil {
+					if runErr == nil {
+						runErr = err
+					}
+					stop()
+				} else {
+					outcomes[i] = c.TestResult
+				}
+				progress(0)
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-runCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats := summarizeOutcomes(outcomes)
+	progress(0)
+
+	if runErr != nil {
+		s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, runErr.Error())
+		return
+	}
+	if ctx.Err() != nil {
+		s.finishRun(run.RunUUID, models.ExecutionRunStatusCancelled, "")
+		fmt.Printf("[processRun] 批次已取消: run_uuid=%s, completed=%d/%d\n",
+			run.RunUUID, stats.OKCount+stats.NGCount+stats.BlockCount, len(cases))
+		return
+	}
+
+	fmt.Printf("[processRun] 执行完成: OK=%d, NG=%d, Block=%d\n", stats.OKCount, stats.NGCount, stats.BlockCount)
+
+	// 更新任务的测试日期和执行人
+	taskUpdates := map[string]interface{}{
+		"test_date": time.Now(),
+		"executor":  run.ExecutedBy,
+	}
+	if err := s.repo.UpdateByUUID(run.TaskUUID, taskUpdates); err != nil {
+		fmt.Printf("[processRun] ❌ 更新任务失败: %v\n", err)
+	}
+
+	s.finishRun(run.RunUUID, models.ExecutionRunStatusFinished, "")
+}
+
+// blockCase 将未执行的用例标记为Block并记录原因
+func (s *executionTaskService) blockCase(c *models.ExecutionCaseResult, remark string) error {
+	c.TestResult = "Block"
+	c.Remark = remark
+	return s.ecrRepo.UpdateResult(c.ID, map[string]interface{}{
+		"test_result": c.TestResult,
+		"remark":      c.Remark,
+	})
+}
+
+// getUndefinedRemarkByLang 根据语言生成未定义变量的Block原因
+func (s *executionTaskService) getUndefinedRemarkByLang(lang string, undefined []string) string {
+	names := make([]string, len(undefined))
+	for i, name := range undefined {
+		names[i] = "${" + name + "}"
+	}
+	list := strings.Join(names, ", ")
+	switch lang {
+	case "jp":
+		return fmt.Sprintf("未定義の変数があるため未実行: %s", list)
+	case "en":
+		return fmt.Sprintf("Not executed, undefined variables: %s", list)
+	default:
+		return fmt.Sprintf("变量未定义，未执行: %s", list)
+	}
+}
+
+// summarizeOutcomes 按用例顺序汇总OK/NG/Block数量，未执行的用例不计入
+func summarizeOutcomes(outcomes []string) ExecuteTaskResult {
+	stats := ExecuteTaskResult{Total: len(outcomes)}
+	for _, o := range outcomes {
+		switch o {
+		case "OK":
+			stats.OKCount++
+		case "NG":
+			stats.NGCount++
+		case "Block":
+			stats.BlockCount++
+		}
+	}
+	return stats
+}
+
+// hasExtractions 批次中是否有用例定义了变量提取
+func hasExtractions(task *models.ExecutionTask, cases []*models.ExecutionCaseResult) bool {
+	if task.ExecutionType != "api" {
+		return false
+	}
+	for _, c := range cases {
+		if strings.TrimSpace(c.Extractions) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// saveRunVariables 保存批次变量：原值用于恢复执行，脱敏值用于报告展示
+func (s *executionTaskService) saveRunVariables(runUUID string, runVars map[string]string) {
+	data, err := json.Marshal(runVars)
+	if err != nil {
+		return
+	}
+	s.updateRun(runUUID, map[string]interface{}{
+		"run_variables":  string(data),
+		"extracted_vars": maskedVariablesJSON(runVars),
+	})
+}
+
+// latestRunVariables 获取任务最近一次批次提取的变量，单条执行时沿用
+func (s *executionTaskService) latestRunVariables(taskUUID string) map[string]string {
+	runs, err := s.runRepo.GetByTaskUUID(taskUUID)
+	if err != nil || len(runs) == 0 || runs[0].RunVariables == "" {
+		return nil
+	}
+	var runVars map[string]string
+	if err := json.Unmarshal([]byte(runs[0].RunVariables), &runVars); err != nil {
+		return nil
+	}
+	return runVars
+}
+
+// runConcurrency 计算批次的工作协程数：任务并发数，受服务器上限、脚本执行器上限和待执行数约束
+func (s *executionTaskService) runConcurrency(task *models.ExecutionTask, pending int) int {
+	n := task.Concurrency
+	if n < 1 {
+		n = 1
+	}
+	if limit := cap(s.execSlots); n > limit {
+		n = limit
+	}
+	if task.ExecutionType == "automation" {
+		if executor, err := s.resolveExecutor(task, CaseKindScript); err == nil {
+			if limit := executor.Capabilities().MaxConcurrency; limit > 0 && n > limit {
+				n = limit
+			}
+		}
+	}
+	if n > pending {
+		n = pending
+	}
+	return n
+}
+
+// caseExecution 单条用例的一次执行明细，用于写入执行记录
+type caseExecution struct {
+	APIResult    bool               // 由内置接口执行器产生，需写回状态码/响应/断言结果
+	Extracted    map[string]string  // 从响应中提取的批次变量
+	Artifacts    []ExecutorArtifact // 执行器采集的产物(截图/trace等)
+	Output       string
+	ErrorMessage string
+	ErrorStack   string
+	DurationMs   int
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	RetryIndex   int    // 0为首次执行，>0为第N次重试
+	Flaky        bool   // 重试后才通过
+	Browser      string // 实际使用的浏览器设置(脱敏JSON)，仅脚本用例
+}
+
+// attemptContext 写入执行记录所需的上下文
+type attemptContext struct {
+	ProjectID        uint
+	RunUUID          string
+	UserID           uint
+	ExecutorName     string
+	VariableSnapshot string
+	SkipCaseResult   bool // 矩阵执行：不写回用例结果，只追加执行记录
+}
+
+// runCase 执行单条用例脚本并写回结果，返回OK或NG
+// ctx 被取消时不写回结果，返回 ctx.Err()
+func (s *executionTaskService) runCase(ctx context.Context, task *models.ExecutionTask, c *models.ExecutionCaseResult, variables []*models.UserDefinedVariable, lang string, actx attemptContext, logPrefix string) (string, error) {
+	s.events.Publish(caseEvent(ExecutionEventCaseStarted, actx.RunUUID, c))
+	exec, err := s.executeWithRetry(ctx, task, c, variables, s.newBrowserResolver(task, nil).forCase(c), lang, logPrefix, func(failed *caseExecution) {
+		s.recordAttempt(c, failed, actx)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.saveCaseResult(c, exec, actx); err != nil {
+		return "", err
+	}
+	return c.TestResult, nil
+}
+
+// maxRetryBackoff 单次重试等待时间上限
+const maxRetryBackoff = 30 * time.Second
+
+// executeWithRetry 按任务的重试策略执行用例
+// NG且仍有重试次数时，通过 onRetry 记录本次失败的执行记录，等待退避时间后重新执行；
+// 重试后通过的用例标记为不稳定(flaky)。ctx 被取消时返回 ctx.Err()
+func (s *executionTaskService) executeWithRetry(ctx context.Context, task *models.ExecutionTask, c *models.ExecutionCaseResult, variables []*models.UserDefinedVariable, browser *BrowserOptions, lang string, logPrefix string, onRetry func(*caseExecution)) (*caseExecution, error) {
+	for retry := 0; ; retry++ {
+		exec, err := s.executeCase(ctx, task, c, variables, browser, lang, logPrefix)
+		if err != nil {
+			return nil, err
+		}
+		exec.RetryIndex = retry
+		if c.TestResult != "NG" || retry >= task.RetryCount {
+			exec.Flaky = retry > 0 && c.TestResult == "OK"
+			if exec.Flaky {
+				fmt.Printf("[%s] ⚠️ 用例重试 %d 次后通过，标记为不稳定: case_id=%s\n", logPrefix, retry, c.CaseID)
+			}
+			return exec, nil
+		}
+
+		onRetry(exec)
+		delay := retryDelay(task, retry)
+		fmt.Printf("[%s] 用例NG，%v 后重试 (%d/%d): case_id=%s\n", logPrefix, delay, retry+1, task.RetryCount, c.CaseID)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryDelay 第 retry 次失败后的等待时间：以任务配置的退避时间为基数逐次翻倍，不超过上限
+func retryDelay(task *models.ExecutionTask, retry int) time.Duration {
+	if task.RetryBackoffMs <= 0 {
+		return 0
+	}
+	delay := time.Duration(task.RetryBackoffMs) * time.Millisecond
+	for i := 0; i < retry && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// isCaseExecutable 用例是否可自动执行
+// API任务中有结构化URL的用例由内置执行器执行，其余用例需要脚本代码
+func isCaseExecutable(task *models.ExecutionTask, c *models.ExecutionCaseResult) bool {
+	if task.ExecutionType == "api" && strings.TrimSpace(c.URL) != "" {
+		return true
+	}
+	return c.ScriptCode != ""
+}
+
+// executableCaseIDs 批次将执行的用例结果ID
+func executableCaseIDs(task *models.ExecutionTask, cases []*models.ExecutionCaseResult) []uint {
+	ids := make([]uint, 0, len(cases))
+	for _, c := range cases {
+		if isCaseExecutable(task, c) {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// executeCase 执行单条用例，结果写入 c 但不落库
+// ctx 被取消时返回 ctx.Err()，c 保持不变
+func (s *executionTaskService) executeCase(ctx context.Context, task *models.ExecutionTask, c *models.ExecutionCaseResult, variables []*models.UserDefinedVariable, browser *BrowserOptions, lang string, logPrefix string) (*caseExecution, error) {
+	if task.ExecutionType == "api" && strings.TrimSpace(c.URL) != "" {
+		return s.executeAPICase(ctx, task, c, variables, lang, logPrefix)
+	}
+	return s.executeScriptCase(ctx, task, c, variables, browser, lang, logPrefix)
+}
+
+// executeAPICase 由接口执行器(默认内置 net/http 执行器)发送结构化请求
+// URL/Header/Body 先做变量替换，状态码>=400或请求失败判定为NG
+// 定义了提取规则时从响应中提取变量，提取失败同样判定为NG
+func (s *executionTaskService) executeAPICase(ctx context.Context, task *models.ExecutionTask, c *models.ExecutionCaseResult, variables []*models.UserDefinedVariable, lang string, logPrefix string) (*caseExecution, error) {
+	apiReq := APIRequest{
+		Method: c.Method,
+		URL:    s.replaceVariables(c.URL, variables),
+		Header: s.replaceVariables(c.Header, variables),
+		Body:   s.replaceVariables(c.Body, variables),
+	}
+	// 只记录替换前的URL模板，避免变量中的Token/Key写入日志
+	fmt.Printf("[%s] 用例 %d 发送接口请求: %s %s\n", logPrefix, c.ID, apiReq.Method, c.URL)
+
+	release, err := s.acquireExecSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	exec := &caseExecution{APIResult: true, StartedAt: time.Now()}
+	var resp *APIResponse
+	executor, reqErr := s.resolveExecutor(task, CaseKindAPI)
+	if reqErr == nil {
+		var result *DockerExecResult
+		result, reqErr = executor.Execute(ctx, ExecutorRequest{API: &apiReq})
+		if reqErr == nil {
+			resp = result.API
+			if resp == nil {
+				reqErr = fmt.Errorf("executor %s returned no api response", executor.Name())
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	exec.FinishedAt = time.Now()
+	exec.DurationMs = int(exec.FinishedAt.Sub(exec.StartedAt).Milliseconds())
+
+	if reqErr != nil {
+		fmt.Printf("[%s] 接口请求失败: %v\n", logPrefix, reqErr)
+		c.TestResult = "NG"
+		c.Remark = s.getRemarkByLang(lang, false, reqErr.Error())
+		c.StatusCode = 0
+		c.ActualResponse = ""
+		c.AssertionResults = ""
+		c.ExtractedVars = ""
+		exec.ErrorMessage = reqErr.Error()
+		return exec, nil
+	}
+
+	exec.DurationMs = resp.LatencyMs
+	exec.Output = fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, resp.Body)
+	c.StatusCode = resp.StatusCode
+	c.ActualResponse = resp.Body
+	c.ResponseTime = fmt.Sprintf("%d", resp.LatencyMs)
+	c.AssertionResults = ""
+	c.ExtractedVars = ""
+
+	// 定义了断言时按断言判定，否则按状态码判定
+	var errMsg string
+	assertions, parseErr := ParseAPIAssertions(c.Assertions)
+	switch {
+	case parseErr != nil:
+		errMsg = parseErr.Error()
+	case len(assertions) > 0:
+		results := EvaluateAPIAssertions(assertions, resp)
+		if data, err := json.Marshal(results); err == nil {
+			c.AssertionResults = string(data)
+		}
+		errMsg = summarizeAssertionFailures(results)
+	case resp.StatusCode >= http.StatusBadRequest:
+		errMsg = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+
+	extractions, extractErr := ParseAPIExtractions(c.Extractions)
+	var failures []string
+	if extractErr != nil {
+		failures = append(failures, extractErr.Error())
+	} else if len(extractions) > 0 {
+		exec.Extracted, failures = ExtractAPIVariables(extractions, resp)
+		c.ExtractedVars = maskedVariablesJSON(exec.Extracted)
+	}
+	if len(failures) > 0 {
+		extractMsg := "extraction failed: " + strings.Join(failures, "; ")
+		if errMsg != "" {
+			errMsg += "; " + extractMsg
+		} else {
+			errMsg = extractMsg
+		}
+	}
+
+	if errMsg != "" {
+		fmt.Printf("[%s] 接口用例判定失败: %s\n", logPrefix, errMsg)
+		c.TestResult = "NG"
+		c.Remark = s.getRemarkByLang(lang, false, errMsg)
+		exec.ErrorMessage = errMsg
+	} else {
+		fmt.Printf("[%s] 接口执行成功: status=%d, latency=%dms\n", logPrefix, resp.StatusCode, resp.LatencyMs)
+		c.TestResult = "OK"
+		c.Remark = s.getRemarkByLang(lang, true, "")
+	}
+	return exec, nil
+}
+
+// executeScriptCase 替换变量并由任务选定的执行器执行用例脚本
+func (s *executionTaskService) executeScriptCase(ctx context.Context, task *models.ExecutionTask, c *models.ExecutionCaseResult, variables []*models.UserDefinedVariable, browser *BrowserOptions, lang string, logPrefix string) (*caseExecution, error) {
+	// 替换脚本中的变量
+	fmt.Printf("[%s] 用例 %d 脚本替换前长度: %d bytes\n", logPrefix, c.ID, len(c.ScriptCode))
+	replacedScript := s.replaceVariables(c.ScriptCode, variables)
+	fmt.Printf("[%s] 用例 %d 脚本替换后长度: %d bytes\n", logPrefix, c.ID, len(replacedScript))
+	if strings.Contains(replacedScript, "${") {
+		fmt.Printf("[%s] ⚠️ 用例 %d 脚本仍包含变量占位符！\n", logPrefix, c.ID)
+	}
+
+	// 调用 Playwright Server 执行脚本
+	exec := &caseExecution{StartedAt: time.Now(), Browser: recordedBrowserOptions(browser)}
+	execResult, execErr := s.executeScript(ctx, task, replacedScript, browser)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	exec.FinishedAt = time.Now()
+	exec.DurationMs = int(exec.FinishedAt.Sub(exec.StartedAt).Milliseconds())
+
+	if execErr != nil {
+		fmt.Printf("[%s] 执行失败: %v\n", logPrefix, execErr)
+		c.TestResult = "NG"
+		c.Remark = s.getRemarkByLang(lang, false, execErr.Error())
+		exec.ErrorMessage = execErr.Error()
+	} else {
+		fmt.Printf("[%s] 执行成功: response_time=%dms\n", logPrefix, execResult.ResponseTime)
+		c.TestResult = "OK"
+		c.Remark = s.getRemarkByLang(lang, true, "")
+	}
+	// 记录执行时间（无论成功失败，只要有结果就记录）
+	if execResult != nil {
+		exec.Output = execResult.Output
+		exec.ErrorStack = execResult.Stack
+		exec.Artifacts = execResult.Artifacts
+		if execResult.ResponseTime > 0 {
+			c.ResponseTime = fmt.Sprintf("%d", execResult.ResponseTime)
+			exec.DurationMs = execResult.ResponseTime
+		}
+	}
+	return exec, nil
+}
+
+// saveCaseResult 将用例执行结果写回数据库，并追加一条执行记录(矩阵执行时只追加执行记录)
+func (s *executionTaskService) saveCaseResult(c *models.ExecutionCaseResult, exec *caseExecution, actx attemptContext) error {
+	updates := map[string]interface{}{
+		"test_result": c.TestResult,
+		"remark":      c.Remark,
+	}
+	if c.ResponseTime != "" {
+		updates["response_time"] = c.ResponseTime
+	}
+	c.RetryCount = exec.RetryIndex
+	c.Flaky = exec.Flaky
+	if actx.SkipCaseResult {
+		s.recordAttempt(c, exec, actx)
+		s.events.Publish(caseEvent(ExecutionEventCaseFinished, actx.RunUUID, c))
+		return nil
+	}
+	updates["retry_count"] = c.RetryCount
+	updates["flaky"] = c.Flaky
+	if !exec.APIResult {
+		c.BrowserOptions = exec.Browser
+		updates["browser_options"] = c.BrowserOptions
+	}
+	if exec.APIResult {
+		updates["status_code"] = c.StatusCode
+		updates["actual_response"] = c.ActualResponse
+		updates["assertion_results"] = c.AssertionResults
+		updates["extracted_vars"] = c.ExtractedVars
+	}
+	if err := s.ecrRepo.UpdateResult(c.ID, updates); err != nil {
+		return fmt.Errorf("update case result: %w", err)
+	}
+	s.recordAttempt(c, exec, actx)
+	s.events.Publish(caseEvent(ExecutionEventCaseFinished, actx.RunUUID, c))
+	return nil
+}
+
+// recordAttempt 追加一条执行记录并保存执行产物，写入失败不影响用例结果
+func (s *executionTaskService) recordAttempt(c *models.ExecutionCaseResult, exec *caseExecution, actx attemptContext) {
+	attempt := &models.ExecutionAttempt{
+		CaseResultID:     c.ID,
+		TaskUUID:         c.TaskUUID,
+		RunUUID:          actx.RunUUID,
+		CaseID:           c.CaseID,
+		TestResult:       c.TestResult,
+		Output:           exec.Output,
+		ErrorMessage:     exec.ErrorMessage,
+		ErrorStack:       exec.ErrorStack,
+		DurationMs:       exec.DurationMs,
+		ExecutedBy:       actx.UserID,
+		ExecutorName:     actx.ExecutorName,
+		VariableSnapshot: actx.VariableSnapshot,
+		RetryIndex:       exec.RetryIndex,
+		Flaky:            exec.Flaky,
+		BrowserOptions:   exec.Browser,
+		StartedAt:        exec.StartedAt,
+		FinishedAt:       exec.FinishedAt,
+	}
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		fmt.Printf("[recordAttempt] ❌ 写入执行记录失败: case_result_id=%d, error=%v\n", c.ID, err)
+		return
+	}
+	s.saveArtifacts(c, attempt.ID, exec.Artifacts, actx)
+}
+
+// variableSnapshot 生成执行时的变量快照(JSON)，敏感值按 maskValue 规则脱敏
+func variableSnapshot(variables []*models.UserDefinedVariable) string {
+	if len(variables) == 0 {
+		return ""
+	}
+	snapshot := make(map[string]string, len(variables))
+	for _, v := range variables {
+		snapshot[v.VarKey] = maskValue(v.VarKey, v.VarValue)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// loadTaskVariables 获取任务变量用于脚本替换，失败时返回空列表继续执行
+func (s *executionTaskService) loadTaskVariables(task *models.ExecutionTask, logPrefix string) []*models.UserDefinedVariable {
+	if task.CaseGroupID == 0 {
+		fmt.Printf("[%s] 跳过变量获取 (case_group_id=%d)\n", logPrefix, task.CaseGroupID)
+		return nil
+	}
+
+	groupType := "web"
+	if task.ExecutionType == "api" {
+		groupType = "api"
+	}
+	fmt.Printf("[%s] 从任务变量表获取变量: taskUUID=%s, groupID=%d, groupType=%s\n", logPrefix, task.TaskUUID, task.CaseGroupID, groupType)
+	variables, err := s.variableService.GetVariablesByTask(task.TaskUUID, task.CaseGroupID, groupType)
+	if err != nil {
+		fmt.Printf("[%s] ❌ 警告: 获取变量失败: %v\n", logPrefix, err)
+		return []*models.UserDefinedVariable{}
+	}
+	fmt.Printf("[%s] ✅ 获取到 %d 个任务变量\n", logPrefix, len(variables))
+	for i, v := range variables {
+		fmt.Printf("[%s]   变量 %d: var_key=%s, var_value=%s (长度:%d)\n", logPrefix, i+1, v.VarKey, maskValue(v.VarKey, v.VarValue), len(v.VarValue))
+	}
+	return variables
+}
+
+// getProjectTask 获取任务并校验项目归属
+func (s *executionTaskService) getProjectTask(projectID uint, taskUUID string) (*models.ExecutionTask, error) {
+	task, err := s.repo.GetByUUID(taskUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("任务不存在")
+		}
+		return nil, fmt.Errorf("get task by uuid: %w", err)
+	}
+	if task.ProjectID != projectID {
+		return nil, errors.New("任务不属于该项目")
+	}
+	return task, nil
+}
+
+// getTaskCaseResult 获取用例结果并校验任务归属
+func (s *executionTaskService) getTaskCaseResult(projectID uint, taskUUID string, caseResultID uint) (*models.ExecutionCaseResult, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	c, err := s.ecrRepo.GetByID(caseResultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用例不存在")
+		}
+		return nil, fmt.Errorf("get case result: %w", err)
+	}
+	if c.TaskUUID != taskUUID {
+		return nil, errors.New("用例不属于该任务")
+	}
+	return c, nil
+}
+
+// getTaskRun 获取批次并校验任务归属
+func (s *executionTaskService) getTaskRun(projectID uint, taskUUID string, runUUID string) (*models.ExecutionRun, error) {
+	if _, err := s.getProjectTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	run, err := s.runRepo.GetByUUID(runUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行批次不存在")
+		}
+		return nil, fmt.Errorf("get run by uuid: %w", err)
+	}
+	if run.TaskUUID != taskUUID {
+		return nil, errors.New("执行批次不存在")
+	}
+	return run, nil
+}
+
+// updateRun 更新批次字段，失败只记录日志
+func (s *executionTaskService) updateRun(runUUID string, updates map[string]interface{}) {
+	if err := s.runRepo.UpdateByUUID(runUUID, updates); err != nil {
+		fmt.Printf("[updateRun] ❌ 更新批次失败: run_uuid=%s, error=%v\n", runUUID, err)
+	}
+}
+
+// finishRun 将批次标记为结束状态，并向观察者推送批次统计
+func (s *executionTaskService) finishRun(runUUID string, status string, errMsg string) {
+	s.updateRun(runUUID, map[string]interface{}{
+		"status":          status,
+		"error_message":   errMsg,
+		"current_case_id": 0,
+		"finished_at":     time.Now(),
+	})
+	run, err := s.runRepo.GetByUUID(runUUID)
+	if err != nil {
+		fmt.Printf("[finishRun] ⚠️ 读取批次失败，未推送结束事件: run_uuid=%s, error=%v\n", runUUID, err)
+		return
+	}
+	s.events.Publish(runEvent(ExecutionEventRunFinished, run))
+	if run.MatrixUUID != "" {
+		s.completeMatrix(run.MatrixUUID)
+	}
+}
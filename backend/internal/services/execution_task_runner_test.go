@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSummarizeOutcomes_IgnoresPending 未执行(空)的用例不计入统计
+func TestSummarizeOutcomes_IgnoresPending(t *testing.T) {
+	stats := summarizeOutcomes([]string{"OK", "", "NG", "Block", "OK", ""})
+
+	assert.Equal(t, 6, stats.Total)
+	assert.Equal(t, 2, stats.OKCount)
+	assert.Equal(t, 1, stats.NGCount)
+	assert.Equal(t, 1, stats.BlockCount)
+}
+
+// TestRunConcurrency_Limits 并发数受服务器上限和待执行数约束
+func TestRunConcurrency_Limits(t *testing.T) {
+	s := &executionTaskService{execSlots: make(chan struct{}, 4)}
+
+	tests := []struct {
+		name        string
+		concurrency int
+		pending     int
+		expected    int
+	}{
+		{"未设置时默认串行", 0, 10, 1},
+		{"按任务设置", 3, 10, 3},
+		{"超过服务器上限", 16, 10, 4},
+		{"不超过待执行数", 4, 2, 2},
+		{"无待执行用例", 4, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.ExecutionTask{Concurrency: tt.concurrency}
+			assert.Equal(t, tt.expected, s.runConcurrency(task, tt.pending))
+		})
+	}
+}
+
+// TestExecuteWithRetry_MarksFlaky NG后按策略重试，重试后通过的用例标记为不稳定
+func TestExecuteWithRetry_MarksFlaky(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s := &executionTaskService{executors: newAPIExecutorRegistry(), execSlots: make(chan struct{}, 1)}
+	task := &models.ExecutionTask{ExecutionType: "api", RetryCount: 3}
+	c := &models.ExecutionCaseResult{ID: 1, Method: "GET", URL: server.URL}
+
+	var retried []int
+	exec, err := s.executeWithRetry(context.Background(), task, c, nil, nil, "en", "test", func(failed *caseExecution) {
+		retried = append(retried, failed.RetryIndex)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "OK", c.TestResult)
+	assert.Equal(t, 2, exec.RetryIndex)
+	assert.True(t, exec.Flaky)
+	assert.Equal(t, []int{0, 1}, retried)
+
+	// 重试次数用尽仍为NG，不标记为不稳定
+	atomic.StoreInt32(&calls, -10)
+	task.RetryCount = 1
+	exec, err = s.executeWithRetry(context.Background(), task, c, nil, nil, "en", "test", func(*caseExecution) {})
+	require.NoError(t, err)
+	assert.Equal(t, "NG", c.TestResult)
+	assert.Equal(t, 1, exec.RetryIndex)
+	assert.False(t, exec.Flaky)
+}
+
+// TestRetryDelay_Backoff 退避时间逐次翻倍且不超过上限
+func TestRetryDelay_Backoff(t *testing.T) {
+	task := &models.ExecutionTask{RetryBackoffMs: 1000}
+	assert.Equal(t, time.Second, retryDelay(task, 0))
+	assert.Equal(t, 4*time.Second, retryDelay(task, 2))
+	assert.Equal(t, maxRetryBackoff, retryDelay(task, 10))
+
+	task.RetryBackoffMs = 0
+	assert.Equal(t, time.Duration(0), retryDelay(task, 3))
+}
+
+// TestCompareRuns_MatchesByCaseID 用例结果重新初始化(ID变化)后仍按用例ID对比批次
+func TestCompareRuns_MatchesByCaseID(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+	project := &models.Project{Name: "demo"}
+	require.NoError(t, db.Create(project).Error)
+	task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "compare", ExecutionType: "automation", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+
+	old := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1", UpdatedBy: 1}
+	require.NoError(t, db.Create(old).Error)
+	base := &models.ExecutionRun{TaskUUID: task.TaskUUID, ProjectID: project.ID, Status: models.ExecutionRunStatusFinished}
+	require.NoError(t, db.Create(base).Error)
+	require.NoError(t, db.Create(&models.ExecutionAttempt{CaseResultID: old.ID, TaskUUID: task.TaskUUID, RunUUID: base.RunUUID,
+		CaseID: "case-1", TestResult: "OK", ExecutedBy: 1}).Error)
+
+	// 重新导入用例：结果行被重建，ID变化
+	require.NoError(t, db.Unscoped().Delete(old).Error)
+	current := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1", UpdatedBy: 1}
+	require.NoError(t, db.Create(current).Error)
+	require.NotEqual(t, old.ID, current.ID)
+	target := &models.ExecutionRun{TaskUUID: task.TaskUUID, ProjectID: project.ID, Status: models.ExecutionRunStatusFinished}
+	require.NoError(t, db.Create(target).Error)
+	require.NoError(t, db.Create(&models.ExecutionAttempt{CaseResultID: current.ID, TaskUUID: task.TaskUUID, RunUUID: target.RunUUID,
+		CaseID: "case-1", TestResult: "NG", ExecutedBy: 1}).Error)
+
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	comparison, err := s.CompareRuns(project.ID, task.TaskUUID, base.RunUUID, target.RunUUID)
+	require.NoError(t, err)
+	require.Len(t, comparison.Cases, 1)
+	assert.Equal(t, "OK", comparison.Cases[0].BaseResult)
+	assert.Equal(t, "NG", comparison.Cases[0].TargetResult)
+	assert.Equal(t, 1, comparison.ChangedCount)
+}
+
+// TestExecuteTask_KeepsNonExecutableResults 执行前只重置将执行的用例，无脚本用例保留原结果
+func TestExecuteTask_KeepsNonExecutableResults(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+	project := &models.Project{Name: "demo", ScriptExecutor: ExecutorFake}
+	require.NoError(t, db.Create(project).Error)
+	task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "reset", ExecutionType: "automation", Concurrency: 1, CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	scripted := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1", ScriptCode: "await page.goto('/');", TestResult: "NG", UpdatedBy: 1}
+	manual := &models.ExecutionCaseResult{TaskUUID: task.TaskUUID, CaseID: "case-2", CaseType: "role1", TestResult: "OK", UpdatedBy: 1}
+	require.NoError(t, db.Create(scripted).Error)
+	require.NoError(t, db.Create(manual).Error)
+
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	run, err := s.ExecuteTask(project.ID, 1, task.TaskUUID)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		detail, err := s.GetRun(project.ID, task.TaskUUID, run.RunUUID)
+		return err == nil && detail.Status == models.ExecutionRunStatusFinished
+	}, 5*time.Second, 20*time.Millisecond)
+
+	var executed, kept models.ExecutionCaseResult
+	require.NoError(t, db.First(&executed, scripted.ID).Error)
+	assert.Equal(t, "OK", executed.TestResult)
+	require.NoError(t, db.First(&kept, manual.ID).Error)
+	assert.Equal(t, "OK", kept.TestResult)
+}
+
+// TestExecuteAPICase_WaitsForExecSlot 接口用例同样受服务器执行槽限制
+func TestExecuteAPICase_WaitsForExecSlot(t *testing.T) {
+	s := &executionTaskService{executors: newAPIExecutorRegistry(), execSlots: make(chan struct{}, 1)}
+	s.execSlots <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	task := &models.ExecutionTask{ExecutionType: "api"}
+	_, err := s.executeAPICase(ctx, task, &models.ExecutionCaseResult{ID: 1, Method: "GET", URL: "http://127.0.0.1:1/"}, nil, "cn", "test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestResumeRuns_ClaimsLeaseOnce 多实例同时恢复时只有接管到租约的实例执行批次，租约未到期的批次不被接管
+func TestResumeRuns_ClaimsLeaseOnce(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+	project := &models.Project{Name: "demo", ScriptExecutor: ExecutorFake}
+	require.NoError(t, db.Create(project).Error)
+	newRun := func(owner string, leaseUntil time.Time) *models.ExecutionRun {
+		task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "resume", ExecutionType: "automation", Concurrency: 1, CreatedBy: 1}
+		require.NoError(t, db.Create(task).Error)
+		require.NoError(t, db.Create(&models.ExecutionCaseResult{
+			TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "role1", ScriptCode: "await page.goto('/');", UpdatedBy: 1,
+		}).Error)
+		run := &models.ExecutionRun{TaskUUID: task.TaskUUID, ProjectID: project.ID, Total: 1, TriggeredBy: 1, Owner: owner, LeaseUntil: &leaseUntil}
+		require.NoError(t, db.Create(run).Error)
+		return run
+	}
+	orphan := newRun("crashed", time.Now().Add(-time.Second))
+	held := newRun("alive", time.Now().Add(time.Hour))
+
+	fakeA, fakeB := NewFakeScriptExecutor(), NewFakeScriptExecutor()
+	a, b := newTestTaskService(t, db, fakeA), newTestTaskService(t, db, fakeB)
+	a.instanceID, b.instanceID = "instance-a", "instance-b"
+	require.NoError(t, a.claimOrphanRuns())
+	require.NoError(t, b.claimOrphanRuns())
+
+	require.Eventually(t, func() bool {
+		var run models.ExecutionRun
+		return db.First(&run, "run_uuid = ?", orphan.RunUUID).Error == nil && run.Status == models.ExecutionRunStatusFinished
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Len(t, append(fakeA.Calls(), fakeB.Calls()...), 1)
+
+	var claimed, kept models.ExecutionRun
+	require.NoError(t, db.First(&claimed, "run_uuid = ?", orphan.RunUUID).Error)
+	assert.Equal(t, "instance-a", claimed.Owner)
+	require.NoError(t, db.First(&kept, "run_uuid = ?", held.RunUUID).Error)
+	assert.Equal(t, "alive", kept.Owner)
+	assert.Equal(t, models.ExecutionRunStatusQueued, kept.Status)
+}
+
+// TestDeleteTask_RemovesSchedules 删除任务时一并删除其定时执行计划
+func TestDeleteTask_RemovesSchedules(t *testing.T) {
+	db := newTestDB(t, &models.ExecutionRun{}, &models.ExecutionSchedule{}, &models.DefectCaseLink{},
+		&models.ManualExecutionSession{}, &models.ManualStepResult{})
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "nightly", ExecutionType: "automation", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	require.NoError(t, db.Create(&models.ExecutionSchedule{ProjectID: 1, TaskUUID: task.TaskUUID, CronExpr: "0 2 * * *", CreatedBy: 1}).Error)
+
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	require.NoError(t, s.DeleteTask(1, 1, task.TaskUUID))
+
+	var count int64
+	require.NoError(t, db.Model(&models.ExecutionSchedule{}).Where("task_uuid = ?", task.TaskUUID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// TestStopProjectRuns_CancelsOnlyProjectRuns 删除项目前只取消该项目的批次
+func TestStopProjectRuns_CancelsOnlyProjectRuns(t *testing.T) {
+	db := newTestDB(t, &models.ExecutionRun{})
+	s := newTestTaskService(t, db, NewFakeScriptExecutor())
+	handles := make(map[uint]*activeRun)
+	for _, projectID := range []uint{1, 2} {
+		run := &models.ExecutionRun{TaskUUID: "task", ProjectID: projectID, Status: models.ExecutionRunStatusRunning, TriggeredBy: 1}
+		require.NoError(t, db.Create(run).Error)
+		ctx, cancel := context.WithCancel(context.Background())
+		handle := &activeRun{cancel: cancel, done: make(chan struct{})}
+		go func() {
+			<-ctx.Done()
+			close(handle.done)
+		}()
+		s.activeRuns[run.RunUUID] = handle
+		handles[projectID] = handle
+	}
+
+	s.StopProjectRuns(1)
+	assert.Eventually(t, func() bool {
+		select {
+		case <-handles[1].done:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-handles[2].done:
+		t.Fatal("other project's run was cancelled")
+	default:
+	}
+	handles[2].cancel()
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
+	"sync"
 	"time"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
@@ -14,26 +17,35 @@ import (
 
 // CreateTaskRequest 创建任务请求
 type CreateTaskRequest struct {
-	TaskName      string `json:"task_name" binding:"required,min=1,max=50"`
-	ExecutionType string `json:"execution_type" binding:"required,oneof=manual automation api"`
-	TaskStatus    string `json:"task_status" binding:"omitempty,oneof=pending in_progress completed"`
+	TaskName       string `json:"task_name" binding:"required,min=1,max=50"`
+	ExecutionType  string `json:"execution_type" binding:"required,oneof=manual automation api"`
+	TaskStatus     string `json:"task_status" binding:"omitempty,oneof=pending in_progress completed"`
+	Concurrency    int    `json:"concurrency" binding:"omitempty,min=1"`
+	RetryCount     int    `json:"retry_count" binding:"omitempty,min=0,max=5"`
+	RetryBackoffMs *int   `json:"retry_backoff_ms" binding:"omitempty,min=0,max=60000"`
+	// 浏览器设置(仅自动化任务)，用例集设置优先
+	BrowserOptions *BrowserOptions `json:"browser_options"`
 }
 
 // UpdateTaskRequest 更新任务请求
 type UpdateTaskRequest struct {
-	TaskName        *string    `json:"task_name" binding:"omitempty,min=1,max=50"`
-	ExecutionType   *string    `json:"execution_type" binding:"omitempty,oneof=manual automation api"`
-	TaskStatus      *string    `json:"task_status" binding:"omitempty,oneof=pending in_progress completed"`
-	CaseGroupID     *uint      `json:"case_group_id"`                               // 关联的用例集ID
-	CaseGroupName   *string    `json:"case_group_name" binding:"omitempty,max=100"` // 关联的用例集名称
-	DisplayLanguage *string    `json:"display_language" binding:"omitempty,max=10"` // 显示语言(cn/jp/en/all)
-	StartDate       *time.Time `json:"start_date"`
-	EndDate         *time.Time `json:"end_date"`
-	TestVersion     *string    `json:"test_version" binding:"omitempty,max=50"`
-	TestEnv         *string    `json:"test_env" binding:"omitempty,max=100"`
-	TestDate        *time.Time `json:"test_date"`
-	Executor        *string    `json:"executor" binding:"omitempty,max=50"`
-	TaskDescription *string    `json:"task_description" binding:"omitempty,max=2000"`
+	TaskName        *string         `json:"task_name" binding:"omitempty,min=1,max=50"`
+	ExecutionType   *string         `json:"execution_type" binding:"omitempty,oneof=manual automation api"`
+	TaskStatus      *string         `json:"task_status" binding:"omitempty,oneof=pending in_progress completed"`
+	CaseGroupID     *uint           `json:"case_group_id"`                                        // 关联的用例集ID
+	CaseGroupName   *string         `json:"case_group_name" binding:"omitempty,max=100"`          // 关联的用例集名称
+	DisplayLanguage *string         `json:"display_language" binding:"omitempty,max=10"`          // 显示语言(cn/jp/en/all)
+	Concurrency     *int            `json:"concurrency" binding:"omitempty,min=1"`                // 自动执行并发数
+	RetryCount      *int            `json:"retry_count" binding:"omitempty,min=0,max=5"`          // NG后重试次数
+	RetryBackoffMs  *int            `json:"retry_backoff_ms" binding:"omitempty,min=0,max=60000"` // 首次重试等待时间(毫秒)
+	BrowserOptions  *BrowserOptions `json:"browser_options"`                                      // 浏览器设置，传 {} 清除
+	StartDate       *time.Time      `json:"start_date"`
+	EndDate         *time.Time      `json:"end_date"`
+	TestVersion     *string         `json:"test_version" binding:"omitempty,max=50"`
+	TestEnv         *string         `json:"test_env" binding:"omitempty,max=100"`
+	TestDate        *time.Time      `json:"test_date"`
+	Executor        *string         `json:"executor" binding:"omitempty,max=50"`
+	TaskDescription *string         `json:"task_description" binding:"omitempty,max=2000"`
 }
 
 // ExecuteTaskResult 执行结果统计
@@ -50,7 +62,10 @@ type ExecuteTaskResult struct {
 type DockerExecResult struct {
 	Success      bool
 	Output       string
-	ResponseTime int // 毫秒
+	Stack        string             // 失败时执行器返回的错误堆栈
+	ResponseTime int                // 毫秒
+	Artifacts    []ExecutorArtifact // 执行器采集的产物
+	API          *APIResponse       // 接口执行器的响应(结构化接口请求)
 }
 
 // ExecutionTaskService 测试执行任务服务接口
@@ -59,8 +74,43 @@ type ExecutionTaskService interface {
 	CreateTask(projectID uint, userID uint, req CreateTaskRequest) (*models.ExecutionTask, error)
 	UpdateTask(projectID uint, userID uint, taskUUID string, req UpdateTaskRequest) (*models.ExecutionTask, error)
 	DeleteTask(projectID uint, userID uint, taskUUID string) error
-	ExecuteTask(projectID uint, userID uint, taskUUID string) (*ExecuteTaskResult, error)
+	ExecuteTask(projectID uint, userID uint, taskUUID string) (*models.ExecutionRun, error)
+	ExecuteScheduledTask(projectID uint, userID uint, taskUUID string, scheduleID uint) (*models.ExecutionRun, error)
 	ExecuteSingleCase(projectID uint, userID uint, taskUUID string, caseResultID uint) (*ExecuteTaskResult, error)
+
+	// 执行批次
+	ListRuns(projectID uint, taskUUID string) ([]*models.ExecutionRun, error)
+	GetRun(projectID uint, taskUUID string, runUUID string) (*ExecutionRunDetail, error)
+	CancelRun(projectID uint, userID uint, taskUUID string, runUUID string) (*models.ExecutionRun, error)
+	ResumeRuns() error
+	StopProjectRuns(projectID uint)
+
+	// 执行记录
+	ListAttempts(projectID uint, taskUUID string, caseResultID uint) ([]*models.ExecutionAttempt, error)
+	ListArtifacts(projectID uint, taskUUID string, caseResultID uint) ([]*models.ExecutionArtifact, error)
+	OpenArtifact(projectID uint, taskUUID string, caseResultID uint, artifactID uint) (*models.ExecutionArtifact, io.ReadCloser, error)
+	CompareRuns(projectID uint, taskUUID string, baseRunUUID string, targetRunUUID string) (*RunComparison, error)
+	ListFlakyCases(projectID uint, limit int) ([]*FlakyCaseReport, error)
+	CompareTasks(projectID uint, baseTaskUUID string, targetTaskUUID string) (*TaskComparison, error)
+
+	// 矩阵执行
+	StartMatrixRun(projectID uint, userID uint, taskUUID string, req MatrixRunRequest) ([]*models.ExecutionRun, error)
+	GetMatrixRun(projectID uint, taskUUID string, matrixUUID string) (*MatrixRunSummary, error)
+
+	// CI 集成
+	TriggerCIRun(projectID uint, userID uint, taskUUID string, req CITriggerRequest) (*models.ExecutionRun, error)
+	WaitRun(ctx context.Context, projectID uint, taskUUID string, runUUID string) (*models.ExecutionRun, error)
+	GetRunReport(projectID uint, taskUUID string, runUUID string) (*RunReport, error)
+
+	// 执行器
+	ListExecutors(ctx context.Context) []ExecutorStatus
+	GetExecutorPool() (*ExecutorPoolStatus, error)
+	GetProjectExecutor(projectID uint) (*ProjectExecutorSelection, error)
+	SetProjectExecutor(projectID uint, req SetProjectExecutorRequest) (*ProjectExecutorSelection, error)
+
+	// 执行进度事件
+	ListEvents(projectID uint, taskUUID string, since int64) ([]ExecutionEvent, error)
+	SubscribeEvents(projectID uint, taskUUID string, since int64) ([]ExecutionEvent, <-chan ExecutionEvent, func(), error)
 }
 
 type executionTaskService struct {
@@ -68,8 +118,22 @@ type executionTaskService struct {
 	projectRepo     repositories.ProjectRepository             // 用于权限验证
 	ecrRepo         repositories.ExecutionCaseResultRepository // 用于级联删除
 	userRepo        repositories.UserRepository                // 用于获取用户名
-	pwClient        *PlaywrightExecutorClient                  // Playwright 执行器客户端
+	runRepo         repositories.ExecutionRunRepository        // 执行批次
+	scheduleRepo    repositories.ExecutionScheduleRepository   // 定时执行计划(删除任务时一并删除)
+	attemptRepo     repositories.ExecutionAttemptRepository    // 用例执行记录
+	artifactRepo    repositories.ExecutionArtifactRepository   // 执行产物
+	profileRepo     repositories.EnvironmentProfileRepository  // 执行环境(矩阵执行)
+	caseGroupRepo   *repositories.CaseGroupRepository          // 用例集(浏览器设置)
+	executors       *ExecutorRegistry                          // 用例执行后端
 	variableService UserDefinedVariableService                 // 用户自定义变量服务
+	storagePath     string                                     // 执行产物存储根目录
+
+	runsMu     sync.Mutex            // 保护 activeRuns 及批次创建
+	activeRuns map[string]*activeRun // 本进程内正在执行的批次
+	execSlots  chan struct{}         // 服务器范围内的用例执行并发槽(脚本与接口用例共用)
+	events     *ExecutionEventHub    // 执行进度事件(SSE)
+	instanceID string                // 本服务实例标识，记录为批次持有者
+	leaseOnce  sync.Once             // 批次续租协程只启动一次
 }
 
 // NewExecutionTaskService 创建任务服务实例
@@ -78,18 +142,35 @@ func NewExecutionTaskService(
 	projectRepo repositories.ProjectRepository,
 	ecrRepo repositories.ExecutionCaseResultRepository,
 	userRepo repositories.UserRepository,
+	runRepo repositories.ExecutionRunRepository,
+	scheduleRepo repositories.ExecutionScheduleRepository,
+	attemptRepo repositories.ExecutionAttemptRepository,
+	artifactRepo repositories.ExecutionArtifactRepository,
+	profileRepo repositories.EnvironmentProfileRepository,
+	caseGroupRepo *repositories.CaseGroupRepository,
 	variableService UserDefinedVariableService,
+	executors *ExecutorRegistry,
+	storagePath string,
 ) ExecutionTaskService {
-	// 初始化 Playwright 执行器客户端
-	pwClient := NewPlaywrightExecutorClient(DefaultExecutorConfig())
 
 	return &executionTaskService{
 		repo:            repo,
 		projectRepo:     projectRepo,
 		ecrRepo:         ecrRepo,
 		userRepo:        userRepo,
-		pwClient:        pwClient,
+		runRepo:         runRepo,
+		scheduleRepo:    scheduleRepo,
+		attemptRepo:     attemptRepo,
+		artifactRepo:    artifactRepo,
+		profileRepo:     profileRepo,
+		caseGroupRepo:   caseGroupRepo,
+		executors:       executors,
 		variableService: variableService,
+		storagePath:     storagePath,
+		activeRuns:      make(map[string]*activeRun),
+		execSlots:       make(chan struct{}, DefaultExecutorConfig().MaxConcurrency),
+		events:          NewExecutionEventHub(),
+		instanceID:      serviceInstanceID(),
 	}
 }
 
@@ -129,6 +210,23 @@ func (s *executionTaskService) CreateTask(projectID uint, userID uint, req Creat
 	if req.TaskStatus != "" {
 		task.TaskStatus = req.TaskStatus
 	}
+	task.Concurrency = 1
+	if req.Concurrency > 0 {
+		if req.Concurrency > cap(s.execSlots) {
+			return nil, errors.New("并发数超过服务器上限")
+		}
+		task.Concurrency = req.Concurrency
+	}
+	task.RetryCount = req.RetryCount
+	task.RetryBackoffMs = 1000
+	if req.RetryBackoffMs != nil {
+		task.RetryBackoffMs = *req.RetryBackoffMs
+	}
+	browserOptions, err := EncodeBrowserOptions(req.BrowserOptions)
+	if err != nil {
+		return nil, err
+	}
+	task.BrowserOptions = browserOptions
 
 	// 3. 创建任务(BeforeCreate Hook会生成UUID)
 	err = s.repo.Create(task)
@@ -176,7 +274,12 @@ func (s *executionTaskService) UpdateTask(projectID uint, userID uint, taskUUID
 		}
 	}
 
-	// 3. 验证日期范围逻辑
+	// 3. 验证并发数不超过服务器上限
+	if req.Concurrency != nil && *req.Concurrency > cap(s.execSlots) {
+		return nil, errors.New("并发数超过服务器上限")
+	}
+
+	// 3B. 验证日期范围逻辑
 	startDate := task.StartDate
 	endDate := task.EndDate
 
@@ -196,6 +299,14 @@ func (s *executionTaskService) UpdateTask(projectID uint, userID uint, taskUUID
 	// 4. 构建更新字段Map
 	updates := make(map[string]interface{})
 
+	if req.BrowserOptions != nil {
+		browserOptions, err := EncodeBrowserOptions(req.BrowserOptions)
+		if err != nil {
+			return nil, err
+		}
+		updates["browser_options"] = browserOptions
+	}
+
 	if req.TaskName != nil {
 		updates["task_name"] = *req.TaskName
 		fmt.Printf("[UpdateTask] Adding task_name to updates: %s\n", *req.TaskName)
@@ -220,6 +331,15 @@ func (s *executionTaskService) UpdateTask(projectID uint, userID uint, taskUUID
 		updates["display_language"] = *req.DisplayLanguage
 		fmt.Printf("[UpdateTask] Adding display_language to updates: %s\n", *req.DisplayLanguage)
 	}
+	if req.Concurrency != nil {
+		updates["concurrency"] = *req.Concurrency
+	}
+	if req.RetryCount != nil {
+		updates["retry_count"] = *req.RetryCount
+	}
+	if req.RetryBackoffMs != nil {
+		updates["retry_backoff_ms"] = *req.RetryBackoffMs
+	}
 	if req.StartDate != nil {
 		updates["start_date"] = *req.StartDate
 	}
@@ -353,13 +473,29 @@ func (s *executionTaskService) DeleteTask(projectID uint, userID uint, taskUUID
 		return errors.New("任务不属于该项目")
 	}
 
-	// 2. 级联删除：先删除执行任务的所有执行用例结果（元数据）
+	// 2. 删除定时执行计划，停止正在执行的批次，并删除批次记录
+	if err := s.scheduleRepo.DeleteByTaskUUID(taskUUID); err != nil {
+		return fmt.Errorf("delete execution schedules: %w", err)
+	}
+	s.stopTaskRuns(taskUUID)
+	s.events.Forget(taskUUID)
+	if err := s.runRepo.DeleteByTaskUUID(taskUUID); err != nil {
+		return fmt.Errorf("delete execution runs: %w", err)
+	}
+	if err := s.attemptRepo.DeleteByTaskUUID(taskUUID); err != nil {
+		return fmt.Errorf("delete execution attempts: %w", err)
+	}
+	if err := s.removeTaskArtifacts(projectID, taskUUID); err != nil {
+		return fmt.Errorf("delete execution artifacts: %w", err)
+	}
+
+	// 3. 级联删除：删除执行任务的所有执行用例结果（元数据）
 	err = s.ecrRepo.DeleteByTaskUUID(taskUUID)
 	if err != nil {
 		return fmt.Errorf("delete execution case results: %w", err)
 	}
 
-	// 3. 执行硬删除执行任务
+	// 4. 执行硬删除执行任务
 	err = s.repo.Delete(taskUUID)
 	if err != nil {
 		return fmt.Errorf("delete task: %w", err)
@@ -381,138 +517,112 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// ExecuteTask 执行测试任务
-func (s *executionTaskService) ExecuteTask(projectID uint, userID uint, taskUUID string) (*ExecuteTaskResult, error) {
-	fmt.Printf("[ExecuteTask] 开始执行任务: projectID=%d, userID=%d, taskUUID=%s\n", projectID, userID, taskUUID)
+// ExecuteTask 创建执行批次并在后台执行测试任务
+// 立即返回排队中的批次，调用方通过 GetRun 轮询进度
+func (s *executionTaskService) ExecuteTask(projectID uint, userID uint, taskUUID string) (*models.ExecutionRun, error) {
+	return s.startTaskRun(projectID, userID, taskUUID, runOptions{TriggerType: models.ExecutionRunTriggerManual})
+}
+
+// ExecuteScheduledTask 由定时执行计划触发任务执行，批次记录计划ID
+func (s *executionTaskService) ExecuteScheduledTask(projectID uint, userID uint, taskUUID string, scheduleID uint) (*models.ExecutionRun, error) {
+	return s.startTaskRun(projectID, userID, taskUUID, runOptions{TriggerType: models.ExecutionRunTriggerSchedule, ScheduleID: scheduleID})
+}
+
+// runOptions 创建执行批次的触发信息
+type runOptions struct {
+	TriggerType       string
+	ScheduleID        uint                   // 定时触发时的执行计划ID
+	VariableOverrides map[string]string      // 覆盖任务变量(仅本批次有效)
+	TaskUpdates       map[string]interface{} // 创建批次前写入任务的字段(如CI触发的测试版本/环境)
+}
+
+// startTaskRun 创建执行批次并在后台执行
+func (s *executionTaskService) startTaskRun(projectID uint, userID uint, taskUUID string, opts runOptions) (*models.ExecutionRun, error) {
+	fmt.Printf("[ExecuteTask] 开始执行任务: projectID=%d, userID=%d, taskUUID=%s, trigger=%s\n", projectID, userID, taskUUID, opts.TriggerType)
+
+	task, cases, err := s.loadRunnableCases(projectID, taskUUID)
+	if err != nil {
+		return nil, err
+	}
 
+	// 4. 同一任务同时只允许一个执行批次
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	if _, err := s.runRepo.GetActiveByTaskUUID(taskUUID); err == nil {
+		return nil, errors.New("任务正在执行中")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("get active run: %w", err)
+	}
+
+	if len(opts.TaskUpdates) > 0 {
+		if err := s.repo.UpdateByUUID(taskUUID, opts.TaskUpdates); err != nil {
+			return nil, fmt.Errorf("update task: %w", err)
+		}
+	}
+
+	// 5. 重置可执行用例的结果为NR，批次以NR标记待执行用例，重启后据此恢复
+	if err := s.ecrRepo.ResetTestResults(taskUUID, executableCaseIDs(task, cases)); err != nil {
+		return nil, fmt.Errorf("reset case results: %w", err)
+	}
+
+	run := &models.ExecutionRun{
+		TaskUUID:    taskUUID,
+		ProjectID:   projectID,
+		Status:      models.ExecutionRunStatusQueued,
+		Total:       len(cases),
+		TriggeredBy: userID,
+		TriggerType: opts.TriggerType,
+		ScheduleID:  opts.ScheduleID,
+		ExecutedBy:  s.getUserName(userID),
+	}
+	if len(opts.VariableOverrides) > 0 {
+		data, err := json.Marshal(opts.VariableOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("marshal variable overrides: %w", err)
+		}
+		run.VariableOverrides = string(data)
+	}
+	s.leaseRun(run)
+	if err := s.runRepo.Create(run); err != nil {
+		return nil, fmt.Errorf("create run: %w", err)
+	}
+
+	s.startRunLocked(run)
+	fmt.Printf("[ExecuteTask] 执行批次已创建: run_uuid=%s\n", run.RunUUID)
+	return run, nil
+}
+
+// loadRunnableCases 校验任务可自动执行并返回任务及其用例结果
+func (s *executionTaskService) loadRunnableCases(projectID uint, taskUUID string) (*models.ExecutionTask, []*models.ExecutionCaseResult, error) {
 	// 1. 获取任务信息
 	task, err := s.repo.GetByUUID(taskUUID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("任务不存在")
+			return nil, nil, errors.New("任务不存在")
 		}
-		return nil, fmt.Errorf("get task by uuid: %w", err)
+		return nil, nil, fmt.Errorf("get task by uuid: %w", err)
 	}
 	if task.ProjectID != projectID {
-		return nil, errors.New("任务不属于该项目")
+		return nil, nil, errors.New("任务不属于该项目")
 	}
 	fmt.Printf("[ExecuteTask] 任务信息: task_name=%s, execution_type=%s\n", task.TaskName, task.ExecutionType)
 
 	// 2. 检查执行类型
 	if task.ExecutionType == "manual" {
-		return nil, errors.New("手工测试类型不支持自动执行")
+		return nil, nil, errors.New("手工测试类型不支持自动执行")
 	}
 
 	// 3. 获取用例列表
 	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
 	if err != nil {
-		return nil, fmt.Errorf("get case results: %w", err)
+		return nil, nil, fmt.Errorf("get case results: %w", err)
 	}
 	if len(cases) == 0 {
-		return nil, errors.New("没有可执行的用例")
+		return nil, nil, errors.New("没有可执行的用例")
 	}
 	fmt.Printf("[ExecuteTask] 获取到 %d 个用例\n", len(cases))
-
-	// 3B. 获取任务变量用于脚本替换
-	var variables []*models.UserDefinedVariable
-	if task.CaseGroupID > 0 {
-		groupType := "web"
-		if task.ExecutionType == "api" {
-			groupType = "api"
-		}
-		fmt.Printf("[ExecuteTask] 从任务变量表获取变量: taskUUID=%s, groupID=%d, groupType=%s\n", taskUUID, task.CaseGroupID, groupType)
-		variables, err = s.variableService.GetVariablesByTask(taskUUID, task.CaseGroupID, groupType)
-		if err != nil {
-			fmt.Printf("[ExecuteTask] ❌ 警告: 获取变量失败: %v\n", err)
-			variables = []*models.UserDefinedVariable{} // 继续执行，不中断
-		}
-		fmt.Printf("[ExecuteTask] ✅ 获取到 %d 个任务变量\n", len(variables))
-		for i, v := range variables {
-			fmt.Printf("[ExecuteTask]   变量 %d: var_key=%s, var_value=%s (长度:%d)\n", i+1, v.VarKey, maskValue(v.VarKey, v.VarValue), len(v.VarValue))
-		}
-	} else {
-		fmt.Printf("[ExecuteTask] 跳过变量获取 (case_group_id=%d)\n", task.CaseGroupID)
-	}
-
-	// 4. 确定remark语言
-	lang := task.DisplayLanguage
-	if lang == "" {
-		lang = "cn"
-	}
-
-	// 5. 逐个执行用例
-	var okCount, ngCount, blockCount int
-	for i, c := range cases {
-		fmt.Printf("[ExecuteTask] 执行用例 %d/%d: case_id=%d, has_script=%v\n",
-			i+1, len(cases), c.ID, c.ScriptCode != "")
-
-		if c.ScriptCode == "" {
-			blockCount++
-			continue
-		}
-
-		// 替换脚本中的变量
-		fmt.Printf("[ExecuteTask] 用例 %d 脚本替换前长度: %d bytes\n", c.ID, len(c.ScriptCode))
-		replacedScript := s.replaceVariables(c.ScriptCode, variables)
-		fmt.Printf("[ExecuteTask] 用例 %d 脚本替换后长度: %d bytes\n", c.ID, len(replacedScript))
-		if strings.Contains(replacedScript, "${") {
-			fmt.Printf("[ExecuteTask] ⚠️ 用例 %d 脚本仍包含变量占位符！\n", c.ID)
-		}
-
-		// 调用 Playwright Server 执行脚本
-		fmt.Printf("[ExecuteTask] 开始执行 Playwright 脚本...\n")
-		execResult, execErr := s.executeViaPlaywright(replacedScript)
-		if execErr != nil {
-			// 执行失败
-			fmt.Printf("[ExecuteTask] 执行失败: %v\n", execErr)
-			c.TestResult = "NG"
-			c.Remark = s.getRemarkByLang(lang, false, execErr.Error())
-			ngCount++
-		} else {
-			fmt.Printf("[ExecuteTask] 执行成功: response_time=%dms\n", execResult.ResponseTime)
-			c.TestResult = "OK"
-			c.Remark = s.getRemarkByLang(lang, true, "")
-			okCount++
-		}
-		// 记录执行时间（无论成功失败，只要有结果就记录）
-		if execResult != nil && execResult.ResponseTime > 0 {
-			c.ResponseTime = fmt.Sprintf("%d", execResult.ResponseTime)
-		}
-
-		// 更新数据库
-		updates := map[string]interface{}{
-			"test_result": c.TestResult,
-			"remark":      c.Remark,
-		}
-		if c.ResponseTime != "" {
-			updates["response_time"] = c.ResponseTime
-		}
-		if updateErr := s.ecrRepo.UpdateResult(c.ID, updates); updateErr != nil {
-			return nil, fmt.Errorf("update case result: %w", updateErr)
-		}
-	}
-
-	fmt.Printf("[ExecuteTask] 执行完成: OK=%d, NG=%d, Block=%d\n", okCount, ngCount, blockCount)
-
-	// 6. 更新任务的测试日期和执行人
-	now := time.Now()
-	executor := s.getUserName(userID)
-	updates := map[string]interface{}{
-		"test_date": now,
-		"executor":  executor,
-	}
-	if updateErr := s.repo.UpdateByUUID(taskUUID, updates); updateErr != nil {
-		return nil, fmt.Errorf("update task: %w", updateErr)
-	}
-
-	return &ExecuteTaskResult{
-		Total:      len(cases),
-		OKCount:    okCount,
-		NGCount:    ngCount,
-		BlockCount: blockCount,
-		ExecutedAt: now,
-		ExecutedBy: executor,
-	}, nil
+	return task, cases, nil
 }
 
 // maskValue masks sensitive variable values
@@ -536,6 +646,11 @@ func (s *executionTaskService) replaceVariables(script string, variables []*mode
 
 	result := script
 	for _, v := range variables {
+		// 0. 原样格式 "${orderId}"（批次提取变量区分大小写）
+		if v.VarKey != "" {
+			result = strings.ReplaceAll(result, fmt.Sprintf("${%s}", v.VarKey), v.VarValue)
+		}
+
 		// 1. 使用 VarKey 替换大写格式 "${BASE_URL}"
 		if v.VarKey != "" {
 			upperKey := strings.ToUpper(v.VarKey)
@@ -588,22 +703,42 @@ func (s *executionTaskService) getRemarkByLang(lang string, success bool, errMsg
 	}
 }
 
-// executeViaPlaywright 通过 Playwright Server 执行脚本
-// 使用 HTTP 调用 playwright-executor 服务
-func (s *executionTaskService) executeViaPlaywright(scriptCode string) (*DockerExecResult, error) {
-	fmt.Printf("[executeViaPlaywright] 开始执行脚本，长度: %d bytes\n", len(scriptCode))
+// executeScript 由任务选定的执行器执行脚本
+// 执行前先占用服务器范围的并发槽，所有批次共享同一上限；脚本失败时结果中保留输出/堆栈/产物
+func (s *executionTaskService) executeScript(ctx context.Context, task *models.ExecutionTask, scriptCode string, browser *BrowserOptions) (*DockerExecResult, error) {
+	executor, err := s.resolveExecutor(task, CaseKindScript)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx := context.Background()
-	result, err := s.pwClient.Execute(ctx, scriptCode)
+	release, err := s.acquireExecSlot(ctx)
 	if err != nil {
-		fmt.Printf("[executeViaPlaywright] 执行失败: %v\n", err)
 		return nil, err
 	}
+	defer release()
+
+	fmt.Printf("[executeScript] 开始执行脚本，执行器: %s，长度: %d bytes\n", executor.Name(), len(scriptCode))
+
+	result, err := executor.Execute(ctx, ExecutorRequest{ScriptCode: scriptCode, Browser: browser})
+	if err != nil {
+		fmt.Printf("[executeScript] 执行失败: %v\n", err)
+		return result, err
+	}
 
-	fmt.Printf("[executeViaPlaywright] 执行完成，耗时: %dms\n", result.ResponseTime)
+	fmt.Printf("[executeScript] 执行完成，耗时: %dms\n", result.ResponseTime)
 	return result, nil
 }
 
+// acquireExecSlot 占用一个服务器范围的执行槽，脚本和接口用例共用；ctx 取消时返回 ctx.Err()
+func (s *executionTaskService) acquireExecSlot(ctx context.Context) (func(), error) {
+	select {
+	case s.execSlots <- struct{}{}:
+		return func() { <-s.execSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // getUserName 获取用户名
 func (s *executionTaskService) getUserName(userID uint) string {
 	user, err := s.userRepo.FindByID(userID)
@@ -649,41 +784,15 @@ func (s *executionTaskService) ExecuteSingleCase(projectID uint, userID uint, ta
 	if c.TaskUUID != taskUUID {
 		return nil, errors.New("用例不属于该任务")
 	}
-	fmt.Printf("[ExecuteSingleCase] 用例信息: case_id=%d, has_script=%v\n", c.ID, c.ScriptCode != "")
+	fmt.Printf("[ExecuteSingleCase] 用例信息: case_id=%d, has_script=%v, has_url=%v\n", c.ID, c.ScriptCode != "", c.URL != "")
 
-	// 4. 检查是否有脚本
-	if c.ScriptCode == "" {
+	// 4. 检查是否可执行(API用例有URL即可，其余需要脚本)
+	if !isCaseExecutable(task, c) {
 		return nil, errors.New("用例没有脚本代码，无法执行")
 	}
 
-	// 4B. 获取任务变量用于脚本替换
-	var variables []*models.UserDefinedVariable
-	if task.CaseGroupID > 0 {
-		groupType := "web"
-		if task.ExecutionType == "api" {
-			groupType = "api"
-		}
-		fmt.Printf("[ExecuteSingleCase] 从任务变量表获取变量: taskUUID=%s, groupID=%d, groupType=%s\n", taskUUID, task.CaseGroupID, groupType)
-		variables, err = s.variableService.GetVariablesByTask(taskUUID, task.CaseGroupID, groupType)
-		if err != nil {
-			fmt.Printf("[ExecuteSingleCase] ❌ 警告: 获取变量失败: %v\n", err)
-			variables = []*models.UserDefinedVariable{} // 继续执行，不中断
-		}
-		fmt.Printf("[ExecuteSingleCase] ✅ 获取到 %d 个任务变量\n", len(variables))
-		for i, v := range variables {
-			fmt.Printf("[ExecuteSingleCase]   变量 %d: var_key=%s, var_value=%s (长度:%d)\n", i+1, v.VarKey, maskValue(v.VarKey, v.VarValue), len(v.VarValue))
-		}
-	} else {
-		fmt.Printf("[ExecuteSingleCase] 跳过变量获取 (case_group_id=%d)\n", task.CaseGroupID)
-	}
-
-	// 4C. 替换脚本中的变量
-	fmt.Printf("[ExecuteSingleCase] 脚本替换前长度: %d bytes\n", len(c.ScriptCode))
-	replacedScript := s.replaceVariables(c.ScriptCode, variables)
-	fmt.Printf("[ExecuteSingleCase] 脚本替换后长度: %d bytes\n", len(replacedScript))
-	if strings.Contains(replacedScript, "${") {
-		fmt.Printf("[ExecuteSingleCase] ⚠️ 脚本仍包含变量占位符！\n")
-	}
+	// 4B. 获取任务变量用于脚本替换，并沿用最近一次批次提取的变量
+	variables := mergeRunVariables(s.loadTaskVariables(task, "ExecuteSingleCase"), s.latestRunVariables(taskUUID))
 
 	// 5. 确定remark语言
 	lang := task.DisplayLanguage
@@ -691,42 +800,33 @@ func (s *executionTaskService) ExecuteSingleCase(projectID uint, userID uint, ta
 		lang = "cn"
 	}
 
-	// 6. 执行用例
-	fmt.Printf("[ExecuteSingleCase] 开始执行 Playwright 脚本...\n")
-	execResult, execErr := s.executeViaPlaywright(replacedScript)
-	var okCount, ngCount, blockCount int
-	if execErr != nil {
-		// 执行失败
-		fmt.Printf("[ExecuteSingleCase] 执行失败: %v\n", execErr)
-		c.TestResult = "NG"
-		c.Remark = s.getRemarkByLang(lang, false, execErr.Error())
-		ngCount = 1
-	} else {
-		fmt.Printf("[ExecuteSingleCase] 执行成功: response_time=%dms\n", execResult.ResponseTime)
-		c.TestResult = "OK"
-		c.Remark = s.getRemarkByLang(lang, true, "")
-		okCount = 1
-	}
-	// 记录执行时间（api和automation类型都记录）
-	if execResult != nil && execResult.ResponseTime > 0 {
-		c.ResponseTime = fmt.Sprintf("%d", execResult.ResponseTime)
+	// 5B. 执行前检查变量，存在未定义变量时拒绝执行
+	lint := lintVariables(caseResultLintInputs(task, []*models.ExecutionCaseResult{c}), variables)
+	if !lint.Valid {
+		return nil, fmt.Errorf("用例引用了未定义的变量: %s", strings.Join(lint.Undefined, ", "))
 	}
 
-	// 7. 更新数据库
-	updates := map[string]interface{}{
-		"test_result": c.TestResult,
-		"remark":      c.Remark,
+	// 6. 执行用例并写回结果
+	executor := s.getUserName(userID)
+	actx := attemptContext{
+		ProjectID:        projectID,
+		UserID:           userID,
+		ExecutorName:     executor,
+		VariableSnapshot: variableSnapshot(variables),
 	}
-	if c.ResponseTime != "" {
-		updates["response_time"] = c.ResponseTime
+	testResult, err := s.runCase(context.Background(), task, c, variables, lang, actx, "ExecuteSingleCase")
+	if err != nil {
+		return nil, err
 	}
-	if updateErr := s.ecrRepo.UpdateResult(c.ID, updates); updateErr != nil {
-		return nil, fmt.Errorf("update case result: %w", updateErr)
+	var okCount, ngCount, blockCount int
+	if testResult == "OK" {
+		okCount = 1
+	} else {
+		ngCount = 1
 	}
 
-	// 8. 更新任务的测试日期和执行人
+	// 7. 更新任务的测试日期和执行人
 	now := time.Now()
-	executor := s.getUserName(userID)
 	taskUpdates := map[string]interface{}{
 		"test_date": now,
 		"executor":  executor,
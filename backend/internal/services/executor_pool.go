@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 执行器节点状态
+const (
+	EndpointStateHealthy   = "healthy"   // 可接收请求
+	EndpointStateUnhealthy = "unhealthy" // 最近一次 /health 探测失败
+	EndpointStateOpen      = "open"      // 连续失败已熔断
+	EndpointStateHalfOpen  = "half_open" // 熔断到期，放行一个试探请求
+)
+
+// throughputWindow 吞吐量统计窗口
+const throughputWindow = time.Minute
+
+// errEndpointDown 节点被判定宕机时取消其上正在执行的请求，用例改投其他节点
+var errEndpointDown = errors.New("执行器节点不可用")
+
+// ExecutorEndpointStatus 执行器节点的状态和吞吐量
+type ExecutorEndpointStatus struct {
+	URL                 string     `json:"url"`
+	State               string     `json:"state"` // healthy/unhealthy/open/half_open
+	InFlight            int        `json:"in_flight"`
+	Completed           int64      `json:"completed"`            // 执行完成数(含脚本失败)
+	Failed              int64      `json:"failed"`               // 节点故障数(网络错误/超时/宕机)
+	Requeued            int64      `json:"requeued"`             // 因节点故障改投其他节点的用例数
+	ConsecutiveFailures int        `json:"consecutive_failures"` // 连续故障次数(含探测失败)
+	ThroughputPerMin    int        `json:"throughput_per_min"`   // 最近一分钟完成数
+	AvgDurationMs       int64      `json:"avg_duration_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheckAt         *time.Time `json:"last_check_at"`
+	CircuitOpenedAt     *time.Time `json:"circuit_opened_at"`
+}
+
+// ExecutorPoolStatus 执行器节点池状态
+type ExecutorPoolStatus struct {
+	Total     int                      `json:"total"`
+	Available int                      `json:"available"`
+	InFlight  int                      `json:"in_flight"`
+	Endpoints []ExecutorEndpointStatus `json:"endpoints"`
+}
+
+// executorEndpoint 单个执行器节点，字段由 ExecutorPool.mu 保护
+type executorEndpoint struct {
+	url    string
+	client *PlaywrightExecutorClient
+
+	healthy             bool
+	consecutiveFailures int
+	openedAt            time.Time // 熔断时间，零值表示未熔断
+	trialInFlight       bool      // 半开状态下已放行试探请求
+
+	inFlight   map[int64]context.CancelCauseFunc
+	dispatched int64
+	completed  int64
+	failed     int64
+	requeued   int64
+	durationMs int64
+	recent     []time.Time // 最近一分钟的完成时间
+	lastError  string
+	lastCheck  time.Time
+}
+
+// ExecutorPool 执行器节点池
+// 定期探测各节点 /health，按正在执行数最少的节点分发脚本；
+// 节点连续故障达到阈值后熔断，其上正在执行的用例改投其他节点
+type ExecutorPool struct {
+	config    PlaywrightExecutorConfig
+	endpoints []*executorEndpoint
+
+	mu     sync.Mutex
+	nextID int64
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// NewExecutorPool 按配置的节点地址创建节点池
+func NewExecutorPool(config PlaywrightExecutorConfig) *ExecutorPool {
+	urls := config.ExecutorURLs
+	if len(urls) == 0 {
+		urls = []string{config.ExecutorURL}
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.CircuitOpenDuration <= 0 {
+		config.CircuitOpenDuration = 30 * time.Second
+	}
+	if config.HealthInterval <= 0 {
+		config.HealthInterval = 15 * time.Second
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 1
+	}
+
+	p := &ExecutorPool{
+		config: config,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	for _, u := range urls {
+		endpointConfig := config
+		endpointConfig.ExecutorURL = u
+		p.endpoints = append(p.endpoints, &executorEndpoint{
+			url:      u,
+			client:   NewPlaywrightExecutorClient(endpointConfig),
+			healthy:  true, // 首次探测前视为可用
+			inFlight: make(map[int64]context.CancelCauseFunc),
+		})
+	}
+	return p
+}
+
+// Start 启动后台健康探测
+func (p *ExecutorPool) Start() {
+	p.startOnce.Do(func() {
+		fmt.Printf("[ExecutorPool] 健康探测启动: endpoints=%d, interval=%v\n", len(p.endpoints), p.config.HealthInterval)
+		go func() {
+			defer close(p.doneCh)
+			ticker := time.NewTicker(p.config.HealthInterval)
+			defer ticker.Stop()
+
+			p.Probe(context.Background())
+			for {
+				select {
+				case <-ticker.C:
+					p.Probe(context.Background())
+				case <-p.stopCh:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// Stop 停止健康探测
+func (p *ExecutorPool) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+	})
+	p.startOnce.Do(func() { close(p.doneCh) }) // 未启动时无需等待
+	<-p.doneCh
+}
+
+// Probe 并发探测所有节点的 /health，没有可用节点时返回错误
+func (p *ExecutorPool) Probe(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *executorEndpoint) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, executorHealthTimeout)
+			defer cancel()
+			p.recordProbe(ep, ep.client.checkHealth(checkCtx))
+		}(ep)
+	}
+	wg.Wait()
+
+	if p.Status().Available == 0 {
+		return errors.New("没有可用的执行器节点")
+	}
+	return nil
+}
+
+// recordProbe 记录探测结果，连续失败达到阈值时熔断并取消节点上正在执行的请求
+// 探测成功与请求成功相同：清零连续失败次数并关闭熔断
+func (p *ExecutorPool) recordProbe(ep *executorEndpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep.lastCheck = time.Now()
+	if err == nil {
+		if !ep.healthy || !ep.openedAt.IsZero() {
+			fmt.Printf("[ExecutorPool] 节点恢复: url=%s\n", ep.url)
+		}
+		p.recordSuccessLocked(ep)
+		return
+	}
+	if ep.healthy {
+		fmt.Printf("[ExecutorPool] ⚠️ 节点探测失败: url=%s, error=%v\n", ep.url, err)
+	}
+	ep.healthy = false
+	ep.lastError = err.Error()
+	p.recordFailureLocked(ep)
+}
+
+// recordSuccessLocked 节点工作正常，清零连续失败次数并关闭熔断
+func (p *ExecutorPool) recordSuccessLocked(ep *executorEndpoint) {
+	ep.consecutiveFailures = 0
+	ep.openedAt = time.Time{}
+	ep.trialInFlight = false
+	ep.healthy = true
+}
+
+// recordFailureLocked 累计节点故障，达到阈值时熔断
+func (p *ExecutorPool) recordFailureLocked(ep *executorEndpoint) {
+	ep.consecutiveFailures++
+	if ep.consecutiveFailures < p.config.FailureThreshold {
+		return
+	}
+	if ep.openedAt.IsZero() || ep.trialInFlight {
+		fmt.Printf("[ExecutorPool] ❌ 节点熔断: url=%s, consecutive_failures=%d, in_flight=%d\n", ep.url, ep.consecutiveFailures, len(ep.inFlight))
+	}
+	ep.openedAt = time.Now()
+	ep.trialInFlight = false
+	// 节点已不可用，正在执行的用例改投其他节点
+	for _, cancel := range ep.inFlight {
+		cancel(errEndpointDown)
+	}
+}
+
+// stateLocked 计算节点当前状态
+func (p *ExecutorPool) stateLocked(ep *executorEndpoint, now time.Time) string {
+	if !ep.openedAt.IsZero() {
+		if now.Sub(ep.openedAt) < p.config.CircuitOpenDuration {
+			return EndpointStateOpen
+		}
+		return EndpointStateHalfOpen
+	}
+	if !ep.healthy {
+		return EndpointStateUnhealthy
+	}
+	return EndpointStateHealthy
+}
+
+// routableLocked 节点是否可接收新请求(半开状态只放行一个试探请求)
+func (p *ExecutorPool) routableLocked(ep *executorEndpoint, now time.Time) bool {
+	switch p.stateLocked(ep, now) {
+	case EndpointStateHealthy:
+		return true
+	case EndpointStateHalfOpen:
+		return !ep.trialInFlight
+	}
+	return false
+}
+
+// acquire 选择正在执行数最少的可用节点，优先选择本次用例未失败过的节点
+func (p *ExecutorPool) acquire(ctx context.Context, tried map[*executorEndpoint]bool) (*executorEndpoint, int64, context.Context, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *executorEndpoint
+	for _, fresh := range []bool{true, false} {
+		for _, ep := range p.endpoints {
+			if tried[ep] == fresh || !p.routableLocked(ep, now) {
+				continue
+			}
+			if best == nil || len(ep.inFlight) < len(best.inFlight) ||
+				(len(ep.inFlight) == len(best.inFlight) && ep.dispatched < best.dispatched) {
+				best = ep
+			}
+		}
+		if best != nil {
+			break
+		}
+	}
+	if best == nil {
+		return nil, 0, nil, errors.New("没有可用的执行器节点")
+	}
+
+	if p.stateLocked(best, now) == EndpointStateHalfOpen {
+		best.trialInFlight = true
+		fmt.Printf("[ExecutorPool] 熔断到期，放行试探请求: url=%s\n", best.url)
+	}
+	p.nextID++
+	id := p.nextID
+	callCtx, cancel := context.WithCancelCause(ctx)
+	best.inFlight[id] = cancel
+	best.dispatched++
+	return best, id, callCtx, nil
+}
+
+// release 记录请求结果；节点故障返回 true，调用方应改投其他节点
+func (p *ExecutorPool) release(ep *executorEndpoint, id int64, callCtx context.Context, err error, duration time.Duration) (endpointFailed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cancel, ok := ep.inFlight[id]; ok {
+		delete(ep.inFlight, id)
+		defer cancel(nil)
+	}
+
+	var scriptErr *ScriptExecutionError
+	switch {
+	case err == nil || errors.As(err, &scriptErr):
+		// 脚本执行失败说明节点工作正常
+		now := time.Now()
+		ep.completed++
+		ep.durationMs += duration.Milliseconds()
+		ep.recent = append(pruneRecent(ep.recent, now), now)
+		if !ep.openedAt.IsZero() {
+			fmt.Printf("[ExecutorPool] 试探请求成功，节点恢复: url=%s\n", ep.url)
+		}
+		p.recordSuccessLocked(ep)
+		return false
+	case errors.Is(context.Cause(callCtx), errEndpointDown):
+		// 节点已被判定宕机，故障已计入
+		ep.failed++
+		ep.requeued++
+		return true
+	case callCtx.Err() != nil:
+		// 调用方取消，不计入节点故障
+		ep.trialInFlight = false
+		return false
+	default:
+		ep.failed++
+		ep.requeued++
+		ep.lastError = err.Error()
+		p.recordFailureLocked(ep)
+		return true
+	}
+}
+
+// Execute 在节点池中执行脚本，节点故障时改投其他节点，最多分发 MaxRetries 次
+func (p *ExecutorPool) Execute(ctx context.Context, scriptCode string, browser *BrowserOptions) (*DockerExecResult, error) {
+	startTime := time.Now()
+	tried := make(map[*executorEndpoint]bool)
+	var lastErr error
+
+	for attempt := 0; attempt < p.config.MaxRetries; attempt++ {
+		ep, id, callCtx, err := p.acquire(ctx, tried)
+		if err != nil {
+			if lastErr == nil {
+				lastErr = err
+			}
+			break
+		}
+		if attempt > 0 {
+			fmt.Printf("[ExecutorPool] 用例改投节点 %d/%d: url=%s\n", attempt, p.config.MaxRetries, ep.url)
+		}
+
+		callStart := time.Now()
+		result, execErr := ep.client.doExecute(callCtx, scriptCode, browser)
+		if execErr != nil && errors.Is(context.Cause(callCtx), errEndpointDown) {
+			execErr = fmt.Errorf("%w: %s", errEndpointDown, ep.url)
+		}
+		if !p.release(ep, id, callCtx, execErr, time.Since(callStart)) {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("execute cancelled: %w", ctx.Err())
+			}
+			if execErr != nil {
+				return failedExecResult(execErr, int(time.Since(startTime).Milliseconds())), execErr
+			}
+			result.ResponseTime = int(time.Since(startTime).Milliseconds())
+			return result, nil
+		}
+
+		tried[ep] = true
+		lastErr = execErr
+		fmt.Printf("[ExecutorPool] ⚠️ 节点执行失败: url=%s, error=%v\n", ep.url, execErr)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("execute cancelled: %w", ctx.Err())
+		}
+	}
+
+	return failedExecResult(lastErr, int(time.Since(startTime).Milliseconds())),
+		fmt.Errorf("execute failed after %d retries: %w", p.config.MaxRetries, lastErr)
+}
+
+// Status 返回各节点状态和吞吐量
+func (p *ExecutorPool) Status() *ExecutorPoolStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	status := &ExecutorPoolStatus{Total: len(p.endpoints), Endpoints: make([]ExecutorEndpointStatus, 0, len(p.endpoints))}
+	for _, ep := range p.endpoints {
+		ep.recent = pruneRecent(ep.recent, now)
+		s := ExecutorEndpointStatus{
+			URL:                 ep.url,
+			State:               p.stateLocked(ep, now),
+			InFlight:            len(ep.inFlight),
+			Completed:           ep.completed,
+			Failed:              ep.failed,
+			Requeued:            ep.requeued,
+			ConsecutiveFailures: ep.consecutiveFailures,
+			ThroughputPerMin:    len(ep.recent),
+			LastError:           ep.lastError,
+		}
+		if ep.completed > 0 {
+			s.AvgDurationMs = ep.durationMs / ep.completed
+		}
+		if !ep.lastCheck.IsZero() {
+			lastCheck := ep.lastCheck
+			s.LastCheckAt = &lastCheck
+		}
+		if !ep.openedAt.IsZero() {
+			openedAt := ep.openedAt
+			s.CircuitOpenedAt = &openedAt
+		}
+		if p.routableLocked(ep, now) {
+			status.Available++
+		}
+		status.InFlight += s.InFlight
+		status.Endpoints = append(status.Endpoints, s)
+	}
+	return status
+}
+
+// pruneRecent 丢弃统计窗口之外的完成时间
+func pruneRecent(recent []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(recent) && now.Sub(recent[i]) > throughputWindow {
+		i++
+	}
+	return recent[i:]
+}
+
+// checkHealth 请求执行器的 /health 接口
+func (c *PlaywrightExecutorClient) checkHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.ExecutorURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request health: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health status %d", resp.StatusCode)
+	}
+	return nil
+}
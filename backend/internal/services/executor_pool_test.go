@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExecutorNode 模拟 playwright-executor 节点，execute 返回脚本执行结果
+func newExecutorNode(t *testing.T, calls *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		atomic.AddInt32(calls, 1)
+		_ = json.NewEncoder(w).Encode(ExecuteResponse{Success: true, Output: "ok", ResponseTime: 5})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestPool(urls ...string) *ExecutorPool {
+	return NewExecutorPool(PlaywrightExecutorConfig{
+		ExecutorURLs:        urls,
+		ExecuteTimeout:      5 * time.Second,
+		MaxRetries:          3,
+		FailureThreshold:    2,
+		CircuitOpenDuration: time.Hour,
+	})
+}
+
+func TestExecutorPool_LeastBusy(t *testing.T) {
+	p := newTestPool("http://a", "http://b")
+	a, b := p.endpoints[0], p.endpoints[1]
+
+	ep, _, _, err := p.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Same(t, a, ep)
+	// a 有正在执行的请求，分发给 b
+	ep, _, _, err = p.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Same(t, b, ep)
+	assert.Equal(t, 2, p.Status().InFlight)
+
+	// 本次用例已在 b 失败，优先其他节点
+	ep, _, _, err = p.acquire(context.Background(), map[*executorEndpoint]bool{b: true})
+	require.NoError(t, err)
+	assert.Same(t, a, ep)
+}
+
+func TestExecutorPool_RequeueAndCircuitBreak(t *testing.T) {
+	var calls int32
+	alive := newExecutorNode(t, &calls)
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	p := newTestPool(deadURL, alive.URL)
+	for i := 0; i < 2; i++ {
+		// 每次都先分发到空闲的故障节点，失败后改投存活节点
+		p.endpoints[1].dispatched = 100
+		result, err := p.Execute(context.Background(), "async (page) => {}", nil)
+		require.NoError(t, err)
+		assert.True(t, result.Success)
+	}
+	assert.Equal(t, int32(2), calls)
+
+	status := p.Status()
+	assert.Equal(t, EndpointStateOpen, status.Endpoints[0].State)
+	assert.Equal(t, int64(2), status.Endpoints[0].Requeued)
+	assert.Equal(t, EndpointStateHealthy, status.Endpoints[1].State)
+	assert.Equal(t, int64(2), status.Endpoints[1].Completed)
+	assert.Equal(t, 2, status.Endpoints[1].ThroughputPerMin)
+	assert.Equal(t, 1, status.Available)
+
+	// 熔断节点不再接收请求
+	_, err := p.Execute(context.Background(), "async (page) => {}", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), calls)
+	assert.Equal(t, int64(2), p.Status().Endpoints[0].Failed)
+}
+
+func TestExecutorPool_ScriptFailureKeepsEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ExecuteResponse{Success: false, Error: "locator not found", Stack: "at line 1"})
+	}))
+	defer server.Close()
+
+	p := newTestPool(server.URL)
+	for i := 0; i < 3; i++ {
+		result, err := p.Execute(context.Background(), "async (page) => {}", nil)
+		require.Error(t, err)
+		assert.Equal(t, "at line 1", result.Stack)
+	}
+	status := p.Status().Endpoints[0]
+	assert.Equal(t, EndpointStateHealthy, status.State)
+	assert.Equal(t, int64(3), status.Completed)
+	assert.Equal(t, int64(0), status.Failed)
+}
+
+func TestExecutorPool_ProbeCancelsInFlight(t *testing.T) {
+	p := newTestPool("http://a", "http://b")
+	a := p.endpoints[0]
+	_, id, callCtx, err := p.acquire(context.Background(), nil)
+	require.NoError(t, err)
+
+	// 探测连续失败达到阈值，节点上正在执行的请求被取消并改投
+	p.recordProbe(a, assert.AnError)
+	assert.NoError(t, callCtx.Err())
+	p.recordProbe(a, assert.AnError)
+	require.Error(t, callCtx.Err())
+	assert.True(t, p.release(a, id, callCtx, context.Canceled, 0))
+
+	status := p.Status()
+	assert.Equal(t, EndpointStateOpen, status.Endpoints[0].State)
+	assert.Equal(t, int64(1), status.Endpoints[0].Requeued)
+	assert.Equal(t, 0, status.InFlight)
+}
+
+func TestExecutorPool_ProbeSuccessResetsCircuit(t *testing.T) {
+	p := newTestPool("http://a")
+	a := p.endpoints[0]
+
+	// 探测成功清零连续失败次数，不连续的失败不会累计熔断
+	p.recordProbe(a, assert.AnError)
+	p.recordProbe(a, nil)
+	p.recordProbe(a, assert.AnError)
+	assert.Equal(t, EndpointStateUnhealthy, p.Status().Endpoints[0].State)
+
+	// 已熔断的节点探测恢复后重新接收请求
+	p.recordProbe(a, assert.AnError)
+	assert.Equal(t, EndpointStateOpen, p.Status().Endpoints[0].State)
+	p.recordProbe(a, nil)
+	status := p.Status().Endpoints[0]
+	assert.Equal(t, EndpointStateHealthy, status.State)
+	assert.Equal(t, 0, status.ConsecutiveFailures)
+	assert.Equal(t, 1, p.Status().Available)
+}
@@ -0,0 +1,686 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// UpdateManualStepRequest 记录步骤结果请求
+type UpdateManualStepRequest struct {
+	Result       string `json:"result" binding:"required,oneof=NR OK NG Block"`
+	ActualResult string `json:"actual_result"`
+}
+
+// FinishManualSessionRequest 完成手工执行请求，result 为空时按步骤结果判定(NG > Block > OK)
+type FinishManualSessionRequest struct {
+	Result  string `json:"result" binding:"omitempty,oneof=OK NG Block"`
+	Comment string `json:"comment"`
+	BugID   string `json:"bug_id" binding:"max=50"`
+}
+
+// ManualStepDetail 步骤结果及其证据
+type ManualStepDetail struct {
+	*models.ManualStepResult
+	Evidence []*models.ExecutionArtifact `json:"evidence"`
+}
+
+// ManualSessionDetail 手工执行会话详情
+type ManualSessionDetail struct {
+	*models.ManualExecutionSession
+	CurrentEffortMs int64              `json:"current_effort_ms"` // 含进行中时段的实际工时
+	Steps           []ManualStepDetail `json:"steps"`
+}
+
+// ManualEffortStat 按测试人员或用例集汇总的实际工时
+type ManualEffortStat struct {
+	TesterID      uint   `json:"tester_id,omitempty"`
+	Name          string `json:"name"`
+	Cases         int    `json:"cases"`          // 执行过的用例数
+	FinishedCases int    `json:"finished_cases"` // 已完成的用例数
+	EffortMs      int64  `json:"effort_ms"`
+	AvgEffortMs   int64  `json:"avg_effort_ms"` // 已完成用例的平均工时
+	OKCount       int    `json:"ok_count"`
+	NGCount       int    `json:"ng_count"`
+	BlockCount    int    `json:"block_count"`
+
+	finishedEffortMs int64
+	cases            map[uint]bool
+}
+
+// ManualEffortMetrics 任务的手工执行工时统计
+type ManualEffortMetrics struct {
+	TaskUUID      string              `json:"task_uuid"`
+	TotalEffortMs int64               `json:"total_effort_ms"`
+	Sessions      int                 `json:"sessions"`
+	FinishedCases int                 `json:"finished_cases"`
+	Testers       []*ManualEffortStat `json:"testers"`
+	CaseGroups    []*ManualEffortStat `json:"case_groups"`
+}
+
+// ManualExecutionService 手工用例引导式执行服务
+type ManualExecutionService interface {
+	StartSession(projectID uint, userID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error)
+	GetSession(projectID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error)
+	PauseSession(projectID uint, userID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error)
+	UpdateStep(projectID uint, userID uint, taskUUID string, caseResultID uint, stepIndex int, req UpdateManualStepRequest) (*models.ManualStepResult, error)
+	UploadEvidence(projectID uint, userID uint, taskUUID string, caseResultID uint, stepIndex int, file *multipart.FileHeader) (*models.ExecutionArtifact, error)
+	FinishSession(projectID uint, userID uint, taskUUID string, caseResultID uint, req FinishManualSessionRequest) (*ManualSessionDetail, error)
+	GetEffortMetrics(projectID uint, taskUUID string) (*ManualEffortMetrics, error)
+}
+
+type manualExecutionService struct {
+	repo         repositories.ManualExecutionRepository
+	taskRepo     repositories.ExecutionTaskRepository
+	ecrRepo      repositories.ExecutionCaseResultRepository
+	attemptRepo  repositories.ExecutionAttemptRepository
+	artifactRepo repositories.ExecutionArtifactRepository
+	userRepo     repositories.UserRepository
+	storagePath  string
+
+	mu sync.Mutex // 串行化会话状态变更，避免同一用例被重复开始
+}
+
+// NewManualExecutionService 创建服务实例
+func NewManualExecutionService(
+	repo repositories.ManualExecutionRepository,
+	taskRepo repositories.ExecutionTaskRepository,
+	ecrRepo repositories.ExecutionCaseResultRepository,
+	attemptRepo repositories.ExecutionAttemptRepository,
+	artifactRepo repositories.ExecutionArtifactRepository,
+	userRepo repositories.UserRepository,
+	storagePath string,
+) ManualExecutionService {
+	return &manualExecutionService{
+		repo:         repo,
+		taskRepo:     taskRepo,
+		ecrRepo:      ecrRepo,
+		attemptRepo:  attemptRepo,
+		artifactRepo: artifactRepo,
+		userRepo:     userRepo,
+		storagePath:  storagePath,
+	}
+}
+
+// StartSession 开始执行用例；测试人员已有未完成的会话时恢复该会话
+// 同一任务中测试人员只能同时进行一条用例，其他进行中的会话自动暂停
+func (s *manualExecutionService) StartSession(projectID uint, userID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error) {
+	task, c, err := s.getManualCase(projectID, taskUUID, caseResultID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	session, err := s.repo.GetLatestSession(caseResultID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("get latest session: %w", err)
+	}
+	if session != nil && session.Status != models.ManualSessionStatusFinished && session.TesterID != userID {
+		return nil, errors.New("该用例正由其他测试人员执行")
+	}
+
+	if err := s.pauseOtherSessions(taskUUID, userID, caseResultID, now); err != nil {
+		return nil, err
+	}
+
+	if session != nil && session.Status != models.ManualSessionStatusFinished {
+		if session.Status == models.ManualSessionStatusPaused {
+			session.Status = models.ManualSessionStatusInProgress
+			session.ResumedAt = &now
+			if err := s.repo.UpdateSession(session); err != nil {
+				return nil, err
+			}
+			fmt.Printf("[ManualExecution] 恢复执行: session_id=%d, case_result_id=%d, tester=%d\n", session.ID, caseResultID, userID)
+		}
+		return s.sessionDetail(session)
+	}
+
+	lang, text := manualStepsText(task.DisplayLanguage, c)
+	steps := make([]*models.ManualStepResult, 0)
+	for i, step := range splitManualSteps(text) {
+		steps = append(steps, &models.ManualStepResult{StepIndex: i + 1, StepText: step, Result: "NR", UpdatedBy: userID})
+	}
+	session = &models.ManualExecutionSession{
+		ProjectID:     projectID,
+		TaskUUID:      taskUUID,
+		CaseResultID:  caseResultID,
+		CaseGroupName: c.CaseGroupName,
+		TesterID:      userID,
+		TesterName:    s.getUserName(userID),
+		Status:        models.ManualSessionStatusInProgress,
+		Language:      lang,
+		ResumedAt:     &now,
+		StartedAt:     now,
+	}
+	if err := s.repo.CreateSession(session, steps); err != nil {
+		return nil, err
+	}
+	fmt.Printf("[ManualExecution] 开始执行: session_id=%d, case_result_id=%d, tester=%d, steps=%d\n", session.ID, caseResultID, userID, len(steps))
+	return s.sessionDetail(session)
+}
+
+// GetSession 获取用例最近一次的执行会话
+func (s *manualExecutionService) GetSession(projectID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error) {
+	if _, _, err := s.getManualCase(projectID, taskUUID, caseResultID); err != nil {
+		return nil, err
+	}
+	session, err := s.repo.GetLatestSession(caseResultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行会话不存在")
+		}
+		return nil, fmt.Errorf("get latest session: %w", err)
+	}
+	return s.sessionDetail(session)
+}
+
+// PauseSession 暂停执行，暂停期间不计入工时
+func (s *manualExecutionService) PauseSession(projectID uint, userID uint, taskUUID string, caseResultID uint) (*ManualSessionDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.getOwnSession(projectID, userID, taskUUID, caseResultID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.ManualSessionStatusInProgress {
+		pauseSession(session, time.Now())
+		if err := s.repo.UpdateSession(session); err != nil {
+			return nil, err
+		}
+		fmt.Printf("[ManualExecution] 暂停执行: session_id=%d, effort_ms=%d\n", session.ID, session.EffortMs)
+	}
+	return s.sessionDetail(session)
+}
+
+// UpdateStep 记录步骤结果和实际结果
+func (s *manualExecutionService) UpdateStep(projectID uint, userID uint, taskUUID string, caseResultID uint, stepIndex int, req UpdateManualStepRequest) (*models.ManualStepResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.getOwnSession(projectID, userID, taskUUID, caseResultID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.ManualSessionStatusPaused {
+		return nil, errors.New("执行会话已暂停")
+	}
+
+	err = s.repo.UpdateStep(session.ID, stepIndex, map[string]interface{}{
+		"result":        req.Result,
+		"actual_result": req.ActualResult,
+		"updated_by":    userID,
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("步骤不存在")
+		}
+		return nil, err
+	}
+
+	steps, err := s.repo.GetSteps(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if step.StepIndex == stepIndex {
+			return step, nil
+		}
+	}
+	return nil, errors.New("步骤不存在")
+}
+
+// UploadEvidence 上传步骤证据，保存为用例结果的执行产物
+func (s *manualExecutionService) UploadEvidence(projectID uint, userID uint, taskUUID string, caseResultID uint, stepIndex int, file *multipart.FileHeader) (*models.ExecutionArtifact, error) {
+	if file.Size > models.MaxAttachmentSize {
+		return nil, errors.New("文件大小超出限制")
+	}
+
+	s.mu.Lock()
+	session, err := s.getOwnSession(projectID, userID, taskUUID, caseResultID)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	steps, err := s.repo.GetSteps(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	if stepIndex < 1 || stepIndex > len(steps) {
+		return nil, errors.New("步骤不存在")
+	}
+
+	fileName := unsafeFileChars.ReplaceAllString(filepath.Base(file.Filename), "_")
+	if fileName == "" || fileName == "." {
+		fileName = models.ArtifactTypeEvidence
+	}
+	relDir := filepath.Join(artifactDir(projectID, taskUUID), fmt.Sprintf("%d", caseResultID))
+	storageName := fmt.Sprintf("evidence_%d_%d_%d_%s", session.ID, stepIndex, time.Now().UnixNano(), fileName)
+	fullDir := filepath.Join(s.storagePath, relDir)
+	if err := os.MkdirAll(fullDir, 0755); err != nil {
+		return nil, fmt.Errorf("create evidence directory: %w", err)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open uploaded file: %w", err)
+	}
+	defer src.Close()
+	fullPath := filepath.Join(fullDir, storageName)
+	dst, err := os.Create(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("create evidence file: %w", err)
+	}
+	size, err := io.Copy(dst, src)
+	dst.Close()
+	if err != nil {
+		os.Remove(fullPath)
+		return nil, fmt.Errorf("copy evidence file: %w", err)
+	}
+
+	mimeType := file.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	artifact := &models.ExecutionArtifact{
+		ProjectID:    projectID,
+		TaskUUID:     taskUUID,
+		CaseResultID: caseResultID,
+		SessionID:    session.ID,
+		StepIndex:    stepIndex,
+		ArtifactType: models.ArtifactTypeEvidence,
+		FileName:     fileName,
+		FilePath:     filepath.Join(relDir, storageName),
+		FileSize:     size,
+		MimeType:     mimeType,
+	}
+	if err := s.artifactRepo.Create(artifact); err != nil {
+		os.Remove(fullPath)
+		return nil, err
+	}
+	fmt.Printf("[ManualExecution] 上传步骤证据: session_id=%d, step=%d, file=%s, size=%d\n", session.ID, stepIndex, fileName, size)
+	return artifact, nil
+}
+
+// FinishSession 完成执行：结果写回用例结果，并追加一条执行记录关联步骤证据
+func (s *manualExecutionService) FinishSession(projectID uint, userID uint, taskUUID string, caseResultID uint, req FinishManualSessionRequest) (*ManualSessionDetail, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.getOwnSession(projectID, userID, taskUUID, caseResultID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := s.repo.GetSteps(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := req.Result
+	if result == "" {
+		if result = manualSessionResult(steps); result == "" {
+			return nil, errors.New("存在未执行的步骤")
+		}
+	}
+	remark := strings.TrimSpace(req.Comment)
+	if remark == "" {
+		remark = manualStepSummary(steps)
+	}
+
+	now := time.Now()
+	if session.Status == models.ManualSessionStatusInProgress {
+		pauseSession(session, now)
+	}
+	session.Status = models.ManualSessionStatusFinished
+	session.TestResult = result
+	session.FinishedAt = &now
+
+	updates := map[string]interface{}{
+		"test_result": result,
+		"remark":      remark,
+		"updated_by":  userID,
+	}
+	if req.BugID != "" {
+		updates["bug_id"] = req.BugID
+	}
+	if err := s.ecrRepo.UpdateResult(caseResultID, updates); err != nil {
+		return nil, fmt.Errorf("update case result: %w", err)
+	}
+
+	c, err := s.ecrRepo.GetByID(caseResultID)
+	if err != nil {
+		return nil, fmt.Errorf("get case result: %w", err)
+	}
+	attempt := &models.ExecutionAttempt{
+		CaseResultID: caseResultID,
+		TaskUUID:     taskUUID,
+		CaseID:       c.CaseID,
+		TestResult:   result,
+		Output:       manualStepOutput(steps),
+		DurationMs:   int(session.EffortMs),
+		ExecutedBy:   userID,
+		ExecutorName: session.TesterName,
+		StartedAt:    session.StartedAt,
+		FinishedAt:   now,
+	}
+	if result != "OK" {
+		attempt.ErrorMessage = remark
+	}
+	if err := s.attemptRepo.Create(attempt); err != nil {
+		return nil, err
+	}
+	if err := s.artifactRepo.AssignSessionAttempt(session.ID, attempt.ID); err != nil {
+		fmt.Printf("[ManualExecution] ⚠️ 关联步骤证据失败: session_id=%d, error=%v\n", session.ID, err)
+	}
+
+	session.AttemptID = attempt.ID
+	if err := s.repo.UpdateSession(session); err != nil {
+		return nil, err
+	}
+	fmt.Printf("[ManualExecution] 完成执行: session_id=%d, case_result_id=%d, result=%s, effort_ms=%d\n", session.ID, caseResultID, result, session.EffortMs)
+	return s.sessionDetail(session)
+}
+
+// GetEffortMetrics 按测试人员和用例集汇总任务的实际工时
+func (s *manualExecutionService) GetEffortMetrics(projectID uint, taskUUID string) (*ManualEffortMetrics, error) {
+	if _, err := s.getManualTask(projectID, taskUUID); err != nil {
+		return nil, err
+	}
+	sessions, err := s.repo.GetSessionsByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, err
+	}
+	return buildManualEffortMetrics(taskUUID, sessions, time.Now()), nil
+}
+
+// buildManualEffortMetrics 汇总会话工时，进行中的会话计入到 now 为止的时长
+func buildManualEffortMetrics(taskUUID string, sessions []*models.ManualExecutionSession, now time.Time) *ManualEffortMetrics {
+	metrics := &ManualEffortMetrics{
+		TaskUUID:   taskUUID,
+		Sessions:   len(sessions),
+		Testers:    []*ManualEffortStat{},
+		CaseGroups: []*ManualEffortStat{},
+	}
+	testers := make(map[uint]*ManualEffortStat)
+	groups := make(map[string]*ManualEffortStat)
+
+	for _, session := range sessions {
+		effort := sessionEffort(session, now)
+		metrics.TotalEffortMs += effort
+		if session.Status == models.ManualSessionStatusFinished {
+			metrics.FinishedCases++
+		}
+
+		tester, ok := testers[session.TesterID]
+		if !ok {
+			tester = &ManualEffortStat{TesterID: session.TesterID, Name: session.TesterName, cases: make(map[uint]bool)}
+			testers[session.TesterID] = tester
+			metrics.Testers = append(metrics.Testers, tester)
+		}
+		group, ok := groups[session.CaseGroupName]
+		if !ok {
+			group = &ManualEffortStat{Name: session.CaseGroupName, cases: make(map[uint]bool)}
+			groups[session.CaseGroupName] = group
+			metrics.CaseGroups = append(metrics.CaseGroups, group)
+		}
+		tester.add(session, effort)
+		group.add(session, effort)
+	}
+
+	for _, list := range [][]*ManualEffortStat{metrics.Testers, metrics.CaseGroups} {
+		for _, stat := range list {
+			stat.Cases = len(stat.cases)
+			if stat.FinishedCases > 0 {
+				stat.AvgEffortMs = stat.finishedEffortMs / int64(stat.FinishedCases)
+			}
+		}
+		sort.SliceStable(list, func(i, j int) bool { return list[i].EffortMs > list[j].EffortMs })
+	}
+	return metrics
+}
+
+// add 累计一个会话
+func (st *ManualEffortStat) add(session *models.ManualExecutionSession, effort int64) {
+	st.cases[session.CaseResultID] = true
+	st.EffortMs += effort
+	if session.Status != models.ManualSessionStatusFinished {
+		return
+	}
+	st.FinishedCases++
+	st.finishedEffortMs += effort
+	switch session.TestResult {
+	case "OK":
+		st.OKCount++
+	case "NG":
+		st.NGCount++
+	case "Block":
+		st.BlockCount++
+	}
+}
+
+// pauseOtherSessions 暂停测试人员在任务中其他进行中的会话
+func (s *manualExecutionService) pauseOtherSessions(taskUUID string, userID uint, caseResultID uint, now time.Time) error {
+	active, err := s.repo.GetActiveSessionsByTester(taskUUID, userID)
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if other.CaseResultID == caseResultID {
+			continue
+		}
+		pauseSession(other, now)
+		if err := s.repo.UpdateSession(other); err != nil {
+			return err
+		}
+		fmt.Printf("[ManualExecution] 开始其他用例，自动暂停: session_id=%d\n", other.ID)
+	}
+	return nil
+}
+
+// getOwnSession 获取测试人员在该用例上未完成的会话
+func (s *manualExecutionService) getOwnSession(projectID uint, userID uint, taskUUID string, caseResultID uint) (*models.ManualExecutionSession, error) {
+	if _, _, err := s.getManualCase(projectID, taskUUID, caseResultID); err != nil {
+		return nil, err
+	}
+	session, err := s.repo.GetLatestSession(caseResultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("执行会话不存在")
+		}
+		return nil, fmt.Errorf("get latest session: %w", err)
+	}
+	if session.Status == models.ManualSessionStatusFinished {
+		return nil, errors.New("执行会话已结束")
+	}
+	if session.TesterID != userID {
+		return nil, errors.New("只能操作自己的执行会话")
+	}
+	return session, nil
+}
+
+// getManualTask 获取手工测试任务并校验项目归属
+func (s *manualExecutionService) getManualTask(projectID uint, taskUUID string) (*models.ExecutionTask, error) {
+	task, err := s.taskRepo.GetByUUID(taskUUID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("任务不存在")
+		}
+		return nil, fmt.Errorf("get task by uuid: %w", err)
+	}
+	if task.ProjectID != projectID {
+		return nil, errors.New("任务不属于该项目")
+	}
+	if task.ExecutionType != "manual" {
+		return nil, errors.New("仅手工测试任务支持手工执行")
+	}
+	return task, nil
+}
+
+// getManualCase 获取手工测试任务中的用例结果
+func (s *manualExecutionService) getManualCase(projectID uint, taskUUID string, caseResultID uint) (*models.ExecutionTask, *models.ExecutionCaseResult, error) {
+	task, err := s.getManualTask(projectID, taskUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := s.ecrRepo.GetByID(caseResultID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("用例不存在")
+		}
+		return nil, nil, fmt.Errorf("get case result: %w", err)
+	}
+	if c.TaskUUID != taskUUID {
+		return nil, nil, errors.New("用例不属于该任务")
+	}
+	return task, c, nil
+}
+
+// sessionDetail 组装会话的步骤和证据
+func (s *manualExecutionService) sessionDetail(session *models.ManualExecutionSession) (*ManualSessionDetail, error) {
+	steps, err := s.repo.GetSteps(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	evidence, err := s.artifactRepo.GetBySessionID(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	byStep := make(map[int][]*models.ExecutionArtifact)
+	for _, a := range evidence {
+		byStep[a.StepIndex] = append(byStep[a.StepIndex], a)
+	}
+
+	detail := &ManualSessionDetail{
+		ManualExecutionSession: session,
+		CurrentEffortMs:        sessionEffort(session, time.Now()),
+		Steps:                  make([]ManualStepDetail, 0, len(steps)),
+	}
+	for _, step := range steps {
+		items := byStep[step.StepIndex]
+		if items == nil {
+			items = []*models.ExecutionArtifact{}
+		}
+		detail.Steps = append(detail.Steps, ManualStepDetail{ManualStepResult: step, Evidence: items})
+	}
+	return detail, nil
+}
+
+// getUserName 获取用户显示名
+func (s *manualExecutionService) getUserName(userID uint) string {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return fmt.Sprintf("user_%d", userID)
+	}
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	return user.Username
+}
+
+// pauseSession 累计本次进行中的时长并暂停
+func pauseSession(session *models.ManualExecutionSession, now time.Time) {
+	session.EffortMs = sessionEffort(session, now)
+	session.ResumedAt = nil
+	session.Status = models.ManualSessionStatusPaused
+}
+
+// sessionEffort 会话截至 now 的实际工时
+func sessionEffort(session *models.ManualExecutionSession, now time.Time) int64 {
+	effort := session.EffortMs
+	if session.Status == models.ManualSessionStatusInProgress && session.ResumedAt != nil && now.After(*session.ResumedAt) {
+		effort += now.Sub(*session.ResumedAt).Milliseconds()
+	}
+	return effort
+}
+
+// manualStepsText 按任务显示语言选择测试步骤，该语言为空时依次回退到中文/日文/英文
+func manualStepsText(displayLanguage string, c *models.ExecutionCaseResult) (string, string) {
+	texts := map[string]string{"cn": c.TestStepsCN, "jp": c.TestStepsJP, "en": c.TestStepsEN}
+	if text := strings.TrimSpace(texts[displayLanguage]); text != "" {
+		return displayLanguage, text
+	}
+	for _, lang := range []string{"cn", "jp", "en"} {
+		if text := strings.TrimSpace(texts[lang]); text != "" {
+			return lang, text
+		}
+	}
+	return "cn", ""
+}
+
+// splitManualSteps 按行拆分测试步骤，忽略空行
+func splitManualSteps(text string) []string {
+	var steps []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			steps = append(steps, line)
+		}
+	}
+	return steps
+}
+
+// manualSessionResult 按步骤结果判定用例结果，存在未执行步骤时返回空串
+func manualSessionResult(steps []*models.ManualStepResult) string {
+	hasNG, hasBlock := false, false
+	for _, step := range steps {
+		switch step.Result {
+		case "NG":
+			hasNG = true
+		case "Block":
+			hasBlock = true
+		case "OK":
+		default:
+			return ""
+		}
+	}
+	switch {
+	case hasNG:
+		return "NG"
+	case hasBlock:
+		return "Block"
+	case len(steps) == 0:
+		return ""
+	}
+	return "OK"
+}
+
+// manualStepSummary 汇总NG/Block步骤的实际结果作为用例备注
+func manualStepSummary(steps []*models.ManualStepResult) string {
+	var lines []string
+	for _, step := range steps {
+		if step.Result != "NG" && step.Result != "Block" {
+			continue
+		}
+		line := fmt.Sprintf("步骤%d(%s)", step.StepIndex, step.Result)
+		if actual := strings.TrimSpace(step.ActualResult); actual != "" {
+			line += ": " + actual
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// manualStepOutput 生成执行记录中的逐步结果
+func manualStepOutput(steps []*models.ManualStepResult) string {
+	var lines []string
+	for _, step := range steps {
+		line := fmt.Sprintf("[%s] %d. %s", step.Result, step.StepIndex, step.StepText)
+		if actual := strings.TrimSpace(step.ActualResult); actual != "" {
+			line += " => " + actual
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
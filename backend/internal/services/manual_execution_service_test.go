@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManualExecution_Session(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.ManualExecutionSession{}, &models.ManualStepResult{})
+	task := &models.ExecutionTask{ProjectID: 1, TaskName: "manual", ExecutionType: "manual", DisplayLanguage: "en", CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	c := &models.ExecutionCaseResult{
+		TaskUUID: task.TaskUUID, CaseID: "case-1", CaseType: "overall", CaseGroupName: "login", UpdatedBy: 1,
+		TestStepsCN: "打开登录页\n输入密码", TestStepsEN: "1. Open login page\n\n2. Enter password\r\n3. Submit",
+	}
+	require.NoError(t, db.Create(c).Error)
+
+	s := NewManualExecutionService(
+		repositories.NewManualExecutionRepository(db), repositories.NewExecutionTaskRepository(db),
+		repositories.NewExecutionCaseResultRepository(db), repositories.NewExecutionAttemptRepository(db),
+		repositories.NewExecutionArtifactRepository(db), repositories.NewUserRepository(db), t.TempDir(),
+	)
+
+	detail, err := s.StartSession(1, 7, task.TaskUUID, c.ID)
+	require.NoError(t, err)
+	require.Len(t, detail.Steps, 3)
+	assert.Equal(t, "en", detail.Language)
+	assert.Equal(t, "2. Enter password", detail.Steps[1].StepText)
+
+	_, err = s.StartSession(1, 8, task.TaskUUID, c.ID)
+	assert.EqualError(t, err, "该用例正由其他测试人员执行")
+
+	_, err = s.UpdateStep(1, 7, task.TaskUUID, c.ID, 1, UpdateManualStepRequest{Result: "OK"})
+	require.NoError(t, err)
+	_, err = s.UpdateStep(1, 7, task.TaskUUID, c.ID, 4, UpdateManualStepRequest{Result: "OK"})
+	assert.EqualError(t, err, "步骤不存在")
+
+	// 暂停后不能记录步骤，恢复后继续
+	detail, err = s.PauseSession(1, 7, task.TaskUUID, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ManualSessionStatusPaused, detail.Status)
+	_, err = s.UpdateStep(1, 7, task.TaskUUID, c.ID, 2, UpdateManualStepRequest{Result: "NG"})
+	assert.EqualError(t, err, "执行会话已暂停")
+	detail, err = s.StartSession(1, 7, task.TaskUUID, c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ManualSessionStatusInProgress, detail.Status)
+
+	_, err = s.UpdateStep(1, 7, task.TaskUUID, c.ID, 2, UpdateManualStepRequest{Result: "NG", ActualResult: "密码框不可输入"})
+	require.NoError(t, err)
+	_, err = s.FinishSession(1, 7, task.TaskUUID, c.ID, FinishManualSessionRequest{})
+	assert.EqualError(t, err, "存在未执行的步骤")
+	_, err = s.UpdateStep(1, 7, task.TaskUUID, c.ID, 3, UpdateManualStepRequest{Result: "Block"})
+	require.NoError(t, err)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "screen shot.png")
+	require.NoError(t, err)
+	_, _ = part.Write([]byte("png"))
+	require.NoError(t, w.Close())
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	_, header, err := req.FormFile("file")
+	require.NoError(t, err)
+	evidence, err := s.UploadEvidence(1, 7, task.TaskUUID, c.ID, 2, header)
+	require.NoError(t, err)
+	assert.Equal(t, "screen_shot.png", evidence.FileName)
+
+	detail, err = s.FinishSession(1, 7, task.TaskUUID, c.ID, FinishManualSessionRequest{BugID: "BUG-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "NG", detail.TestResult)
+	assert.NotZero(t, detail.AttemptID)
+	require.Len(t, detail.Steps[1].Evidence, 1)
+	assert.Equal(t, detail.AttemptID, detail.Steps[1].Evidence[0].AttemptID)
+
+	var saved models.ExecutionCaseResult
+	require.NoError(t, db.First(&saved, c.ID).Error)
+	assert.Equal(t, "NG", saved.TestResult)
+	assert.Equal(t, "BUG-1", saved.BugID)
+	assert.Equal(t, "步骤2(NG): 密码框不可输入\n步骤3(Block)", saved.Remark)
+
+	_, err = s.PauseSession(1, 7, task.TaskUUID, c.ID)
+	assert.EqualError(t, err, "执行会话已结束")
+}
+
+func TestBuildManualEffortMetrics(t *testing.T) {
+	now := time.Now()
+	resumed := now.Add(-2 * time.Second)
+	sessions := []*models.ManualExecutionSession{
+		{CaseResultID: 1, CaseGroupName: "login", TesterID: 1, TesterName: "alice", Status: models.ManualSessionStatusFinished, TestResult: "OK", EffortMs: 4000},
+		{CaseResultID: 2, CaseGroupName: "login", TesterID: 1, TesterName: "alice", Status: models.ManualSessionStatusFinished, TestResult: "NG", EffortMs: 2000},
+		{CaseResultID: 3, CaseGroupName: "order", TesterID: 2, TesterName: "bob", Status: models.ManualSessionStatusInProgress, EffortMs: 1000, ResumedAt: &resumed},
+		{CaseResultID: 4, CaseGroupName: "order", TesterID: 2, TesterName: "bob", Status: models.ManualSessionStatusPaused, EffortMs: 500},
+	}
+
+	m := buildManualEffortMetrics("task-1", sessions, now)
+	assert.Equal(t, int64(9500), m.TotalEffortMs)
+	assert.Equal(t, 2, m.FinishedCases)
+	require.Len(t, m.Testers, 2)
+	alice := m.Testers[0]
+	assert.Equal(t, "alice", alice.Name)
+	assert.Equal(t, 2, alice.Cases)
+	assert.Equal(t, int64(3000), alice.AvgEffortMs)
+	assert.Equal(t, 1, alice.OKCount)
+	assert.Equal(t, 1, alice.NGCount)
+	bob := m.Testers[1]
+	assert.Equal(t, int64(3500), bob.EffortMs)
+	assert.Equal(t, 0, bob.FinishedCases)
+
+	require.Len(t, m.CaseGroups, 2)
+	assert.Equal(t, "login", m.CaseGroups[0].Name)
+	assert.Equal(t, int64(6000), m.CaseGroups[0].EffortMs)
+}
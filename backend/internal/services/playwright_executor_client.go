@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,6 +19,15 @@ type PlaywrightExecutorConfig struct {
 	ExecutorURL    string        // 执行器服务地址，默认 http://playwright-executor:3001
 	ExecuteTimeout time.Duration // 执行超时，默认 60s
 	MaxRetries     int           // 最大重试次数，默认 3
+	MaxConcurrency int           // 服务器范围内同时执行的脚本上限，默认 4
+	CaptureTrace   bool          // 是否录制 Playwright trace，默认关闭
+	CaptureVideo   bool          // 是否录制执行视频，默认关闭
+
+	// 执行器节点池
+	ExecutorURLs        []string      // 执行器节点地址列表，未设置时只使用 ExecutorURL
+	HealthInterval      time.Duration // /health 探测间隔，默认 15s
+	FailureThreshold    int           // 连续失败多少次后熔断节点，默认 3
+	CircuitOpenDuration time.Duration // 熔断持续时间，到期后放行一个试探请求，默认 30s
 }
 
 // DefaultExecutorConfig 返回默认配置
@@ -25,10 +37,49 @@ func DefaultExecutorConfig() PlaywrightExecutorConfig {
 		executorURL = "http://playwright-executor:53730"
 	}
 
+	maxConcurrency := 4
+	if v, err := strconv.Atoi(os.Getenv("PLAYWRIGHT_EXECUTOR_MAX_CONCURRENCY")); err == nil && v > 0 {
+		maxConcurrency = v
+	}
+
+	captureTrace, _ := strconv.ParseBool(os.Getenv("PLAYWRIGHT_EXECUTOR_TRACE"))
+	captureVideo, _ := strconv.ParseBool(os.Getenv("PLAYWRIGHT_EXECUTOR_VIDEO"))
+
+	// PLAYWRIGHT_EXECUTOR_URLS 以逗号分隔多个执行器节点
+	var executorURLs []string
+	for _, u := range strings.Split(os.Getenv("PLAYWRIGHT_EXECUTOR_URLS"), ",") {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			executorURLs = append(executorURLs, u)
+		}
+	}
+	if len(executorURLs) == 0 {
+		executorURLs = []string{executorURL}
+	}
+
+	healthInterval := 15 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("PLAYWRIGHT_EXECUTOR_HEALTH_INTERVAL")); err == nil && v > 0 {
+		healthInterval = time.Duration(v) * time.Second
+	}
+	failureThreshold := 3
+	if v, err := strconv.Atoi(os.Getenv("PLAYWRIGHT_EXECUTOR_FAILURE_THRESHOLD")); err == nil && v > 0 {
+		failureThreshold = v
+	}
+	circuitOpenDuration := 30 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("PLAYWRIGHT_EXECUTOR_CIRCUIT_OPEN")); err == nil && v > 0 {
+		circuitOpenDuration = time.Duration(v) * time.Second
+	}
+
 	return PlaywrightExecutorConfig{
-		ExecutorURL:    executorURL,
-		ExecuteTimeout: 60 * time.Second,
-		MaxRetries:     3,
+		ExecutorURL:         executorURL,
+		ExecuteTimeout:      60 * time.Second,
+		MaxRetries:          3,
+		MaxConcurrency:      maxConcurrency,
+		CaptureTrace:        captureTrace,
+		CaptureVideo:        captureVideo,
+		ExecutorURLs:        executorURLs,
+		HealthInterval:      healthInterval,
+		FailureThreshold:    failureThreshold,
+		CircuitOpenDuration: circuitOpenDuration,
 	}
 }
 
@@ -48,23 +99,65 @@ func NewPlaywrightExecutorClient(config PlaywrightExecutorConfig) *PlaywrightExe
 	}
 }
 
+// ArtifactOptions 需要执行器采集的产物
+type ArtifactOptions struct {
+	ScreenshotOnFailure bool `json:"screenshotOnFailure"`
+	ConsoleLogs         bool `json:"consoleLogs"`
+	Trace               bool `json:"trace"`
+	Video               bool `json:"video"`
+}
+
+// Viewport 浏览器视口尺寸
+type Viewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// BrowserOptions 执行脚本使用的浏览器设置，未设置的项使用执行器默认值
+type BrowserOptions struct {
+	Browser          string            `json:"browser,omitempty"`  // chromium/firefox/webkit
+	Headless         *bool             `json:"headless,omitempty"` // 为nil时使用执行器默认(无头)
+	Device           string            `json:"device,omitempty"`   // Playwright设备名，如 "iPhone 13"
+	Viewport         *Viewport         `json:"viewport,omitempty"`
+	Locale           string            `json:"locale,omitempty"`           // 如 "ja-JP"
+	TimezoneID       string            `json:"timezoneId,omitempty"`       // 如 "Asia/Tokyo"
+	StorageState     json.RawMessage   `json:"storageState,omitempty"`     // Playwright storageState(JSON对象: cookies/origins)
+	ExtraHTTPHeaders map[string]string `json:"extraHTTPHeaders,omitempty"` // 每个请求附加的HTTP头
+}
+
 // ExecuteRequest 执行请求
 type ExecuteRequest struct {
-	ScriptCode string `json:"scriptCode"`
-	Timeout    int    `json:"timeout,omitempty"` // 毫秒
+	ScriptCode     string           `json:"scriptCode"`
+	Timeout        int              `json:"timeout,omitempty"` // 毫秒
+	Artifacts      *ArtifactOptions `json:"artifacts,omitempty"`
+	BrowserOptions *BrowserOptions  `json:"browserOptions,omitempty"`
+}
+
+// ExecutorArtifact 执行器返回的产物(截图/trace/视频/控制台日志)，内容为base64
+type ExecutorArtifact struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
 }
 
 // ExecuteResponse 执行响应
 type ExecuteResponse struct {
-	Success      bool   `json:"success"`
-	Output       string `json:"output,omitempty"`
-	Error        string `json:"error,omitempty"`
-	Stack        string `json:"stack,omitempty"`
-	ResponseTime int    `json:"responseTime"`
+	Success      bool               `json:"success"`
+	Output       string             `json:"output,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Stack        string             `json:"stack,omitempty"`
+	ResponseTime int                `json:"responseTime"`
+	Artifacts    []ExecutorArtifact `json:"artifacts,omitempty"`
 }
 
-// Execute 执行 Playwright 脚本
+// Execute 使用执行器默认浏览器设置执行 Playwright 脚本
 func (c *PlaywrightExecutorClient) Execute(ctx context.Context, scriptCode string) (*DockerExecResult, error) {
+	return c.ExecuteWithOptions(ctx, scriptCode, nil)
+}
+
+// ExecuteWithOptions 按指定的浏览器设置执行 Playwright 脚本
+func (c *PlaywrightExecutorClient) ExecuteWithOptions(ctx context.Context, scriptCode string, browser *BrowserOptions) (*DockerExecResult, error) {
 	fmt.Printf("[ExecutorClient] 开始执行脚本，长度: %d 字节\n", len(scriptCode))
 
 	var lastErr error
@@ -75,7 +168,7 @@ func (c *PlaywrightExecutorClient) Execute(ctx context.Context, scriptCode strin
 			fmt.Printf("[ExecutorClient] 重试 %d/%d...\n", i, c.config.MaxRetries)
 		}
 
-		result, err := c.doExecute(ctx, scriptCode)
+		result, err := c.doExecute(ctx, scriptCode, browser)
 		if err == nil {
 			responseTime := int(time.Since(startTime).Milliseconds())
 			result.ResponseTime = responseTime
@@ -84,22 +177,64 @@ func (c *PlaywrightExecutorClient) Execute(ctx context.Context, scriptCode strin
 		}
 		lastErr = err
 		fmt.Printf("[ExecutorClient] 执行失败: %v\n", err)
+
+		// 调用方已取消，不再重试
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("execute cancelled: %w", ctx.Err())
+		}
+
+		// 脚本本身执行失败不属于传输错误，是否重试由任务的重试策略决定
+		var scriptErr *ScriptExecutionError
+		if errors.As(err, &scriptErr) {
+			return failedExecResult(err, int(time.Since(startTime).Milliseconds())), err
+		}
 	}
 
-	responseTime := int(time.Since(startTime).Milliseconds())
-	return &DockerExecResult{
+	failed := failedExecResult(lastErr, int(time.Since(startTime).Milliseconds()))
+	return failed, fmt.Errorf("execute failed after %d retries: %w", c.config.MaxRetries, lastErr)
+}
+
+// failedExecResult 生成执行失败的结果，脚本执行失败时保留输出/堆栈/产物
+func failedExecResult(err error, responseTime int) *DockerExecResult {
+	failed := &DockerExecResult{
 		Success:      false,
-		Output:       lastErr.Error(),
+		Output:       err.Error(),
 		ResponseTime: responseTime,
-	}, fmt.Errorf("execute failed after %d retries: %w", c.config.MaxRetries, lastErr)
+	}
+	var scriptErr *ScriptExecutionError
+	if errors.As(err, &scriptErr) {
+		failed.Output = scriptErr.Output
+		failed.Stack = scriptErr.Stack
+		failed.Artifacts = scriptErr.Artifacts
+	}
+	return failed
+}
+
+// ScriptExecutionError 执行器返回的脚本执行失败(区别于网络等传输错误)
+type ScriptExecutionError struct {
+	Message   string
+	Output    string
+	Stack     string
+	Artifacts []ExecutorArtifact
+}
+
+func (e *ScriptExecutionError) Error() string {
+	return fmt.Sprintf("script execution failed: %s", e.Message)
 }
 
 // doExecute 执行脚本的实际逻辑
-func (c *PlaywrightExecutorClient) doExecute(ctx context.Context, scriptCode string) (*DockerExecResult, error) {
+func (c *PlaywrightExecutorClient) doExecute(ctx context.Context, scriptCode string, browser *BrowserOptions) (*DockerExecResult, error) {
 	// 构造请求
 	reqBody := ExecuteRequest{
 		ScriptCode: scriptCode,
 		Timeout:    int(c.config.ExecuteTimeout.Milliseconds()),
+		Artifacts: &ArtifactOptions{
+			ScreenshotOnFailure: true,
+			ConsoleLogs:         true,
+			Trace:               c.config.CaptureTrace,
+			Video:               c.config.CaptureVideo,
+		},
+		BrowserOptions: browser,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -142,13 +277,14 @@ func (c *PlaywrightExecutorClient) doExecute(ctx context.Context, scriptCode str
 		if errorMsg == "" {
 			errorMsg = "Unknown error"
 		}
-		return nil, fmt.Errorf("script execution failed: %s", errorMsg)
+		return nil, &ScriptExecutionError{Message: errorMsg, Output: execResp.Output, Stack: execResp.Stack, Artifacts: execResp.Artifacts}
 	}
 
 	return &DockerExecResult{
 		Success:      true,
 		Output:       execResp.Output,
 		ResponseTime: execResp.ResponseTime,
+		Artifacts:    execResp.Artifacts,
 	}, nil
 }
 
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"webtest/internal/constants"
 	"webtest/internal/models"
 	"webtest/internal/repositories"
@@ -50,12 +52,19 @@ type ProjectService interface {
 	UpdateProjectMembers(projectID uint, managers []uint, members []uint, currentUserID uint) (*ProjectMembersResponse, error)
 }
 
+// ProjectRunStopper 停止项目中正在执行的批次(由执行任务服务实现)
+type ProjectRunStopper interface {
+	StopProjectRuns(projectID uint)
+}
+
 // projectService 项目服务实现
 type projectService struct {
 	projectRepo repositories.ProjectRepository
 	memberRepo  repositories.ProjectMemberRepository
 	userRepo    repositories.UserRepository
 	db          *gorm.DB
+	runStopper  ProjectRunStopper // 删除项目前停止正在执行的批次
+	storagePath string            // 存储根目录，删除项目时清理执行产物文件
 }
 
 // NewProjectService 创建项目服务实例
@@ -64,12 +73,16 @@ func NewProjectService(
 	memberRepo repositories.ProjectMemberRepository,
 	userRepo repositories.UserRepository,
 	db *gorm.DB,
+	runStopper ProjectRunStopper,
+	storagePath string,
 ) ProjectService {
 	return &projectService{
 		projectRepo: projectRepo,
 		memberRepo:  memberRepo,
 		userRepo:    userRepo,
 		db:          db,
+		runStopper:  runStopper,
+		storagePath: storagePath,
 	}
 }
 
@@ -199,8 +212,22 @@ func (s *projectService) DeleteProject(projectID uint, userID uint, role string)
 		return ErrProjectNotFound
 	}
 
-	// 3. 级联删除项目
-	return s.projectRepo.DeleteWithCascade(projectID)
+	// 3. 停止项目中正在执行的批次，避免后台协程继续写入已删除的记录
+	if s.runStopper != nil {
+		s.runStopper.StopProjectRuns(projectID)
+	}
+
+	// 4. 级联删除项目
+	if err := s.projectRepo.DeleteWithCascade(projectID); err != nil {
+		return err
+	}
+
+	// 5. 删除执行产物文件(截图、Trace、视频、手工执行证据等)
+	fullDir := filepath.Join(s.storagePath, projectArtifactDir(projectID))
+	if err := os.RemoveAll(fullDir); err != nil {
+		log.Printf("[Project Delete] remove artifact dir failed: project_id=%d, dir=%s, error=%v", projectID, fullDir, err)
+	}
+	return nil
 }
 
 // GetByID 获取项目详情并返回用户角色
@@ -185,7 +185,7 @@ func TestProjectService_GetUserProjects(t *testing.T) {
 			}
 
 			mockUserRepo := new(MockUserRepository)
-			service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, &gorm.DB{})
+			service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, &gorm.DB{}, nil, "")
 
 			projects, err := service.GetUserProjects(tt.userID, tt.role)
 
@@ -211,7 +211,7 @@ func TestProjectService_CreateProject_Success(t *testing.T) {
 	creatorID := uint(1)
 
 	// Mock ExistsByName返回false(不存在)
-	mockRepo.On("ExistsByName", projectName).Return(false, nil)
+	mockRepo.On("ExistsByName", projectName).Return(false, nil, "")
 
 	// Mock Create成功
 	mockRepo.On("Create", mock.AnythingOfType("*models.Project")).Return(nil)
@@ -258,10 +258,10 @@ func TestProjectService_CreateProject_DuplicateName(t *testing.T) {
 	creatorID := uint(1)
 
 	// Mock ExistsByName返回true(已存在)
-	mockRepo.On("ExistsByName", projectName).Return(true, nil)
+	mockRepo.On("ExistsByName", projectName).Return(true, nil, "")
 
 	mockUserRepo := new(MockUserRepository)
-	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB)
+	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB, nil, "")
 
 	project, err := service.CreateProject(projectName, description, creatorID)
 
@@ -290,7 +290,7 @@ func TestProjectService_CreateProject_ExistsByNameError(t *testing.T) {
 	mockRepo.On("ExistsByName", projectName).Return(false, errors.New("database error"))
 
 	mockUserRepo := new(MockUserRepository)
-	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB)
+	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB, nil, "")
 
 	project, err := service.CreateProject(projectName, description, creatorID)
 
@@ -345,7 +345,7 @@ func TestProjectService_IsProjectMember(t *testing.T) {
 			mockMemberRepo.On("IsMember", tt.projectID, tt.userID).Return(tt.mockResp, tt.mockError)
 
 			mockUserRepo := new(MockUserRepository)
-			service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, &gorm.DB{})
+			service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, &gorm.DB{}, nil, "")
 
 			isMember, err := service.IsProjectMember(tt.projectID, tt.userID)
 
@@ -373,7 +373,7 @@ func TestNewProjectService(t *testing.T) {
 	mockUserRepo := new(MockUserRepository)
 	mockDB := &gorm.DB{}
 
-	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB)
+	service := NewProjectService(mockRepo, mockMemberRepo, mockUserRepo, mockDB, nil, "")
 
 	assert.NotNil(t, service)
 }
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置执行器名称
+const (
+	ExecutorRemote       = "remote"        // playwright-executor HTTP 服务
+	ExecutorPlaywrightGo = "playwright-go" // 进程内 playwright-go 客户端
+	ExecutorNativeAPI    = "native-api"    // 内置 net/http 接口执行器
+	ExecutorFake         = "fake"          // 确定性的假执行器(测试/本地联调)
+)
+
+// 执行器可处理的用例类别
+const (
+	CaseKindScript = "script" // Playwright 脚本
+	CaseKindAPI    = "api"    // 结构化接口请求(URL/Method/Header/Body)
+)
+
+// executorHealthTimeout 单个执行器健康检查超时
+const executorHealthTimeout = 5 * time.Second
+
+// ExecutorRequest 发送给执行器的请求，ScriptCode 和 API 二选一
+type ExecutorRequest struct {
+	ScriptCode string
+	Browser    *BrowserOptions
+	API        *APIRequest
+}
+
+// ExecutorCapabilities 执行器能力
+type ExecutorCapabilities struct {
+	CaseKinds      []string `json:"case_kinds"`      // script/api
+	Browsers       []string `json:"browsers"`        // 支持的浏览器引擎
+	MaxConcurrency int      `json:"max_concurrency"` // 同时执行上限，0为不限
+	Artifacts      bool     `json:"artifacts"`       // 支持采集截图/trace等产物
+	BrowserOptions bool     `json:"browser_options"` // 支持设备/视口/语言等浏览器设置
+}
+
+// supports 是否可处理指定类别的用例
+func (c ExecutorCapabilities) supports(kind string) bool {
+	for _, k := range c.CaseKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ScriptExecutor 用例执行后端
+// 脚本执行失败时返回包含输出/堆栈的结果和非nil错误；接口请求的非2xx状态码不视为错误
+type ScriptExecutor interface {
+	Name() string
+	Capabilities() ExecutorCapabilities
+	HealthCheck(ctx context.Context) error
+	Execute(ctx context.Context, req ExecutorRequest) (*DockerExecResult, error)
+}
+
+// ExecutorStatus 执行器的能力和健康状态
+type ExecutorStatus struct {
+	Name         string               `json:"name"`
+	Capabilities ExecutorCapabilities `json:"capabilities"`
+	Healthy      bool                 `json:"healthy"`
+	Error        string               `json:"error,omitempty"`
+	LatencyMs    int                  `json:"latency_ms"`
+}
+
+// ExecutorRegistry 已注册的执行器及按任务类型的默认选择
+type ExecutorRegistry struct {
+	mu           sync.RWMutex
+	executors    map[string]ScriptExecutor
+	typeDefaults map[string]string // 执行类型(automation/api) -> 执行器名称
+}
+
+// NewExecutorRegistry 创建空的执行器注册表
+func NewExecutorRegistry() *ExecutorRegistry {
+	return &ExecutorRegistry{
+		executors:    make(map[string]ScriptExecutor),
+		typeDefaults: make(map[string]string),
+	}
+}
+
+// NewDefaultExecutorRegistry 注册内置执行器，remote 执行器使用传入的节点池
+// SCRIPT_EXECUTOR_AUTOMATION / SCRIPT_EXECUTOR_API 指定任务类型的默认执行器，
+// SCRIPT_EXECUTOR_FAKE=true 时注册假执行器
+func NewDefaultExecutorRegistry(pool *ExecutorPool) *ExecutorRegistry {
+	config := DefaultExecutorConfig()
+	r := NewExecutorRegistry()
+	r.Register(NewRemoteScriptExecutor(pool))
+	r.Register(NewPlaywrightGoExecutor(NewPlaywrightClient(DefaultPlaywrightConfig())))
+	r.Register(NewNativeAPIExecutor(NewAPIRunner(config.ExecuteTimeout)))
+	if os.Getenv("SCRIPT_EXECUTOR_FAKE") == "true" {
+		r.Register(NewFakeScriptExecutor())
+	}
+	for executionType, env := range map[string]string{"automation": "SCRIPT_EXECUTOR_AUTOMATION", "api": "SCRIPT_EXECUTOR_API"} {
+		if name := strings.TrimSpace(os.Getenv(env)); name != "" {
+			r.SetTypeDefault(executionType, name)
+		}
+	}
+	return r
+}
+
+// Register 注册执行器，同名执行器会被替换
+func (r *ExecutorRegistry) Register(e ScriptExecutor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[e.Name()] = e
+}
+
+// SetTypeDefault 设置执行类型的默认执行器
+func (r *ExecutorRegistry) SetTypeDefault(executionType string, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.typeDefaults[executionType] = name
+}
+
+// Get 按名称获取执行器
+func (r *ExecutorRegistry) Get(name string) (ScriptExecutor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.executors[name]
+	return e, ok
+}
+
+// List 按名称排序返回所有执行器
+func (r *ExecutorRegistry) List() []ScriptExecutor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]ScriptExecutor, 0, len(r.executors))
+	for _, e := range r.executors {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// Resolve 选择执行用例的执行器
+// 依次尝试：项目指定的执行器、任务类型的默认执行器、用例类别的内置执行器，跳过不支持该类别的执行器
+func (r *ExecutorRegistry) Resolve(projectExecutor string, executionType string, kind string) (ScriptExecutor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	kindDefault := ExecutorRemote
+	if kind == CaseKindAPI {
+		kindDefault = ExecutorNativeAPI
+	}
+	for _, name := range []string{projectExecutor, r.typeDefaults[executionType], kindDefault} {
+		if name == "" {
+			continue
+		}
+		if e, ok := r.executors[name]; ok && e.Capabilities().supports(kind) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("没有可执行%s用例的执行器", kind)
+}
+
+// Status 检查所有执行器的健康状态
+func (r *ExecutorRegistry) Status(ctx context.Context) []ExecutorStatus {
+	executors := r.List()
+	statuses := make([]ExecutorStatus, len(executors))
+	var wg sync.WaitGroup
+	for i, e := range executors {
+		wg.Add(1)
+		go func(i int, e ScriptExecutor) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, executorHealthTimeout)
+			defer cancel()
+			start := time.Now()
+			err := e.HealthCheck(checkCtx)
+			statuses[i] = ExecutorStatus{
+				Name:         e.Name(),
+				Capabilities: e.Capabilities(),
+				Healthy:      err == nil,
+				LatencyMs:    int(time.Since(start).Milliseconds()),
+			}
+			if err != nil {
+				statuses[i].Error = err.Error()
+			}
+		}(i, e)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// remoteScriptExecutor 通过 HTTP 调用 playwright-executor 服务节点池
+type remoteScriptExecutor struct {
+	pool *ExecutorPool
+}
+
+// NewRemoteScriptExecutor 创建远程执行器
+func NewRemoteScriptExecutor(pool *ExecutorPool) ScriptExecutor {
+	return &remoteScriptExecutor{pool: pool}
+}
+
+func (e *remoteScriptExecutor) Name() string { return ExecutorRemote }
+
+func (e *remoteScriptExecutor) Capabilities() ExecutorCapabilities {
+	return ExecutorCapabilities{
+		CaseKinds:      []string{CaseKindScript},
+		Browsers:       []string{"chromium", "firefox", "webkit"},
+		MaxConcurrency: e.pool.config.MaxConcurrency,
+		Artifacts:      true,
+		BrowserOptions: true,
+	}
+}
+
+// HealthCheck 探测所有节点，至少一个节点可用即为健康
+func (e *remoteScriptExecutor) HealthCheck(ctx context.Context) error {
+	return e.pool.Probe(ctx)
+}
+
+func (e *remoteScriptExecutor) Execute(ctx context.Context, req ExecutorRequest) (*DockerExecResult, error) {
+	return e.pool.Execute(ctx, req.ScriptCode, req.Browser)
+}
+
+// Pool 返回执行器节点池
+func (e *remoteScriptExecutor) Pool() *ExecutorPool {
+	return e.pool
+}
+
+// playwrightGoExecutor 进程内 playwright-go 客户端
+// 只能连接远程浏览器并验证脚本中的首个导航，不执行完整脚本，也不支持浏览器设置
+type playwrightGoExecutor struct {
+	client *PlaywrightClient
+}
+
+// NewPlaywrightGoExecutor 创建进程内执行器
+func NewPlaywrightGoExecutor(client *PlaywrightClient) ScriptExecutor {
+	return &playwrightGoExecutor{client: client}
+}
+
+func (e *playwrightGoExecutor) Name() string { return ExecutorPlaywrightGo }
+
+func (e *playwrightGoExecutor) Capabilities() ExecutorCapabilities {
+	return ExecutorCapabilities{
+		CaseKinds:      []string{CaseKindScript},
+		Browsers:       []string{"chromium"},
+		MaxConcurrency: 1,
+	}
+}
+
+// HealthCheck 启动本地 Playwright 驱动(首次调用时启动)
+func (e *playwrightGoExecutor) HealthCheck(ctx context.Context) error {
+	return e.client.ensurePlaywright()
+}
+
+func (e *playwrightGoExecutor) Execute(ctx context.Context, req ExecutorRequest) (*DockerExecResult, error) {
+	return e.client.ExecuteScript(ctx, req.ScriptCode)
+}
+
+// nativeAPIExecutor 使用内置 APIRunner 发送结构化接口请求
+type nativeAPIExecutor struct {
+	runner *APIRunner
+}
+
+// NewNativeAPIExecutor 创建接口执行器
+func NewNativeAPIExecutor(runner *APIRunner) ScriptExecutor {
+	return &nativeAPIExecutor{runner: runner}
+}
+
+func (e *nativeAPIExecutor) Name() string { return ExecutorNativeAPI }
+
+func (e *nativeAPIExecutor) Capabilities() ExecutorCapabilities {
+	return ExecutorCapabilities{CaseKinds: []string{CaseKindAPI}}
+}
+
+func (e *nativeAPIExecutor) HealthCheck(ctx context.Context) error { return nil }
+
+func (e *nativeAPIExecutor) Execute(ctx context.Context, req ExecutorRequest) (*DockerExecResult, error) {
+	if req.API == nil {
+		return nil, errors.New("native-api executor only handles api requests")
+	}
+	resp, err := e.runner.Do(ctx, *req.API)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerExecResult{
+		Success:      true,
+		Output:       fmt.Sprintf("HTTP %d", resp.StatusCode),
+		ResponseTime: resp.LatencyMs,
+		API:          resp,
+	}, nil
+}
+
+// fakeFailMarker 脚本中包含该标记时假执行器判定失败，标记后的同行文本作为错误信息
+const fakeFailMarker = "fake:fail"
+
+// FakeScriptExecutor 确定性的假执行器，不依赖浏览器和网络
+// 脚本包含 "fake:fail" 时失败，否则成功；接口请求返回 StatusCode(默认200)
+type FakeScriptExecutor struct {
+	StatusCode int
+
+	mu    sync.Mutex
+	calls []ExecutorRequest
+}
+
+// NewFakeScriptExecutor 创建假执行器
+func NewFakeScriptExecutor() *FakeScriptExecutor {
+	return &FakeScriptExecutor{StatusCode: http.StatusOK}
+}
+
+func (e *FakeScriptExecutor) Name() string { return ExecutorFake }
+
+func (e *FakeScriptExecutor) Capabilities() ExecutorCapabilities {
+	return ExecutorCapabilities{
+		CaseKinds:      []string{CaseKindScript, CaseKindAPI},
+		Browsers:       []string{"chromium", "firefox", "webkit"},
+		BrowserOptions: true,
+	}
+}
+
+func (e *FakeScriptExecutor) HealthCheck(ctx context.Context) error { return nil }
+
+// Calls 返回已收到的请求
+func (e *FakeScriptExecutor) Calls() []ExecutorRequest {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	calls := make([]ExecutorRequest, len(e.calls))
+	copy(calls, e.calls)
+	return calls
+}
+
+func (e *FakeScriptExecutor) Execute(ctx context.Context, req ExecutorRequest) (*DockerExecResult, error) {
+	e.mu.Lock()
+	e.calls = append(e.calls, req)
+	e.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if req.API != nil {
+		return &DockerExecResult{
+			Success:      true,
+			Output:       fmt.Sprintf("HTTP %d", e.StatusCode),
+			ResponseTime: 1,
+			API:          &APIResponse{StatusCode: e.StatusCode, Header: http.Header{}, Body: "{}", LatencyMs: 1},
+		}, nil
+	}
+
+	if idx := strings.Index(req.ScriptCode, fakeFailMarker); idx >= 0 {
+		msg := strings.TrimSpace(strings.SplitN(req.ScriptCode[idx+len(fakeFailMarker):], "\n", 2)[0])
+		if msg == "" {
+			msg = "fake failure"
+		}
+		return &DockerExecResult{Success: false, Output: msg, ResponseTime: 1}, &ScriptExecutionError{Message: msg, Output: msg}
+	}
+	return &DockerExecResult{Success: true, Output: "fake executed", ResponseTime: 1}, nil
+}
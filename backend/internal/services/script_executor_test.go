@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB 创建内存 sqlite 数据库并迁移执行任务相关表
+func newTestDB(t *testing.T, extra ...interface{}) *gorm.DB {
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: "file::memory:?cache=private"}, &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	tables := append([]interface{}{
+		&models.ExecutionTask{}, &models.ExecutionCaseResult{}, &models.ExecutionAttempt{}, &models.ExecutionArtifact{},
+	}, extra...)
+	require.NoError(t, db.AutoMigrate(tables...))
+	return db
+}
+
+func TestExecutorRegistry_Resolve(t *testing.T) {
+	r := NewExecutorRegistry()
+	r.Register(NewNativeAPIExecutor(NewAPIRunner(time.Second)))
+	r.Register(NewFakeScriptExecutor())
+
+	// 未注册 remote 时脚本用例没有可用执行器
+	_, err := r.Resolve("", "automation", CaseKindScript)
+	assert.EqualError(t, err, "没有可执行script用例的执行器")
+
+	// 项目指定优先
+	e, err := r.Resolve(ExecutorFake, "automation", CaseKindScript)
+	require.NoError(t, err)
+	assert.Equal(t, ExecutorFake, e.Name())
+
+	// 任务类型默认执行器
+	r.SetTypeDefault("automation", ExecutorFake)
+	e, err = r.Resolve("", "automation", CaseKindScript)
+	require.NoError(t, err)
+	assert.Equal(t, ExecutorFake, e.Name())
+
+	// 项目指定的执行器不支持该类别时回退到内置执行器
+	e, err = r.Resolve(ExecutorNativeAPI, "api", CaseKindAPI)
+	require.NoError(t, err)
+	assert.Equal(t, ExecutorNativeAPI, e.Name())
+	e, err = r.Resolve(ExecutorNativeAPI, "automation", CaseKindScript)
+	require.NoError(t, err)
+	assert.Equal(t, ExecutorFake, e.Name())
+
+	statuses := r.Status(context.Background())
+	require.Len(t, statuses, 2)
+	assert.Equal(t, ExecutorFake, statuses[0].Name)
+	assert.True(t, statuses[0].Healthy)
+}
+
+// newTestTaskService 创建只注册了给定执行器的执行任务服务
+func newTestTaskService(t *testing.T, db *gorm.DB, executor ScriptExecutor) *executionTaskService {
+	executors := NewExecutorRegistry()
+	executors.Register(executor)
+	return NewExecutionTaskService(
+		repositories.NewExecutionTaskRepository(db), repositories.NewProjectRepository(db),
+		repositories.NewExecutionCaseResultRepository(db), repositories.NewUserRepository(db),
+		repositories.NewExecutionRunRepository(db), repositories.NewExecutionScheduleRepository(db),
+		repositories.NewExecutionAttemptRepository(db),
+		repositories.NewExecutionArtifactRepository(db), repositories.NewEnvironmentProfileRepository(db),
+		repositories.NewCaseGroupRepository(db),
+		NewUserDefinedVariableService(repositories.NewUserDefinedVariableRepository(db)),
+		executors, t.TempDir(),
+	).(*executionTaskService)
+}
+
+// TestExecuteTask_FakeExecutor 使用假执行器完成一次完整的执行批次，不依赖 Node 执行器
+func TestExecuteTask_FakeExecutor(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Project{}, &models.CaseGroup{}, &models.UserDefinedVariable{},
+		&models.ExecutionRun{}, &models.EnvironmentProfile{})
+
+	project := &models.Project{Name: "demo", ScriptExecutor: ExecutorFake}
+	require.NoError(t, db.Create(project).Error)
+	task := &models.ExecutionTask{ProjectID: project.ID, TaskName: "fake run", ExecutionType: "automation", Concurrency: 1, CreatedBy: 1}
+	require.NoError(t, db.Create(task).Error)
+	for i, script := range []string{"await page.goto('/');", "// fake:fail 按钮不存在"} {
+		require.NoError(t, db.Create(&models.ExecutionCaseResult{
+			TaskUUID: task.TaskUUID, CaseID: fmt.Sprintf("case-%d", i), CaseType: "role1",
+			ScriptCode: script, UpdatedBy: 1,
+		}).Error)
+	}
+
+	fake := NewFakeScriptExecutor()
+	s := newTestTaskService(t, db, fake)
+
+	run, err := s.ExecuteTask(project.ID, 1, task.TaskUUID)
+	require.NoError(t, err)
+
+	var detail *ExecutionRunDetail
+	require.Eventually(t, func() bool {
+		detail, err = s.GetRun(project.ID, task.TaskUUID, run.RunUUID)
+		return err == nil && detail.Status == models.ExecutionRunStatusFinished
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 2, detail.Total)
+	assert.Equal(t, 1, detail.OKCount)
+	assert.Equal(t, 1, detail.NGCount)
+	assert.Len(t, fake.Calls(), 2)
+}
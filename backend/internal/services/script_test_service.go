@@ -24,6 +24,8 @@ type ScriptTestResult struct {
 	ErrorMessage string    `json:"error_message,omitempty"`
 	ResponseTime int       `json:"response_time"` // 毫秒
 	ExecutedAt   time.Time `json:"executed_at"`
+
+	UndefinedVariables []string `json:"undefined_variables,omitempty"` // 执行前检查出的未定义变量
 }
 
 // ScriptTestService 脚本测试服务接口
@@ -77,7 +79,19 @@ func (s *scriptTestService) TestScript(projectID uint, userID uint, req ScriptTe
 		fmt.Printf("[ScriptTest] 跳过变量获取 (groupID=%d, groupType=%s)\n", req.GroupID, req.GroupType)
 	}
 
-	// 3. 替换脚本中的变量
+	// 3. 执行前检查变量，存在未定义变量时不发送给执行器
+	lint := lintVariables([]lintCaseInput{{Text: req.ScriptCode}}, variables)
+	if !lint.Valid {
+		fmt.Printf("[ScriptTest] ❌ 脚本引用了未定义的变量: %v\n", lint.Undefined)
+		return &ScriptTestResult{
+			Success:            false,
+			ErrorMessage:       fmt.Sprintf("脚本引用了未定义的变量: %s\n请检查变量表配置。", strings.Join(lint.Undefined, ", ")),
+			ExecutedAt:         time.Now(),
+			UndefinedVariables: lint.Undefined,
+		}, nil
+	}
+
+	// 4. 替换脚本中的变量
 	replacedScript := s.replaceVariables(req.ScriptCode, variables)
 
 	// 🔍 调试日志：检查变量替换效果
@@ -98,7 +112,7 @@ func (s *scriptTestService) TestScript(projectID uint, userID uint, req ScriptTe
 		fmt.Printf("[ScriptTest] ✅ 变量替换成功\n")
 	}
 
-	// 4. 执行脚本
+	// 5. 执行脚本
 	fmt.Printf("[ScriptTest] 开始执行 Playwright 脚本...\n")
 	ctx := context.Background()
 	execResult, execErr := s.pwClient.Execute(ctx, replacedScript)
@@ -162,6 +176,16 @@ func (s *scriptTestService) replaceVariables(script string, variables []*models.
 	result := script
 	replacedCount := 0
 	for _, v := range variables {
+		// 0. 原样格式 "${baseUrl}"
+		if v.VarKey != "" {
+			placeholder := fmt.Sprintf("${%s}", v.VarKey)
+			if strings.Contains(result, placeholder) {
+				result = strings.ReplaceAll(result, placeholder, v.VarValue)
+				replacedCount++
+				fmt.Printf("[ScriptTest] 替换变量: ${%s} -> %s\n", v.VarKey, maskSensitive(v.VarKey, v.VarValue))
+			}
+		}
+
 		// 1. 使用 VarKey 替换大写格式 "${BASE_URL}"
 		if v.VarKey != "" {
 			upperKey := strings.ToUpper(v.VarKey)
@@ -0,0 +1,361 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"webtest/internal/models"
+	"webtest/internal/repositories"
+)
+
+// placeholderPattern 脚本中的变量占位符 ${VAR}
+var placeholderPattern = regexp.MustCompile(`\$\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}`)
+
+// 脚本内声明的局部变量(模板字符串 `${i}` 引用的是JS变量而非用户变量)
+var (
+	localDeclPattern   = regexp.MustCompile(`\b(?:const|let|var)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	funcParamsPattern  = regexp.MustCompile(`\bfunction\b[^(]*\(([^)]*)\)`)
+	arrowParamsPattern = regexp.MustCompile(`\(([^()]*)\)\s*=>`)
+	arrowParamPattern  = regexp.MustCompile(`\b([A-Za-z_$][A-Za-z0-9_$]*)\s*=>`)
+	identPattern       = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+)
+
+// VariableLintCase 单条用例的变量检查结果
+type VariableLintCase struct {
+	CaseResultID uint     `json:"case_result_id,omitempty"` // 执行任务中的用例结果ID
+	CaseID       string   `json:"case_id"`
+	DisplayID    uint     `json:"display_id"`
+	CaseNum      string   `json:"case_num"`
+	Placeholders []string `json:"placeholders"` // 引用的变量
+	Undefined    []string `json:"undefined"`    // 未定义的变量
+}
+
+// VariableLintReport 用例集/执行任务的变量检查报告
+type VariableLintReport struct {
+	Valid     bool               `json:"valid"`     // 没有未定义变量
+	Defined   []string           `json:"defined"`   // 变量表中定义的变量
+	Used      []string           `json:"used"`      // 脚本中引用的变量
+	Undefined []string           `json:"undefined"` // 引用但未定义的变量
+	Unused    []string           `json:"unused"`    // 定义但未被引用的变量
+	Cases     []VariableLintCase `json:"cases"`     // 引用了变量的用例
+}
+
+// lintCaseInput 变量检查的用例输入，Text 为需要替换变量的全部文本
+type lintCaseInput struct {
+	CaseResultID uint
+	CaseID       string
+	DisplayID    uint
+	CaseNum      string
+	Text         string
+	Extracts     []string // 该用例提取的批次变量，对后续用例视为已定义
+}
+
+// scanPlaceholders 按出现顺序返回文本中引用的变量名(去重)，排除脚本内声明的局部变量
+func scanPlaceholders(text string) []string {
+	matches := placeholderPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	locals := scriptLocals(text)
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range matches {
+		name := m[1]
+		if seen[name] || locals[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// scriptLocals 收集脚本中声明的变量和函数参数名
+func scriptLocals(text string) map[string]bool {
+	locals := make(map[string]bool)
+	for _, m := range localDeclPattern.FindAllStringSubmatch(text, -1) {
+		locals[m[1]] = true
+	}
+	for _, re := range []*regexp.Regexp{funcParamsPattern, arrowParamsPattern} {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			for _, param := range strings.Split(m[1], ",") {
+				// 去掉默认值，只取参数名
+				param = strings.TrimSpace(strings.SplitN(param, "=", 2)[0])
+				if ident := identPattern.FindString(param); ident != "" {
+					locals[ident] = true
+				}
+			}
+		}
+	}
+	for _, m := range arrowParamPattern.FindAllStringSubmatch(text, -1) {
+		locals[m[1]] = true
+	}
+	return locals
+}
+
+// resolveVariableKey 按 replaceVariables 的匹配规则(原样/大写/小写)查找占位符对应的变量Key
+func resolveVariableKey(name string, variables []*models.UserDefinedVariable) (string, bool) {
+	for _, v := range variables {
+		if v.VarKey == "" {
+			continue
+		}
+		if name == v.VarKey || name == strings.ToUpper(v.VarKey) || name == strings.ToLower(v.VarKey) {
+			return v.VarKey, true
+		}
+	}
+	return "", false
+}
+
+// lintVariables 检查用例引用的变量是否都已定义
+// 用例按执行顺序传入，前序用例提取的批次变量对后续用例视为已定义
+func lintVariables(inputs []lintCaseInput, variables []*models.UserDefinedVariable) *VariableLintReport {
+	report := &VariableLintReport{
+		Defined:   []string{},
+		Used:      []string{},
+		Undefined: []string{},
+		Unused:    []string{},
+		Cases:     []VariableLintCase{},
+	}
+
+	usedKeys := make(map[string]bool)
+	usedSet := make(map[string]bool)
+	undefinedSet := make(map[string]bool)
+	extracted := make(map[string]bool)
+
+	for _, in := range inputs {
+		names := scanPlaceholders(in.Text)
+		lc := VariableLintCase{
+			CaseResultID: in.CaseResultID,
+			CaseID:       in.CaseID,
+			DisplayID:    in.DisplayID,
+			CaseNum:      in.CaseNum,
+			Placeholders: names,
+			Undefined:    []string{},
+		}
+		for _, name := range names {
+			if !usedSet[name] {
+				usedSet[name] = true
+				report.Used = append(report.Used, name)
+			}
+			if key, ok := resolveVariableKey(name, variables); ok {
+				usedKeys[key] = true
+				continue
+			}
+			if extracted[name] {
+				continue
+			}
+			lc.Undefined = append(lc.Undefined, name)
+			if !undefinedSet[name] {
+				undefinedSet[name] = true
+				report.Undefined = append(report.Undefined, name)
+			}
+		}
+		if len(names) > 0 {
+			report.Cases = append(report.Cases, lc)
+		}
+		for _, name := range in.Extracts {
+			extracted[name] = true
+		}
+	}
+
+	// 兼容旧格式：通过 VarName 或 {{key}} 引用的变量同样视为已使用
+	for _, v := range variables {
+		if v.VarKey == "" {
+			continue
+		}
+		report.Defined = append(report.Defined, v.VarKey)
+		if usedKeys[v.VarKey] {
+			continue
+		}
+		legacy := false
+		for _, in := range inputs {
+			if strings.Contains(in.Text, fmt.Sprintf("{{%s}}", v.VarKey)) ||
+				(v.VarName != "" && !strings.HasPrefix(v.VarName, "${") && strings.Contains(in.Text, v.VarName)) {
+				legacy = true
+				break
+			}
+		}
+		if !legacy {
+			report.Unused = append(report.Unused, v.VarKey)
+		}
+	}
+	sort.Strings(report.Defined)
+	sort.Strings(report.Unused)
+
+	report.Valid = len(report.Undefined) == 0
+	return report
+}
+
+// caseResultLintText 执行用例中需要变量替换的文本
+func caseResultLintText(task *models.ExecutionTask, c *models.ExecutionCaseResult) string {
+	if task.ExecutionType == "api" && strings.TrimSpace(c.URL) != "" {
+		return strings.Join([]string{c.URL, c.Header, c.Body}, "\n")
+	}
+	return c.ScriptCode
+}
+
+// extractionVars 用例提取规则中声明的变量名
+func extractionVars(text string) []string {
+	extractions, err := ParseAPIExtractions(text)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(extractions))
+	for _, e := range extractions {
+		names = append(names, e.Var)
+	}
+	return names
+}
+
+// caseResultLintInputs 将执行任务的用例结果转换为变量检查输入
+func caseResultLintInputs(task *models.ExecutionTask, cases []*models.ExecutionCaseResult) []lintCaseInput {
+	inputs := make([]lintCaseInput, 0, len(cases))
+	for _, c := range cases {
+		in := lintCaseInput{
+			CaseResultID: c.ID,
+			CaseID:       c.CaseID,
+			DisplayID:    c.DisplayID,
+			CaseNum:      c.CaseNum,
+			Text:         caseResultLintText(task, c),
+		}
+		if task.ExecutionType == "api" {
+			in.Extracts = extractionVars(c.Extractions)
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs
+}
+
+// VariableLintService 执行前变量检查服务
+type VariableLintService interface {
+	// LintTask 检查执行任务中所有用例引用的变量
+	LintTask(projectID uint, taskUUID string) (*VariableLintReport, error)
+	// LintCaseGroup 检查用例集中所有用例引用的变量
+	LintCaseGroup(projectID uint, groupID uint, groupType string) (*VariableLintReport, error)
+}
+
+type variableLintService struct {
+	taskRepo        repositories.ExecutionTaskRepository
+	ecrRepo         repositories.ExecutionCaseResultRepository
+	caseGroupRepo   *repositories.CaseGroupRepository
+	autoRepo        repositories.AutoTestCaseRepository
+	apiRepo         repositories.ApiTestCaseRepository
+	variableService UserDefinedVariableService
+}
+
+// NewVariableLintService 创建变量检查服务实例
+func NewVariableLintService(
+	taskRepo repositories.ExecutionTaskRepository,
+	ecrRepo repositories.ExecutionCaseResultRepository,
+	caseGroupRepo *repositories.CaseGroupRepository,
+	autoRepo repositories.AutoTestCaseRepository,
+	apiRepo repositories.ApiTestCaseRepository,
+	variableService UserDefinedVariableService,
+) VariableLintService {
+	return &variableLintService{
+		taskRepo:        taskRepo,
+		ecrRepo:         ecrRepo,
+		caseGroupRepo:   caseGroupRepo,
+		autoRepo:        autoRepo,
+		apiRepo:         apiRepo,
+		variableService: variableService,
+	}
+}
+
+// LintTask 检查执行任务中所有用例引用的变量(使用任务变量表)
+func (s *variableLintService) LintTask(projectID uint, taskUUID string) (*VariableLintReport, error) {
+	task, err := s.taskRepo.GetByUUID(taskUUID)
+	if err != nil {
+		return nil, errors.New("任务不存在")
+	}
+	if task.ProjectID != projectID {
+		return nil, errors.New("任务不属于该项目")
+	}
+
+	cases, err := s.ecrRepo.GetByTaskUUID(taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get case results: %w", err)
+	}
+
+	var variables []*models.UserDefinedVariable
+	if task.CaseGroupID > 0 {
+		groupType := "web"
+		if task.ExecutionType == "api" {
+			groupType = "api"
+		}
+		variables, err = s.variableService.GetVariablesByTask(taskUUID, task.CaseGroupID, groupType)
+		if err != nil {
+			return nil, fmt.Errorf("get task variables: %w", err)
+		}
+	}
+
+	return lintVariables(caseResultLintInputs(task, cases), variables), nil
+}
+
+// LintCaseGroup 检查用例集中所有用例引用的变量(使用用例集变量表)
+func (s *variableLintService) LintCaseGroup(projectID uint, groupID uint, groupType string) (*VariableLintReport, error) {
+	group, err := s.caseGroupRepo.GetByID(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get case group: %w", err)
+	}
+	if group == nil {
+		return nil, errors.New("用例集不存在")
+	}
+	if group.ProjectID != projectID {
+		return nil, errors.New("用例集不属于该项目")
+	}
+
+	variables, err := s.variableService.GetVariablesByGroup(groupID, groupType)
+	if err != nil {
+		return nil, fmt.Errorf("get group variables: %w", err)
+	}
+
+	var inputs []lintCaseInput
+	if groupType == "api" {
+		cases, err := s.apiRepo.GetByProjectAndType(projectID, "api")
+		if err != nil {
+			return nil, fmt.Errorf("get api cases: %w", err)
+		}
+		displayID := uint(0)
+		for _, c := range cases {
+			if c.CaseGroup != group.GroupName {
+				continue
+			}
+			displayID++
+			text := c.ScriptCode
+			if strings.TrimSpace(c.URL) != "" {
+				text = strings.Join([]string{c.URL, c.Header, c.Body}, "\n")
+			}
+			inputs = append(inputs, lintCaseInput{
+				CaseID:    c.ID,
+				DisplayID: displayID,
+				CaseNum:   c.CaseNumber,
+				Text:      text,
+				Extracts:  extractionVars(c.Extractions),
+			})
+		}
+	} else {
+		for _, caseType := range []string{"role1", "role2", "role3", "role4", "web"} {
+			cases, err := s.autoRepo.GetByProjectAndType(projectID, caseType)
+			if err != nil {
+				return nil, fmt.Errorf("get %s cases: %w", caseType, err)
+			}
+			for _, c := range cases {
+				if c.CaseGroup != group.GroupName {
+					continue
+				}
+				inputs = append(inputs, lintCaseInput{
+					CaseID:    c.CaseID,
+					DisplayID: c.ID,
+					CaseNum:   c.CaseNumber,
+					Text:      c.ScriptCode,
+				})
+			}
+		}
+	}
+
+	return lintVariables(inputs, variables), nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"webtest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestScanPlaceholders_IgnoresScriptLocals 模板字符串中引用的JS局部变量不视为用户变量
+func TestScanPlaceholders_IgnoresScriptLocals(t *testing.T) {
+	script := "const url = `${BASE_URL}/login`;\n" +
+		"for (let i = 0; i < 3; i++) { console.log(`${i} ${USER_NAME}`); }\n" +
+		"items.forEach((item, idx) => log(`${item}-${idx}`));\n" +
+		"await page.fill('#pwd', '${password}');\n" +
+		"console.log(`${url} ${BASE_URL}`);"
+
+	assert.Equal(t, []string{"BASE_URL", "USER_NAME", "password"}, scanPlaceholders(script))
+}
+
+// TestLintVariables 列出未定义/未使用的变量，前序用例提取的变量对后续用例可用
+func TestLintVariables(t *testing.T) {
+	variables := []*models.UserDefinedVariable{
+		{VarKey: "base_url", VarValue: "http://localhost"},
+		{VarKey: "PASSWORD", VarValue: "secret"},
+		{VarKey: "UNUSED_KEY", VarValue: "x"},
+		{VarKey: "legacy", VarValue: "y"},
+	}
+	inputs := []lintCaseInput{
+		{CaseResultID: 1, DisplayID: 1, Text: "${BASE_URL}/login\n{\"pwd\":\"${password}\"}", Extracts: []string{"token"}},
+		{CaseResultID: 2, DisplayID: 2, Text: "${BASE_URL}/orders\nAuthorization: Bearer ${token}\n{{legacy}}"},
+		{CaseResultID: 3, DisplayID: 3, Text: "${BASE_URL}/users/${USER_ID}"},
+		{CaseResultID: 4, DisplayID: 4, Text: "no placeholders"},
+	}
+
+	report := lintVariables(inputs, variables)
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{"BASE_URL", "password", "token", "USER_ID"}, report.Used)
+	assert.Equal(t, []string{"USER_ID"}, report.Undefined)
+	assert.Equal(t, []string{"UNUSED_KEY"}, report.Unused)
+	assert.Len(t, report.Cases, 3)
+	assert.Empty(t, report.Cases[1].Undefined)
+	assert.Equal(t, []string{"USER_ID"}, report.Cases[2].Undefined)
+
+	// 提取变量只对后续用例生效
+	report = lintVariables([]lintCaseInput{
+		{CaseResultID: 1, Text: "${token}"},
+		{CaseResultID: 2, Text: "${BASE_URL}", Extracts: []string{"token"}},
+	}, variables)
+	assert.Equal(t, []string{"token"}, report.Undefined)
+}
@@ -35,6 +35,14 @@ func ResponseError(c *gin.Context, httpCode int, message string) {
 	})
 }
 
+// ResponseErrorWithCode 携带业务错误码的错误响应(错误码见 constants/error_codes.go)
+func ResponseErrorWithCode(c *gin.Context, httpCode int, code int, message string) {
+	c.JSON(httpCode, Response{
+		Code:    code,
+		Message: message,
+	})
+}
+
 // SuccessResponse 成功响应别名
 func SuccessResponse(c *gin.Context, data interface{}) {
 	ResponseSuccess(c, data)
@@ -1,5 +1,8 @@
 const express = require('express');
-const { chromium } = require('playwright');
+const fs = require('fs');
+const os = require('os');
+const path = require('path');
+const playwright = require('playwright');
 
 const app = express();
 const PORT = process.env.PORT || 53730;
@@ -7,6 +10,64 @@ const PLAYWRIGHT_WS = process.env.PLAYWRIGHT_WS || 'ws://playwright-runner:53729
 
 app.use(express.json({ limit: '10mb' }));
 
+// 单个产物的大小上限（超过则丢弃，避免响应体过大）
+const MAX_ARTIFACT_BYTES = 20 * 1024 * 1024;
+
+// 读取文件为 base64 产物
+function fileArtifact(type, filePath, name, mimeType) {
+  const stat = fs.statSync(filePath);
+  if (stat.size > MAX_ARTIFACT_BYTES) {
+    console.warn(`[Executor] 产物过大已丢弃: ${name} (${stat.size} bytes)`);
+    return null;
+  }
+  return { type, name, mimeType, data: fs.readFileSync(filePath).toString('base64') };
+}
+
+// 支持的浏览器引擎
+const BROWSER_TYPES = ['chromium', 'firefox', 'webkit'];
+
+// 根据 browserOptions 生成浏览器类型、连接选项和上下文选项（设备仿真 < 显式视口）
+function resolveBrowserOptions(browserOptions = {}) {
+  const browserName = browserOptions.browser || 'chromium';
+  if (!BROWSER_TYPES.includes(browserName)) {
+    throw new Error(`Unsupported browser: ${browserName}`);
+  }
+  const connectOptions = {};
+  if (typeof browserOptions.headless === 'boolean') {
+    // 由 Playwright Server 按该启动参数启动浏览器
+    connectOptions.headers = {
+      'x-playwright-launch-options': JSON.stringify({ headless: browserOptions.headless })
+    };
+  }
+  const contextOptions = {};
+  if (browserOptions.device) {
+    const device = playwright.devices[browserOptions.device];
+    if (!device) {
+      throw new Error(`Unknown device: ${browserOptions.device}`);
+    }
+    Object.assign(contextOptions, device);
+  }
+  if (browserOptions.viewport && browserOptions.viewport.width > 0 && browserOptions.viewport.height > 0) {
+    contextOptions.viewport = {
+      width: browserOptions.viewport.width,
+      height: browserOptions.viewport.height
+    };
+  }
+  if (browserOptions.locale) {
+    contextOptions.locale = browserOptions.locale;
+  }
+  if (browserOptions.timezoneId) {
+    contextOptions.timezoneId = browserOptions.timezoneId;
+  }
+  if (browserOptions.storageState) {
+    contextOptions.storageState = browserOptions.storageState;
+  }
+  if (browserOptions.extraHTTPHeaders && Object.keys(browserOptions.extraHTTPHeaders).length > 0) {
+    contextOptions.extraHTTPHeaders = browserOptions.extraHTTPHeaders;
+  }
+  return { browserName, connectOptions, contextOptions };
+}
+
 // 健康检查
 app.get('/health', (req, res) => {
   res.json({ 
@@ -17,7 +78,7 @@ app.get('/health', (req, res) => {
 
 // 执行脚本
 app.post('/execute', async (req, res) => {
-  const { scriptCode, timeout = 60000 } = req.body;
+  const { scriptCode, timeout = 60000, artifacts: artifactOptions = {}, browserOptions = {} } = req.body;
   
   if (!scriptCode) {
     return res.status(400).json({ 
@@ -35,16 +96,41 @@ app.post('/execute', async (req, res) => {
   let context = null;
   let page = null;
 
+  // 产物采集：失败截图、trace、视频、控制台日志
+  const artifacts = [];
+  const consoleLines = [];
+  const tmpDir = fs.mkdtempSync(path.join(os.tmpdir(), 'executor-'));
+  let tracing = false;
+  let failed = false;
+
   try {
     // 连接到远程 Playwright Server
-    console.log('[Executor] 连接到 Playwright Server...');
-    browser = await chromium.connect(PLAYWRIGHT_WS, { timeout });
+    const { browserName, connectOptions, contextOptions: emulation } = resolveBrowserOptions(browserOptions);
+    console.log(`[Executor] 连接到 Playwright Server... (browser=${browserName})`);
+    browser = await playwright[browserName].connect(PLAYWRIGHT_WS, { ...connectOptions, timeout });
     
     // 创建浏览器上下文和页面（忽略 HTTPS 证书错误）
-    context = await browser.newContext({
+    const contextOptions = {
+      ...emulation,
       ignoreHTTPSErrors: true  // 跳过自签名证书验证
-    });
+    };
+    if (artifactOptions.video) {
+      contextOptions.recordVideo = { dir: tmpDir };
+    }
+    context = await browser.newContext(contextOptions);
+    if (artifactOptions.trace) {
+      await context.tracing.start({ screenshots: true, snapshots: true });
+      tracing = true;
+    }
     page = await context.newPage();
+    if (artifactOptions.consoleLogs) {
+      page.on('console', (msg) => {
+        consoleLines.push(`[${new Date().toISOString()}] [${msg.type()}] ${msg.text()}`);
+      });
+      page.on('pageerror', (err) => {
+        consoleLines.push(`[${new Date().toISOString()}] [pageerror] ${err.message}`);
+      });
+    }
     
     console.log('[Executor] 开始执行脚本...');
     
@@ -67,33 +153,74 @@ app.post('/execute', async (req, res) => {
     const responseTime = Date.now() - startTime;
     console.log('[Executor] 执行成功，耗时:', responseTime, 'ms');
     
+    await collectArtifacts();
     res.json({
       success: true,
       output: result !== undefined ? JSON.stringify(result) : 'Script executed successfully',
-      responseTime
+      responseTime,
+      artifacts
     });
     
   } catch (error) {
     const responseTime = Date.now() - startTime;
     console.error('[Executor] 执行失败:', error.message);
     console.error('[Executor] 错误堆栈:', error.stack);
-    
+    failed = true;
+
+    await collectArtifacts();
     res.json({
       success: false,
       error: error.message,
       stack: error.stack,
-      responseTime
+      responseTime,
+      artifacts
     });
     
   } finally {
     // 清理资源
     try {
-      if (page) await page.close();
+      if (page && !page.isClosed()) await page.close();
       if (context) await context.close();
       if (browser) await browser.close();
     } catch (cleanupError) {
       console.error('[Executor] 清理资源失败:', cleanupError.message);
     }
+    fs.rmSync(tmpDir, { recursive: true, force: true });
+  }
+
+  // 收集产物（需在关闭浏览器前调用），采集失败不影响执行结果
+  async function collectArtifacts() {
+    try {
+      if (failed && artifactOptions.screenshotOnFailure && page && !page.isClosed()) {
+        const screenshot = await page.screenshot({ fullPage: true });
+        artifacts.push({ type: 'screenshot', name: 'failure.png', mimeType: 'image/png', data: screenshot.toString('base64') });
+      }
+      if (tracing) {
+        const tracePath = path.join(tmpDir, 'trace.zip');
+        await context.tracing.stop({ path: tracePath });
+        tracing = false;
+        const trace = fileArtifact('trace', tracePath, 'trace.zip', 'application/zip');
+        if (trace) artifacts.push(trace);
+      }
+      if (artifactOptions.video && page && page.video()) {
+        const video = page.video();
+        await page.close();
+        const videoPath = path.join(tmpDir, 'video.webm');
+        await video.saveAs(videoPath);
+        const recorded = fileArtifact('video', videoPath, 'video.webm', 'video/webm');
+        if (recorded) artifacts.push(recorded);
+      }
+      if (consoleLines.length > 0) {
+        artifacts.push({
+          type: 'console_log',
+          name: 'console.log',
+          mimeType: 'text/plain',
+          data: Buffer.from(consoleLines.join('\n'), 'utf8').toString('base64')
+        });
+      }
+    } catch (artifactError) {
+      console.error('[Executor] 采集产物失败:', artifactError.message);
+    }
   }
 });
 